@@ -0,0 +1,232 @@
+// Package aging builds accounts-receivable aging reports: the amounts still
+// due on finalized invoices, by customer and currency, bucketed by the
+// number of days they are past due.
+package aging
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+const dateLayout = "2006-01-02"
+
+// Bucket is a range of days past due.
+type Bucket int
+
+const (
+	// Current amounts are not due yet, or due today.
+	Current Bucket = iota
+	// Days1To30 to Over90 are ranges of days past the due date.
+	Days1To30
+	Days31To60
+	Days61To90
+	Over90
+)
+
+// Buckets lists the buckets in order.
+var Buckets = []Bucket{Current, Days1To30, Days31To60, Days61To90, Over90}
+
+var bucketNames = []string{"current", "1-30", "31-60", "61-90", "90+"}
+
+// BucketOf returns the bucket of an amount daysPastDue days past due.
+func BucketOf(daysPastDue int) Bucket {
+	switch {
+	case daysPastDue <= 0:
+		return Current
+	case daysPastDue <= 30:
+		return Days1To30
+	case daysPastDue <= 60:
+		return Days31To60
+	case daysPastDue <= 90:
+		return Days61To90
+	default:
+		return Over90
+	}
+}
+
+// String returns the name of the bucket: "current", "1-30", "31-60",
+// "61-90" or "90+".
+func (b Bucket) String() string {
+	if b < Current || b > Over90 {
+		return fmt.Sprintf("Bucket(%d)", int(b))
+	}
+	return bucketNames[b]
+}
+
+// MarshalText encodes the bucket by its name.
+func (b Bucket) MarshalText() ([]byte, error) {
+	if b < Current || b > Over90 {
+		return nil, fmt.Errorf("aging: invalid bucket %d", int(b))
+	}
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText decodes a bucket name.
+func (b *Bucket) UnmarshalText(text []byte) error {
+	for i, name := range bucketNames {
+		if name == string(text) {
+			*b = Bucket(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("aging: invalid bucket %q", text)
+}
+
+// Item is an invoice with an amount still due.
+type Item struct {
+	LagoInvoiceID      uuid.UUID     `json:"lago_invoice_id"`
+	Number             string        `json:"number"`
+	ExternalCustomerID string        `json:"external_customer_id"`
+	Currency           lago.Currency `json:"currency"`
+	IssuingDate        string        `json:"issuing_date"`
+	DueDate            string        `json:"due_date"`
+	DaysPastDue        int           `json:"days_past_due"`
+	Bucket             Bucket        `json:"bucket"`
+	TotalCents         int64         `json:"total_cents"`
+	// OutstandingCents is the total due by Lago, which accounts for
+	// partial payments, less the credit notes offsetting the invoice.
+	OutstandingCents int64 `json:"outstanding_cents"`
+}
+
+// Row is the amount due by a customer in a currency, by bucket.
+type Row struct {
+	ExternalCustomerID string           `json:"external_customer_id,omitempty"`
+	Currency           lago.Currency    `json:"currency"`
+	AmountsCents       map[Bucket]int64 `json:"amounts_cents"`
+	TotalCents         int64            `json:"total_cents"`
+}
+
+func (r *Row) add(bucket Bucket, cents int64) {
+	r.AmountsCents[bucket] += cents
+	r.TotalCents += cents
+}
+
+// Report is the aging of the receivables at a date.
+type Report struct {
+	AsOf string `json:"as_of"`
+	// Items are sorted by customer, currency and due date.
+	Items []Item `json:"items"`
+	// Rows are sorted by customer and currency.
+	Rows []Row `json:"rows"`
+	// Totals are the rows of every currency, for all customers.
+	Totals []Row `json:"totals"`
+}
+
+// Build ages the finalized invoices that are not paid yet at asOf.
+//
+// The due date of an invoice is its payment due date when Lago returns one,
+// or its issuing date plus its net payment term, falling back to the net
+// payment term of its customer. Credit notes are matched with their invoice
+// by Lago ID and their offset amounts deducted from the amount due.
+func Build(invoices []lago.Invoice, creditNotes []lago.CreditNote, asOf time.Time) (*Report, error) {
+	offsets := map[uuid.UUID]int64{}
+	for _, creditNote := range creditNotes {
+		offsets[creditNote.LagoInvoiceID] += int64(creditNote.OffsetAmountCents)
+	}
+
+	y, m, d := asOf.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	report := &Report{AsOf: today.Format(dateLayout), Items: []Item{}, Rows: []Row{}, Totals: []Row{}}
+
+	for i := range invoices {
+		invoice := &invoices[i]
+		if invoice.Status != lago.InvoiceStatusFinalized || invoice.PaymentStatus == lago.InvoicePaymentStatusSucceeded {
+			continue
+		}
+		outstanding := int64(invoice.TotalDueAmountCents) - offsets[invoice.LagoID]
+		if outstanding <= 0 {
+			continue
+		}
+
+		due, err := dueDate(invoice)
+		if err != nil {
+			return nil, err
+		}
+		days := int(today.Sub(due).Hours() / 24)
+
+		item := Item{
+			LagoInvoiceID:    invoice.LagoID,
+			Number:           invoice.Number,
+			Currency:         invoice.Currency,
+			IssuingDate:      invoice.IssuingDate,
+			DueDate:          due.Format(dateLayout),
+			DaysPastDue:      max(days, 0),
+			Bucket:           BucketOf(days),
+			TotalCents:       int64(invoice.TotalAmountCents),
+			OutstandingCents: outstanding,
+		}
+		if invoice.Customer != nil {
+			item.ExternalCustomerID = invoice.Customer.ExternalID
+		}
+		report.Items = append(report.Items, item)
+	}
+
+	sort.SliceStable(report.Items, func(i, j int) bool {
+		a, b := report.Items[i], report.Items[j]
+		if a.ExternalCustomerID != b.ExternalCustomerID {
+			return a.ExternalCustomerID < b.ExternalCustomerID
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.DueDate < b.DueDate
+	})
+
+	totals := map[lago.Currency]*Row{}
+	for _, item := range report.Items {
+		n := len(report.Rows)
+		if n == 0 || report.Rows[n-1].ExternalCustomerID != item.ExternalCustomerID || report.Rows[n-1].Currency != item.Currency {
+			report.Rows = append(report.Rows, newRow(item.ExternalCustomerID, item.Currency))
+			n++
+		}
+		report.Rows[n-1].add(item.Bucket, item.OutstandingCents)
+
+		total, ok := totals[item.Currency]
+		if !ok {
+			row := newRow("", item.Currency)
+			total = &row
+			totals[item.Currency] = total
+		}
+		total.add(item.Bucket, item.OutstandingCents)
+	}
+	for _, total := range totals {
+		report.Totals = append(report.Totals, *total)
+	}
+	sort.Slice(report.Totals, func(i, j int) bool {
+		return report.Totals[i].Currency < report.Totals[j].Currency
+	})
+	return report, nil
+}
+
+func newRow(externalCustomerID string, currency lago.Currency) Row {
+	amounts := make(map[Bucket]int64, len(Buckets))
+	for _, bucket := range Buckets {
+		amounts[bucket] = 0
+	}
+	return Row{ExternalCustomerID: externalCustomerID, Currency: currency, AmountsCents: amounts}
+}
+
+func dueDate(invoice *lago.Invoice) (time.Time, error) {
+	if invoice.PaymentDueDate != "" {
+		due, err := time.Parse(dateLayout, invoice.PaymentDueDate)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("aging: invoice %s: payment due date: %w", invoice.Number, err)
+		}
+		return due, nil
+	}
+
+	issuing, err := time.Parse(dateLayout, invoice.IssuingDate)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("aging: invoice %s: issuing date: %w", invoice.Number, err)
+	}
+	term := invoice.NetPaymentTerm
+	if term == 0 && invoice.Customer != nil {
+		term = invoice.Customer.NetPaymentTerm
+	}
+	return issuing.AddDate(0, 0, term), nil
+}
@@ -0,0 +1,101 @@
+package aging_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/getlago/lago-go-client/aging"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+var responses = map[string]string{
+	"/invoices?status=finalized&payment_status=pending": `{"invoices": [
+		{"lago_id": "00000000-0000-0000-0000-000000000001", "number": "INV-1", "status": "finalized", "payment_status": "pending", "currency": "EUR",
+		 "issuing_date": "2026-03-20", "net_payment_term": 30, "total_amount_cents": 10000, "total_due_amount_cents": 10000,
+		 "customer": {"external_id": "cus_a"}},
+		{"lago_id": "00000000-0000-0000-0000-000000000002", "number": "INV-2", "status": "finalized", "payment_status": "pending", "currency": "EUR",
+		 "issuing_date": "2026-02-01", "payment_due_date": "2026-02-15", "total_amount_cents": 5000, "total_due_amount_cents": 3000,
+		 "customer": {"external_id": "cus_a"}},
+		{"lago_id": "00000000-0000-0000-0000-000000000003", "number": "INV-3", "status": "finalized", "payment_status": "pending", "currency": "USD",
+		 "issuing_date": "2025-12-01", "total_amount_cents": 700, "total_due_amount_cents": 700,
+		 "customer": {"external_id": "cus_b"}},
+		{"lago_id": "00000000-0000-0000-0000-000000000005", "number": "INV-5", "status": "finalized", "payment_status": "pending", "currency": "EUR",
+		 "issuing_date": "2026-01-01", "total_amount_cents": 500, "total_due_amount_cents": 500,
+		 "customer": {"external_id": "cus_a", "net_payment_term": 5}}
+	], "meta": {"current_page": 1}}`,
+	"/invoices?status=finalized&payment_status=failed": `{"invoices": [
+		{"lago_id": "00000000-0000-0000-0000-000000000004", "number": "INV-4", "status": "finalized", "payment_status": "failed", "currency": "EUR",
+		 "issuing_date": "2026-03-01", "total_amount_cents": 400, "total_due_amount_cents": 400,
+		 "customer": {"external_id": "cus_a", "net_payment_term": 5}}
+	], "meta": {"current_page": 1}}`,
+	"/credit_notes": `{"credit_notes": [
+		{"lago_invoice_id": "00000000-0000-0000-0000-000000000002", "offset_amount_cents": 1000},
+		{"lago_invoice_id": "00000000-0000-0000-0000-000000000005", "offset_amount_cents": 500},
+		{"lago_invoice_id": "00000000-0000-0000-0000-000000000001", "refund_amount_cents": 500}
+	], "meta": {"current_page": 1}}`,
+	"/customers/cus_b": `{"customer": {"external_id": "cus_b", "net_payment_term": 15}}`,
+}
+
+func TestBucketOf(t *testing.T) {
+	c := qt.New(t)
+
+	for days, bucket := range map[int]aging.Bucket{
+		-3: aging.Current, 0: aging.Current, 1: aging.Days1To30, 30: aging.Days1To30,
+		31: aging.Days31To60, 60: aging.Days31To60, 61: aging.Days61To90, 90: aging.Days61To90, 91: aging.Over90,
+	} {
+		c.Assert(aging.BucketOf(days), qt.Equals, bucket, qt.Commentf("%d days", days))
+	}
+	c.Assert(aging.Over90.String(), qt.Equals, "90+")
+}
+
+func TestFetch(t *testing.T) {
+	c := qt.New(t)
+
+	report, err := aging.Fetch(context.Background(), lt.NewRoutesServer(c, responses).Client(), aging.Options{
+		AsOf: time.Date(2026, 3, 31, 18, 0, 0, 0, time.UTC),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.AsOf, qt.Equals, "2026-03-31")
+
+	type item struct {
+		Number      string
+		Due         string
+		Days        int
+		Bucket      aging.Bucket
+		Outstanding int64
+	}
+	var items []item
+	for _, i := range report.Items {
+		items = append(items, item{i.Number, i.DueDate, i.DaysPastDue, i.Bucket, i.OutstandingCents})
+	}
+	c.Assert(items, qt.DeepEquals, []item{
+		// Partially paid, and partially offset by a credit note.
+		{"INV-2", "2026-02-15", 44, aging.Days31To60, 2000},
+		// Due after the net payment term of the customer.
+		{"INV-4", "2026-03-06", 25, aging.Days1To30, 400},
+		{"INV-1", "2026-04-19", 0, aging.Current, 10000},
+		// The customer was fetched for its net payment term.
+		{"INV-3", "2025-12-16", 105, aging.Over90, 700},
+	})
+
+	var buf bytes.Buffer
+	c.Assert(report.WriteCSV(&buf), qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `external_customer_id,currency,current,1-30,31-60,61-90,90+,total
+cus_a,EUR,100.00,4.00,20.00,0.00,0.00,124.00
+cus_b,USD,0.00,0.00,0.00,0.00,7.00,7.00
+`)
+
+	buf.Reset()
+	c.Assert(report.WriteJSON(&buf), qt.IsNil)
+	var decoded aging.Report
+	c.Assert(json.Unmarshal(buf.Bytes(), &decoded), qt.IsNil)
+	c.Assert(decoded.Rows[0].AmountsCents[aging.Days31To60], qt.Equals, int64(2000))
+	c.Assert(decoded.Totals, qt.DeepEquals, report.Totals)
+	c.Assert(decoded.Totals, qt.HasLen, 2)
+	c.Assert(decoded.Totals[0].TotalCents, qt.Equals, int64(12400))
+}
@@ -0,0 +1,99 @@
+package aging
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+const perPage = 100
+
+// Options configures Fetch.
+type Options struct {
+	// AsOf is the date of the report. It defaults to today.
+	AsOf time.Time
+	// ExternalCustomerID restricts the report to one customer.
+	ExternalCustomerID string
+	// Currency restricts the report to one currency.
+	Currency lago.Currency
+	// OverdueOnly restricts the report to the invoices Lago flags as overdue,
+	// leaving the current bucket empty.
+	OverdueOnly bool
+}
+
+// Fetch lists the finalized invoices whose payment is pending or failed and
+// the credit notes of the same customers, and ages them with Build.
+//
+// Invoices listed without their customer are completed with the customer's
+// net payment term when they have no term of their own.
+func Fetch(ctx context.Context, client *lago.Client, opts Options) (*Report, error) {
+	var overdue *bool
+	if opts.OverdueOnly {
+		overdue = lago.Ptr(true)
+	}
+
+	var invoices []lago.Invoice
+	for _, status := range []lago.InvoicePaymentStatus{lago.InvoicePaymentStatusPending, lago.InvoicePaymentStatusFailed} {
+		list, err := lago.FetchPages(0, func(page int) ([]lago.Invoice, lago.Metadata, *lago.Error) {
+			result, err := client.Invoice().GetList(ctx, &lago.InvoiceListInput{
+				Page:               lago.Ptr(page),
+				PerPage:            lago.Ptr(perPage),
+				ExternalCustomerID: opts.ExternalCustomerID,
+				Currency:           opts.Currency,
+				Status:             lago.InvoiceStatusFinalized,
+				PaymentStatus:      status,
+				PaymentOverdue:     overdue,
+			})
+			if err != nil {
+				return nil, lago.Metadata{}, err
+			}
+			return result.Invoices, result.Meta, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("aging: %s invoices: %w", status, err)
+		}
+		invoices = append(invoices, list...)
+	}
+
+	creditNotes, err := lago.FetchPages(0, func(page int) ([]lago.CreditNote, lago.Metadata, *lago.Error) {
+		result, err := client.CreditNote().GetList(ctx, &lago.CreditNoteListInput{
+			Page:               lago.Ptr(page),
+			PerPage:            lago.Ptr(perPage),
+			ExternalCustomerID: opts.ExternalCustomerID,
+			Currency:           opts.Currency,
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.CreditNotes, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aging: credit notes: %w", err)
+	}
+
+	customers := map[string]*lago.Customer{}
+	for i := range invoices {
+		invoice := &invoices[i]
+		if invoice.PaymentDueDate != "" || invoice.NetPaymentTerm != 0 || invoice.Customer == nil || invoice.Customer.NetPaymentTerm != 0 {
+			continue
+		}
+		externalID := invoice.Customer.ExternalID
+		customer, ok := customers[externalID]
+		if !ok {
+			var lagoErr *lago.Error
+			if customer, lagoErr = client.Customer().Get(ctx, externalID); lagoErr != nil {
+				return nil, fmt.Errorf("aging: customer %s: %w", externalID, lagoErr)
+			}
+			customers[externalID] = customer
+		}
+		invoice.Customer = customer
+	}
+
+	asOf := opts.AsOf
+	if asOf.IsZero() {
+		asOf = time.Now()
+	}
+	return Build(invoices, creditNotes, asOf)
+}
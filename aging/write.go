@@ -0,0 +1,56 @@
+package aging
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// WriteJSON writes the report as a JSON document of the form
+//
+//	{"as_of": "2026-03-31", "items": [...], "rows": [{
+//		"external_customer_id": "cus_1", "currency": "EUR",
+//		"amounts_cents": {"current": 0, "1-30": 1200, "31-60": 0, "61-90": 0, "90+": 0},
+//		"total_cents": 1200
+//	}], "totals": [...]}
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes one row per customer and currency, with a column per
+// bucket and a total column, amounts in major units ("12.00").
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	header := []string{"external_customer_id", "currency"}
+	for _, bucket := range Buckets {
+		header = append(header, bucket.String())
+	}
+	if err := writer.Write(append(header, "total")); err != nil {
+		return err
+	}
+
+	for _, row := range r.Rows {
+		record := []string{row.ExternalCustomerID, string(row.Currency)}
+		for _, bucket := range Buckets {
+			record = append(record, major(row.AmountsCents[bucket], row.Currency))
+		}
+		if err := writer.Write(append(record, major(row.TotalCents, row.Currency))); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func major(cents int64, currency lago.Currency) string {
+	if currency == "" {
+		return strconv.FormatInt(cents, 10)
+	}
+	return lago.NewMoney(cents, currency).Major()
+}
@@ -0,0 +1,277 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Series names, used as column names by Join.
+const (
+	MrrSeries            = "mrr"
+	GrossRevenueSeries   = "gross_revenue"
+	OverdueBalanceSeries = "overdue_balance"
+)
+
+// Query selects the months and currency of an analytics query.
+type Query struct {
+	// From and To are included in the range by their month. A zero From
+	// lets Lago choose the number of months, a zero To ends the range with
+	// the current month.
+	From, To time.Time
+	// Currency restricts the amounts to one currency.
+	Currency lago.Currency
+	// ExternalCustomerID restricts the amounts to one customer, for the
+	// gross revenue and overdue balance only.
+	ExternalCustomerID string
+	// BillingEntityCode restricts the amounts to one billing entity.
+	BillingEntityCode string
+}
+
+// Analytics runs analytics queries.
+type Analytics struct {
+	client *lago.Client
+	// Now returns the current time, from which Lago counts months back. It
+	// defaults to time.Now.
+	Now func() time.Time
+}
+
+// New returns an Analytics querying Lago with client.
+func New(client *lago.Client) *Analytics {
+	return &Analytics{client: client, Now: time.Now}
+}
+
+// point is the shape shared by the analytics results.
+type point struct {
+	Month          string                    `json:"month"`
+	AmountCents    int64                     `json:"amount_cents"`
+	AmountCurrency lago.Currency             `json:"currency"`
+	InvoicesCount  int                       `json:"invoices_count"`
+	Code           string                    `json:"code"`
+	PaymentStatus  lago.InvoicePaymentStatus `json:"payment_status"`
+}
+
+// get fetches the points of an analytics endpoint listed under key,
+// keeping those within the range of the query.
+func (a *Analytics) get(ctx context.Context, path, key string, q Query, customer bool) (map[Month][]point, error) {
+	params := map[string]string{}
+	if q.Currency != "" {
+		params["currency"] = string(q.Currency)
+	}
+	if customer && q.ExternalCustomerID != "" {
+		params["external_customer_id"] = q.ExternalCustomerID
+	}
+	if q.BillingEntityCode != "" {
+		params["billing_entity_code"] = q.BillingEntityCode
+	}
+	now := MonthOf(a.Now())
+	if !q.From.IsZero() {
+		if months := monthsBetween(MonthOf(q.From), now); months > 0 {
+			params["months"] = strconv.Itoa(months)
+		}
+	}
+
+	result := map[string][]point{}
+	_, lagoErr := a.client.Get(ctx, &lago.ClientRequest{
+		Path:        path,
+		QueryParams: params,
+		Result:      &result,
+	})
+	if lagoErr != nil {
+		return nil, fmt.Errorf("analytics: %s: %w", path, lagoErr)
+	}
+
+	to := now
+	if !q.To.IsZero() {
+		to = MonthOf(q.To)
+	}
+	var from Month
+	if !q.From.IsZero() {
+		from = MonthOf(q.From)
+	}
+
+	points := map[Month][]point{}
+	for _, p := range result[key] {
+		month, err := ParseMonth(p.Month)
+		if err != nil {
+			return nil, err
+		}
+		if month.Before(from) || to.Before(month) {
+			continue
+		}
+		points[month] = append(points[month], p)
+	}
+	return points, nil
+}
+
+func (a *Analytics) series(ctx context.Context, name, path, key string, q Query, customer bool) (*MonthlySeries, error) {
+	points, err := a.get(ctx, path, key, q, customer)
+	if err != nil {
+		return nil, err
+	}
+	series := NewMonthlySeries(name)
+	for month, monthPoints := range points {
+		for _, p := range monthPoints {
+			series.Add(Key{Month: month, Currency: p.AmountCurrency}, Value{AmountCents: p.AmountCents, Count: p.InvoicesCount})
+		}
+	}
+	return series, nil
+}
+
+// Mrr returns the monthly recurring revenue.
+func (a *Analytics) Mrr(ctx context.Context, q Query) (*MonthlySeries, error) {
+	return a.series(ctx, MrrSeries, "analytics/mrr", "mrrs", q, false)
+}
+
+// GrossRevenue returns the invoiced revenue, with the number of invoices.
+func (a *Analytics) GrossRevenue(ctx context.Context, q Query) (*MonthlySeries, error) {
+	return a.series(ctx, GrossRevenueSeries, "analytics/gross_revenue", "gross_revenues", q, true)
+}
+
+// OverdueBalance returns the amounts overdue.
+func (a *Analytics) OverdueBalance(ctx context.Context, q Query) (*MonthlySeries, error) {
+	return a.series(ctx, OverdueBalanceSeries, "analytics/overdue_balance", "overdue_balances", q, true)
+}
+
+// InvoicedUsage returns the invoiced usage by billable metric code, each
+// series being named after its code.
+func (a *Analytics) InvoicedUsage(ctx context.Context, q Query) (map[string]*MonthlySeries, error) {
+	points, err := a.get(ctx, "analytics/invoiced_usage", "invoiced_usages", q, false)
+	if err != nil {
+		return nil, err
+	}
+	byCode := map[string]*MonthlySeries{}
+	for month, monthPoints := range points {
+		for _, p := range monthPoints {
+			series, ok := byCode[p.Code]
+			if !ok {
+				series = NewMonthlySeries(p.Code)
+				byCode[p.Code] = series
+			}
+			series.Add(Key{Month: month, Currency: p.AmountCurrency}, Value{AmountCents: p.AmountCents})
+		}
+	}
+	return byCode, nil
+}
+
+// InvoiceCollection returns the invoiced amounts and number of invoices by
+// payment status, each series being named after its status.
+func (a *Analytics) InvoiceCollection(ctx context.Context, q Query) (map[lago.InvoicePaymentStatus]*MonthlySeries, error) {
+	points, err := a.get(ctx, "analytics/invoice_collection", "invoice_collections", q, false)
+	if err != nil {
+		return nil, err
+	}
+	byStatus := map[lago.InvoicePaymentStatus]*MonthlySeries{}
+	for month, monthPoints := range points {
+		for _, p := range monthPoints {
+			series, ok := byStatus[p.PaymentStatus]
+			if !ok {
+				series = NewMonthlySeries(string(p.PaymentStatus))
+				byStatus[p.PaymentStatus] = series
+			}
+			series.Add(Key{Month: month, Currency: p.AmountCurrency}, Value{AmountCents: p.AmountCents, Count: p.InvoicesCount})
+		}
+	}
+	return byStatus, nil
+}
+
+// UsageQuery selects the usage returned by Usage.
+type UsageQuery struct {
+	// From and To are the first and last days of the range.
+	From, To               time.Time
+	Granularity            lago.UsageTimeGranularityType
+	Currency               lago.Currency
+	ExternalCustomerID     string
+	ExternalSubscriptionID string
+	BillableMetricCode     string
+	PlanCode               string
+}
+
+// UsagePoint is the usage of a billable metric over a period.
+type UsagePoint struct {
+	Start, End         time.Time
+	BillableMetricCode string
+	Units              lago.Decimal
+	AmountCents        int64
+	Currency           lago.Currency
+}
+
+// Usage returns the usage by billable metric and period.
+func (a *Analytics) Usage(ctx context.Context, q UsageQuery) ([]UsagePoint, error) {
+	params := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	if !q.From.IsZero() {
+		set("from_date", q.From.UTC().Format("2006-01-02"))
+	}
+	if !q.To.IsZero() {
+		set("to_date", q.To.UTC().Format("2006-01-02"))
+	}
+	set("time_granularity", string(q.Granularity))
+	set("currency", string(q.Currency))
+	set("external_customer_id", q.ExternalCustomerID)
+	set("external_subscription_id", q.ExternalSubscriptionID)
+	set("billable_metric_code", q.BillableMetricCode)
+	set("plan_code", q.PlanCode)
+
+	result := &lago.UsageResult{}
+	if _, lagoErr := a.client.Get(ctx, &lago.ClientRequest{Path: "analytics/usage", QueryParams: params, Result: result}); lagoErr != nil {
+		return nil, fmt.Errorf("analytics: analytics/usage: %w", lagoErr)
+	}
+
+	points := make([]UsagePoint, 0, len(result.Usages))
+	for _, usage := range result.Usages {
+		p := UsagePoint{
+			BillableMetricCode: usage.BillableMetricCode,
+			AmountCents:        int64(usage.AmountCents),
+			Currency:           usage.AmountCurrency,
+		}
+		var err error
+		if p.Start, err = parseTime(usage.StartOfPeriodDt); err != nil {
+			return nil, err
+		}
+		if p.End, err = parseTime(usage.EndOfPeriodDt); err != nil {
+			return nil, err
+		}
+		if usage.Units != "" {
+			if p.Units, err = lago.ParseDecimal(usage.Units); err != nil {
+				return nil, fmt.Errorf("analytics: usage units: %w", err)
+			}
+		}
+		points = append(points, p)
+	}
+	return points, nil
+}
+
+// MonthlyUsage sums usage points by billable metric code and month of their
+// start, each series being named after its code.
+func MonthlyUsage(points []UsagePoint) map[string]*MonthlySeries {
+	byCode := map[string]*MonthlySeries{}
+	for _, p := range points {
+		series, ok := byCode[p.BillableMetricCode]
+		if !ok {
+			series = NewMonthlySeries(p.BillableMetricCode)
+			byCode[p.BillableMetricCode] = series
+		}
+		series.Add(Key{Month: MonthOf(p.Start), Currency: p.Currency}, Value{AmountCents: p.AmountCents})
+	}
+	return byCode
+}
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("analytics: invalid date %q", value)
+}
@@ -0,0 +1,139 @@
+package analytics_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/analytics"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+// newServer returns analytics of a server answering responses, in March 2026.
+func newServer(c *qt.C, responses map[string]string) (*analytics.Analytics, *lt.RoutesServer) {
+	server := lt.NewRoutesServer(c, responses)
+	a := analytics.New(server.Client())
+	a.Now = func() time.Time { return time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC) }
+	return a, server
+}
+
+func month(year int, m time.Month) analytics.Month {
+	return analytics.Month{Year: year, Month: m}
+}
+
+func TestParseMonth(t *testing.T) {
+	c := qt.New(t)
+
+	for _, value := range []string{"2024-01-01T00:00:00.000Z", "2024-01-01T00:00:00Z", "2024-01-01", "2024-01"} {
+		m, err := analytics.ParseMonth(value)
+		c.Assert(err, qt.IsNil, qt.Commentf(value))
+		c.Assert(m, qt.Equals, month(2024, time.January))
+	}
+
+	_, err := analytics.ParseMonth("January")
+	c.Assert(err, qt.ErrorMatches, `analytics: invalid month "January"`)
+
+	c.Assert(month(2024, time.December).AddMonths(1).String(), qt.Equals, "2025-01")
+}
+
+func TestJoin(t *testing.T) {
+	c := qt.New(t)
+
+	a, server := newServer(c, map[string]string{
+		"/analytics/mrr": `{"mrrs": [
+			{"month": "2025-12-01T00:00:00.000Z", "amount_cents": 900, "currency": "EUR"},
+			{"month": "2026-01-01T00:00:00.000Z", "amount_cents": 1000, "currency": "EUR"},
+			{"month": "2026-02-01T00:00:00.000Z", "amount_cents": 1200, "currency": "EUR"},
+			{"month": "2026-02-01T00:00:00.000Z", "amount_cents": 5000, "currency": "USD"},
+			{"month": "2026-03-01T00:00:00.000Z", "amount_cents": 1300, "currency": "EUR"}
+		]}`,
+		"/analytics/gross_revenue": `{"gross_revenues": [
+			{"month": "2026-01-01T00:00:00.000Z", "amount_cents": 2500, "currency": "EUR", "invoices_count": 3}
+		]}`,
+		"/analytics/overdue_balance": `{"overdue_balances": [
+			{"month": "2026-02-01T00:00:00.000Z", "amount_cents": 300, "currency": "EUR"}
+		]}`,
+	})
+
+	ctx := context.Background()
+	q := analytics.Query{
+		From:               time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC),
+		To:                 time.Date(2026, 2, 20, 0, 0, 0, 0, time.UTC),
+		ExternalCustomerID: "cus_1",
+	}
+	mrr, err := a.Mrr(ctx, q)
+	c.Assert(err, qt.IsNil)
+	revenue, err := a.GrossRevenue(ctx, q)
+	c.Assert(err, qt.IsNil)
+	overdue, err := a.OverdueBalance(ctx, q)
+	c.Assert(err, qt.IsNil)
+
+	// Lago counts months back from the current month; the customer filter
+	// is only sent where it is supported.
+	c.Assert(server.Last("GET", "/analytics/mrr").Query.Encode(), qt.Equals, "months=3")
+	c.Assert(server.Last("GET", "/analytics/gross_revenue").Query.Encode(), qt.Equals, "external_customer_id=cus_1&months=3")
+
+	c.Assert(mrr.Keys(), qt.DeepEquals, []analytics.Key{
+		{Month: month(2026, time.January), Currency: "EUR"},
+		{Month: month(2026, time.February), Currency: "EUR"},
+		{Month: month(2026, time.February), Currency: "USD"},
+	})
+	c.Assert(revenue.Get(month(2026, time.January), "EUR"), qt.Equals, analytics.Value{AmountCents: 2500, Count: 3})
+	c.Assert(mrr.Money(month(2026, time.February), "USD"), qt.Equals, lago.NewMoney(5000, "USD"))
+
+	table := analytics.Join(mrr, revenue, overdue)
+	var buf bytes.Buffer
+	c.Assert(table.WriteCSV(&buf), qt.IsNil)
+	c.Assert(buf.String(), qt.Equals, `month,currency,mrr,gross_revenue,overdue_balance
+2026-01,EUR,10.00,25.00,0.00
+2026-02,EUR,12.00,0.00,3.00
+2026-02,USD,50.00,0.00,0.00
+`)
+}
+
+func TestBreakdowns(t *testing.T) {
+	c := qt.New(t)
+
+	a, server := newServer(c, map[string]string{
+		"/analytics/invoiced_usage": `{"invoiced_usages": [
+			{"month": "2026-03-01T00:00:00.000Z", "code": "api_calls", "amount_cents": 700, "currency": "EUR"},
+			{"month": "2026-03-01T00:00:00.000Z", "code": "storage", "amount_cents": 200, "currency": "EUR"}
+		]}`,
+		"/analytics/invoice_collection": `{"invoice_collections": [
+			{"month": "2026-03-01T00:00:00.000Z", "payment_status": "succeeded", "invoices_count": 4, "amount_cents": 4000, "currency": "EUR"},
+			{"month": "2026-03-01T00:00:00.000Z", "payment_status": "failed", "invoices_count": 1, "amount_cents": 1000, "currency": "EUR"}
+		]}`,
+		"/analytics/usage": `{"usages": [
+			{"start_of_period_dt": "2026-03-01", "end_of_period_dt": "2026-03-01", "billable_metric_code": "api_calls", "units": "10.5", "amount_cents": 105, "amount_currency": "EUR"},
+			{"start_of_period_dt": "2026-03-02", "end_of_period_dt": "2026-03-02", "billable_metric_code": "api_calls", "units": "2", "amount_cents": 20, "amount_currency": "EUR"}
+		]}`,
+	})
+	ctx := context.Background()
+	march := month(2026, time.March)
+
+	usage, err := a.InvoicedUsage(ctx, analytics.Query{Currency: "EUR"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(server.Last("GET", "/analytics/invoiced_usage").Query.Encode(), qt.Equals, "currency=EUR")
+	c.Assert(usage["api_calls"].Get(march, "EUR").AmountCents, qt.Equals, int64(700))
+	c.Assert(usage["storage"].Name, qt.Equals, "storage")
+
+	collection, err := a.InvoiceCollection(ctx, analytics.Query{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(collection[lago.InvoicePaymentStatusSucceeded].Get(march, "EUR"), qt.Equals, analytics.Value{AmountCents: 4000, Count: 4})
+	c.Assert(collection[lago.InvoicePaymentStatusFailed].Get(march, "EUR").Count, qt.Equals, 1)
+
+	points, err := a.Usage(ctx, analytics.UsageQuery{
+		From:        time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:          time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+		Granularity: lago.UsageDailyTimeGranularity,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(server.Last("GET", "/analytics/usage").Query.Encode(), qt.Equals, "from_date=2026-03-01&time_granularity=daily&to_date=2026-03-31")
+	c.Assert(points, qt.HasLen, 2)
+	c.Assert(points[0].Units.String(), qt.Equals, "10.5")
+	c.Assert(analytics.MonthlyUsage(points)["api_calls"].Get(march, "EUR").AmountCents, qt.Equals, int64(125))
+}
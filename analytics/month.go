@@ -0,0 +1,78 @@
+// Package analytics queries the Lago analytics endpoints with time based
+// ranges and returns their results as monthly series that can be joined into
+// a single table.
+package analytics
+
+import (
+	"fmt"
+	"time"
+)
+
+// Month is a calendar month.
+type Month struct {
+	Year  int
+	Month time.Month
+}
+
+// MonthOf returns the month of t in UTC.
+func MonthOf(t time.Time) Month {
+	t = t.UTC()
+	return Month{Year: t.Year(), Month: t.Month()}
+}
+
+// ParseMonth parses the months returned by Lago, such as
+// "2024-01-01T00:00:00.000Z", as well as "2024-01-01" and "2024-01".
+func ParseMonth(value string) (Month, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02", "2006-01"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return MonthOf(t), nil
+		}
+	}
+	return Month{}, fmt.Errorf("analytics: invalid month %q", value)
+}
+
+// Start returns the first instant of the month in UTC.
+func (m Month) Start() time.Time {
+	return time.Date(m.Year, m.Month, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// AddMonths returns the month n months after m.
+func (m Month) AddMonths(n int) Month {
+	return MonthOf(m.Start().AddDate(0, n, 0))
+}
+
+// Before tells whether m is before other.
+func (m Month) Before(other Month) bool {
+	return m.Year < other.Year || m.Year == other.Year && m.Month < other.Month
+}
+
+// IsZero tells whether m is the zero Month.
+func (m Month) IsZero() bool {
+	return m == Month{}
+}
+
+// String formats the month as "2006-01".
+func (m Month) String() string {
+	return fmt.Sprintf("%04d-%02d", m.Year, int(m.Month))
+}
+
+// MarshalText formats the month as "2006-01".
+func (m Month) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// UnmarshalText parses the month with ParseMonth.
+func (m *Month) UnmarshalText(text []byte) error {
+	month, err := ParseMonth(string(text))
+	if err != nil {
+		return err
+	}
+	*m = month
+	return nil
+}
+
+// monthsBetween returns the number of months from from to to, both
+// included.
+func monthsBetween(from, to Month) int {
+	return (to.Year-from.Year)*12 + int(to.Month-from.Month) + 1
+}
@@ -0,0 +1,433 @@
+package analytics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Movement classifies the change of the MRR of a customer from one month to
+// the next.
+type Movement string
+
+const (
+	// NewMrr is the MRR of a customer who never had any before.
+	NewMrr Movement = "new"
+	// Expansion is an increase of the MRR of a customer.
+	Expansion Movement = "expansion"
+	// Contraction is a decrease of the MRR of a customer who keeps some.
+	Contraction Movement = "contraction"
+	// Churn is the loss of all the MRR of a customer.
+	Churn Movement = "churn"
+	// Reactivation is the MRR of a customer who had churned.
+	Reactivation Movement = "reactivation"
+)
+
+// MonthlyAmountCents normalises the amount of a plan billed every interval
+// to a monthly amount, rounded to the cent. Weekly plans count 52 weeks a
+// year.
+func MonthlyAmountCents(amountCents int64, interval lago.PlanInterval) int64 {
+	switch interval {
+	case lago.PlanWeekly:
+		return int64(math.Round(float64(amountCents) * 52 / 12))
+	case lago.PlanQuarterly:
+		return int64(math.Round(float64(amountCents) / 3))
+	case lago.PlanSemiannual:
+		return int64(math.Round(float64(amountCents) / 6))
+	case lago.PlanYearly:
+		return int64(math.Round(float64(amountCents) / 12))
+	default:
+		return amountCents
+	}
+}
+
+// CustomerMovement is the change of the MRR of a customer in a currency
+// between the end of the previous month and the end of Month.
+type CustomerMovement struct {
+	Month              Month
+	ExternalCustomerID string
+	Currency           lago.Currency
+	Movement           Movement
+	FromCents, ToCents int64
+}
+
+// AmountCents returns the change of MRR, negative for contractions and churn.
+func (m CustomerMovement) AmountCents() int64 {
+	return m.ToCents - m.FromCents
+}
+
+// MrrBreakdown is the MRR of a month and currency, from its starting amount
+// to its ending amount through its movements. Contraction and churn are
+// negative.
+type MrrBreakdown struct {
+	Key
+	StartingCents     int64
+	NewCents          int64
+	ExpansionCents    int64
+	ContractionCents  int64
+	ChurnCents        int64
+	ReactivationCents int64
+	EndingCents       int64
+}
+
+// MrrDiscrepancy is a month and currency for which the MRR computed from
+// subscriptions differs from the MRR reported by Lago.
+type MrrDiscrepancy struct {
+	Key
+	ComputedCents int64
+	ReportedCents int64
+}
+
+// DifferenceCents returns the computed MRR minus the reported MRR.
+func (d MrrDiscrepancy) DifferenceCents() int64 {
+	return d.ComputedCents - d.ReportedCents
+}
+
+// MrrMovements are the MRR movements of a range of months.
+type MrrMovements struct {
+	// From and To are the first and last months of the movements.
+	From, To Month
+	// Customers are the movements of every customer whose MRR changed,
+	// sorted by month, currency and customer.
+	Customers []CustomerMovement
+	// Months are the breakdowns of every month and currency with MRR,
+	// sorted by month and currency.
+	Months []MrrBreakdown
+	// Discrepancies are filled by Reconcile.
+	Discrepancies []MrrDiscrepancy
+}
+
+// MrrMovementsOptions configure the computation of MRR movements.
+type MrrMovementsOptions struct {
+	// From and To are the first and last months of the movements. A zero
+	// From starts with the first month of the subscriptions, and leaves the
+	// movements empty when there are none.
+	From, To Month
+	// Plans are the plans of the subscriptions by code, for the subscriptions
+	// returned without their plan.
+	Plans map[string]*lago.Plan
+	// Overrides are the plan overrides of subscriptions by external ID. The
+	// amount and currency of an override replace those of the subscription.
+	Overrides map[string]*lago.PlanOverridesInput
+}
+
+type customerKey struct {
+	customer string
+	currency lago.Currency
+}
+
+// ComputeMrrMovements computes the MRR of every customer at the end of each
+// month from the subscription fees of their subscriptions, normalised to
+// monthly amounts, and classifies its changes.
+//
+// A subscription counts from the end of its trial until it is terminated.
+// Months before From are walked as well, so that customers who come back
+// are told apart from new ones.
+func ComputeMrrMovements(subscriptions []lago.Subscription, opts MrrMovementsOptions) (*MrrMovements, error) {
+	type span struct {
+		customerKey
+		start, end time.Time
+		cents      int64
+	}
+
+	var spans []span
+	var first Month
+	for _, sub := range subscriptions {
+		start := sub.SubscriptionAt
+		if sub.StartedAt != nil {
+			start = *sub.StartedAt
+		} else if sub.Status != lago.SubscriptionStatusTerminated && sub.Status != lago.SubscriptionStatusActive {
+			continue
+		}
+		if sub.TrialEndedAt != nil && sub.TrialEndedAt.After(start) {
+			start = *sub.TrialEndedAt
+		}
+		var end time.Time
+		if sub.TerminatedAt != nil {
+			end = *sub.TerminatedAt
+		}
+
+		plan := sub.Plan
+		if plan == nil || plan.Interval == "" {
+			plan = opts.Plans[sub.PlanCode]
+		}
+		if plan == nil {
+			return nil, fmt.Errorf("analytics: subscription %s: unknown plan %q", sub.ExternalID, sub.PlanCode)
+		}
+
+		cents, currency := int64(sub.PlanAmountCents), sub.PlanAmountCurrency
+		if override, ok := opts.Overrides[sub.ExternalID]; ok && override != nil {
+			cents = int64(override.AmountCents)
+			if override.AmountCurrency != "" {
+				currency = override.AmountCurrency
+			}
+		}
+		if currency == "" {
+			currency = plan.AmountCurrency
+		}
+
+		spans = append(spans, span{
+			customerKey: customerKey{customer: sub.ExternalCustomerID, currency: currency},
+			start:       start,
+			end:         end,
+			cents:       MonthlyAmountCents(cents, plan.Interval),
+		})
+		if month := MonthOf(start); first.IsZero() || month.Before(first) {
+			first = month
+		}
+	}
+
+	if first.IsZero() && opts.From.IsZero() {
+		// Without subscriptions, there is no first month to start with.
+		return &MrrMovements{To: opts.To}, nil
+	}
+	if opts.From.IsZero() {
+		opts.From = first
+	}
+	movements := &MrrMovements{From: opts.From, To: opts.To}
+	if first.IsZero() || opts.From.Before(first) {
+		first = opts.From
+	}
+
+	// mrr returns the MRR of every customer at the end of month.
+	mrr := func(month Month) map[customerKey]int64 {
+		next := month.AddMonths(1).Start()
+		amounts := map[customerKey]int64{}
+		for _, s := range spans {
+			if !s.start.Before(next) || !s.end.IsZero() && s.end.Before(next) {
+				continue
+			}
+			amounts[s.customerKey] += s.cents
+		}
+		return amounts
+	}
+
+	seen := map[customerKey]bool{}
+	previous := mrr(first.AddMonths(-1))
+	for month := first; !opts.To.Before(month); month = month.AddMonths(1) {
+		current := mrr(month)
+		inRange := !month.Before(opts.From)
+
+		breakdowns := map[lago.Currency]*MrrBreakdown{}
+		breakdown := func(currency lago.Currency) *MrrBreakdown {
+			b, ok := breakdowns[currency]
+			if !ok {
+				b = &MrrBreakdown{Key: Key{Month: month, Currency: currency}}
+				breakdowns[currency] = b
+			}
+			return b
+		}
+
+		keys := map[customerKey]bool{}
+		for key := range previous {
+			keys[key] = true
+		}
+		for key := range current {
+			keys[key] = true
+		}
+		for key := range keys {
+			from, to := previous[key], current[key]
+			if from != 0 {
+				seen[key] = true
+			}
+			if !inRange {
+				continue
+			}
+
+			b := breakdown(key.currency)
+			b.StartingCents += from
+			b.EndingCents += to
+			if from == to {
+				continue
+			}
+
+			m := CustomerMovement{
+				Month:              month,
+				ExternalCustomerID: key.customer,
+				Currency:           key.currency,
+				FromCents:          from,
+				ToCents:            to,
+			}
+			switch {
+			case from == 0 && seen[key]:
+				m.Movement = Reactivation
+				b.ReactivationCents += to
+			case from == 0:
+				m.Movement = NewMrr
+				b.NewCents += to
+			case to == 0:
+				m.Movement = Churn
+				b.ChurnCents -= from
+			case to > from:
+				m.Movement = Expansion
+				b.ExpansionCents += to - from
+			default:
+				m.Movement = Contraction
+				b.ContractionCents += to - from
+			}
+			movements.Customers = append(movements.Customers, m)
+		}
+
+		for _, b := range breakdowns {
+			movements.Months = append(movements.Months, *b)
+		}
+		for key, cents := range current {
+			if cents != 0 {
+				seen[key] = true
+			}
+		}
+		previous = current
+	}
+
+	sort.Slice(movements.Customers, func(i, j int) bool {
+		a, b := movements.Customers[i], movements.Customers[j]
+		if a.Month != b.Month {
+			return a.Month.Before(b.Month)
+		}
+		if a.Currency != b.Currency {
+			return a.Currency < b.Currency
+		}
+		return a.ExternalCustomerID < b.ExternalCustomerID
+	})
+	sort.Slice(movements.Months, func(i, j int) bool {
+		a, b := movements.Months[i].Key, movements.Months[j].Key
+		if a.Month != b.Month {
+			return a.Month.Before(b.Month)
+		}
+		return a.Currency < b.Currency
+	})
+	return movements, nil
+}
+
+// Reconcile compares the ending MRR of every month from From to To with the
+// MRR reported by Lago, and records their differences as
+// discrepancies.
+func (m *MrrMovements) Reconcile(reported *MonthlySeries) {
+	computed := map[Key]int64{}
+	keys := map[Key]bool{}
+	for _, b := range m.Months {
+		computed[b.Key] = b.EndingCents
+		keys[b.Key] = true
+	}
+	for key := range reported.Values {
+		if !key.Month.Before(m.From) && !m.To.Before(key.Month) {
+			keys[key] = true
+		}
+	}
+
+	m.Discrepancies = nil
+	for key := range keys {
+		d := MrrDiscrepancy{Key: key, ComputedCents: computed[key], ReportedCents: reported.Get(key.Month, key.Currency).AmountCents}
+		if d.DifferenceCents() != 0 {
+			m.Discrepancies = append(m.Discrepancies, d)
+		}
+	}
+	sort.Slice(m.Discrepancies, func(i, j int) bool {
+		a, b := m.Discrepancies[i].Key, m.Discrepancies[j].Key
+		if a.Month != b.Month {
+			return a.Month.Before(b.Month)
+		}
+		return a.Currency < b.Currency
+	})
+}
+
+// MrrMovementsQuery selects the MRR movements returned by MrrMovements.
+type MrrMovementsQuery struct {
+	// From and To are included in the range by their month. A zero From
+	// starts with the first subscription, a zero To ends with the current
+	// month.
+	From, To           time.Time
+	Currency           lago.Currency
+	ExternalCustomerID string
+	// Overrides are the plan overrides of subscriptions by external ID.
+	Overrides map[string]*lago.PlanOverridesInput
+}
+
+const perPage = 100
+
+// MrrMovements lists the active and terminated subscriptions, computes their
+// MRR movements with ComputeMrrMovements and, unless the query is restricted
+// to a customer or no month is covered, reconciles them with the MRR reported
+// by Lago.
+func (a *Analytics) MrrMovements(ctx context.Context, q MrrMovementsQuery) (*MrrMovements, error) {
+	subscriptions, lagoErr := lago.FetchPages(0, func(page int) ([]lago.Subscription, lago.Metadata, *lago.Error) {
+		result, lagoErr := a.client.Subscription().GetList(ctx, lago.SubscriptionListInput{
+			ExternalCustomerID: q.ExternalCustomerID,
+			Currency:           q.Currency,
+			Status:             []lago.SubscriptionStatus{lago.SubscriptionStatusActive, lago.SubscriptionStatusTerminated},
+			Page:               lago.Ptr(page),
+			PerPage:            lago.Ptr(perPage),
+		})
+		if lagoErr != nil {
+			return nil, lago.Metadata{}, lagoErr
+		}
+		return result.Subscriptions, result.Meta, nil
+	})
+	if lagoErr != nil {
+		return nil, fmt.Errorf("analytics: subscriptions: %w", lagoErr)
+	}
+
+	plans := map[string]*lago.Plan{}
+	for _, sub := range subscriptions {
+		if sub.Plan != nil && sub.Plan.Interval != "" || plans[sub.PlanCode] != nil {
+			continue
+		}
+		plan, lagoErr := a.client.Plan().Get(ctx, sub.PlanCode)
+		if lagoErr != nil {
+			return nil, fmt.Errorf("analytics: plan %s: %w", sub.PlanCode, lagoErr)
+		}
+		plans[sub.PlanCode] = plan
+	}
+
+	opts := MrrMovementsOptions{
+		To:        MonthOf(a.Now()),
+		Plans:     plans,
+		Overrides: q.Overrides,
+	}
+	if !q.From.IsZero() {
+		opts.From = MonthOf(q.From)
+	}
+	if !q.To.IsZero() {
+		opts.To = MonthOf(q.To)
+	}
+	movements, err := ComputeMrrMovements(subscriptions, opts)
+	if err != nil {
+		return nil, err
+	}
+	if q.Currency != "" {
+		movements.filterCurrency(q.Currency)
+	}
+
+	if q.ExternalCustomerID == "" && !movements.From.IsZero() {
+		reported, err := a.Mrr(ctx, Query{From: movements.From.Start(), To: movements.To.Start(), Currency: q.Currency})
+		if err != nil {
+			return nil, err
+		}
+		movements.Reconcile(reported)
+	}
+	return movements, nil
+}
+
+// filterCurrency keeps the movements in currency only, as overrides may
+// change the currency of a subscription.
+func (m *MrrMovements) filterCurrency(currency lago.Currency) {
+	customers := m.Customers[:0]
+	for _, c := range m.Customers {
+		if c.Currency == currency {
+			customers = append(customers, c)
+		}
+	}
+	m.Customers = customers
+
+	months := m.Months[:0]
+	for _, b := range m.Months {
+		if b.Currency == currency {
+			months = append(months, b)
+		}
+	}
+	m.Months = months
+}
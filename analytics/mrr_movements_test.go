@@ -0,0 +1,101 @@
+package analytics_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/analytics"
+)
+
+func TestMonthlyAmountCents(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(analytics.MonthlyAmountCents(1000, lago.PlanMonthly), qt.Equals, int64(1000))
+	c.Assert(analytics.MonthlyAmountCents(300, lago.PlanWeekly), qt.Equals, int64(1300))
+	c.Assert(analytics.MonthlyAmountCents(1000, lago.PlanQuarterly), qt.Equals, int64(333))
+	c.Assert(analytics.MonthlyAmountCents(1000, lago.PlanSemiannual), qt.Equals, int64(167))
+	c.Assert(analytics.MonthlyAmountCents(24000, lago.PlanYearly), qt.Equals, int64(2000))
+}
+
+const subscriptionsResponse = `{"subscriptions": [
+	{"external_id": "a", "external_customer_id": "cus_a", "status": "terminated", "plan_code": "monthly", "plan_amount_cents": 1000, "plan_amount_currency": "EUR",
+	 "started_at": "2026-01-05T00:00:00Z", "terminated_at": "2026-02-10T00:00:00Z", "plan": {"code": "monthly", "interval": "monthly"}},
+	{"external_id": "a", "external_customer_id": "cus_a", "status": "active", "plan_code": "yearly", "plan_amount_cents": 24000, "plan_amount_currency": "EUR",
+	 "started_at": "2026-02-10T00:00:00Z", "plan": {"code": "yearly", "interval": "yearly"}},
+	{"external_id": "b1", "external_customer_id": "cus_b", "status": "terminated", "plan_code": "quarterly", "plan_amount_cents": 3000, "plan_amount_currency": "EUR",
+	 "started_at": "2025-12-01T00:00:00Z", "terminated_at": "2026-02-20T00:00:00Z", "plan": {"code": "quarterly", "interval": "quarterly"}},
+	{"external_id": "b2", "external_customer_id": "cus_b", "status": "active", "plan_code": "weekly", "plan_amount_cents": 300, "plan_amount_currency": "EUR",
+	 "started_at": "2026-03-03T00:00:00Z"},
+	{"external_id": "c", "external_customer_id": "cus_c", "status": "active", "plan_code": "monthly", "plan_amount_cents": 500, "plan_amount_currency": "EUR",
+	 "started_at": "2026-01-10T00:00:00Z", "trial_ended_at": "2026-02-10T00:00:00Z", "plan": {"code": "monthly", "interval": "monthly"}},
+	{"external_id": "d1", "external_customer_id": "cus_d", "status": "terminated", "plan_code": "monthly", "plan_amount_cents": 1500, "plan_amount_currency": "EUR",
+	 "started_at": "2025-11-01T00:00:00Z", "terminated_at": "2026-03-15T00:00:00Z", "plan": {"code": "monthly", "interval": "monthly"}},
+	{"external_id": "d2", "external_customer_id": "cus_d", "status": "active", "plan_code": "monthly", "plan_amount_cents": 500, "plan_amount_currency": "EUR",
+	 "started_at": "2025-11-01T00:00:00Z", "plan": {"code": "monthly", "interval": "monthly"}}
+], "meta": {"current_page": 1}}`
+
+func TestMrrMovements(t *testing.T) {
+	c := qt.New(t)
+
+	a, server := newServer(c, map[string]string{
+		"/subscriptions": subscriptionsResponse,
+		"/plans/weekly":  `{"plan": {"code": "weekly", "interval": "weekly", "amount_currency": "EUR"}}`,
+		"/analytics/mrr": `{"mrrs": [
+			{"month": "2025-12-01T00:00:00.000Z", "amount_cents": 3000, "currency": "EUR"},
+			{"month": "2026-01-01T00:00:00.000Z", "amount_cents": 4000, "currency": "EUR"},
+			{"month": "2026-02-01T00:00:00.000Z", "amount_cents": 4400, "currency": "EUR"},
+			{"month": "2026-03-01T00:00:00.000Z", "amount_cents": 4100, "currency": "EUR"}
+		]}`,
+	})
+
+	movements, err := a.MrrMovements(context.Background(), analytics.MrrMovementsQuery{
+		From:      time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		Overrides: map[string]*lago.PlanOverridesInput{"c": {AmountCents: 400}},
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(server.Last("GET", "/subscriptions").Query.Encode(), qt.Equals, "page=1&per_page=100&status%5B%5D=active&status%5B%5D=terminated")
+
+	jan, feb, mar := month(2026, time.January), month(2026, time.February), month(2026, time.March)
+	c.Assert(movements.From, qt.Equals, jan)
+	c.Assert(movements.To, qt.Equals, mar)
+
+	c.Assert(movements.Customers, qt.DeepEquals, []analytics.CustomerMovement{
+		{Month: jan, ExternalCustomerID: "cus_a", Currency: "EUR", Movement: analytics.NewMrr, ToCents: 1000},
+		{Month: feb, ExternalCustomerID: "cus_a", Currency: "EUR", Movement: analytics.Expansion, FromCents: 1000, ToCents: 2000},
+		{Month: feb, ExternalCustomerID: "cus_b", Currency: "EUR", Movement: analytics.Churn, FromCents: 1000},
+		{Month: feb, ExternalCustomerID: "cus_c", Currency: "EUR", Movement: analytics.NewMrr, ToCents: 400},
+		{Month: mar, ExternalCustomerID: "cus_b", Currency: "EUR", Movement: analytics.Reactivation, ToCents: 1300},
+		{Month: mar, ExternalCustomerID: "cus_d", Currency: "EUR", Movement: analytics.Contraction, FromCents: 2000, ToCents: 500},
+	})
+
+	c.Assert(movements.Months, qt.DeepEquals, []analytics.MrrBreakdown{
+		{Key: analytics.Key{Month: jan, Currency: "EUR"}, StartingCents: 3000, NewCents: 1000, EndingCents: 4000},
+		{Key: analytics.Key{Month: feb, Currency: "EUR"}, StartingCents: 4000, NewCents: 400, ExpansionCents: 1000, ChurnCents: -1000, EndingCents: 4400},
+		{Key: analytics.Key{Month: mar, Currency: "EUR"}, StartingCents: 4400, ContractionCents: -1500, ReactivationCents: 1300, EndingCents: 4200},
+	})
+
+	c.Assert(movements.Discrepancies, qt.DeepEquals, []analytics.MrrDiscrepancy{
+		{Key: analytics.Key{Month: mar, Currency: "EUR"}, ComputedCents: 4200, ReportedCents: 4100},
+	})
+	c.Assert(movements.Discrepancies[0].DifferenceCents(), qt.Equals, int64(100))
+}
+
+func TestMrrMovements_NoSubscriptions(t *testing.T) {
+	c := qt.New(t)
+
+	// Without subscriptions nor From, the MRR reported by Lago is not read.
+	a, server := newServer(c, map[string]string{
+		"/subscriptions": `{"subscriptions": [], "meta": {"current_page": 1}}`,
+	})
+	movements, err := a.MrrMovements(context.Background(), analytics.MrrMovementsQuery{Currency: "EUR"})
+	c.Assert(err, qt.IsNil)
+	c.Assert(movements.From.IsZero(), qt.IsTrue)
+	c.Assert(movements.Customers, qt.HasLen, 0)
+	c.Assert(movements.Months, qt.HasLen, 0)
+	c.Assert(movements.Discrepancies, qt.HasLen, 0)
+	c.Assert(server.Last("GET", "/analytics/mrr").Path, qt.Equals, "")
+}
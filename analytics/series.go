@@ -0,0 +1,139 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Key identifies a point of a series.
+type Key struct {
+	Month    Month
+	Currency lago.Currency
+}
+
+// Value is the amount of a series for a month and a currency.
+type Value struct {
+	AmountCents int64
+	// Count is the number of invoices, for the series that have one.
+	Count int
+}
+
+// MonthlySeries is a metric by month and currency.
+type MonthlySeries struct {
+	Name   string
+	Values map[Key]Value
+}
+
+// NewMonthlySeries returns an empty series.
+func NewMonthlySeries(name string) *MonthlySeries {
+	return &MonthlySeries{Name: name, Values: map[Key]Value{}}
+}
+
+// Add adds a value to the point of a key.
+func (s *MonthlySeries) Add(key Key, value Value) {
+	total := s.Values[key]
+	total.AmountCents += value.AmountCents
+	total.Count += value.Count
+	s.Values[key] = total
+}
+
+// Get returns the value for a month and a currency, zero when missing.
+func (s *MonthlySeries) Get(month Month, currency lago.Currency) Value {
+	return s.Values[Key{Month: month, Currency: currency}]
+}
+
+// Money returns the amount for a month and a currency as Money.
+func (s *MonthlySeries) Money(month Month, currency lago.Currency) lago.Money {
+	return lago.NewMoney(s.Get(month, currency).AmountCents, currency)
+}
+
+// Keys returns the keys of the series sorted by month, then currency.
+func (s *MonthlySeries) Keys() []Key {
+	keys := make([]Key, 0, len(s.Values))
+	for key := range s.Values {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	return keys
+}
+
+func sortKeys(keys []Key) {
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Month != keys[j].Month {
+			return keys[i].Month.Before(keys[j].Month)
+		}
+		return keys[i].Currency < keys[j].Currency
+	})
+}
+
+// Table is several series joined on their month and currency.
+type Table struct {
+	// Columns are the names of the joined series.
+	Columns []string
+	Rows    []Row
+}
+
+// Row is a month and currency of a table, with one value per column.
+type Row struct {
+	Key
+	Values []Value
+}
+
+// Join joins series into a table with a row for every month and currency
+// found in any of them, sorted by month then currency. Values missing from a
+// series are zero.
+func Join(series ...*MonthlySeries) *Table {
+	table := &Table{Columns: make([]string, len(series))}
+
+	seen := map[Key]bool{}
+	var keys []Key
+	for i, s := range series {
+		table.Columns[i] = s.Name
+		for key := range s.Values {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sortKeys(keys)
+
+	for _, key := range keys {
+		row := Row{Key: key, Values: make([]Value, len(series))}
+		for i, s := range series {
+			row.Values[i] = s.Values[key]
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	return table
+}
+
+// WriteCSV writes the table with a month and a currency column followed by
+// one column per series, with amounts in major units ("12.50").
+func (t *Table) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(append([]string{"month", "currency"}, t.Columns...)); err != nil {
+		return err
+	}
+
+	for _, row := range t.Rows {
+		record := []string{row.Month.String(), string(row.Currency)}
+		for _, value := range row.Values {
+			if row.Currency == "" {
+				record = append(record, strconv.FormatInt(value.AmountCents, 10))
+				continue
+			}
+			record = append(record, lago.NewMoney(value.AmountCents, row.Currency).Major())
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,320 @@
+// Package billing computes offline when Lago will invoice a subscription and
+// for which periods, from the subscription, its plan and the timezone of its
+// customer.
+package billing
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// DefaultCount is the number of invoices returned by Upcoming by default.
+const DefaultCount = 12
+
+const dateLayout = "2006-01-02"
+
+// ErrNoPlan is returned for subscriptions without a plan.
+var ErrNoPlan = errors.New("billing: subscription without plan")
+
+// UpcomingInvoice is an invoice Lago will issue for a subscription.
+type UpcomingInvoice struct {
+	// IssuingDate is the date of the invoice in the timezone of the customer.
+	IssuingDate string
+	// IssuedAt is the time the invoice is generated.
+	IssuedAt time.Time
+	// SubscriptionFee tells whether the invoice bills the subscription fee
+	// of the subscription period. It is prorated when the period is shorter
+	// than the plan interval.
+	SubscriptionFee bool
+	// Charges tells whether the invoice bills the usage of the charges
+	// period.
+	Charges       bool
+	BillingPeriod lago.BillingPeriod
+}
+
+// Options configures Upcoming.
+type Options struct {
+	// Plan is the plan of the subscription. It defaults to the plan the
+	// subscription was returned with.
+	Plan *lago.Plan
+	// Timezone is the applicable timezone of the customer, such as
+	// "Europe/Paris". It defaults to UTC.
+	Timezone string
+	// After excludes the invoices issued until then. It defaults to now.
+	After time.Time
+	// Count is the number of invoices to return. It defaults to
+	// DefaultCount.
+	Count int
+}
+
+// Upcoming returns the next invoices of a subscription, following the
+// rules of Lago:
+//
+//   - periods start at midnight in the timezone of the customer, on the
+//     anniversary of the subscription date or at the start of the calendar
+//     week (Monday), month, quarter, half-year or year; the first period
+//     starts with the subscription and is prorated;
+//   - an invoice is issued at the end of every period, billing the usage of
+//     the charges over the period and the subscription fee of the period
+//     when the plan is paid in arrears, or of the next one when it is paid
+//     in advance;
+//   - plans paid in advance are also invoiced when the subscription starts;
+//   - plans billing their charges monthly are invoiced every month for their
+//     charges only, and with their subscription fee at the end of the plan
+//     interval;
+//   - no subscription fee is billed during the trial period: plans paid in
+//     advance are invoiced when the trial ends;
+//   - the subscription is invoiced a last time at its ending date.
+//
+// Subscriptions without billing time are billed on calendar periods.
+func Upcoming(sub *lago.Subscription, opts Options) ([]UpcomingInvoice, error) {
+	if opts.Count < 0 {
+		return nil, fmt.Errorf("billing: negative count %d", opts.Count)
+	}
+	plan, loc, err := resolve(sub, opts)
+	if err != nil {
+		return nil, err
+	}
+	after := opts.After
+	if after.IsZero() {
+		after = time.Now()
+	}
+	count := opts.Count
+	if count == 0 {
+		count = DefaultCount
+	}
+
+	start := startOf(sub, loc)
+	anniversary := sub.BillingTime == lago.Anniversary
+	subscriptionPeriods, err := newCalendar(anchorOf(sub, loc), plan.Interval, anniversary)
+	if err != nil {
+		return nil, err
+	}
+	chargePeriods := subscriptionPeriods
+	if plan.BillChargesMonthly && subscriptionPeriods.months > 1 {
+		chargePeriods, _ = newCalendar(anchorOf(sub, loc), lago.PlanMonthly, anniversary)
+	}
+
+	trialEnd := start
+	if sub.TrialEndedAt != nil {
+		trialEnd = sub.TrialEndedAt.In(loc)
+	} else if plan.TrialPeriod > 0 {
+		trialEnd = start.Add(time.Duration(float64(plan.TrialPeriod) * float64(24*time.Hour)))
+	}
+	inTrial := trialEnd.After(start)
+
+	var invoices []UpcomingInvoice
+	add := func(issuedAt time.Time, reason lago.InvoicingReason, fee bool, subFrom, subTo time.Time, charges bool, chargesFrom, chargesTo time.Time) bool {
+		if issuedAt.After(after) {
+			invoices = append(invoices, UpcomingInvoice{
+				IssuingDate:     issuedAt.Format(dateLayout),
+				IssuedAt:        issuedAt,
+				SubscriptionFee: fee,
+				Charges:         charges,
+				BillingPeriod: lago.BillingPeriod{
+					LagoSubscriptionId:       sub.LagoID,
+					ExternalSubscriptionId:   sub.ExternalID,
+					LagoPlanId:               plan.LagoID,
+					SubscriptionFromDatetime: subFrom,
+					SubscriptionToDatetime:   subTo,
+					ChargesFromDatetime:      chargesFrom,
+					ChargesToDatetime:        chargesTo,
+					InvoicingReason:          reason,
+				},
+			})
+		}
+		return len(invoices) < count
+	}
+	end := func(boundary time.Time) time.Time {
+		return boundary.Add(-time.Second)
+	}
+
+	k := subscriptionPeriods.next(start)
+	nextSubscription := subscriptionPeriods.boundary(k)
+	if plan.PayInAdvance && !inTrial {
+		if !add(start, lago.BillingPeriodSubscriptionStarting, true, start, end(nextSubscription), false, time.Time{}, time.Time{}) {
+			return invoices, nil
+		}
+	}
+
+	previousSubscription, previousCharges := start, start
+	for j := chargePeriods.next(start); ; j++ {
+		boundary := chargePeriods.boundary(j)
+
+		if plan.PayInAdvance && inTrial && trialEnd.After(previousCharges) && trialEnd.Before(boundary) {
+			if !add(trialEnd, lago.BillingPeriodSubscriptionStarting, true, trialEnd, end(nextSubscription), false, time.Time{}, time.Time{}) {
+				return invoices, nil
+			}
+		}
+
+		if sub.EndingAt != nil && !sub.EndingAt.After(boundary) {
+			ending := sub.EndingAt.In(loc)
+			feeFrom := later(previousSubscription, trialEnd)
+			fee := !plan.PayInAdvance && ending.After(feeFrom)
+			if !fee {
+				feeFrom = previousSubscription
+			}
+			add(ending, lago.BillingPeriodSubscriptionTerminating, fee, feeFrom, ending, true, previousCharges, ending)
+			return invoices, nil
+		}
+
+		var more bool
+		if boundary.Equal(nextSubscription) {
+			following := subscriptionPeriods.boundary(k + 1)
+			if plan.PayInAdvance {
+				more = add(boundary, lago.BillingPeriodSubscriptionPeriodic, !boundary.Before(trialEnd), boundary, end(following),
+					true, previousCharges, end(boundary))
+			} else {
+				// The fee covers the part of the period after the trial.
+				feeFrom := later(previousSubscription, trialEnd)
+				fee := boundary.After(feeFrom)
+				if !fee {
+					feeFrom = previousSubscription
+				}
+				more = add(boundary, lago.BillingPeriodSubscriptionPeriodic, fee, feeFrom, end(boundary),
+					true, previousCharges, end(boundary))
+			}
+			k++
+			previousSubscription, nextSubscription = boundary, following
+		} else {
+			more = add(boundary, lago.BillingPeriodSubscriptionPeriodic, false, previousSubscription, end(nextSubscription),
+				true, previousCharges, end(boundary))
+		}
+		previousCharges = boundary
+		if !more {
+			return invoices, nil
+		}
+	}
+}
+
+// Period returns the subscription period containing at, from its start to
+// its last second, for the plan and timezone of opts. The first period starts
+// with the subscription.
+func Period(sub *lago.Subscription, opts Options, at time.Time) (from, to time.Time, err error) {
+	plan, loc, err := resolve(sub, opts)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	start := startOf(sub, loc)
+	periods, err := newCalendar(anchorOf(sub, loc), plan.Interval, sub.BillingTime == lago.Anniversary)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	from = start
+	for k := periods.next(start); ; k++ {
+		next := periods.boundary(k)
+		if next.After(at) {
+			return from, next.Add(-time.Second), nil
+		}
+		from = next
+	}
+}
+
+// resolve returns the plan of the subscription and the timezone of opts.
+func resolve(sub *lago.Subscription, opts Options) (*lago.Plan, *time.Location, error) {
+	plan := opts.Plan
+	if plan == nil {
+		plan = sub.Plan
+	}
+	if plan == nil {
+		return nil, nil, ErrNoPlan
+	}
+	if opts.Timezone == "" {
+		return plan, time.UTC, nil
+	}
+	loc, err := time.LoadLocation(opts.Timezone)
+	if err != nil {
+		return nil, nil, fmt.Errorf("billing: timezone: %w", err)
+	}
+	return plan, loc, nil
+}
+
+// startOf returns the start of the subscription in loc.
+func startOf(sub *lago.Subscription, loc *time.Location) time.Time {
+	if sub.StartedAt != nil {
+		return sub.StartedAt.In(loc)
+	}
+	return sub.SubscriptionAt.In(loc)
+}
+
+// anchorOf returns the subscription date in loc, from which anniversary
+// periods are counted.
+func anchorOf(sub *lago.Subscription, loc *time.Location) time.Time {
+	if sub.SubscriptionAt.IsZero() {
+		return startOf(sub, loc)
+	}
+	return sub.SubscriptionAt.In(loc)
+}
+
+func later(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+// calendar generates the boundaries of the periods of an interval.
+type calendar struct {
+	// anchor is the start of the period containing the subscription date,
+	// boundary(0).
+	anchor time.Time
+	// months is the length of the periods in months, or zero for weeks.
+	months int
+}
+
+func newCalendar(subscriptionAt time.Time, interval lago.PlanInterval, anniversary bool) (calendar, error) {
+	var months int
+	switch interval {
+	case lago.PlanWeekly:
+	case lago.PlanMonthly:
+		months = 1
+	case lago.PlanQuarterly:
+		months = 3
+	case lago.PlanSemiannual:
+		months = 6
+	case lago.PlanYearly:
+		months = 12
+	default:
+		return calendar{}, fmt.Errorf("billing: unknown plan interval %q", interval)
+	}
+
+	y, m, d := subscriptionAt.Date()
+	day := time.Date(y, m, d, 0, 0, 0, 0, subscriptionAt.Location())
+	switch {
+	case anniversary:
+		return calendar{anchor: day, months: months}, nil
+	case months == 0:
+		// Calendar weeks start on Monday.
+		return calendar{anchor: day.AddDate(0, 0, -(int(day.Weekday())+6)%7)}, nil
+	default:
+		first := time.Month((int(m)-1)/months*months + 1)
+		return calendar{anchor: time.Date(y, first, 1, 0, 0, 0, 0, subscriptionAt.Location()), months: months}, nil
+	}
+}
+
+// boundary returns the start of the k-th period after the anchor. Anniversary
+// days missing from shorter months fall on their last day.
+func (c calendar) boundary(k int) time.Time {
+	if c.months == 0 {
+		return c.anchor.AddDate(0, 0, 7*k)
+	}
+	y, m, d := c.anchor.Date()
+	first := time.Date(y, m+time.Month(k*c.months), 1, 0, 0, 0, 0, c.anchor.Location())
+	if last := first.AddDate(0, 1, -1).Day(); d > last {
+		d = last
+	}
+	return first.AddDate(0, 0, d-1)
+}
+
+// next returns the index of the first boundary after t.
+func (c calendar) next(t time.Time) int {
+	k := 1
+	for !c.boundary(k).After(t) {
+		k++
+	}
+	return k
+}
@@ -0,0 +1,222 @@
+package billing_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/billing"
+)
+
+// describe formats an invoice as its issuing date, reason, and the periods
+// of the subscription fee and charges it bills.
+func describe(invoice billing.UpcomingInvoice) string {
+	period := invoice.BillingPeriod
+	s := fmt.Sprintf("%s %s", invoice.IssuingDate, period.InvoicingReason)
+	if invoice.SubscriptionFee {
+		s += fmt.Sprintf(" fee %s..%s", period.SubscriptionFromDatetime.Format(time.DateTime), period.SubscriptionToDatetime.Format(time.DateTime))
+	}
+	if invoice.Charges {
+		s += fmt.Sprintf(" charges %s..%s", period.ChargesFromDatetime.Format(time.DateTime), period.ChargesToDatetime.Format(time.DateTime))
+	}
+	return s
+}
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestUpcoming(t *testing.T) {
+	tests := []struct {
+		name     string
+		sub      lago.Subscription
+		plan     lago.Plan
+		timezone string
+		after    string
+		count    int
+		want     []string
+	}{{
+		name: "calendar monthly in arrears",
+		sub:  lago.Subscription{BillingTime: lago.Calendar, SubscriptionAt: date("2026-03-15T10:00:00Z")},
+		plan: lago.Plan{Interval: lago.PlanMonthly},
+		want: []string{
+			"2026-04-01 subscription_periodic fee 2026-03-15 10:00:00..2026-03-31 23:59:59 charges 2026-03-15 10:00:00..2026-03-31 23:59:59",
+			"2026-05-01 subscription_periodic fee 2026-04-01 00:00:00..2026-04-30 23:59:59 charges 2026-04-01 00:00:00..2026-04-30 23:59:59",
+		},
+	}, {
+		name: "anniversary monthly in advance on the 31st",
+		sub:  lago.Subscription{BillingTime: lago.Anniversary, SubscriptionAt: date("2026-01-31T00:00:00Z")},
+		plan: lago.Plan{Interval: lago.PlanMonthly, PayInAdvance: true},
+		want: []string{
+			"2026-01-31 subscription_starting fee 2026-01-31 00:00:00..2026-02-27 23:59:59",
+			"2026-02-28 subscription_periodic fee 2026-02-28 00:00:00..2026-03-30 23:59:59 charges 2026-01-31 00:00:00..2026-02-27 23:59:59",
+			"2026-03-31 subscription_periodic fee 2026-03-31 00:00:00..2026-04-29 23:59:59 charges 2026-02-28 00:00:00..2026-03-30 23:59:59",
+		},
+	}, {
+		name: "calendar weekly starts on Monday",
+		sub:  lago.Subscription{SubscriptionAt: date("2026-03-18T00:00:00Z")},
+		plan: lago.Plan{Interval: lago.PlanWeekly},
+		want: []string{
+			"2026-03-23 subscription_periodic fee 2026-03-18 00:00:00..2026-03-22 23:59:59 charges 2026-03-18 00:00:00..2026-03-22 23:59:59",
+			"2026-03-30 subscription_periodic fee 2026-03-23 00:00:00..2026-03-29 23:59:59 charges 2026-03-23 00:00:00..2026-03-29 23:59:59",
+		},
+	}, {
+		name: "calendar quarterly",
+		sub:  lago.Subscription{SubscriptionAt: date("2026-02-10T00:00:00Z")},
+		plan: lago.Plan{Interval: lago.PlanQuarterly},
+		want: []string{
+			"2026-04-01 subscription_periodic fee 2026-02-10 00:00:00..2026-03-31 23:59:59 charges 2026-02-10 00:00:00..2026-03-31 23:59:59",
+			"2026-07-01 subscription_periodic fee 2026-04-01 00:00:00..2026-06-30 23:59:59 charges 2026-04-01 00:00:00..2026-06-30 23:59:59",
+		},
+	}, {
+		name:  "calendar yearly in advance with charges billed monthly",
+		sub:   lago.Subscription{SubscriptionAt: date("2026-11-15T00:00:00Z")},
+		plan:  lago.Plan{Interval: lago.PlanYearly, PayInAdvance: true, BillChargesMonthly: true},
+		count: 4,
+		want: []string{
+			"2026-11-15 subscription_starting fee 2026-11-15 00:00:00..2026-12-31 23:59:59",
+			"2026-12-01 subscription_periodic charges 2026-11-15 00:00:00..2026-11-30 23:59:59",
+			"2027-01-01 subscription_periodic fee 2027-01-01 00:00:00..2027-12-31 23:59:59 charges 2026-12-01 00:00:00..2026-12-31 23:59:59",
+			"2027-02-01 subscription_periodic charges 2027-01-01 00:00:00..2027-01-31 23:59:59",
+		},
+	}, {
+		name: "anniversary semiannual",
+		sub:  lago.Subscription{BillingTime: lago.Anniversary, SubscriptionAt: date("2026-08-31T00:00:00Z")},
+		plan: lago.Plan{Interval: lago.PlanSemiannual},
+		want: []string{
+			"2027-02-28 subscription_periodic fee 2026-08-31 00:00:00..2027-02-27 23:59:59 charges 2026-08-31 00:00:00..2027-02-27 23:59:59",
+			"2027-08-31 subscription_periodic fee 2027-02-28 00:00:00..2027-08-30 23:59:59 charges 2027-02-28 00:00:00..2027-08-30 23:59:59",
+		},
+	}, {
+		name: "trial in advance is billed when the trial ends",
+		sub:  lago.Subscription{BillingTime: lago.Anniversary, SubscriptionAt: date("2026-03-01T00:00:00Z")},
+		plan: lago.Plan{Interval: lago.PlanMonthly, PayInAdvance: true, TrialPeriod: 14},
+		want: []string{
+			"2026-03-15 subscription_starting fee 2026-03-15 00:00:00..2026-03-31 23:59:59",
+			"2026-04-01 subscription_periodic fee 2026-04-01 00:00:00..2026-04-30 23:59:59 charges 2026-03-01 00:00:00..2026-03-31 23:59:59",
+		},
+	}, {
+		name: "trial in arrears prorates the first fee",
+		sub:  lago.Subscription{SubscriptionAt: date("2026-03-01T00:00:00Z"), TrialEndedAt: lago.Ptr(date("2026-03-11T00:00:00Z"))},
+		plan: lago.Plan{Interval: lago.PlanMonthly},
+		want: []string{
+			"2026-04-01 subscription_periodic fee 2026-03-11 00:00:00..2026-03-31 23:59:59 charges 2026-03-01 00:00:00..2026-03-31 23:59:59",
+			"2026-05-01 subscription_periodic fee 2026-04-01 00:00:00..2026-04-30 23:59:59 charges 2026-04-01 00:00:00..2026-04-30 23:59:59",
+		},
+	}, {
+		name:     "periods follow the timezone of the customer",
+		sub:      lago.Subscription{SubscriptionAt: date("2026-04-01T02:00:00Z")},
+		plan:     lago.Plan{Interval: lago.PlanMonthly},
+		timezone: "America/New_York",
+		want: []string{
+			"2026-04-01 subscription_periodic fee 2026-03-31 22:00:00..2026-03-31 23:59:59 charges 2026-03-31 22:00:00..2026-03-31 23:59:59",
+			"2026-05-01 subscription_periodic fee 2026-04-01 00:00:00..2026-04-30 23:59:59 charges 2026-04-01 00:00:00..2026-04-30 23:59:59",
+		},
+	}, {
+		name: "anniversary of the subscription date and ending date",
+		sub: lago.Subscription{
+			BillingTime:    lago.Anniversary,
+			SubscriptionAt: date("2026-01-10T00:00:00Z"),
+			StartedAt:      lago.Ptr(date("2026-01-12T00:00:00Z")),
+			EndingAt:       lago.Ptr(date("2026-03-20T00:00:00Z")),
+		},
+		plan: lago.Plan{Interval: lago.PlanMonthly},
+		want: []string{
+			"2026-02-10 subscription_periodic fee 2026-01-12 00:00:00..2026-02-09 23:59:59 charges 2026-01-12 00:00:00..2026-02-09 23:59:59",
+			"2026-03-10 subscription_periodic fee 2026-02-10 00:00:00..2026-03-09 23:59:59 charges 2026-02-10 00:00:00..2026-03-09 23:59:59",
+			"2026-03-20 subscription_terminating fee 2026-03-10 00:00:00..2026-03-20 00:00:00 charges 2026-03-10 00:00:00..2026-03-20 00:00:00",
+		},
+	}, {
+		name:  "invoices after a date",
+		sub:   lago.Subscription{SubscriptionAt: date("2025-06-15T00:00:00Z")},
+		plan:  lago.Plan{Interval: lago.PlanMonthly, PayInAdvance: true},
+		after: "2026-03-10T00:00:00Z",
+		want: []string{
+			"2026-04-01 subscription_periodic fee 2026-04-01 00:00:00..2026-04-30 23:59:59 charges 2026-03-01 00:00:00..2026-03-31 23:59:59",
+			"2026-05-01 subscription_periodic fee 2026-05-01 00:00:00..2026-05-31 23:59:59 charges 2026-04-01 00:00:00..2026-04-30 23:59:59",
+		},
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			after := date("2025-01-01T00:00:00Z")
+			if test.after != "" {
+				after = date(test.after)
+			}
+			count := test.count
+			if count == 0 {
+				count = len(test.want)
+			}
+			invoices, err := billing.Upcoming(&test.sub, billing.Options{
+				Plan:     &test.plan,
+				Timezone: test.timezone,
+				After:    after,
+				Count:    count,
+			})
+			c.Assert(err, qt.IsNil)
+
+			var got []string
+			for _, invoice := range invoices {
+				got = append(got, describe(invoice))
+			}
+			c.Assert(got, qt.DeepEquals, test.want)
+		})
+	}
+}
+
+func TestUpcoming_IssuedAt(t *testing.T) {
+	c := qt.New(t)
+
+	// Invoices are generated at midnight in the timezone of the customer.
+	invoices, err := billing.Upcoming(&lago.Subscription{SubscriptionAt: date("2026-04-01T02:00:00Z")}, billing.Options{
+		Plan:     &lago.Plan{Interval: lago.PlanMonthly},
+		Timezone: "America/New_York",
+		After:    date("2026-03-01T00:00:00Z"),
+		Count:    1,
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(invoices, qt.HasLen, 1)
+	c.Assert(invoices[0].IssuedAt.UTC(), qt.Equals, date("2026-04-01T04:00:00Z"))
+}
+
+func TestUpcoming_Errors(t *testing.T) {
+	c := qt.New(t)
+
+	_, err := billing.Upcoming(&lago.Subscription{}, billing.Options{})
+	c.Assert(err, qt.Equals, billing.ErrNoPlan)
+
+	_, err = billing.Upcoming(&lago.Subscription{Plan: &lago.Plan{Interval: "daily"}}, billing.Options{})
+	c.Assert(err, qt.ErrorMatches, `billing: unknown plan interval "daily"`)
+
+	_, err = billing.Upcoming(&lago.Subscription{Plan: &lago.Plan{Interval: lago.PlanMonthly}}, billing.Options{Timezone: "Mars/Olympus"})
+	c.Assert(err, qt.ErrorMatches, `billing: timezone: .*`)
+
+	_, err = billing.Upcoming(&lago.Subscription{Plan: &lago.Plan{Interval: lago.PlanMonthly}}, billing.Options{Count: -1})
+	c.Assert(err, qt.ErrorMatches, `billing: negative count -1`)
+}
+
+func TestPeriod(t *testing.T) {
+	c := qt.New(t)
+
+	sub := &lago.Subscription{BillingTime: lago.Anniversary, SubscriptionAt: date("2026-01-31T09:00:00Z")}
+	opts := billing.Options{Plan: &lago.Plan{Interval: lago.PlanMonthly}}
+
+	from, to, err := billing.Period(sub, opts, date("2026-02-10T00:00:00Z"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(from, qt.Equals, date("2026-01-31T09:00:00Z"))
+	c.Assert(to, qt.Equals, date("2026-02-27T23:59:59Z"))
+
+	from, to, err = billing.Period(sub, opts, date("2026-03-31T00:00:00Z"))
+	c.Assert(err, qt.IsNil)
+	c.Assert(from, qt.Equals, date("2026-03-31T00:00:00Z"))
+	c.Assert(to, qt.Equals, date("2026-04-29T23:59:59Z"))
+}
@@ -0,0 +1,555 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/uuid"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+type ApplyOptions struct {
+	// DryRun only reports the changes, nothing is sent to Lago.
+	DryRun bool
+	// Log receives one line per applied change. It may be nil.
+	Log io.Writer
+	// CascadeUpdates propagates plan and charge updates to the subscriptions
+	// overriding the plan.
+	CascadeUpdates bool
+}
+
+// Apply applies the changes in order, stopping at the first failure. It
+// returns the changes that were applied, or that would be applied in dry-run
+// mode.
+func Apply(ctx context.Context, client *lago.Client, changeset *Changeset, opts ApplyOptions) ([]Change, error) {
+	return newApplier(client, opts).applyAll(ctx, changeset)
+}
+
+func newApplier(client *lago.Client, opts ApplyOptions) *applier {
+	return &applier{
+		client:    client,
+		opts:      opts,
+		metricIDs: make(map[string]string),
+		addOnIDs:  make(map[string]uuid.UUID),
+		filterIDs: make(map[string]map[string]string),
+	}
+}
+
+func (a *applier) applyAll(ctx context.Context, changeset *Changeset) ([]Change, error) {
+	applied := make([]Change, 0, len(changeset.Changes))
+	for _, change := range changeset.Changes {
+		if a.opts.DryRun {
+			a.log("(dry run) %s", change)
+			applied = append(applied, change)
+			continue
+		}
+
+		if err := a.apply(ctx, change); err != nil {
+			return applied, fmt.Errorf("catalog: %s: %w", change, err)
+		}
+		a.log("%s", change)
+		applied = append(applied, change)
+	}
+
+	return applied, nil
+}
+
+type applier struct {
+	client *lago.Client
+	opts   ApplyOptions
+
+	metricIDs map[string]string
+	addOnIDs  map[string]uuid.UUID
+	// filterIDs caches the filter IDs of a charge, by plan/charge code then filter key.
+	filterIDs map[string]map[string]string
+}
+
+func (a *applier) log(format string, args ...any) {
+	if a.opts.Log != nil {
+		fmt.Fprintf(a.opts.Log, format+"\n", args...)
+	}
+}
+
+func (a *applier) apply(ctx context.Context, change Change) *lago.Error {
+	switch change.Kind {
+	case KindTax:
+		return a.applyTax(ctx, change)
+	case KindBillableMetric:
+		return a.applyBillableMetric(ctx, change)
+	case KindAddOn:
+		return a.applyAddOn(ctx, change)
+	case KindFeature:
+		return a.applyFeature(ctx, change)
+	case KindPlan:
+		return a.applyPlan(ctx, change)
+	case KindCharge:
+		return a.applyCharge(ctx, change)
+	case KindChargeFilter:
+		return a.applyChargeFilter(ctx, change)
+	case KindFixedCharge:
+		return a.applyFixedCharge(ctx, change)
+	case KindEntitlement:
+		return a.applyEntitlement(ctx, change)
+	case KindCoupon:
+		return a.applyCoupon(ctx, change)
+	default:
+		return &lago.Error{Err: fmt.Errorf("unknown kind %q", change.Kind)}
+	}
+}
+
+func (a *applier) applyTax(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete {
+		_, err := a.client.Tax().Delete(ctx, change.Code)
+		return err
+	}
+
+	tax := change.Desired.(Tax)
+	input := &lago.TaxInput{
+		Code:                  tax.Code,
+		Name:                  tax.Name,
+		Rate:                  lago.Ptr(tax.Rate),
+		Description:           tax.Description,
+		AppliedToOrganization: tax.AppliedToOrganization,
+	}
+
+	var err *lago.Error
+	if change.Action == ActionCreate {
+		_, err = a.client.Tax().Create(ctx, input)
+	} else {
+		_, err = a.client.Tax().Update(ctx, input)
+	}
+
+	return err
+}
+
+func (a *applier) applyBillableMetric(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete {
+		_, err := a.client.BillableMetric().Delete(ctx, change.Code)
+		return err
+	}
+
+	metric := change.Desired.(BillableMetric)
+	input := &lago.BillableMetricInput{
+		Code:              metric.Code,
+		Name:              metric.Name,
+		Description:       metric.Description,
+		AggregationType:   metric.AggregationType,
+		FieldName:         metric.FieldName,
+		Expression:        metric.Expression,
+		Recurring:         metric.Recurring,
+		RoundingPrecision: metric.RoundingPrecision,
+		WeightedInterval:  metric.WeightedInterval,
+		Filters:           metric.Filters,
+	}
+	if metric.RoundingFunction != "" {
+		input.RoundingFunction = lago.Ptr(metric.RoundingFunction)
+	}
+
+	var result *lago.BillableMetric
+	var err *lago.Error
+	if change.Action == ActionCreate {
+		result, err = a.client.BillableMetric().Create(ctx, input)
+	} else {
+		result, err = a.client.BillableMetric().Update(ctx, input)
+	}
+	if err != nil {
+		return err
+	}
+	a.metricIDs[metric.Code] = result.LagoID.String()
+
+	return nil
+}
+
+func (a *applier) applyAddOn(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete {
+		_, err := a.client.AddOn().Delete(ctx, change.Code)
+		return err
+	}
+
+	addOn := change.Desired.(AddOn)
+	input := &lago.AddOnInput{
+		Code:               addOn.Code,
+		Name:               addOn.Name,
+		InvoiceDisplayName: addOn.InvoiceDisplayName,
+		Description:        addOn.Description,
+		AmountCents:        addOn.AmountCents,
+		AmountCurrency:     addOn.AmountCurrency,
+		TaxCodes:           addOn.TaxCodes,
+	}
+
+	var result *lago.AddOn
+	var err *lago.Error
+	if change.Action == ActionCreate {
+		result, err = a.client.AddOn().Create(ctx, input)
+	} else {
+		result, err = a.client.AddOn().Update(ctx, input)
+	}
+	if err != nil {
+		return err
+	}
+	a.addOnIDs[addOn.Code] = result.LagoID
+
+	return nil
+}
+
+func (a *applier) applyFeature(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete {
+		_, err := a.client.Feature().Delete(ctx, change.Code)
+		return err
+	}
+
+	feature := change.Desired.(Feature)
+	input := &lago.FeatureInput{
+		Code:        feature.Code,
+		Name:        feature.Name,
+		Description: feature.Description,
+	}
+	for _, privilege := range feature.Privileges {
+		input.Privileges = append(input.Privileges, lago.PrivilegeInput{
+			Code:      privilege.Code,
+			Name:      privilege.Name,
+			ValueType: privilege.ValueType,
+			Config:    lago.ConfigInput{SelectOptions: privilege.SelectOptions},
+		})
+	}
+
+	if change.Action == ActionCreate {
+		_, err := a.client.Feature().Create(ctx, input)
+		return err
+	}
+	if _, err := a.client.Feature().Update(ctx, input); err != nil {
+		return err
+	}
+
+	// Privileges are only added or updated by a feature update.
+	declared := make(map[string]bool, len(feature.Privileges))
+	for _, privilege := range feature.Privileges {
+		declared[privilege.Code] = true
+	}
+	for _, privilege := range change.Current.(Feature).Privileges {
+		if declared[privilege.Code] {
+			continue
+		}
+		if _, err := a.client.Feature().DeletePrivilege(ctx, feature.Code, privilege.Code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *applier) applyPlan(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete {
+		_, err := a.client.Plan().Delete(ctx, change.Code)
+		return err
+	}
+
+	plan := change.Desired.(Plan)
+	input := &lago.PlanInput{
+		Code:               plan.Code,
+		Name:               plan.Name,
+		InvoiceDisplayName: plan.InvoiceDisplayName,
+		Description:        plan.Description,
+		Interval:           plan.Interval,
+		AmountCents:        plan.AmountCents,
+		AmountCurrency:     plan.AmountCurrency,
+		PayInAdvance:       plan.PayInAdvance,
+		BillChargesMonthly: plan.BillChargesMonthly,
+		TrialPeriod:        plan.TrialPeriod,
+		TaxCodes:           plan.TaxCodes,
+		CascadeUpdates:     a.opts.CascadeUpdates,
+	}
+	if plan.MinimumCommitment != nil {
+		input.MinimumCommitment = &lago.MinimumCommitmentInput{
+			AmountCents:        plan.MinimumCommitment.AmountCents,
+			InvoiceDisplayName: plan.MinimumCommitment.InvoiceDisplayName,
+			TaxCodes:           plan.MinimumCommitment.TaxCodes,
+		}
+	}
+	for _, threshold := range plan.UsageThresholds {
+		input.UsageThresholds = append(input.UsageThresholds, lago.UsageThresholdInput{
+			ThresholdDisplayName: threshold.ThresholdDisplayName,
+			AmountCents:          threshold.AmountCents,
+			Recurring:            threshold.Recurring,
+		})
+	}
+
+	// Charges, fixed charges and entitlements are applied by their own changes.
+	var err *lago.Error
+	if change.Action == ActionCreate {
+		_, err = a.client.Plan().Create(ctx, input)
+	} else {
+		_, err = a.client.Plan().Update(ctx, input)
+	}
+
+	return err
+}
+
+func (a *applier) applyCharge(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete || change.Action == ActionReplace {
+		if _, err := a.client.Plan().DeleteCharge(ctx, change.PlanCode, change.Code); err != nil {
+			return err
+		}
+		if change.Action == ActionDelete {
+			return nil
+		}
+	}
+
+	charge := change.Desired.(Charge)
+	metricID, err := a.metricID(ctx, charge.BillableMetricCode)
+	if err != nil {
+		return err
+	}
+
+	input := &lago.ChargeInput{
+		BillableMetricID:   metricID,
+		Code:               charge.Code,
+		ChargeModel:        charge.ChargeModel,
+		InvoiceDisplayName: charge.InvoiceDisplayName,
+		PayInAdvance:       charge.PayInAdvance,
+		Prorated:           charge.Prorated,
+		MinAmountCents:     charge.MinAmountCents,
+		Properties:         charge.Properties,
+		TaxCodes:           charge.TaxCodes,
+	}
+	if a.opts.CascadeUpdates {
+		input.CascadeUpdates = lago.Ptr(true)
+	}
+
+	if change.Action == ActionUpdate {
+		_, err = a.client.Plan().UpdateCharge(ctx, change.PlanCode, charge.Code, input)
+		return err
+	}
+
+	for _, filter := range charge.Filters {
+		input.Filters = append(input.Filters, lago.ChargeFilter{
+			InvoiceDisplayName: filter.InvoiceDisplayName,
+			Properties:         filter.Properties,
+			Values:             filterValues(filter),
+		})
+	}
+	_, err = a.client.Plan().CreateCharge(ctx, change.PlanCode, input)
+
+	return err
+}
+
+func (a *applier) applyChargeFilter(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionCreate {
+		filter := change.Desired.(ChargeFilter)
+		_, err := a.client.Plan().CreateChargeFilter(ctx, change.PlanCode, change.ChargeCode, chargeFilterInput(filter, a.opts.CascadeUpdates))
+		return err
+	}
+
+	filterID, err := a.filterID(ctx, change.PlanCode, change.ChargeCode, change.Code)
+	if err != nil {
+		return err
+	}
+
+	if change.Action == ActionDelete {
+		_, err = a.client.Plan().DeleteChargeFilter(ctx, change.PlanCode, change.ChargeCode, filterID)
+		return err
+	}
+
+	filter := change.Desired.(ChargeFilter)
+	_, err = a.client.Plan().UpdateChargeFilter(ctx, change.PlanCode, change.ChargeCode, filterID, chargeFilterInput(filter, a.opts.CascadeUpdates))
+
+	return err
+}
+
+func (a *applier) applyFixedCharge(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete || change.Action == ActionReplace {
+		if _, err := a.client.Plan().DeleteFixedCharge(ctx, change.PlanCode, change.Code); err != nil {
+			return err
+		}
+		if change.Action == ActionDelete {
+			return nil
+		}
+	}
+
+	fixedCharge := change.Desired.(FixedCharge)
+	addOnID, err := a.addOnID(ctx, fixedCharge.AddOnCode)
+	if err != nil {
+		return err
+	}
+
+	input := &lago.FixedChargeInput{
+		AddOnID:            addOnID,
+		Code:               fixedCharge.Code,
+		ChargeModel:        fixedCharge.ChargeModel,
+		InvoiceDisplayName: fixedCharge.InvoiceDisplayName,
+		Units:              fixedCharge.Units,
+		PayInAdvance:       fixedCharge.PayInAdvance,
+		Prorated:           fixedCharge.Prorated,
+		Properties:         fixedCharge.Properties,
+		TaxCodes:           fixedCharge.TaxCodes,
+	}
+	if a.opts.CascadeUpdates {
+		input.CascadeUpdates = lago.Ptr(true)
+	}
+
+	if change.Action == ActionUpdate {
+		_, err = a.client.Plan().UpdateFixedCharge(ctx, change.PlanCode, fixedCharge.Code, input)
+	} else {
+		_, err = a.client.Plan().CreateFixedCharge(ctx, change.PlanCode, input)
+	}
+
+	return err
+}
+
+func (a *applier) applyEntitlement(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete {
+		_, err := a.client.PlanEntitlement().Delete(ctx, change.PlanCode, change.Code)
+		return err
+	}
+
+	entitlement := change.Desired.(Entitlement)
+	input := lago.EntitlementInput{Code: entitlement.FeatureCode, Privileges: []lago.EntitlementPrivilegeInput{}}
+
+	codes := make([]string, 0, len(entitlement.Privileges))
+	for code := range entitlement.Privileges {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	for _, code := range codes {
+		input.Privileges = append(input.Privileges, lago.EntitlementPrivilegeInput{Code: code, Value: entitlement.Privileges[code]})
+	}
+
+	if _, err := a.client.PlanEntitlement().Update(ctx, change.PlanCode, []lago.EntitlementInput{input}); err != nil {
+		return err
+	}
+
+	// The update leaves the privileges it does not list untouched.
+	current, _ := change.Current.(Entitlement)
+	var removed []string
+	for code := range current.Privileges {
+		if _, ok := entitlement.Privileges[code]; !ok {
+			removed = append(removed, code)
+		}
+	}
+	sort.Strings(removed)
+	for _, code := range removed {
+		if _, err := a.client.PlanEntitlement().DeletePrivilege(ctx, change.PlanCode, change.Code, code); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (a *applier) applyCoupon(ctx context.Context, change Change) *lago.Error {
+	if change.Action == ActionDelete {
+		_, err := a.client.Coupon().Delete(ctx, change.Code)
+		return err
+	}
+
+	coupon := change.Desired.(Coupon)
+	input := &lago.CouponInput{
+		Code:              coupon.Code,
+		Name:              coupon.Name,
+		Description:       coupon.Description,
+		CouponType:        coupon.CouponType,
+		AmountCents:       coupon.AmountCents,
+		AmountCurrency:    coupon.AmountCurrency,
+		PercentageRate:    coupon.PercentageRate,
+		Frequency:         coupon.Frequency,
+		FrequencyDuration: coupon.FrequencyDuration,
+		Reusable:          coupon.Reusable,
+		Expiration:        coupon.Expiration,
+		ExpirationAt:      coupon.ExpirationAt,
+		AppliesTo: lago.LimitationInput{
+			PlanCodes:           coupon.PlanCodes,
+			BillableMetricCodes: coupon.BillableMetricCodes,
+		},
+	}
+
+	var err *lago.Error
+	if change.Action == ActionCreate {
+		_, err = a.client.Coupon().Create(ctx, input)
+	} else {
+		_, err = a.client.Coupon().Update(ctx, input)
+	}
+
+	return err
+}
+
+func (a *applier) metricID(ctx context.Context, code string) (string, *lago.Error) {
+	if id, ok := a.metricIDs[code]; ok {
+		return id, nil
+	}
+
+	metric, err := a.client.BillableMetric().Get(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	a.metricIDs[code] = metric.LagoID.String()
+
+	return a.metricIDs[code], nil
+}
+
+func (a *applier) addOnID(ctx context.Context, code string) (uuid.UUID, *lago.Error) {
+	if id, ok := a.addOnIDs[code]; ok {
+		return id, nil
+	}
+
+	addOn, err := a.client.AddOn().Get(ctx, code)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	a.addOnIDs[code] = addOn.LagoID
+
+	return addOn.LagoID, nil
+}
+
+func (a *applier) filterID(ctx context.Context, planCode, chargeCode, key string) (string, *lago.Error) {
+	chargePath := planCode + "/" + chargeCode
+	if a.filterIDs[chargePath] == nil {
+		filters, err := lago.FetchPages(0, func(page int) ([]lago.ChargeFilterResponse, lago.Metadata, *lago.Error) {
+			result, err := a.client.Plan().GetChargeFilterList(ctx, planCode, chargeCode, &lago.ChargeFilterListInput{Page: page, PerPage: fetchPerPage})
+			if err != nil {
+				return nil, lago.Metadata{}, err
+			}
+			return result.Filters, result.Meta, nil
+		})
+		if err != nil {
+			return "", err
+		}
+
+		ids := make(map[string]string)
+		for _, filter := range filters {
+			ids[chargeFilterFromLago(filter.InvoiceDisplayName, filter.Values, nil).Key()] = filter.LagoID.String()
+		}
+		a.filterIDs[chargePath] = ids
+	}
+
+	id, ok := a.filterIDs[chargePath][key]
+	if !ok {
+		return "", &lago.Error{Err: fmt.Errorf("filter %q of charge %s not found", key, chargePath)}
+	}
+
+	return id, nil
+}
+
+func chargeFilterInput(filter ChargeFilter, cascadeUpdates bool) *lago.ChargeFilterInput {
+	input := &lago.ChargeFilterInput{
+		InvoiceDisplayName: filter.InvoiceDisplayName,
+		Properties:         filter.Properties,
+		Values:             filterValues(filter),
+	}
+	if cascadeUpdates {
+		input.CascadeUpdates = lago.Ptr(true)
+	}
+
+	return input
+}
+
+func filterValues(filter ChargeFilter) map[string]interface{} {
+	values := make(map[string]interface{}, len(filter.Values))
+	for key, keyValues := range filter.Values {
+		values[key] = keyValues
+	}
+
+	return values
+}
@@ -0,0 +1,206 @@
+package catalog_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/getlago/lago-go-client/catalog"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+const metricID = "1a901a90-1a90-1a90-1a90-1a901a901a90"
+
+// calls returns the requests received by server as "METHOD /path".
+func calls(server *lt.RoutesServer) []string {
+	var calls []string
+	for _, request := range server.Requests() {
+		calls = append(calls, request.Method+" "+request.Path)
+	}
+	return calls
+}
+
+// body decodes the body of the last request received by server for method
+// and path.
+func body(c *qt.C, server *lt.RoutesServer, method, path string) map[string]any {
+	var decoded map[string]any
+	c.Assert(json.Unmarshal([]byte(server.Last(method, path).Body), &decoded), qt.IsNil)
+	return decoded
+}
+
+func TestApply_DryRun(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, nil)
+	client := server.Client()
+
+	changeset, err := catalog.Diff(mustParse(c, catalogYAML), &catalog.Catalog{}, catalog.DiffOptions{})
+	c.Assert(err, qt.IsNil)
+
+	var log bytes.Buffer
+	applied, err := catalog.Apply(context.Background(), client, changeset, catalog.ApplyOptions{DryRun: true, Log: &log})
+	c.Assert(err, qt.IsNil)
+	c.Assert(applied, qt.HasLen, len(changeset.Changes))
+	c.Assert(calls(server), qt.HasLen, 0)
+	c.Assert(strings.HasPrefix(log.String(), "(dry run) + create tax vat_fr\n"), qt.IsTrue)
+}
+
+func TestApply_CreatesCatalog(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{
+		"POST /billable_metrics":            `{"billable_metric": {"lago_id": "` + metricID + `", "code": "api_calls"}}`,
+		"GET /add_ons/onboarding":           `{"add_on": {"lago_id": "2b902b90-2b90-2b90-2b90-2b902b902b90", "code": "onboarding"}}`,
+		"POST /add_ons":                     `{"add_on": {"lago_id": "2b902b90-2b90-2b90-2b90-2b902b902b90", "code": "onboarding"}}`,
+		"POST /taxes":                       `{}`,
+		"POST /features":                    `{}`,
+		"POST /plans":                       `{}`,
+		"POST /plans/startup/charges":       `{}`,
+		"POST /plans/startup/fixed_charges": `{}`,
+		"PATCH /plans/startup/entitlements": `{}`,
+		"POST /coupons":                     `{}`,
+	})
+	client := server.Client()
+
+	changeset, err := catalog.Diff(mustParse(c, catalogYAML), &catalog.Catalog{}, catalog.DiffOptions{})
+	c.Assert(err, qt.IsNil)
+
+	applied, err := catalog.Apply(context.Background(), client, changeset, catalog.ApplyOptions{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(applied, qt.HasLen, len(changeset.Changes))
+
+	c.Assert(calls(server), qt.DeepEquals, []string{
+		"POST /taxes",
+		"POST /billable_metrics",
+		"POST /add_ons",
+		"POST /features",
+		"POST /plans",
+		"POST /plans/startup/charges",
+		"POST /plans/startup/fixed_charges",
+		"PATCH /plans/startup/entitlements",
+		"POST /coupons",
+	})
+
+	charge := body(c, server, "POST", "/plans/startup/charges")["charge"].(map[string]any)
+	c.Assert(charge["billable_metric_id"], qt.Equals, metricID)
+	c.Assert(charge["filters"], qt.DeepEquals, []any{map[string]any{
+		"properties": map[string]any{"amount": "0.02"},
+		"values":     map[string]any{"region": []any{"eu", "us"}},
+	}})
+
+	plan := body(c, server, "POST", "/plans")["plan"].(map[string]any)
+	c.Assert(plan["charges"], qt.IsNil)
+	c.Assert(plan["usage_thresholds"], qt.HasLen, 1)
+
+	c.Assert(body(c, server, "PATCH", "/plans/startup/entitlements"), qt.DeepEquals, map[string]any{
+		"entitlements": map[string]any{"seats": map[string]any{"max": float64(10)}},
+	})
+}
+
+func TestFetchAndApply_Updates(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{
+		"GET /taxes":            `{"taxes": [{"code": "vat_fr", "name": "VAT FR", "rate": 20}], "meta": {"current_page": 1}}`,
+		"GET /billable_metrics": `{"billable_metrics": [{"lago_id": "` + metricID + `", "code": "api_calls", "name": "API calls", "aggregation_type": "count_agg"}], "meta": {}}`,
+		"GET /add_ons":          `{"add_ons": [], "meta": {}}`,
+		"GET /features":         `{"features": [], "meta": {}}`,
+		"GET /coupons":          `{"coupons": [], "meta": {}}`,
+		"GET /plans": `{"plans": [{"code": "startup", "name": "Startup", "interval": "monthly", "amount_cents": 4900, "amount_currency": "EUR",
+			"charges": [{"code": "api_calls", "billable_metric_code": "api_calls", "charge_model": "standard", "properties": {"amount": "0.01"},
+				"filters": [{"values": {"region": ["us"]}, "properties": {"amount": "0.05"}}]}]}], "meta": {}}`,
+		"GET /plans/startup/entitlements":                                                      `{"entitlements": []}`,
+		"GET /plans/startup/charges/api_calls/filters":                                         `{"filters": [{"lago_id": "3c903c90-3c90-3c90-3c90-3c903c903c90", "values": {"region": ["us"]}}], "meta": {}}`,
+		"GET /billable_metrics/api_calls":                                                      `{"billable_metric": {"lago_id": "` + metricID + `", "code": "api_calls"}}`,
+		"DELETE /plans/startup/charges/api_calls/filters/3c903c90-3c90-3c90-3c90-3c903c903c90": `{}`,
+		"PUT /taxes/vat_fr":                                                                    `{}`,
+		"PUT /plans/startup/charges/api_calls":                                                 `{}`,
+		"POST /plans/startup/charges/api_calls/filters":                                        `{}`,
+	})
+	client := server.Client()
+
+	current, err := catalog.Fetch(context.Background(), client)
+	c.Assert(err, qt.IsNil)
+	c.Assert(current.Plans[0].Charges[0].Filters[0].Key(), qt.Equals, "region=us")
+
+	desired := mustParse(c, `
+taxes:
+  - code: vat_fr
+    name: VAT FR
+    rate: 5.5
+    applied_to_organization: false
+plans:
+  - code: startup
+    name: Startup
+    interval: monthly
+    amount_cents: 4900
+    amount_currency: EUR
+    pay_in_advance: false
+    bill_charges_monthly: false
+    trial_period: 0
+    charges:
+      - billable_metric_code: api_calls
+        charge_model: standard
+        pay_in_advance: false
+        prorated: false
+        properties:
+          amount: 0.02
+        filters:
+          - values:
+              region: [eu]
+            properties:
+              amount: "0.03"
+`)
+
+	changeset, err := catalog.Diff(desired, current, catalog.DiffOptions{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(changePaths(changeset), qt.DeepEquals, []string{
+		"~ update tax vat_fr",
+		"~ update charge startup/api_calls",
+		"+ create charge_filter startup/api_calls[region=eu]",
+		"- delete charge_filter startup/api_calls[region=us]",
+	})
+
+	fetched := len(server.Requests())
+	_, err = catalog.Apply(context.Background(), client, changeset, catalog.ApplyOptions{CascadeUpdates: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(calls(server)[fetched:], qt.DeepEquals, []string{
+		"PUT /taxes/vat_fr",
+		"GET /billable_metrics/api_calls",
+		"PUT /plans/startup/charges/api_calls",
+		"POST /plans/startup/charges/api_calls/filters",
+		"GET /plans/startup/charges/api_calls/filters",
+		"DELETE /plans/startup/charges/api_calls/filters/3c903c90-3c90-3c90-3c90-3c903c903c90",
+	})
+
+	charge := body(c, server, "PUT", "/plans/startup/charges/api_calls")["charge"].(map[string]any)
+	c.Assert(charge["cascade_updates"], qt.Equals, true)
+	c.Assert(charge["filters"], qt.IsNil)
+}
+
+func TestApply_RemovesPrivileges(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{
+		"PATCH /plans/startup/entitlements":                       `{}`,
+		"DELETE /plans/startup/entitlements/seats/privileges/sso": `{}`,
+	})
+
+	current := mustParse(c, catalogYAML)
+	current.Plans[0].Entitlements[0].Privileges["sso"] = true
+	changeset, err := catalog.Diff(mustParse(c, catalogYAML), current, catalog.DiffOptions{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(changePaths(changeset), qt.DeepEquals, []string{"~ update entitlement startup/seats"})
+	c.Assert(changeset.Changes[0].Fields, qt.DeepEquals, []catalog.FieldDiff{{Path: "privileges.sso", Old: true}})
+
+	_, err = catalog.Apply(context.Background(), server.Client(), changeset, catalog.ApplyOptions{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(calls(server), qt.DeepEquals, []string{
+		"PATCH /plans/startup/entitlements",
+		"DELETE /plans/startup/entitlements/seats/privileges/sso",
+	})
+}
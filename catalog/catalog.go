@@ -0,0 +1,393 @@
+// Package catalog keeps a Lago pricing catalog (taxes, billable metrics,
+// add-ons, features, plans and coupons) in sync with a declarative file kept
+// in version control.
+//
+// A sync is done in three steps:
+//
+//	desired, err := catalog.Load("catalog.yaml")
+//	current, err := catalog.Fetch(ctx, client)
+//	changes, err := catalog.Diff(desired, current, catalog.DiffOptions{})
+//	fmt.Print(changes)
+//	_, err = catalog.Apply(ctx, client, changes, catalog.ApplyOptions{DryRun: true})
+//
+// Resources reference each other by code only, so a catalog file is portable
+// across organizations.
+package catalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// ErrInvalidCatalog is returned when a catalog file cannot be decoded or fails validation.
+var ErrInvalidCatalog = errors.New("catalog: invalid catalog")
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+)
+
+// Catalog is the declarative description of a pricing catalog.
+//
+// Optional fields left empty are not managed: Diff ignores them instead of
+// resetting the value found in Lago.
+type Catalog struct {
+	Taxes           []Tax            `json:"taxes,omitempty"`
+	BillableMetrics []BillableMetric `json:"billable_metrics,omitempty"`
+	AddOns          []AddOn          `json:"add_ons,omitempty"`
+	Features        []Feature        `json:"features,omitempty"`
+	Plans           []Plan           `json:"plans,omitempty"`
+	Coupons         []Coupon         `json:"coupons,omitempty"`
+}
+
+type Tax struct {
+	Code                  string  `json:"code"`
+	Name                  string  `json:"name,omitempty"`
+	Rate                  float32 `json:"rate"`
+	Description           string  `json:"description,omitempty"`
+	AppliedToOrganization bool    `json:"applied_to_organization"`
+}
+
+type BillableMetric struct {
+	Code              string                      `json:"code"`
+	Name              string                      `json:"name,omitempty"`
+	Description       string                      `json:"description,omitempty"`
+	AggregationType   lago.AggregationType        `json:"aggregation_type"`
+	FieldName         string                      `json:"field_name,omitempty"`
+	Expression        string                      `json:"expression,omitempty"`
+	Recurring         bool                        `json:"recurring"`
+	RoundingFunction  lago.RoundingFunction       `json:"rounding_function,omitempty"`
+	RoundingPrecision *int                        `json:"rounding_precision,omitempty"`
+	WeightedInterval  lago.WeightedInterval       `json:"weighted_interval,omitempty"`
+	Filters           []lago.BillableMetricFilter `json:"filters,omitempty"`
+}
+
+type AddOn struct {
+	Code               string        `json:"code"`
+	Name               string        `json:"name,omitempty"`
+	InvoiceDisplayName string        `json:"invoice_display_name,omitempty"`
+	Description        string        `json:"description,omitempty"`
+	AmountCents        int           `json:"amount_cents"`
+	AmountCurrency     lago.Currency `json:"amount_currency"`
+	TaxCodes           []string      `json:"tax_codes,omitempty"`
+}
+
+type Feature struct {
+	Code        string      `json:"code"`
+	Name        string      `json:"name,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Privileges  []Privilege `json:"privileges,omitempty"`
+}
+
+type Privilege struct {
+	Code          string         `json:"code"`
+	Name          string         `json:"name,omitempty"`
+	ValueType     lago.ValueType `json:"value_type"`
+	SelectOptions []string       `json:"select_options,omitempty"`
+}
+
+type Plan struct {
+	Code               string             `json:"code"`
+	Name               string             `json:"name,omitempty"`
+	InvoiceDisplayName string             `json:"invoice_display_name,omitempty"`
+	Description        string             `json:"description,omitempty"`
+	Interval           lago.PlanInterval  `json:"interval"`
+	AmountCents        int                `json:"amount_cents"`
+	AmountCurrency     lago.Currency      `json:"amount_currency"`
+	PayInAdvance       bool               `json:"pay_in_advance"`
+	BillChargesMonthly bool               `json:"bill_charges_monthly"`
+	TrialPeriod        float32            `json:"trial_period"`
+	TaxCodes           []string           `json:"tax_codes,omitempty"`
+	MinimumCommitment  *MinimumCommitment `json:"minimum_commitment,omitempty"`
+	UsageThresholds    []UsageThreshold   `json:"usage_thresholds,omitempty"`
+
+	Charges      []Charge      `json:"charges,omitempty"`
+	FixedCharges []FixedCharge `json:"fixed_charges,omitempty"`
+	Entitlements []Entitlement `json:"entitlements,omitempty"`
+}
+
+type MinimumCommitment struct {
+	AmountCents        int      `json:"amount_cents"`
+	InvoiceDisplayName string   `json:"invoice_display_name,omitempty"`
+	TaxCodes           []string `json:"tax_codes,omitempty"`
+}
+
+type UsageThreshold struct {
+	ThresholdDisplayName string `json:"threshold_display_name,omitempty"`
+	AmountCents          int    `json:"amount_cents"`
+	Recurring            bool   `json:"recurring"`
+}
+
+// Charge is a usage based charge of a plan. Code defaults to the billable
+// metric code.
+type Charge struct {
+	Code               string           `json:"code,omitempty"`
+	BillableMetricCode string           `json:"billable_metric_code"`
+	ChargeModel        lago.ChargeModel `json:"charge_model"`
+	InvoiceDisplayName string           `json:"invoice_display_name,omitempty"`
+	PayInAdvance       bool             `json:"pay_in_advance"`
+	Prorated           bool             `json:"prorated"`
+	MinAmountCents     int              `json:"min_amount_cents,omitempty"`
+	Properties         map[string]any   `json:"properties,omitempty"`
+	Filters            []ChargeFilter   `json:"filters,omitempty"`
+	TaxCodes           []string         `json:"tax_codes,omitempty"`
+}
+
+// ChargeFilter prices the events of a charge matching the filter values.
+// Filters are identified by their values.
+type ChargeFilter struct {
+	InvoiceDisplayName string              `json:"invoice_display_name,omitempty"`
+	Values             map[string][]string `json:"values"`
+	Properties         map[string]any      `json:"properties,omitempty"`
+}
+
+// Key returns the identity of the filter, such as "cloud=aws|gcp,region=eu".
+func (cf ChargeFilter) Key() string {
+	keys := make([]string, 0, len(cf.Values))
+	for key := range cf.Values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values := append([]string(nil), cf.Values[key]...)
+		sort.Strings(values)
+		parts = append(parts, key+"="+strings.Join(values, "|"))
+	}
+
+	return strings.Join(parts, ",")
+}
+
+// FixedCharge is a fixed charge of a plan, billed through an add-on. Code
+// defaults to the add-on code.
+type FixedCharge struct {
+	Code               string                      `json:"code,omitempty"`
+	AddOnCode          string                      `json:"add_on_code"`
+	ChargeModel        lago.FixedChargeModel       `json:"charge_model"`
+	InvoiceDisplayName string                      `json:"invoice_display_name,omitempty"`
+	Units              float64                     `json:"units"`
+	PayInAdvance       bool                        `json:"pay_in_advance"`
+	Prorated           bool                        `json:"prorated"`
+	Properties         *lago.FixedChargeProperties `json:"properties,omitempty"`
+	TaxCodes           []string                    `json:"tax_codes,omitempty"`
+}
+
+// Entitlement grants a feature to a plan, with its privilege values keyed by
+// privilege code.
+type Entitlement struct {
+	FeatureCode string         `json:"feature_code"`
+	Privileges  map[string]any `json:"privileges,omitempty"`
+}
+
+type Coupon struct {
+	Code                string                     `json:"code"`
+	Name                string                     `json:"name,omitempty"`
+	Description         string                     `json:"description,omitempty"`
+	CouponType          lago.CouponCalculationType `json:"coupon_type"`
+	AmountCents         int                        `json:"amount_cents,omitempty"`
+	AmountCurrency      lago.Currency              `json:"amount_currency,omitempty"`
+	PercentageRate      float64                    `json:"percentage_rate,omitempty"`
+	Frequency           lago.CouponFrequency       `json:"frequency"`
+	FrequencyDuration   int                        `json:"frequency_duration,omitempty"`
+	Reusable            bool                       `json:"reusable"`
+	Expiration          lago.CouponExpiration      `json:"expiration"`
+	ExpirationAt        *time.Time                 `json:"expiration_at,omitempty"`
+	PlanCodes           []string                   `json:"plan_codes,omitempty"`
+	BillableMetricCodes []string                   `json:"billable_metric_codes,omitempty"`
+}
+
+// Load reads a catalog file. The format is chosen from the file extension:
+// .json files are decoded as JSON, anything else as YAML.
+func Load(path string) (*Catalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	format := FormatYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = FormatJSON
+	}
+
+	return Parse(data, format)
+}
+
+// Parse decodes and validates a catalog. Unknown fields are rejected so that
+// typos do not silently drop a setting.
+func Parse(data []byte, format Format) (*Catalog, error) {
+	catalog := &Catalog{}
+	if err := decode(data, format, catalog); err != nil {
+		return nil, err
+	}
+
+	catalog.normalize()
+	if err := catalog.Validate(); err != nil {
+		return nil, err
+	}
+
+	return catalog, nil
+}
+
+// Marshal encodes the catalog in the given format.
+func (c *Catalog) Marshal(format Format) ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil || format == FormatJSON {
+		return data, err
+	}
+
+	return jsonToYAML(data)
+}
+
+// decode decodes data into v, rejecting unknown fields. YAML documents are
+// converted to JSON first so that a single set of field names applies.
+func decode(data []byte, format Format, v any) error {
+	if format == FormatYAML {
+		var document any
+		if err := yaml.Unmarshal(data, &document); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+		}
+
+		var err error
+		if data, err = json.Marshal(document); err != nil {
+			return fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+		}
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidCatalog, err)
+	}
+
+	return nil
+}
+
+func jsonToYAML(data []byte) ([]byte, error) {
+	var document any
+	if err := json.Unmarshal(data, &document); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(document)
+}
+
+// Validate checks that every resource has a code, that codes are unique per
+// kind and that charges reference a billable metric or an add-on.
+func (c *Catalog) Validate() error {
+	var problems []string
+	seen := make(map[string]bool)
+	check := func(kind Kind, code string) {
+		switch {
+		case code == "":
+			problems = append(problems, fmt.Sprintf("%s without code", kind))
+		case seen[string(kind)+"/"+code]:
+			problems = append(problems, fmt.Sprintf("duplicate %s %q", kind, code))
+		}
+		seen[string(kind)+"/"+code] = true
+	}
+
+	for _, tax := range c.Taxes {
+		check(KindTax, tax.Code)
+	}
+	for _, metric := range c.BillableMetrics {
+		check(KindBillableMetric, metric.Code)
+	}
+	for _, addOn := range c.AddOns {
+		check(KindAddOn, addOn.Code)
+	}
+	for _, feature := range c.Features {
+		check(KindFeature, feature.Code)
+	}
+	for _, coupon := range c.Coupons {
+		check(KindCoupon, coupon.Code)
+	}
+	for _, plan := range c.Plans {
+		check(KindPlan, plan.Code)
+
+		for _, charge := range plan.Charges {
+			if charge.BillableMetricCode == "" {
+				problems = append(problems, fmt.Sprintf("charge %q of plan %q without billable_metric_code", charge.Code, plan.Code))
+			}
+			check(KindCharge, plan.Code+"/"+charge.Code)
+
+			for _, filter := range charge.Filters {
+				if len(filter.Values) == 0 {
+					problems = append(problems, fmt.Sprintf("filter of charge %q of plan %q without values", charge.Code, plan.Code))
+					continue
+				}
+				check(KindChargeFilter, plan.Code+"/"+charge.Code+"/"+filter.Key())
+			}
+		}
+		for _, fixedCharge := range plan.FixedCharges {
+			if fixedCharge.AddOnCode == "" {
+				problems = append(problems, fmt.Sprintf("fixed charge %q of plan %q without add_on_code", fixedCharge.Code, plan.Code))
+			}
+			check(KindFixedCharge, plan.Code+"/"+fixedCharge.Code)
+		}
+		for _, entitlement := range plan.Entitlements {
+			check(KindEntitlement, plan.Code+"/"+entitlement.FeatureCode)
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrInvalidCatalog, strings.Join(problems, "; "))
+	}
+
+	return nil
+}
+
+// normalize fills defaulted codes and sorts unordered lists so that two
+// equivalent catalogs compare equal.
+func (c *Catalog) normalize() {
+	for i := range c.AddOns {
+		sort.Strings(c.AddOns[i].TaxCodes)
+	}
+	for i := range c.Coupons {
+		sort.Strings(c.Coupons[i].PlanCodes)
+		sort.Strings(c.Coupons[i].BillableMetricCodes)
+	}
+	for i := range c.Plans {
+		plan := &c.Plans[i]
+		sort.Strings(plan.TaxCodes)
+		if plan.MinimumCommitment != nil {
+			sort.Strings(plan.MinimumCommitment.TaxCodes)
+		}
+		sort.Slice(plan.UsageThresholds, func(a, b int) bool {
+			return plan.UsageThresholds[a].AmountCents < plan.UsageThresholds[b].AmountCents
+		})
+
+		for j := range plan.Charges {
+			charge := &plan.Charges[j]
+			if charge.Code == "" {
+				charge.Code = charge.BillableMetricCode
+			}
+			sort.Strings(charge.TaxCodes)
+			for _, filter := range charge.Filters {
+				for _, values := range filter.Values {
+					sort.Strings(values)
+				}
+			}
+		}
+		for j := range plan.FixedCharges {
+			fixedCharge := &plan.FixedCharges[j]
+			if fixedCharge.Code == "" {
+				fixedCharge.Code = fixedCharge.AddOnCode
+			}
+			sort.Strings(fixedCharge.TaxCodes)
+		}
+	}
+}
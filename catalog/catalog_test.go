@@ -0,0 +1,135 @@
+package catalog_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/catalog"
+)
+
+const catalogYAML = `
+taxes:
+  - code: vat_fr
+    name: VAT FR
+    rate: 20
+    applied_to_organization: false
+billable_metrics:
+  - code: api_calls
+    name: API calls
+    aggregation_type: count_agg
+    recurring: false
+add_ons:
+  - code: onboarding
+    name: Onboarding
+    amount_cents: 50000
+    amount_currency: EUR
+features:
+  - code: seats
+    name: Seats
+    privileges:
+      - code: max
+        value_type: integer
+plans:
+  - code: startup
+    name: Startup
+    interval: monthly
+    amount_cents: 4900
+    amount_currency: EUR
+    pay_in_advance: true
+    bill_charges_monthly: false
+    trial_period: 0
+    tax_codes: [vat_fr]
+    usage_thresholds:
+      - amount_cents: 10000
+        recurring: false
+    charges:
+      - billable_metric_code: api_calls
+        charge_model: standard
+        pay_in_advance: false
+        prorated: false
+        properties:
+          amount: "0.01"
+        filters:
+          - values:
+              region: [us, eu]
+            properties:
+              amount: "0.02"
+    fixed_charges:
+      - add_on_code: onboarding
+        charge_model: standard
+        units: 1
+        pay_in_advance: true
+        prorated: false
+    entitlements:
+      - feature_code: seats
+        privileges:
+          max: 10
+coupons:
+  - code: welcome
+    name: Welcome
+    coupon_type: percentage
+    percentage_rate: 10
+    frequency: once
+    reusable: false
+    expiration: no_expiration
+    plan_codes: [startup]
+`
+
+func TestParse_YAML(t *testing.T) {
+	c := qt.New(t)
+
+	cat, err := catalog.Parse([]byte(catalogYAML), catalog.FormatYAML)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(cat.Taxes, qt.HasLen, 1)
+	c.Assert(cat.Taxes[0].Rate, qt.Equals, float32(20))
+	c.Assert(cat.Plans, qt.HasLen, 1)
+
+	plan := cat.Plans[0]
+	c.Assert(plan.Interval, qt.Equals, lago.PlanMonthly)
+	c.Assert(plan.Charges[0].Code, qt.Equals, "api_calls")
+	c.Assert(plan.Charges[0].Filters[0].Key(), qt.Equals, "region=eu|us")
+	c.Assert(plan.FixedCharges[0].Code, qt.Equals, "onboarding")
+	c.Assert(plan.Entitlements[0].Privileges["max"], qt.Equals, float64(10))
+	c.Assert(cat.Coupons[0].CouponType, qt.Equals, lago.CouponTypePercentage)
+}
+
+func TestParse_JSONRoundTrip(t *testing.T) {
+	c := qt.New(t)
+
+	cat, err := catalog.Parse([]byte(catalogYAML), catalog.FormatYAML)
+	c.Assert(err, qt.IsNil)
+
+	data, err := cat.Marshal(catalog.FormatJSON)
+	c.Assert(err, qt.IsNil)
+
+	path := filepath.Join(c.TempDir(), "catalog.json")
+	c.Assert(os.WriteFile(path, data, 0o600), qt.IsNil)
+
+	loaded, err := catalog.Load(path)
+	c.Assert(err, qt.IsNil)
+	c.Assert(loaded, qt.DeepEquals, cat)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field":  "plans:\n  - code: startup\n    amount: 10\n",
+		"missing code":   "taxes:\n  - name: VAT\n",
+		"duplicate code": "add_ons:\n  - code: a\n  - code: a\n",
+		"charge metric":  "plans:\n  - code: startup\n    charges:\n      - code: c\n",
+		"malformed yaml": "plans: [",
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			c := qt.New(t)
+			_, err := catalog.Parse([]byte(input), catalog.FormatYAML)
+			c.Assert(errors.Is(err, catalog.ErrInvalidCatalog), qt.IsTrue, qt.Commentf("err: %v", err))
+		})
+	}
+}
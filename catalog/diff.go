@@ -0,0 +1,573 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnresolvedReference is returned by Diff when a resource references a code
+// that exists neither in the desired catalog nor in Lago.
+var ErrUnresolvedReference = errors.New("catalog: unresolved reference")
+
+type Action string
+
+const (
+	ActionCreate Action = "create"
+	ActionUpdate Action = "update"
+	// ActionReplace deletes and recreates a charge or fixed charge whose
+	// billable metric or add-on changed, as Lago does not allow updating them.
+	ActionReplace Action = "replace"
+	ActionDelete  Action = "delete"
+)
+
+type Kind string
+
+const (
+	KindTax            Kind = "tax"
+	KindBillableMetric Kind = "billable_metric"
+	KindAddOn          Kind = "add_on"
+	KindFeature        Kind = "feature"
+	KindPlan           Kind = "plan"
+	KindCharge         Kind = "charge"
+	KindChargeFilter   Kind = "charge_filter"
+	KindFixedCharge    Kind = "fixed_charge"
+	KindEntitlement    Kind = "entitlement"
+	KindCoupon         Kind = "coupon"
+)
+
+// kindOrder lists kinds in dependency order: a resource only references kinds
+// listed before its own.
+var kindOrder = []Kind{
+	KindTax,
+	KindBillableMetric,
+	KindAddOn,
+	KindFeature,
+	KindPlan,
+	KindCharge,
+	KindChargeFilter,
+	KindFixedCharge,
+	KindEntitlement,
+	KindCoupon,
+}
+
+// FieldDiff is a changed field of an updated resource. Path uses the catalog
+// file field names, such as "properties.amount".
+type FieldDiff struct {
+	Path string
+	Old  any
+	New  any
+}
+
+// Change is a single operation needed to reach the desired catalog.
+type Change struct {
+	Action Action
+	Kind   Kind
+	// Code of the resource. For charge filters, the filter key (see ChargeFilter.Key).
+	Code string
+	// PlanCode is set for charges, charge filters, fixed charges and entitlements.
+	PlanCode string
+	// ChargeCode is set for charge filters.
+	ChargeCode string
+	// Fields lists the changed fields of an update.
+	Fields []FieldDiff
+
+	// Desired is the catalog resource (Tax, Plan, Charge...) to create or
+	// update. It is nil for deletions.
+	Desired any
+	// Current is the resource found in Lago. It is nil for creations.
+	Current any
+}
+
+// Path identifies the resource of the change, such as "startup/api_calls" for
+// the api_calls charge of the startup plan.
+func (c Change) Path() string {
+	switch c.Kind {
+	case KindCharge, KindFixedCharge, KindEntitlement:
+		return c.PlanCode + "/" + c.Code
+	case KindChargeFilter:
+		return c.PlanCode + "/" + c.ChargeCode + "[" + c.Code + "]"
+	default:
+		return c.Code
+	}
+}
+
+func (c Change) String() string {
+	symbols := map[Action]string{
+		ActionCreate:  "+",
+		ActionUpdate:  "~",
+		ActionReplace: "±",
+		ActionDelete:  "-",
+	}
+
+	return fmt.Sprintf("%s %s %s %s", symbols[c.Action], c.Action, c.Kind, c.Path())
+}
+
+// Changeset is the ordered list of changes computed by Diff.
+type Changeset struct {
+	Changes []Change
+}
+
+func (cs *Changeset) IsEmpty() bool {
+	return len(cs.Changes) == 0
+}
+
+// Count returns the number of changes with the given action.
+func (cs *Changeset) Count(action Action) int {
+	count := 0
+	for _, change := range cs.Changes {
+		if change.Action == action {
+			count++
+		}
+	}
+
+	return count
+}
+
+// String renders the changeset as a human-readable diff.
+func (cs *Changeset) String() string {
+	if cs.IsEmpty() {
+		return "No changes, the catalog is up to date.\n"
+	}
+
+	var b strings.Builder
+	for _, change := range cs.Changes {
+		b.WriteString(change.String())
+		b.WriteString("\n")
+		for _, field := range change.Fields {
+			fmt.Fprintf(&b, "    %s: %s -> %s\n", field.Path, formatValue(field.Old), formatValue(field.New))
+		}
+	}
+	fmt.Fprintf(&b, "%d to create, %d to update, %d to replace, %d to delete.\n",
+		cs.Count(ActionCreate), cs.Count(ActionUpdate), cs.Count(ActionReplace), cs.Count(ActionDelete))
+
+	return b.String()
+}
+
+func formatValue(value any) string {
+	if value == nil {
+		return "(none)"
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+
+	return string(data)
+}
+
+type DiffOptions struct {
+	// Prune deletes the taxes, billable metrics, add-ons, features, plans and
+	// coupons that are not in the desired catalog. Charges, filters, fixed
+	// charges, entitlements and privileges of a declared plan are always
+	// pruned.
+	Prune bool
+}
+
+// Diff computes the changes turning current into desired. Both catalogs are
+// left untouched.
+func Diff(desired, current *Catalog, opts DiffOptions) (*Changeset, error) {
+	if err := checkReferences(desired, current, opts.Prune); err != nil {
+		return nil, err
+	}
+
+	var changes []Change
+	add := func(change Change) {
+		changes = append(changes, change)
+	}
+
+	diffList(desired.Taxes, current.Taxes, func(tax Tax) string { return tax.Code }, identity[Tax], opts.Prune,
+		func(action Action, code string, desired, current *Tax, fields []FieldDiff) {
+			add(newChange(action, KindTax, code, desired, current, fields))
+		})
+
+	diffList(desired.BillableMetrics, current.BillableMetrics, func(metric BillableMetric) string { return metric.Code }, identity[BillableMetric], opts.Prune,
+		func(action Action, code string, desired, current *BillableMetric, fields []FieldDiff) {
+			add(newChange(action, KindBillableMetric, code, desired, current, fields))
+		})
+
+	diffList(desired.AddOns, current.AddOns, func(addOn AddOn) string { return addOn.Code }, identity[AddOn], opts.Prune,
+		func(action Action, code string, desired, current *AddOn, fields []FieldDiff) {
+			add(newChange(action, KindAddOn, code, desired, current, fields))
+		})
+
+	diffList(desired.Features, current.Features, func(feature Feature) string { return feature.Code }, identity[Feature], opts.Prune,
+		func(action Action, code string, desired, current *Feature, fields []FieldDiff) {
+			add(newChange(action, KindFeature, code, desired, current, fields))
+		})
+
+	diffList(desired.Plans, current.Plans, func(plan Plan) string { return plan.Code }, stripPlan, opts.Prune,
+		func(action Action, code string, desired, current *Plan, fields []FieldDiff) {
+			add(newChange(action, KindPlan, code, desired, current, fields))
+		})
+
+	currentPlans := make(map[string]Plan, len(current.Plans))
+	for _, plan := range current.Plans {
+		currentPlans[plan.Code] = plan
+	}
+	for _, plan := range desired.Plans {
+		for _, change := range diffPlanChildren(plan, currentPlans[plan.Code]) {
+			add(change)
+		}
+	}
+
+	diffList(desired.Coupons, current.Coupons, func(coupon Coupon) string { return coupon.Code }, identity[Coupon], opts.Prune,
+		func(action Action, code string, desired, current *Coupon, fields []FieldDiff) {
+			add(newChange(action, KindCoupon, code, desired, current, fields))
+		})
+
+	sort.SliceStable(changes, func(i, j int) bool {
+		return changePhase(changes[i]) < changePhase(changes[j])
+	})
+
+	return &Changeset{Changes: changes}, nil
+}
+
+// changePhase orders creations and updates in dependency order, followed by
+// deletions in reverse dependency order.
+func changePhase(change Change) int {
+	for i, kind := range kindOrder {
+		if kind == change.Kind {
+			if change.Action == ActionDelete {
+				return 2*len(kindOrder) - i
+			}
+			return i
+		}
+	}
+
+	return len(kindOrder)
+}
+
+func diffPlanChildren(desired, current Plan) []Change {
+	var changes []Change
+
+	diffList(desired.Charges, current.Charges, func(charge Charge) string { return charge.Code }, stripCharge, true,
+		func(action Action, code string, desiredCharge, currentCharge *Charge, fields []FieldDiff) {
+			if action == ActionUpdate && desiredCharge.BillableMetricCode != currentCharge.BillableMetricCode {
+				action = ActionReplace
+			}
+
+			change := newChange(action, KindCharge, code, desiredCharge, currentCharge, fields)
+			change.PlanCode = desired.Code
+			changes = append(changes, change)
+		})
+
+	// Filters of created or replaced charges are sent along with the charge,
+	// those of charges present on both sides are diffed one by one.
+	currentCharges := make(map[string]Charge, len(current.Charges))
+	for _, charge := range current.Charges {
+		currentCharges[charge.Code] = charge
+	}
+	for _, desiredCharge := range desired.Charges {
+		currentCharge, ok := currentCharges[desiredCharge.Code]
+		if !ok || currentCharge.BillableMetricCode != desiredCharge.BillableMetricCode {
+			continue
+		}
+
+		diffList(desiredCharge.Filters, currentCharge.Filters, ChargeFilter.Key, identity[ChargeFilter], true,
+			func(action Action, key string, desiredFilter, currentFilter *ChargeFilter, fields []FieldDiff) {
+				change := newChange(action, KindChargeFilter, key, desiredFilter, currentFilter, fields)
+				change.PlanCode = desired.Code
+				change.ChargeCode = desiredCharge.Code
+				changes = append(changes, change)
+			})
+	}
+
+	diffList(desired.FixedCharges, current.FixedCharges, func(fixedCharge FixedCharge) string { return fixedCharge.Code }, identity[FixedCharge], true,
+		func(action Action, code string, desiredFixedCharge, currentFixedCharge *FixedCharge, fields []FieldDiff) {
+			if action == ActionUpdate && desiredFixedCharge.AddOnCode != currentFixedCharge.AddOnCode {
+				action = ActionReplace
+			}
+
+			change := newChange(action, KindFixedCharge, code, desiredFixedCharge, currentFixedCharge, fields)
+			change.PlanCode = desired.Code
+			changes = append(changes, change)
+		})
+
+	updated := make(map[string]bool)
+	diffList(desired.Entitlements, current.Entitlements, func(entitlement Entitlement) string { return entitlement.FeatureCode }, identity[Entitlement], true,
+		func(action Action, code string, desiredEntitlement, currentEntitlement *Entitlement, fields []FieldDiff) {
+			if action == ActionUpdate {
+				fields = append(fields, removedPrivileges(*desiredEntitlement, *currentEntitlement)...)
+				updated[code] = true
+			}
+			change := newChange(action, KindEntitlement, code, desiredEntitlement, currentEntitlement, fields)
+			change.PlanCode = desired.Code
+			changes = append(changes, change)
+		})
+
+	// Privileges are managed as a whole: those missing from a declared
+	// entitlement, which compareFields does not report, are removed.
+	currentEntitlements := make(map[string]*Entitlement, len(current.Entitlements))
+	for i := range current.Entitlements {
+		currentEntitlements[current.Entitlements[i].FeatureCode] = &current.Entitlements[i]
+	}
+	for i := range desired.Entitlements {
+		entitlement := &desired.Entitlements[i]
+		currentEntitlement, ok := currentEntitlements[entitlement.FeatureCode]
+		if !ok || updated[entitlement.FeatureCode] {
+			continue
+		}
+		if fields := removedPrivileges(*entitlement, *currentEntitlement); len(fields) > 0 {
+			change := newChange(ActionUpdate, KindEntitlement, entitlement.FeatureCode, entitlement, currentEntitlement, fields)
+			change.PlanCode = desired.Code
+			changes = append(changes, change)
+		}
+	}
+
+	return changes
+}
+
+func newChange[T any](action Action, kind Kind, code string, desired, current *T, fields []FieldDiff) Change {
+	change := Change{Action: action, Kind: kind, Code: code, Fields: fields}
+	if desired != nil {
+		change.Desired = *desired
+	}
+	if current != nil {
+		change.Current = *current
+	}
+
+	return change
+}
+
+// diffList matches desired and current items by code and calls emit for each
+// item to create, update or (when prune is set) delete. Items are compared
+// after strip, which removes the nested resources diffed separately.
+func diffList[T any](desired, current []T, codeOf func(T) string, strip func(T) T, prune bool, emit func(action Action, code string, desired, current *T, fields []FieldDiff)) {
+	currentByCode := make(map[string]*T, len(current))
+	for i := range current {
+		currentByCode[codeOf(current[i])] = &current[i]
+	}
+
+	desiredCodes := make(map[string]bool, len(desired))
+	for i := range desired {
+		code := codeOf(desired[i])
+		desiredCodes[code] = true
+
+		existing, ok := currentByCode[code]
+		if !ok {
+			emit(ActionCreate, code, &desired[i], nil, nil)
+			continue
+		}
+		if fields := compareFields(strip(desired[i]), strip(*existing)); len(fields) > 0 {
+			emit(ActionUpdate, code, &desired[i], existing, fields)
+		}
+	}
+
+	if !prune {
+		return
+	}
+	for i := range current {
+		if code := codeOf(current[i]); !desiredCodes[code] {
+			emit(ActionDelete, code, nil, &current[i], nil)
+		}
+	}
+}
+
+// removedPrivileges lists the privileges of current missing from desired.
+func removedPrivileges(desired, current Entitlement) []FieldDiff {
+	var fields []FieldDiff
+	for code, value := range current.Privileges {
+		if _, ok := desired.Privileges[code]; !ok {
+			fields = append(fields, FieldDiff{Path: "privileges." + code, Old: value})
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Path < fields[j].Path })
+
+	return fields
+}
+
+func identity[T any](value T) T {
+	return value
+}
+
+func stripPlan(plan Plan) Plan {
+	plan.Charges = nil
+	plan.FixedCharges = nil
+	plan.Entitlements = nil
+
+	return plan
+}
+
+func stripCharge(charge Charge) Charge {
+	charge.Filters = nil
+
+	return charge
+}
+
+// compareFields lists the fields set in desired whose value differs in
+// current. Fields omitted from desired are not managed and never reported.
+func compareFields(desired, current any) []FieldDiff {
+	var fields []FieldDiff
+	compareValues("", toGeneric(desired), toGeneric(current), &fields)
+
+	return fields
+}
+
+func compareValues(path string, desired, current any, fields *[]FieldDiff) {
+	desiredMap, desiredIsMap := desired.(map[string]any)
+	currentMap, currentIsMap := current.(map[string]any)
+	if desiredIsMap && currentIsMap {
+		keys := make([]string, 0, len(desiredMap))
+		for key := range desiredMap {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			fieldPath := key
+			if path != "" {
+				fieldPath = path + "." + key
+			}
+			compareValues(fieldPath, desiredMap[key], currentMap[key], fields)
+		}
+		return
+	}
+
+	if !contains(desired, current) {
+		*fields = append(*fields, FieldDiff{Path: path, Old: current, New: desired})
+	}
+}
+
+// contains reports whether every value set in desired has the same value in current.
+func contains(desired, current any) bool {
+	switch desired := desired.(type) {
+	case map[string]any:
+		currentMap, ok := current.(map[string]any)
+		if !ok {
+			return false
+		}
+		for key, value := range desired {
+			if !contains(value, currentMap[key]) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		currentSlice, ok := current.([]any)
+		if !ok || len(currentSlice) != len(desired) {
+			return false
+		}
+		for i := range desired {
+			if !contains(desired[i], currentSlice[i]) {
+				return false
+			}
+		}
+		return true
+	case float64:
+		// Lago returns most amounts as strings, catalogs often write them as numbers.
+		if currentString, ok := current.(string); ok {
+			currentNumber, err := strconv.ParseFloat(currentString, 64)
+			return err == nil && currentNumber == desired
+		}
+		return reflect.DeepEqual(desired, current)
+	case string:
+		if currentNumber, ok := current.(float64); ok {
+			desiredNumber, err := strconv.ParseFloat(desired, 64)
+			return err == nil && currentNumber == desiredNumber
+		}
+		return desired == current
+	default:
+		return reflect.DeepEqual(desired, current)
+	}
+}
+
+func toGeneric(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil
+	}
+
+	var generic any
+	if err := json.Unmarshal(data, &generic); err != nil {
+		return nil
+	}
+
+	return generic
+}
+
+// checkReferences ensures every code referenced by the desired catalog exists
+// either in the desired catalog or in Lago. When pruning, the resources of
+// Lago missing from the desired catalog are deleted and cannot be referenced.
+func checkReferences(desired, current *Catalog, prune bool) error {
+	known := make(map[Kind]map[string]bool)
+	register := func(kind Kind, code string) {
+		if known[kind] == nil {
+			known[kind] = make(map[string]bool)
+		}
+		known[kind][code] = true
+	}
+	catalogs := []*Catalog{desired, current}
+	if prune {
+		catalogs = catalogs[:1]
+	}
+	for _, catalog := range catalogs {
+		for _, tax := range catalog.Taxes {
+			register(KindTax, tax.Code)
+		}
+		for _, metric := range catalog.BillableMetrics {
+			register(KindBillableMetric, metric.Code)
+		}
+		for _, addOn := range catalog.AddOns {
+			register(KindAddOn, addOn.Code)
+		}
+		for _, feature := range catalog.Features {
+			register(KindFeature, feature.Code)
+		}
+		for _, plan := range catalog.Plans {
+			register(KindPlan, plan.Code)
+		}
+	}
+
+	var missing []string
+	require := func(kind Kind, code, owner string) {
+		if !known[kind][code] {
+			missing = append(missing, fmt.Sprintf("%s %q referenced by %s", kind, code, owner))
+		}
+	}
+	requireTaxes := func(codes []string, owner string) {
+		for _, code := range codes {
+			require(KindTax, code, owner)
+		}
+	}
+
+	for _, addOn := range desired.AddOns {
+		requireTaxes(addOn.TaxCodes, "add_on "+addOn.Code)
+	}
+	for _, plan := range desired.Plans {
+		owner := "plan " + plan.Code
+		requireTaxes(plan.TaxCodes, owner)
+		if plan.MinimumCommitment != nil {
+			requireTaxes(plan.MinimumCommitment.TaxCodes, owner)
+		}
+		for _, charge := range plan.Charges {
+			require(KindBillableMetric, charge.BillableMetricCode, "charge "+plan.Code+"/"+charge.Code)
+			requireTaxes(charge.TaxCodes, "charge "+plan.Code+"/"+charge.Code)
+		}
+		for _, fixedCharge := range plan.FixedCharges {
+			require(KindAddOn, fixedCharge.AddOnCode, "fixed_charge "+plan.Code+"/"+fixedCharge.Code)
+			requireTaxes(fixedCharge.TaxCodes, "fixed_charge "+plan.Code+"/"+fixedCharge.Code)
+		}
+		for _, entitlement := range plan.Entitlements {
+			require(KindFeature, entitlement.FeatureCode, "entitlement "+plan.Code+"/"+entitlement.FeatureCode)
+		}
+	}
+	for _, coupon := range desired.Coupons {
+		for _, code := range coupon.PlanCodes {
+			require(KindPlan, code, "coupon "+coupon.Code)
+		}
+		for _, code := range coupon.BillableMetricCodes {
+			require(KindBillableMetric, code, "coupon "+coupon.Code)
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrUnresolvedReference, strings.Join(missing, "; "))
+	}
+
+	return nil
+}
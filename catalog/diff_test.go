@@ -0,0 +1,122 @@
+package catalog_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/getlago/lago-go-client/catalog"
+)
+
+func mustParse(c *qt.C, input string) *catalog.Catalog {
+	cat, err := catalog.Parse([]byte(input), catalog.FormatYAML)
+	c.Assert(err, qt.IsNil)
+
+	return cat
+}
+
+func changePaths(changeset *catalog.Changeset) []string {
+	var paths []string
+	for _, change := range changeset.Changes {
+		paths = append(paths, change.String())
+	}
+
+	return paths
+}
+
+func TestDiff_CreatesInDependencyOrder(t *testing.T) {
+	c := qt.New(t)
+
+	changeset, err := catalog.Diff(mustParse(c, catalogYAML), &catalog.Catalog{}, catalog.DiffOptions{})
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(changePaths(changeset), qt.DeepEquals, []string{
+		"+ create tax vat_fr",
+		"+ create billable_metric api_calls",
+		"+ create add_on onboarding",
+		"+ create feature seats",
+		"+ create plan startup",
+		"+ create charge startup/api_calls",
+		"+ create fixed_charge startup/onboarding",
+		"+ create entitlement startup/seats",
+		"+ create coupon welcome",
+	})
+}
+
+func TestDiff_UpToDate(t *testing.T) {
+	c := qt.New(t)
+
+	changeset, err := catalog.Diff(mustParse(c, catalogYAML), mustParse(c, catalogYAML), catalog.DiffOptions{Prune: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(changeset.IsEmpty(), qt.IsTrue)
+	c.Assert(changeset.String(), qt.Equals, "No changes, the catalog is up to date.\n")
+}
+
+func TestDiff_NestedChanges(t *testing.T) {
+	c := qt.New(t)
+
+	current := mustParse(c, catalogYAML)
+	// Lago returns amounts as strings and fills optional fields.
+	current.Plans[0].Description = "Set from the UI"
+	current.Plans[0].AmountCents = 3900
+	current.Plans[0].Charges[0].Properties = map[string]any{"amount": "0.01", "pricing_group_keys": nil}
+	current.Plans[0].Charges[0].Filters[0].Properties = map[string]any{"amount": "0.03"}
+	current.Plans[0].Charges = append(current.Plans[0].Charges, catalog.Charge{Code: "legacy", BillableMetricCode: "api_calls"})
+	current.Plans[0].Entitlements[0].Privileges["max"] = float64(5)
+	current.Plans[0].UsageThresholds = nil
+	current.Coupons = append(current.Coupons, catalog.Coupon{Code: "old"})
+
+	desired := mustParse(c, catalogYAML)
+	desired.Plans[0].FixedCharges[0].AddOnCode = "setup"
+	desired.AddOns = append(desired.AddOns, catalog.AddOn{Code: "setup"})
+
+	changeset, err := catalog.Diff(desired, current, catalog.DiffOptions{})
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(changePaths(changeset), qt.DeepEquals, []string{
+		"+ create add_on setup",
+		"~ update plan startup",
+		"~ update charge_filter startup/api_calls[region=eu|us]",
+		"± replace fixed_charge startup/onboarding",
+		"~ update entitlement startup/seats",
+		"- delete charge startup/legacy",
+	})
+
+	plan := changeset.Changes[1]
+	c.Assert(plan.Fields, qt.HasLen, 2)
+	c.Assert(plan.Fields[0].Path, qt.Equals, "amount_cents")
+	c.Assert(plan.Fields[1].Path, qt.Equals, "usage_thresholds")
+
+	output := changeset.String()
+	c.Assert(strings.Contains(output, "    amount_cents: 3900 -> 4900\n"), qt.IsTrue, qt.Commentf(output))
+	c.Assert(strings.Contains(output, `    properties.amount: "0.03" -> "0.02"`), qt.IsTrue, qt.Commentf(output))
+	c.Assert(strings.HasSuffix(output, "1 to create, 3 to update, 1 to replace, 1 to delete.\n"), qt.IsTrue, qt.Commentf(output))
+
+	// The extra coupon is only deleted when pruning.
+	changeset, err = catalog.Diff(desired, current, catalog.DiffOptions{Prune: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(changeset.Changes[0].String(), qt.Equals, "+ create add_on setup")
+	c.Assert(changeset.Changes[len(changeset.Changes)-2].String(), qt.Equals, "- delete coupon old")
+}
+
+func TestDiff_UnresolvedReference(t *testing.T) {
+	c := qt.New(t)
+
+	desired := mustParse(c, catalogYAML)
+	desired.BillableMetrics = nil
+
+	_, err := catalog.Diff(desired, &catalog.Catalog{}, catalog.DiffOptions{})
+	c.Assert(errors.Is(err, catalog.ErrUnresolvedReference), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, `.*billable_metric "api_calls" referenced by charge startup/api_calls.*`)
+
+	// A metric found in Lago resolves the reference, unless pruning deletes
+	// it.
+	current := &catalog.Catalog{BillableMetrics: mustParse(c, catalogYAML).BillableMetrics}
+	_, err = catalog.Diff(desired, current, catalog.DiffOptions{})
+	c.Assert(err, qt.IsNil)
+	_, err = catalog.Diff(desired, current, catalog.DiffOptions{Prune: true})
+	c.Assert(errors.Is(err, catalog.ErrUnresolvedReference), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, `.*billable_metric "api_calls" referenced by charge startup/api_calls.*`)
+}
@@ -0,0 +1,157 @@
+package catalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// SnapshotVersion is the version of the snapshot format written by Export.
+const SnapshotVersion = 1
+
+// Snapshot is a portable copy of the catalog of an organization. Resources
+// reference each other by code, so a snapshot taken in one organization can
+// be imported in another one.
+type Snapshot struct {
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// Source is a free label describing the exported organization, such as "staging".
+	Source  string  `json:"source,omitempty"`
+	Catalog Catalog `json:"catalog"`
+}
+
+// Export reads the full catalog of the organization behind client. Charges,
+// charge filters and fixed charges are read from the plan charge endpoints,
+// and the billable metric and add-on IDs they carry are replaced by codes.
+func Export(ctx context.Context, client *lago.Client, source string) (*Snapshot, error) {
+	catalog, err := fetch(ctx, client, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Snapshot{
+		Version:    SnapshotVersion,
+		ExportedAt: time.Now().UTC().Truncate(time.Second),
+		Source:     source,
+		Catalog:    *catalog,
+	}, nil
+}
+
+// Write encodes the snapshot in the given format.
+func (s *Snapshot) Write(w io.Writer, format Format) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if format == FormatYAML {
+		if data, err = jsonToYAML(data); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Write(data)
+
+	return err
+}
+
+// ReadSnapshot decodes and validates a snapshot written by Snapshot.Write.
+func ReadSnapshot(r io.Reader, format Format) (*Snapshot, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &Snapshot{}
+	if err := decode(data, format, snapshot); err != nil {
+		return nil, err
+	}
+	if snapshot.Version != SnapshotVersion {
+		return nil, fmt.Errorf("%w: unsupported snapshot version %d", ErrInvalidCatalog, snapshot.Version)
+	}
+
+	snapshot.Catalog.normalize()
+	if err := snapshot.Catalog.Validate(); err != nil {
+		return nil, err
+	}
+
+	return snapshot, nil
+}
+
+// LoadSnapshot reads a snapshot file, choosing the format from its extension
+// like Load.
+func LoadSnapshot(path string) (*Snapshot, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	format := FormatYAML
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		format = FormatJSON
+	}
+
+	return ReadSnapshot(file, format)
+}
+
+type ImportOptions struct {
+	// Prune deletes the resources of the target organization missing from the snapshot.
+	Prune bool
+	// DryRun, Log and CascadeUpdates behave as in ApplyOptions.
+	DryRun         bool
+	Log            io.Writer
+	CascadeUpdates bool
+}
+
+// ImportReport describes an import.
+type ImportReport struct {
+	// Changes are the changes computed against the target organization.
+	Changes *Changeset
+	// Applied are the changes applied before the import completed or failed.
+	Applied []Change
+	// BillableMetricIDs maps the code of every billable metric created,
+	// updated or referenced by a charge during the import to its ID in the
+	// target organization.
+	BillableMetricIDs map[string]string
+	// AddOnIDs does the same for add-ons.
+	AddOnIDs map[string]string
+}
+
+// Import replays a snapshot into the organization behind client: the target
+// catalog is fetched, diffed against the snapshot and the changes applied.
+// Billable metric and add-on IDs are resolved by code in the target
+// organization.
+func Import(ctx context.Context, client *lago.Client, snapshot *Snapshot, opts ImportOptions) (*ImportReport, error) {
+	current, err := Fetch(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := Diff(&snapshot.Catalog, current, DiffOptions{Prune: opts.Prune})
+	if err != nil {
+		return nil, err
+	}
+
+	a := newApplier(client, ApplyOptions{DryRun: opts.DryRun, Log: opts.Log, CascadeUpdates: opts.CascadeUpdates})
+	applied, err := a.applyAll(ctx, changes)
+
+	report := &ImportReport{
+		Changes:           changes,
+		Applied:           applied,
+		BillableMetricIDs: a.metricIDs,
+		AddOnIDs:          make(map[string]string, len(a.addOnIDs)),
+	}
+	for code, id := range a.addOnIDs {
+		report.AddOnIDs[code] = id.String()
+	}
+
+	return report, err
+}
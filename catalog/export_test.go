@@ -0,0 +1,117 @@
+package catalog_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/getlago/lago-go-client/catalog"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+const (
+	stagingMetricID    = "5e905e90-5e90-5e90-5e90-5e905e905e90"
+	productionMetricID = "6f906f90-6f90-6f90-6f90-6f906f906f90"
+)
+
+func stagingServer(c *qt.C) *lt.RoutesServer {
+	return lt.NewRoutesServer(c, map[string]string{
+		"GET /taxes":            `{"taxes": [], "meta": {}}`,
+		"GET /billable_metrics": `{"billable_metrics": [{"lago_id": "` + stagingMetricID + `", "code": "api_calls", "name": "API calls", "aggregation_type": "count_agg"}], "meta": {}}`,
+		"GET /add_ons":          `{"add_ons": [{"lago_id": "7a907a90-7a90-7a90-7a90-7a907a907a90", "code": "setup", "amount_cents": 100, "amount_currency": "EUR"}], "meta": {}}`,
+		"GET /features":         `{"features": [{"code": "sso", "name": "SSO", "privileges": []}], "meta": {}}`,
+		"GET /coupons":          `{"coupons": [], "meta": {}}`,
+		"GET /plans":            `{"plans": [{"code": "pro", "name": "Pro", "interval": "yearly", "amount_cents": 100000, "amount_currency": "EUR", "trial_period": 14}], "meta": {}}`,
+		// Charges only carry the billable metric ID: the exporter maps it back to its code.
+		"GET /plans/pro/charges":               `{"charges": [{"code": "calls", "lago_billable_metric_id": "` + stagingMetricID + `", "charge_model": "standard", "properties": {"amount": "1"}}], "meta": {"next_page": 2, "current_page": 1}}`,
+		"GET /plans/pro/charges?page=2":        `{"charges": [], "meta": {"current_page": 2}}`,
+		"GET /plans/pro/charges/calls/filters": `{"filters": [{"lago_id": "8b908b90-8b90-8b90-8b90-8b908b908b90", "values": {"region": ["eu"]}, "properties": {"amount": "2"}}], "meta": {}}`,
+		"GET /plans/pro/fixed_charges":         `{"fixed_charges": [{"code": "setup", "lago_add_on_id": "7a907a90-7a90-7a90-7a90-7a907a907a90", "charge_model": "standard", "units": 1}], "meta": {}}`,
+		"GET /plans/pro/entitlements":          `{"entitlements": [{"code": "sso", "privileges": []}]}`,
+	})
+}
+
+func TestExport(t *testing.T) {
+	c := qt.New(t)
+
+	server := stagingServer(c)
+	client := server.Client()
+
+	snapshot, err := catalog.Export(context.Background(), client, "staging")
+	c.Assert(err, qt.IsNil)
+	c.Assert(snapshot.Version, qt.Equals, catalog.SnapshotVersion)
+	c.Assert(snapshot.Source, qt.Equals, "staging")
+
+	plan := snapshot.Catalog.Plans[0]
+	c.Assert(plan.TrialPeriod, qt.Equals, float32(14))
+	c.Assert(plan.Charges, qt.HasLen, 1)
+	c.Assert(plan.Charges[0].BillableMetricCode, qt.Equals, "api_calls")
+	c.Assert(plan.Charges[0].Filters[0].Key(), qt.Equals, "region=eu")
+	c.Assert(plan.FixedCharges[0].AddOnCode, qt.Equals, "setup")
+	c.Assert(plan.Entitlements, qt.DeepEquals, []catalog.Entitlement{{FeatureCode: "sso"}})
+
+	// The charge list is paginated.
+	c.Assert(calls(server), qt.Contains, "GET /plans/pro/charges")
+	count := 0
+	for _, request := range calls(server) {
+		if request == "GET /plans/pro/charges" {
+			count++
+		}
+	}
+	c.Assert(count, qt.Equals, 2)
+
+	for _, format := range []catalog.Format{catalog.FormatJSON, catalog.FormatYAML} {
+		var buf bytes.Buffer
+		c.Assert(snapshot.Write(&buf, format), qt.IsNil)
+		c.Assert(bytes.Contains(buf.Bytes(), []byte(stagingMetricID)), qt.IsFalse)
+
+		read, err := catalog.ReadSnapshot(&buf, format)
+		c.Assert(err, qt.IsNil)
+		c.Assert(read, qt.DeepEquals, snapshot)
+	}
+}
+
+func TestImport_RemapsBillableMetrics(t *testing.T) {
+	c := qt.New(t)
+
+	staging := stagingServer(c)
+	snapshot, err := catalog.Export(context.Background(), staging.Client(), "staging")
+	c.Assert(err, qt.IsNil)
+
+	production := lt.NewRoutesServer(c, map[string]string{
+		"GET /taxes":                      `{"taxes": [], "meta": {}}`,
+		"GET /billable_metrics":           `{"billable_metrics": [{"lago_id": "` + productionMetricID + `", "code": "api_calls", "name": "API calls", "aggregation_type": "count_agg"}], "meta": {}}`,
+		"GET /add_ons":                    `{"add_ons": [], "meta": {}}`,
+		"GET /features":                   `{"features": [], "meta": {}}`,
+		"GET /coupons":                    `{"coupons": [], "meta": {}}`,
+		"GET /plans":                      `{"plans": [], "meta": {}}`,
+		"GET /billable_metrics/api_calls": `{"billable_metric": {"lago_id": "` + productionMetricID + `", "code": "api_calls"}}`,
+		"POST /add_ons":                   `{"add_on": {"lago_id": "9c909c90-9c90-9c90-9c90-9c909c909c90", "code": "setup"}}`,
+		"POST /features":                  `{}`,
+		"POST /plans":                     `{}`,
+		"POST /plans/pro/charges":         `{}`,
+		"POST /plans/pro/fixed_charges":   `{}`,
+		"PATCH /plans/pro/entitlements":   `{}`,
+	})
+	client := production.Client()
+
+	report, err := catalog.Import(context.Background(), client, snapshot, catalog.ImportOptions{DryRun: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Changes.Count(catalog.ActionCreate), qt.Equals, 6)
+	for _, request := range calls(production) {
+		c.Assert(request[:4], qt.Equals, "GET ")
+	}
+
+	report, err = catalog.Import(context.Background(), client, snapshot, catalog.ImportOptions{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Applied, qt.HasLen, 6)
+	c.Assert(report.BillableMetricIDs, qt.DeepEquals, map[string]string{"api_calls": productionMetricID})
+	c.Assert(report.AddOnIDs, qt.DeepEquals, map[string]string{"setup": "9c909c90-9c90-9c90-9c90-9c909c909c90"})
+
+	charge := body(c, production, "POST", "/plans/pro/charges")["charge"].(map[string]any)
+	c.Assert(charge["billable_metric_id"], qt.Equals, productionMetricID)
+	fixedCharge := body(c, production, "POST", "/plans/pro/fixed_charges")["fixed_charge"].(map[string]any)
+	c.Assert(fixedCharge["add_on_id"], qt.Equals, "9c909c90-9c90-9c90-9c90-9c909c909c90")
+}
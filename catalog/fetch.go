@@ -0,0 +1,396 @@
+package catalog
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+const fetchPerPage = 100
+
+// Fetch reads the current catalog of the organization behind client.
+func Fetch(ctx context.Context, client *lago.Client) (*Catalog, error) {
+	return fetch(ctx, client, false)
+}
+
+// fetch reads the catalog. Plans come with their charges and fixed charges;
+// when detailed is set, those are read again from the plan charge endpoints,
+// which also return the filters of every charge.
+func fetch(ctx context.Context, client *lago.Client, detailed bool) (*Catalog, error) {
+	catalog := &Catalog{}
+
+	taxes, err := lago.FetchPages(0, func(page int) ([]lago.Tax, lago.Metadata, *lago.Error) {
+		result, err := client.Tax().GetList(ctx, &lago.TaxListInput{Page: lago.Ptr(page), PerPage: lago.Ptr(fetchPerPage)})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Taxes, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: fetching taxes: %w", err)
+	}
+	for _, tax := range taxes {
+		catalog.Taxes = append(catalog.Taxes, taxFromLago(tax))
+	}
+
+	metrics, err := lago.FetchPages(0, func(page int) ([]lago.BillableMetric, lago.Metadata, *lago.Error) {
+		result, err := client.BillableMetric().GetList(ctx, &lago.BillableMetricListInput{Page: lago.Ptr(page), PerPage: lago.Ptr(fetchPerPage)})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.BillableMetrics, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: fetching billable metrics: %w", err)
+	}
+	metricCodes := make(map[string]string, len(metrics))
+	for _, metric := range metrics {
+		metricCodes[metric.LagoID.String()] = metric.Code
+		catalog.BillableMetrics = append(catalog.BillableMetrics, billableMetricFromLago(metric))
+	}
+
+	addOns, err := lago.FetchPages(0, func(page int) ([]lago.AddOn, lago.Metadata, *lago.Error) {
+		result, err := client.AddOn().GetList(ctx, &lago.AddOnListInput{Page: lago.Ptr(page), PerPage: lago.Ptr(fetchPerPage)})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.AddOns, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: fetching add-ons: %w", err)
+	}
+	addOnCodes := make(map[string]string, len(addOns))
+	for _, addOn := range addOns {
+		addOnCodes[addOn.LagoID.String()] = addOn.Code
+		catalog.AddOns = append(catalog.AddOns, addOnFromLago(addOn))
+	}
+
+	features, err := lago.FetchPages(0, func(page int) ([]lago.Feature, lago.Metadata, *lago.Error) {
+		result, err := client.Feature().GetList(ctx, &lago.FeatureListInput{Page: lago.Ptr(page), PerPage: lago.Ptr(fetchPerPage)})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Features, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: fetching features: %w", err)
+	}
+	for _, feature := range features {
+		catalog.Features = append(catalog.Features, featureFromLago(feature))
+	}
+
+	plans, err := lago.FetchPages(0, func(page int) ([]lago.Plan, lago.Metadata, *lago.Error) {
+		result, err := client.Plan().GetList(ctx, &lago.PlanListInput{Page: lago.Ptr(page), PerPage: lago.Ptr(fetchPerPage)})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Plans, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: fetching plans: %w", err)
+	}
+	for _, plan := range plans {
+		entitlements, err := client.PlanEntitlement().GetList(ctx, plan.Code)
+		if err != nil {
+			return nil, fmt.Errorf("catalog: fetching entitlements of plan %q: %w", plan.Code, err)
+		}
+		plan.Entitlements = entitlements.Entitlements
+
+		if detailed {
+			if err := fetchPlanCharges(ctx, client, &plan); err != nil {
+				return nil, err
+			}
+		}
+
+		// Charges reference billable metrics and add-ons by code only.
+		for i, charge := range plan.Charges {
+			if charge.BillableMetricCode == "" {
+				plan.Charges[i].BillableMetricCode = metricCodes[charge.LagoBillableMetricID.String()]
+			}
+		}
+		for i, fixedCharge := range plan.FixedCharges {
+			if fixedCharge.AddOnCode == "" {
+				plan.FixedCharges[i].AddOnCode = addOnCodes[fixedCharge.LagoAddOnID.String()]
+			}
+		}
+
+		catalog.Plans = append(catalog.Plans, planFromLago(plan))
+	}
+
+	coupons, err := lago.FetchPages(0, func(page int) ([]lago.Coupon, lago.Metadata, *lago.Error) {
+		result, err := client.Coupon().GetList(ctx, &lago.CouponListInput{Page: lago.Ptr(page), PerPage: lago.Ptr(fetchPerPage)})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Coupons, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("catalog: fetching coupons: %w", err)
+	}
+	for _, coupon := range coupons {
+		catalog.Coupons = append(catalog.Coupons, couponFromLago(coupon))
+	}
+
+	catalog.normalize()
+
+	return catalog, nil
+}
+
+// fetchPlanCharges replaces the charges and fixed charges of plan with those
+// listed by the plan charge endpoints.
+func fetchPlanCharges(ctx context.Context, client *lago.Client, plan *lago.Plan) error {
+	charges, err := lago.FetchPages(0, func(page int) ([]lago.Charge, lago.Metadata, *lago.Error) {
+		result, err := client.Plan().GetChargeList(ctx, plan.Code, &lago.ChargeListInput{Page: page, PerPage: fetchPerPage})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Charges, result.Meta, nil
+	})
+	if err != nil {
+		return fmt.Errorf("catalog: fetching charges of plan %q: %w", plan.Code, err)
+	}
+
+	for i, charge := range charges {
+		// Charges created before charge codes existed cannot be addressed
+		// individually and keep the filters returned along with them.
+		if charge.Code == "" {
+			continue
+		}
+
+		filters, err := lago.FetchPages(0, func(page int) ([]lago.ChargeFilterResponse, lago.Metadata, *lago.Error) {
+			result, err := client.Plan().GetChargeFilterList(ctx, plan.Code, charge.Code, &lago.ChargeFilterListInput{Page: page, PerPage: fetchPerPage})
+			if err != nil {
+				return nil, lago.Metadata{}, err
+			}
+			return result.Filters, result.Meta, nil
+		})
+		if err != nil {
+			return fmt.Errorf("catalog: fetching filters of charge %s/%s: %w", plan.Code, charge.Code, err)
+		}
+
+		charges[i].Filters = nil
+		for _, filter := range filters {
+			charges[i].Filters = append(charges[i].Filters, lago.ChargeFilter{
+				InvoiceDisplayName: filter.InvoiceDisplayName,
+				Properties:         filter.Properties,
+				Values:             filter.Values,
+			})
+		}
+	}
+	plan.Charges = charges
+
+	fixedCharges, err := lago.FetchPages(0, func(page int) ([]lago.FixedCharge, lago.Metadata, *lago.Error) {
+		result, err := client.Plan().GetFixedChargeList(ctx, plan.Code, &lago.FixedChargeListInput{Page: page, PerPage: fetchPerPage})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.FixedCharges, result.Meta, nil
+	})
+	if err != nil {
+		return fmt.Errorf("catalog: fetching fixed charges of plan %q: %w", plan.Code, err)
+	}
+	plan.FixedCharges = fixedCharges
+
+	return nil
+}
+
+func taxCodes(taxes []lago.Tax) []string {
+	var codes []string
+	for _, tax := range taxes {
+		codes = append(codes, tax.Code)
+	}
+	sort.Strings(codes)
+
+	return codes
+}
+
+func taxFromLago(tax lago.Tax) Tax {
+	return Tax{
+		Code:                  tax.Code,
+		Name:                  tax.Name,
+		Rate:                  tax.Rate,
+		Description:           tax.Description,
+		AppliedToOrganization: tax.AppliedToOrganization,
+	}
+}
+
+func billableMetricFromLago(metric lago.BillableMetric) BillableMetric {
+	result := BillableMetric{
+		Code:              metric.Code,
+		Name:              metric.Name,
+		Description:       metric.Description,
+		AggregationType:   metric.AggregationType,
+		FieldName:         metric.FieldName,
+		Expression:        metric.Expression,
+		Recurring:         metric.Recurring,
+		RoundingPrecision: metric.RoundingPrecision,
+		Filters:           metric.Filters,
+	}
+	if metric.RoundingFunction != nil {
+		result.RoundingFunction = *metric.RoundingFunction
+	}
+	if metric.WeightedInterval != nil {
+		result.WeightedInterval = *metric.WeightedInterval
+	}
+
+	return result
+}
+
+func addOnFromLago(addOn lago.AddOn) AddOn {
+	return AddOn{
+		Code:               addOn.Code,
+		Name:               addOn.Name,
+		InvoiceDisplayName: addOn.InvoiceDisplayName,
+		Description:        addOn.Description,
+		AmountCents:        addOn.AmountCents,
+		AmountCurrency:     addOn.AmountCurrency,
+		TaxCodes:           taxCodes(addOn.Taxes),
+	}
+}
+
+func featureFromLago(feature lago.Feature) Feature {
+	result := Feature{
+		Code:        feature.Code,
+		Name:        feature.Name,
+		Description: feature.Description,
+	}
+	for _, privilege := range feature.Privileges {
+		result.Privileges = append(result.Privileges, Privilege{
+			Code:          privilege.Code,
+			Name:          privilege.Name,
+			ValueType:     privilege.ValueType,
+			SelectOptions: privilege.Config.SelectOptions,
+		})
+	}
+
+	return result
+}
+
+func planFromLago(plan lago.Plan) Plan {
+	result := Plan{
+		Code:               plan.Code,
+		Name:               plan.Name,
+		InvoiceDisplayName: plan.InvoiceDisplayName,
+		Description:        plan.Description,
+		Interval:           plan.Interval,
+		AmountCents:        plan.AmountCents,
+		AmountCurrency:     plan.AmountCurrency,
+		PayInAdvance:       plan.PayInAdvance,
+		BillChargesMonthly: plan.BillChargesMonthly,
+		TrialPeriod:        plan.TrialPeriod,
+		TaxCodes:           taxCodes(plan.Taxes),
+	}
+	if plan.MinimumCommitment != nil {
+		result.MinimumCommitment = &MinimumCommitment{
+			AmountCents:        plan.MinimumCommitment.AmountCents,
+			InvoiceDisplayName: plan.MinimumCommitment.InvoiceDisplayName,
+			TaxCodes:           taxCodes(plan.MinimumCommitment.Taxes),
+		}
+	}
+	for _, threshold := range plan.UsageThresholds {
+		result.UsageThresholds = append(result.UsageThresholds, UsageThreshold{
+			ThresholdDisplayName: threshold.ThresholdDisplayName,
+			AmountCents:          threshold.AmountCents,
+			Recurring:            threshold.Recurring,
+		})
+	}
+	for _, charge := range plan.Charges {
+		result.Charges = append(result.Charges, chargeFromLago(charge))
+	}
+	for _, fixedCharge := range plan.FixedCharges {
+		result.FixedCharges = append(result.FixedCharges, fixedChargeFromLago(fixedCharge))
+	}
+	for _, entitlement := range plan.Entitlements {
+		result.Entitlements = append(result.Entitlements, entitlementFromLago(entitlement))
+	}
+
+	return result
+}
+
+func chargeFromLago(charge lago.Charge) Charge {
+	result := Charge{
+		Code:               charge.Code,
+		BillableMetricCode: charge.BillableMetricCode,
+		ChargeModel:        charge.ChargeModel,
+		InvoiceDisplayName: charge.InvoiceDisplayName,
+		PayInAdvance:       charge.PayInAdvance,
+		Prorated:           charge.Prorated,
+		MinAmountCents:     charge.MinAmountCents,
+		Properties:         charge.Properties,
+		TaxCodes:           taxCodes(charge.Taxes),
+	}
+	for _, filter := range charge.Filters {
+		result.Filters = append(result.Filters, chargeFilterFromLago(filter.InvoiceDisplayName, filter.Values, filter.Properties))
+	}
+
+	return result
+}
+
+func chargeFilterFromLago(invoiceDisplayName string, values map[string]interface{}, properties map[string]interface{}) ChargeFilter {
+	filter := ChargeFilter{
+		InvoiceDisplayName: invoiceDisplayName,
+		Values:             make(map[string][]string, len(values)),
+		Properties:         properties,
+	}
+	for key, value := range values {
+		switch value := value.(type) {
+		case []interface{}:
+			for _, item := range value {
+				filter.Values[key] = append(filter.Values[key], fmt.Sprint(item))
+			}
+		case []string:
+			filter.Values[key] = value
+		default:
+			filter.Values[key] = []string{fmt.Sprint(value)}
+		}
+	}
+
+	return filter
+}
+
+func fixedChargeFromLago(fixedCharge lago.FixedCharge) FixedCharge {
+	return FixedCharge{
+		Code:               fixedCharge.Code,
+		AddOnCode:          fixedCharge.AddOnCode,
+		ChargeModel:        fixedCharge.ChargeModel,
+		InvoiceDisplayName: fixedCharge.InvoiceDisplayName,
+		Units:              fixedCharge.Units,
+		PayInAdvance:       fixedCharge.PayInAdvance,
+		Prorated:           fixedCharge.Prorated,
+		Properties:         fixedCharge.Properties,
+		TaxCodes:           taxCodes(fixedCharge.Taxes),
+	}
+}
+
+func entitlementFromLago(entitlement lago.PlanEntitlement) Entitlement {
+	result := Entitlement{FeatureCode: entitlement.Code}
+	if len(entitlement.Privileges) > 0 {
+		result.Privileges = make(map[string]any, len(entitlement.Privileges))
+		for _, privilege := range entitlement.Privileges {
+			result.Privileges[privilege.Code] = privilege.Value
+		}
+	}
+
+	return result
+}
+
+func couponFromLago(coupon lago.Coupon) Coupon {
+	return Coupon{
+		Code:                coupon.Code,
+		Name:                coupon.Name,
+		Description:         coupon.Description,
+		CouponType:          coupon.CouponType,
+		AmountCents:         coupon.AmountCents,
+		AmountCurrency:      coupon.AmountCurrency,
+		PercentageRate:      coupon.PercentageRate,
+		Frequency:           coupon.Frequency,
+		FrequencyDuration:   coupon.FrequencyDuration,
+		Reusable:            coupon.Reusable,
+		Expiration:          coupon.Expiration,
+		ExpirationAt:        coupon.ExpirationAt,
+		PlanCodes:           coupon.PlanCodes,
+		BillableMetricCodes: coupon.BillableMetricCodes,
+	}
+}
@@ -0,0 +1,278 @@
+package lago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// DefaultPoolConcurrency is the default number of tenants queried in parallel
+// by FanOut.
+const DefaultPoolConcurrency int = 4
+
+// KeyProvider resolves the Lago API key of a tenant (one tenant per Lago organization).
+type KeyProvider interface {
+	ApiKey(ctx context.Context, tenant string) (string, error)
+}
+
+// TenantLister is an optional interface a KeyProvider can implement to let the
+// pool enumerate every known tenant.
+type TenantLister interface {
+	Tenants(ctx context.Context) ([]string, error)
+}
+
+// KeyProviderFunc adapts a function to the KeyProvider interface.
+type KeyProviderFunc func(ctx context.Context, tenant string) (string, error)
+
+func (f KeyProviderFunc) ApiKey(ctx context.Context, tenant string) (string, error) {
+	return f(ctx, tenant)
+}
+
+// StaticKeyProvider is a KeyProvider backed by a fixed tenant to API key map.
+type StaticKeyProvider map[string]string
+
+func (p StaticKeyProvider) ApiKey(_ context.Context, tenant string) (string, error) {
+	apiKey, ok := p[tenant]
+	if !ok || apiKey == "" {
+		return "", fmt.Errorf("%w: %s", ErrUnknownTenant, tenant)
+	}
+
+	return apiKey, nil
+}
+
+func (p StaticKeyProvider) Tenants(_ context.Context) ([]string, error) {
+	tenants := make([]string, 0, len(p))
+	for tenant := range p {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+
+	return tenants, nil
+}
+
+// ErrUnknownTenant is returned when no API key can be found for a tenant.
+var ErrUnknownTenant = errors.New("lago: unknown tenant")
+
+// ClientPool hands out one Client per tenant.
+//
+// All clients share the same HTTP transport, so connections are pooled across
+// organizations, while each client gets its own RetryPolicy so rate limit
+// retries and observations stay isolated per organization.
+type ClientPool struct {
+	keys        KeyProvider
+	baseURL     string
+	retryPolicy *RetryPolicy
+	concurrency int
+	httpClient  *http.Client
+
+	mu         sync.Mutex
+	clients    map[string]*Client
+	rateLimits map[string]*RateLimitInfo
+}
+
+// TenantResult holds the outcome of a fanned out call for one tenant.
+type TenantResult[T any] struct {
+	Tenant string
+	Value  T
+	Err    *Error
+}
+
+func NewClientPool(keys KeyProvider) *ClientPool {
+	return &ClientPool{
+		keys:        keys,
+		retryPolicy: DefaultRetryPolicy(),
+		concurrency: DefaultPoolConcurrency,
+		httpClient:  &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()},
+		clients:     make(map[string]*Client),
+		rateLimits:  make(map[string]*RateLimitInfo),
+	}
+}
+
+// SetBaseURL sets the Lago API URL used by every tenant client.
+// It only affects clients created afterwards.
+func (p *ClientPool) SetBaseURL(url string) *ClientPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.baseURL = url
+	return p
+}
+
+// SetRetryPolicy sets the policy template copied into every tenant client.
+// It only affects clients created afterwards. Pass nil to disable retries.
+func (p *ClientPool) SetRetryPolicy(policy *RetryPolicy) *ClientPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if policy == nil {
+		policy = &RetryPolicy{EnableRetry: false}
+	}
+	p.retryPolicy = policy
+	return p
+}
+
+// SetConcurrency sets the maximum number of tenants queried in parallel by FanOut.
+func (p *ClientPool) SetConcurrency(concurrency int) *ClientPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	p.concurrency = concurrency
+	return p
+}
+
+// SetHTTPClient replaces the http.Client shared by tenant clients.
+// It only affects clients created afterwards.
+func (p *ClientPool) SetHTTPClient(httpClient *http.Client) *ClientPool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.httpClient = httpClient
+	return p
+}
+
+// Client returns the client of the given tenant, creating it on first use.
+func (p *ClientPool) Client(ctx context.Context, tenant string) (*Client, *Error) {
+	p.mu.Lock()
+	client, ok := p.clients[tenant]
+	p.mu.Unlock()
+	if ok {
+		return client, nil
+	}
+
+	apiKey, err := p.keys.ApiKey(ctx, tenant)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	// Another goroutine may have created it while the key was being resolved.
+	if client, ok := p.clients[tenant]; ok {
+		return client, nil
+	}
+
+	client = NewWithHTTPClient(p.httpClient).SetApiKey(apiKey)
+	if p.baseURL != "" {
+		client.SetBaseURL(p.baseURL)
+	}
+	client.SetRetryPolicy(p.tenantRetryPolicy(tenant))
+
+	p.clients[tenant] = client
+	return client, nil
+}
+
+// Invalidate drops the cached client of a tenant, for example after its API
+// key has been rotated. The next call to Client resolves the key again.
+func (p *ClientPool) Invalidate(tenant string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.clients, tenant)
+	delete(p.rateLimits, tenant)
+}
+
+// RateLimitInfo returns the last rate limit headers observed for a tenant, or
+// nil when none were received yet.
+func (p *ClientPool) RateLimitInfo(tenant string) *RateLimitInfo {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.rateLimits[tenant]
+}
+
+// Tenants lists the known tenants when the KeyProvider implements TenantLister.
+func (p *ClientPool) Tenants(ctx context.Context) ([]string, *Error) {
+	lister, ok := p.keys.(TenantLister)
+	if !ok {
+		return nil, &Error{Err: errors.New("lago: key provider cannot list tenants")}
+	}
+
+	tenants, err := lister.Tenants(ctx)
+	if err != nil {
+		return nil, &Error{Err: err}
+	}
+
+	return tenants, nil
+}
+
+// tenantRetryPolicy copies the pool policy for a tenant and wraps the
+// OnRateLimitInfo callback to record the tenant rate limit state.
+// Must be called with p.mu held.
+func (p *ClientPool) tenantRetryPolicy(tenant string) *RetryPolicy {
+	policy := *p.retryPolicy
+	observer := policy.OnRateLimitInfo
+
+	policy.OnRateLimitInfo = func(info *RateLimitInfo) {
+		p.mu.Lock()
+		p.rateLimits[tenant] = info
+		p.mu.Unlock()
+
+		if observer != nil {
+			observer(info)
+		}
+	}
+
+	return &policy
+}
+
+// FanOut runs fn against the client of every tenant, with at most the pool
+// concurrency in flight, and returns the results in the order of tenants.
+// When tenants is empty, every tenant known to the KeyProvider is used.
+//
+// Example:
+//
+//	results, err := lago.FanOut(ctx, pool, nil, func(ctx context.Context, c *lago.Client) (*lago.MrrResult, *lago.Error) {
+//		return c.Mrr().GetList(ctx, &lago.MrrListInput{AmountCurrency: "EUR"})
+//	})
+func FanOut[T any](ctx context.Context, pool *ClientPool, tenants []string, fn func(ctx context.Context, client *Client) (T, *Error)) ([]TenantResult[T], *Error) {
+	if len(tenants) == 0 {
+		var err *Error
+		tenants, err = pool.Tenants(ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	pool.mu.Lock()
+	concurrency := pool.concurrency
+	pool.mu.Unlock()
+
+	results := make([]TenantResult[T], len(tenants))
+	semaphore := make(chan struct{}, concurrency)
+
+	var wg sync.WaitGroup
+	for i, tenant := range tenants {
+		results[i].Tenant = tenant
+
+		select {
+		case semaphore <- struct{}{}:
+		case <-ctx.Done():
+			results[i].Err = &Error{Err: ctx.Err()}
+			continue
+		}
+
+		wg.Add(1)
+		go func(result *TenantResult[T]) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+
+			client, err := pool.Client(ctx, result.Tenant)
+			if err != nil {
+				result.Err = err
+				return
+			}
+
+			result.Value, result.Err = fn(ctx, client)
+		}(&results[i])
+	}
+	wg.Wait()
+
+	return results, nil
+}
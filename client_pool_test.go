@@ -0,0 +1,135 @@
+package lago_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+func TestClientPool_ResolvesKeyPerTenant(t *testing.T) {
+	c := qt.New(t)
+
+	var mu sync.Mutex
+	seen := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		seen[r.Header.Get("Authorization")]++
+		mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-limit", "100")
+		if r.Header.Get("Authorization") == "Bearer key_a" {
+			w.Header().Set("x-ratelimit-remaining", "10")
+			_, _ = w.Write([]byte(`{"mrrs": [{"month": "2024-01-01", "amount_cents": 100, "currency": "EUR"}]}`))
+			return
+		}
+		w.Header().Set("x-ratelimit-remaining", "90")
+		_, _ = w.Write([]byte(`{"mrrs": [{"month": "2024-01-01", "amount_cents": 200, "currency": "USD"}]}`))
+	}))
+	defer server.Close()
+
+	pool := NewClientPool(StaticKeyProvider{"a": "key_a", "b": "key_b"}).SetBaseURL(server.URL)
+
+	results, err := FanOut(context.Background(), pool, nil, func(ctx context.Context, client *Client) (*MrrResult, *Error) {
+		return client.Mrr().GetList(ctx, &MrrListInput{})
+	})
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(results, qt.HasLen, 2)
+
+	c.Assert(results[0].Tenant, qt.Equals, "a")
+	c.Assert(results[0].Err == nil, qt.IsTrue)
+	c.Assert(results[0].Value.Mrrs[0].AmountCents, qt.Equals, 100)
+	c.Assert(results[1].Tenant, qt.Equals, "b")
+	c.Assert(results[1].Value.Mrrs[0].AmountCents, qt.Equals, 200)
+
+	c.Assert(seen["Bearer key_a"], qt.Equals, 1)
+	c.Assert(seen["Bearer key_b"], qt.Equals, 1)
+
+	c.Assert(*pool.RateLimitInfo("a").Remaining, qt.Equals, 10)
+	c.Assert(*pool.RateLimitInfo("b").Remaining, qt.Equals, 90)
+}
+
+func TestClientPool_CachesClientsAndIsolatesPolicies(t *testing.T) {
+	c := qt.New(t)
+
+	calls := 0
+	provider := KeyProviderFunc(func(_ context.Context, tenant string) (string, error) {
+		calls++
+		return "key_" + tenant, nil
+	})
+	pool := NewClientPool(provider)
+
+	a1, err := pool.Client(context.Background(), "a")
+	c.Assert(err == nil, qt.IsTrue)
+	a2, _ := pool.Client(context.Background(), "a")
+	b, _ := pool.Client(context.Background(), "b")
+
+	c.Assert(a1, qt.Equals, a2)
+	c.Assert(a1, qt.Not(qt.Equals), b)
+	c.Assert(a1.RetryPolicy, qt.Not(qt.Equals), b.RetryPolicy)
+	c.Assert(calls, qt.Equals, 2)
+
+	pool.Invalidate("a")
+	a3, _ := pool.Client(context.Background(), "a")
+	c.Assert(a3, qt.Not(qt.Equals), a1)
+	c.Assert(calls, qt.Equals, 3)
+}
+
+func TestClientPool_UnknownTenant(t *testing.T) {
+	c := qt.New(t)
+
+	pool := NewClientPool(StaticKeyProvider{"a": "key_a"})
+
+	results, err := FanOut(context.Background(), pool, []string{"missing"}, func(ctx context.Context, client *Client) (bool, *Error) {
+		return true, nil
+	})
+	c.Assert(err == nil, qt.IsTrue)
+	c.Assert(results[0].Err, qt.Not(qt.IsNil))
+	c.Assert(errors.Is(results[0].Err.Err, ErrUnknownTenant), qt.IsTrue)
+}
+
+func TestClientPool_TenantsRequiresLister(t *testing.T) {
+	c := qt.New(t)
+
+	pool := NewClientPool(KeyProviderFunc(func(context.Context, string) (string, error) { return "k", nil }))
+
+	_, err := pool.Tenants(context.Background())
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(strings.Contains(err.Error(), "cannot list tenants"), qt.IsTrue)
+}
+
+func TestFanOut_BoundedConcurrency(t *testing.T) {
+	c := qt.New(t)
+
+	keys := StaticKeyProvider{}
+	for _, tenant := range []string{"a", "b", "c", "d", "e", "f"} {
+		keys[tenant] = "key_" + tenant
+	}
+	pool := NewClientPool(keys).SetConcurrency(2)
+
+	var inFlight, maxInFlight int32
+	_, err := FanOut(context.Background(), pool, nil, func(ctx context.Context, client *Client) (int, *Error) {
+		current := atomic.AddInt32(&inFlight, 1)
+		for {
+			observed := atomic.LoadInt32(&maxInFlight)
+			if current <= observed || atomic.CompareAndSwapInt32(&maxInFlight, observed, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		atomic.AddInt32(&inFlight, -1)
+		return 0, nil
+	})
+	c.Assert(err == nil, qt.IsTrue)
+	c.Assert(atomic.LoadInt32(&maxInFlight) <= 2, qt.IsTrue)
+}
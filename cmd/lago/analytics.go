@@ -0,0 +1,111 @@
+package main
+
+import (
+	"flag"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+var mrrColumns = []column[lago.Mrr]{
+	{"month", func(m lago.Mrr) any { return m.Month }},
+	{"mrr", func(m lago.Mrr) any { return money(m.AmountCents, m.AmountCurrency) }},
+}
+
+var grossRevenueColumns = []column[lago.GrossRevenue]{
+	{"month", func(r lago.GrossRevenue) any { return r.Month }},
+	{"gross_revenue", func(r lago.GrossRevenue) any { return money(r.AmountCents, r.AmountCurrency) }},
+	{"invoices_count", func(r lago.GrossRevenue) any { return r.InvoicesCount }},
+}
+
+var overdueBalanceColumns = []column[lago.OverdueBalance]{
+	{"month", func(b lago.OverdueBalance) any { return b.Month }},
+	{"overdue_balance", func(b lago.OverdueBalance) any { return money(b.AmountCents, b.AmountCurrency) }},
+}
+
+func analyticsCommand() *command {
+	return &command{
+		name:    "analytics",
+		summary: "Show MRR, gross revenue and overdue balances by month",
+		subcommands: []*command{
+			{
+				name:    "mrr",
+				summary: "Show the monthly recurring revenue",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					currency := flags.String("currency", "", "currency of the amounts")
+					months := flags.Int("months", 0, "number of months, 0 for the API default")
+
+					return func([]string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						result, lagoErr := client.Mrr().GetList(e.ctx, &lago.MrrListInput{
+							AmountCurrency: *currency,
+							Months:         *months,
+						})
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return render(e, mrrColumns, result.Mrrs)
+					}
+				},
+			},
+			{
+				name:    "gross-revenue",
+				summary: "Show the gross revenue",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					currency := flags.String("currency", "", "currency of the amounts")
+					customer := flags.String("customer", "", "external ID of the customer")
+					months := flags.Int("months", 0, "number of months, 0 for the API default")
+
+					return func([]string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						result, lagoErr := client.GrossRevenue().GetList(e.ctx, &lago.GrossRevenueListInput{
+							AmountCurrency:     *currency,
+							ExternalCustomerId: *customer,
+							Months:             *months,
+						})
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return render(e, grossRevenueColumns, result.GrossRevenues)
+					}
+				},
+			},
+			{
+				name:    "overdue-balance",
+				summary: "Show the overdue balance",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					currency := flags.String("currency", "", "currency of the amounts")
+					customer := flags.String("customer", "", "external ID of the customer")
+					months := flags.Int("months", 0, "number of months, 0 for the API default")
+
+					return func([]string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						result, lagoErr := client.OverdueBalance().GetList(e.ctx, &lago.OverdueBalanceListInput{
+							AmountCurrency:     *currency,
+							ExternalCustomerId: *customer,
+							Months:             *months,
+						})
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return render(e, overdueBalanceColumns, result.OverdueBalances)
+					}
+				},
+			},
+		},
+	}
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// config is the content of the configuration file, holding one profile per
+// organization or environment.
+type config struct {
+	DefaultProfile string              `json:"default_profile,omitempty"`
+	Profiles       map[string]*profile `json:"profiles"`
+
+	path string
+}
+
+type profile struct {
+	ApiKey string `json:"api_key"`
+	ApiURL string `json:"api_url,omitempty"`
+}
+
+// defaultConfigPath is $XDG_CONFIG_HOME/lago/config.json or its platform
+// equivalent.
+func defaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "lago", "config.json"), nil
+}
+
+// loadConfig reads the configuration file. A missing file is an empty
+// configuration.
+func loadConfig(path string) (*config, error) {
+	if path == "" {
+		var err error
+		if path, err = defaultConfigPath(); err != nil {
+			return nil, err
+		}
+	}
+
+	c := &config{Profiles: map[string]*profile{}, path: path}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("invalid configuration file %s: %w", path, err)
+	}
+	if c.Profiles == nil {
+		c.Profiles = map[string]*profile{}
+	}
+
+	return c, nil
+}
+
+// save writes the configuration file, readable by its owner only since it
+// holds API keys.
+func (c *config) save() error {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return err
+	}
+
+	return os.WriteFile(c.path, append(data, '\n'), 0o600)
+}
+
+// profile returns the named profile, or the default one when name is empty.
+func (c *config) profile(name string) (*profile, error) {
+	if name == "" {
+		name = c.DefaultProfile
+	}
+	if name == "" {
+		if len(c.Profiles) == 1 {
+			for _, p := range c.Profiles {
+				return p, nil
+			}
+		}
+		return &profile{}, errors.New("no profile selected: use --profile or \"lago profiles use\"")
+	}
+
+	p, ok := c.Profiles[name]
+	if !ok {
+		return &profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+
+	return p, nil
+}
+
+func (c *config) names() []string {
+	names := make([]string, 0, len(c.Profiles))
+	for name := range c.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func profilesCommand() *command {
+	return &command{
+		name:    "profiles",
+		summary: "Manage the API keys of several organizations",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "List the profiles",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return func([]string) error {
+						c, err := loadConfig(e.configPath)
+						if err != nil {
+							return err
+						}
+
+						rows := make([]profileRow, 0, len(c.Profiles))
+						for _, name := range c.names() {
+							rows = append(rows, profileRow{
+								Name:    name,
+								ApiURL:  c.Profiles[name].ApiURL,
+								ApiKey:  maskSecret(c.Profiles[name].ApiKey),
+								Default: name == c.DefaultProfile,
+							})
+						}
+
+						return render(e, profileColumns, rows)
+					}
+				},
+			},
+			{
+				name:    "set",
+				summary: "Create or update a profile",
+				usage:   "<name>",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					apiKey := flags.String("api-key", "", "API key of the organization (required)")
+					apiURL := flags.String("api-url", "", "API URL, for self-hosted instances")
+
+					return func(args []string) error {
+						if *apiKey == "" {
+							return errors.New("--api-key is required")
+						}
+
+						c, err := loadConfig(e.configPath)
+						if err != nil {
+							return err
+						}
+						c.Profiles[args[0]] = &profile{ApiKey: *apiKey, ApiURL: *apiURL}
+						if c.DefaultProfile == "" {
+							c.DefaultProfile = args[0]
+						}
+
+						return c.save()
+					}
+				},
+			},
+			{
+				name:    "use",
+				summary: "Select the default profile",
+				usage:   "<name>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return func(args []string) error {
+						c, err := loadConfig(e.configPath)
+						if err != nil {
+							return err
+						}
+						if _, ok := c.Profiles[args[0]]; !ok {
+							return fmt.Errorf("unknown profile %q", args[0])
+						}
+						c.DefaultProfile = args[0]
+
+						return c.save()
+					}
+				},
+			},
+			{
+				name:    "remove",
+				summary: "Remove a profile",
+				usage:   "<name>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return func(args []string) error {
+						c, err := loadConfig(e.configPath)
+						if err != nil {
+							return err
+						}
+						if _, ok := c.Profiles[args[0]]; !ok {
+							return fmt.Errorf("unknown profile %q", args[0])
+						}
+						delete(c.Profiles, args[0])
+						if c.DefaultProfile == args[0] {
+							c.DefaultProfile = ""
+						}
+
+						return c.save()
+					}
+				},
+			},
+		},
+	}
+}
+
+type profileRow struct {
+	Name    string `json:"name"`
+	ApiURL  string `json:"api_url,omitempty"`
+	ApiKey  string `json:"api_key"`
+	Default bool   `json:"default"`
+}
+
+var profileColumns = []column[profileRow]{
+	{"name", func(p profileRow) any { return p.Name }},
+	{"api_url", func(p profileRow) any { return p.ApiURL }},
+	{"api_key", func(p profileRow) any { return p.ApiKey }},
+	{"default", func(p profileRow) any { return p.Default }},
+}
+
+// maskSecret keeps the last four characters of an API key.
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return "****"
+	}
+
+	return "****" + secret[len(secret)-4:]
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+var customerColumns = []column[lago.Customer]{
+	{"external_id", func(c lago.Customer) any { return c.ExternalID }},
+	{"name", func(c lago.Customer) any { return c.Name }},
+	{"email", func(c lago.Customer) any { return c.Email }},
+	{"currency", func(c lago.Customer) any { return c.Currency }},
+	{"country", func(c lago.Customer) any { return c.Country }},
+	{"created_at", func(c lago.Customer) any { return c.CreatedAt }},
+}
+
+func customersCommand() *command {
+	return &command{
+		name:    "customers",
+		summary: "List and inspect customers",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "List customers",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					search := flags.String("search", "", "filter on name, email or external ID")
+					limit := flags.Int("limit", 0, "maximum number of customers, 0 for all")
+
+					return func([]string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						customers, err := collect(*limit, func(page, perPage int) ([]lago.Customer, lago.Metadata, *lago.Error) {
+							result, err := client.Customer().GetList(e.ctx, &lago.CustomerListInput{
+								Page:       lago.Ptr(page),
+								PerPage:    lago.Ptr(perPage),
+								SearchTerm: *search,
+							})
+							if err != nil {
+								return nil, lago.Metadata{}, err
+							}
+							return result.Customers, result.Meta, nil
+						})
+						if err != nil {
+							return err
+						}
+
+						return render(e, customerColumns, customers)
+					}
+				},
+			},
+			{
+				name:    "get",
+				summary: "Show a customer",
+				usage:   "<external-id>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return func(args []string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						customer, lagoErr := client.Customer().Get(e.ctx, args[0])
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return renderOne(e, customerColumns, *customer)
+					}
+				},
+			},
+		},
+	}
+}
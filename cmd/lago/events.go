@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/google/uuid"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+var eventColumns = []column[lago.Event]{
+	{"transaction_id", func(ev lago.Event) any { return ev.TransactionID }},
+	{"code", func(ev lago.Event) any { return ev.Code }},
+	{"external_subscription_id", func(ev lago.Event) any { return ev.ExternalSubscriptionID }},
+	{"timestamp", func(ev lago.Event) any { return ev.Timestamp }},
+	{"properties", func(ev lago.Event) any {
+		if len(ev.Properties) == 0 {
+			return nil
+		}
+		data, _ := json.Marshal(ev.Properties)
+		return string(data)
+	}},
+}
+
+func eventsCommand() *command {
+	return &command{
+		name:    "events",
+		summary: "Send and inspect usage events",
+		subcommands: []*command{
+			{
+				name:    "send",
+				summary: "Send an event, or a batch of events, read as JSON from a file or the standard input",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					file := flags.String("file", "-", "JSON file holding an event object or an array of events, - for the standard input")
+
+					return func([]string) error {
+						events, err := readEvents(e, *file)
+						if err != nil {
+							return err
+						}
+
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						if len(events) == 1 {
+							event, lagoErr := client.Event().Create(e.ctx, &events[0])
+							if lagoErr != nil {
+								return apiError(lagoErr)
+							}
+							return renderOne(e, eventColumns, *event)
+						}
+
+						sent, lagoErr := client.Event().Batch(e.ctx, events)
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return render(e, eventColumns, sent)
+					}
+				},
+			},
+			{
+				name:    "get",
+				summary: "Show an event",
+				usage:   "<transaction-id>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return func(args []string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						event, lagoErr := client.Event().Get(e.ctx, args[0])
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return renderOne(e, eventColumns, *event)
+					}
+				},
+			},
+		},
+	}
+}
+
+// readEvents decodes an event object or an array of events. Events without a
+// transaction ID get a random one.
+func readEvents(e *env, path string) ([]lago.EventInput, error) {
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(e.stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var events []lago.EventInput
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '[' {
+		err = json.Unmarshal(data, &events)
+	} else {
+		var event lago.EventInput
+		err = json.Unmarshal(data, &event)
+		events = append(events, event)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid events: %w", err)
+	}
+	if len(events) == 0 {
+		return nil, errors.New("no events to send")
+	}
+
+	for i := range events {
+		if events[i].Code == "" {
+			return nil, fmt.Errorf("event %d: code is required", i)
+		}
+		if events[i].TransactionID == "" {
+			events[i].TransactionID = uuid.NewString()
+		}
+	}
+
+	return events, nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+var invoiceColumns = []column[lago.Invoice]{
+	{"lago_id", func(i lago.Invoice) any { return i.LagoID }},
+	{"number", func(i lago.Invoice) any { return i.Number }},
+	{"customer", func(i lago.Invoice) any {
+		if i.Customer == nil {
+			return nil
+		}
+		return i.Customer.ExternalID
+	}},
+	{"issuing_date", func(i lago.Invoice) any { return i.IssuingDate }},
+	{"status", func(i lago.Invoice) any { return i.Status }},
+	{"payment_status", func(i lago.Invoice) any { return i.PaymentStatus }},
+	{"total", func(i lago.Invoice) any { return money(i.TotalAmountCents, i.Currency) }},
+	{"total_due", func(i lago.Invoice) any { return money(i.TotalDueAmountCents, i.Currency) }},
+}
+
+func invoicesCommand() *command {
+	return &command{
+		name:    "invoices",
+		summary: "List, finalize, void and download invoices",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "List invoices",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					customer := flags.String("customer", "", "external ID of the customer")
+					status := flags.String("status", "", "invoice status: draft, finalized, voided, failed or pending")
+					paymentStatus := flags.String("payment-status", "", "payment status: pending, succeeded or failed")
+					from := flags.String("from", "", "first issuing date, as YYYY-MM-DD")
+					to := flags.String("to", "", "last issuing date, as YYYY-MM-DD")
+					limit := flags.Int("limit", 0, "maximum number of invoices, 0 for all")
+
+					return func([]string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						invoices, err := collect(*limit, func(page, perPage int) ([]lago.Invoice, lago.Metadata, *lago.Error) {
+							result, err := client.Invoice().GetList(e.ctx, &lago.InvoiceListInput{
+								Page:               lago.Ptr(page),
+								PerPage:            lago.Ptr(perPage),
+								ExternalCustomerID: *customer,
+								Status:             lago.InvoiceStatus(*status),
+								PaymentStatus:      lago.InvoicePaymentStatus(*paymentStatus),
+								IssuingDateFrom:    *from,
+								IssuingDateTo:      *to,
+							})
+							if err != nil {
+								return nil, lago.Metadata{}, err
+							}
+							return result.Invoices, result.Meta, nil
+						})
+						if err != nil {
+							return err
+						}
+
+						return render(e, invoiceColumns, invoices)
+					}
+				},
+			},
+			{
+				name:    "get",
+				summary: "Show an invoice",
+				usage:   "<invoice-id>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return invoiceAction(e, func(client *lago.Client, id string) (*lago.Invoice, *lago.Error) {
+						return client.Invoice().Get(e.ctx, id)
+					})
+				},
+			},
+			{
+				name:    "finalize",
+				summary: "Finalize a draft invoice",
+				usage:   "<invoice-id>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return invoiceAction(e, func(client *lago.Client, id string) (*lago.Invoice, *lago.Error) {
+						return client.Invoice().Finalize(e.ctx, id)
+					})
+				},
+			},
+			{
+				name:    "void",
+				summary: "Void a finalized invoice",
+				usage:   "<invoice-id>",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					creditNote := flags.Bool("credit-note", false, "generate a credit note")
+					refund := flags.Int("refund", 0, "amount in cents to refund with the credit note")
+					credit := flags.Int("credit", 0, "amount in cents to credit with the credit note")
+
+					return invoiceAction(e, func(client *lago.Client, id string) (*lago.Invoice, *lago.Error) {
+						return client.Invoice().Void(e.ctx, id, &lago.VoidInvoiceOptions{
+							GenerateCreditNote: *creditNote,
+							RefundAmount:       *refund,
+							CreditAmount:       *credit,
+						})
+					})
+				},
+			},
+			{
+				name:    "download",
+				summary: "Download the PDF of an invoice, waiting for its generation",
+				usage:   "<invoice-id>",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					out := flags.String("out", "", "output file, - for the standard output (default: the invoice number)")
+
+					return func(args []string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						return downloadInvoice(e, client, args[0], *out)
+					}
+				},
+			},
+		},
+	}
+}
+
+// invoiceAction runs an action on the invoice given as argument and prints
+// the resulting invoice.
+func invoiceAction(e *env, action func(client *lago.Client, id string) (*lago.Invoice, *lago.Error)) func([]string) error {
+	return func(args []string) error {
+		client, err := e.lagoClient()
+		if err != nil {
+			return err
+		}
+
+		invoice, lagoErr := action(client, args[0])
+		if lagoErr != nil {
+			return apiError(lagoErr)
+		}
+
+		return renderOne(e, invoiceColumns, *invoice)
+	}
+}
+
+func downloadInvoice(e *env, client *lago.Client, id string, out string) error {
+	if out == "-" {
+		_, err := client.Documents().Download(e.ctx, lago.InvoiceDocument, id, e.stdout)
+		return apiError(err)
+	}
+
+	// The file name is only known once the invoice is read, so the document
+	// is written to a temporary file first.
+	dir := "."
+	if out != "" {
+		dir = filepath.Dir(out)
+	}
+	file, err := os.CreateTemp(dir, ".lago-invoice-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	info, lagoErr := client.Documents().Download(e.ctx, lago.InvoiceDocument, id, file)
+	if closeErr := file.Close(); lagoErr == nil && closeErr != nil {
+		return closeErr
+	}
+	if lagoErr != nil {
+		return apiError(lagoErr)
+	}
+
+	if out == "" {
+		out = info.Name
+	}
+	if err := os.Rename(file.Name(), out); err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(e.stderr, "Downloaded %s (%d bytes)\n", out, info.Size)
+
+	return err
+}
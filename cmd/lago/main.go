@@ -0,0 +1,261 @@
+// Command lago is a command-line client for the Lago API.
+//
+// Usage:
+//
+//	lago [global flags] <command> <subcommand> [flags] [arguments]
+//
+// Run "lago help" for the list of commands. The API key and URL come from the
+// --api-key and --api-url flags, the LAGO_API_KEY and LAGO_API_URL environment
+// variables, or a profile saved with "lago profiles set".
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	os.Exit(run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// errUsage reports invalid arguments. The usage of the command has already
+// been printed.
+var errUsage = errors.New("invalid usage")
+
+// env carries what a command needs to run.
+type env struct {
+	ctx    context.Context
+	stdin  io.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	output     outputFormat
+	configPath string
+	profile    string
+	apiKey     string
+	apiURL     string
+
+	client *lago.Client
+}
+
+// lagoClient builds the API client from the flags, the environment and the
+// selected profile, in that order of precedence.
+func (e *env) lagoClient() (*lago.Client, error) {
+	if e.client != nil {
+		return e.client, nil
+	}
+
+	apiKey, apiURL := e.apiKey, e.apiURL
+	if apiKey == "" {
+		apiKey = os.Getenv("LAGO_API_KEY")
+	}
+	if apiURL == "" {
+		apiURL = os.Getenv("LAGO_API_URL")
+	}
+
+	if apiKey == "" || apiURL == "" {
+		config, err := loadConfig(e.configPath)
+		if err != nil {
+			return nil, err
+		}
+		profile, err := config.profile(e.profile)
+		if err != nil && apiKey == "" {
+			return nil, err
+		}
+		if apiKey == "" {
+			apiKey = profile.ApiKey
+		}
+		if apiURL == "" {
+			apiURL = profile.ApiURL
+		}
+	}
+	if apiKey == "" {
+		return nil, errors.New("no API key: use --api-key, LAGO_API_KEY or \"lago profiles set\"")
+	}
+
+	e.client = lago.New().SetApiKey(apiKey)
+	if apiURL != "" {
+		e.client.SetBaseURL(apiURL)
+	}
+
+	return e.client, nil
+}
+
+func run(ctx context.Context, args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	e := &env{ctx: ctx, stdin: stdin, stdout: stdout, stderr: stderr, output: outputTable}
+
+	flags := flag.NewFlagSet("lago", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.StringVar(&e.profile, "profile", os.Getenv("LAGO_PROFILE"), "profile to use (default: the profile selected with \"lago profiles use\")")
+	flags.StringVar(&e.apiKey, "api-key", "", "API key, overrides the profile")
+	flags.StringVar(&e.apiURL, "api-url", "", "API URL, overrides the profile")
+	flags.StringVar(&e.configPath, "config", os.Getenv("LAGO_CONFIG"), "path of the configuration file")
+	flags.Var(&e.output, "output", "output format: table, json or csv")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: lago [global flags] <command> <subcommand> [flags] [arguments]\n\nCommands:\n")
+		for _, cmd := range commands() {
+			fmt.Fprintf(stderr, "  %-14s %s\n", cmd.name, cmd.summary)
+		}
+		fmt.Fprintf(stderr, "\nGlobal flags:\n")
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if flags.NArg() == 0 || flags.Arg(0) == "help" {
+		flags.Usage()
+		return 0
+	}
+
+	err := dispatch(e, commands(), flags.Args(), "lago")
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "lago: %s\n", err)
+		return 1
+	}
+}
+
+// command is a node of the command tree: either a group of subcommands or a
+// runnable command.
+type command struct {
+	name    string
+	summary string
+	// usage lists the arguments, such as "<invoice-id>".
+	usage       string
+	subcommands []*command
+	// flags registers the command flags; the returned function runs the
+	// command with the remaining arguments.
+	flags func(e *env, flags *flag.FlagSet) func(args []string) error
+}
+
+func commands() []*command {
+	return []*command{
+		customersCommand(),
+		subscriptionsCommand(),
+		invoicesCommand(),
+		walletsCommand(),
+		eventsCommand(),
+		analyticsCommand(),
+		webhooksCommand(),
+		profilesCommand(),
+	}
+}
+
+func dispatch(e *env, cmds []*command, args []string, path string) error {
+	usage := func() {
+		fmt.Fprintf(e.stderr, "Usage: %s <command>\n\nCommands:\n", path)
+		for _, cmd := range cmds {
+			fmt.Fprintf(e.stderr, "  %-16s %s\n", cmd.name, cmd.summary)
+		}
+	}
+
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage()
+		if len(args) == 0 {
+			return errUsage
+		}
+		return nil
+	}
+
+	for _, cmd := range cmds {
+		if cmd.name != args[0] {
+			continue
+		}
+		if cmd.subcommands != nil {
+			return dispatch(e, cmd.subcommands, args[1:], path+" "+cmd.name)
+		}
+
+		flags := flag.NewFlagSet(path+" "+cmd.name, flag.ContinueOnError)
+		flags.SetOutput(e.stderr)
+		runCmd := cmd.flags(e, flags)
+		flags.Usage = func() {
+			fmt.Fprintf(e.stderr, "Usage: %s %s [flags] %s\n\n%s\n", path, cmd.name, cmd.usage, cmd.summary)
+			flags.PrintDefaults()
+		}
+		positional, err := parseInterspersed(flags, args[1:])
+		if err != nil {
+			if errors.Is(err, flag.ErrHelp) {
+				return nil
+			}
+			return errUsage
+		}
+		if want := len(strings.Fields(cmd.usage)); len(positional) < want {
+			flags.Usage()
+			return errUsage
+		}
+
+		return runCmd(positional)
+	}
+
+	fmt.Fprintf(e.stderr, "%s: unknown command %q\n\n", path, args[0])
+	usage()
+
+	return errUsage
+}
+
+// parseInterspersed parses flags placed before, between or after the
+// positional arguments, such as "profiles set prod --api-key k".
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		rest := flags.Args()
+		if len(rest) == 0 {
+			return positional, nil
+		}
+		// Parsing stops after "--": everything left is positional.
+		if consumed := len(args) - len(rest); consumed > 0 && args[consumed-1] == "--" {
+			return append(positional, rest...), nil
+		}
+		positional = append(positional, rest[0])
+		args = rest[1:]
+	}
+}
+
+// apiError converts a client error to an error, keeping nil as nil.
+func apiError(err *lago.Error) error {
+	if err == nil {
+		return nil
+	}
+	if err.Err != nil && err.HTTPStatusCode == 0 {
+		return err.Err
+	}
+
+	text := err.Message
+	if text == "" {
+		text = http.StatusText(err.HTTPStatusCode)
+	}
+	message := fmt.Sprintf("API error %d: %s", err.HTTPStatusCode, text)
+	if err.ErrorCode != "" {
+		message += " (" + err.ErrorCode + ")"
+	}
+	if err.ErrorDetail != nil {
+		if details, detailErr := err.ErrorDetail.Details(); detailErr == nil && len(details) > 0 {
+			message += fmt.Sprintf(" %v", details)
+		}
+	}
+
+	return errors.New(message)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	lt "github.com/getlago/lago-go-client/testing"
+	"github.com/getlago/lago-go-client/webhooks"
+)
+
+func runCLI(c *qt.C, stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	c.Setenv("LAGO_API_KEY", "")
+	c.Setenv("LAGO_API_URL", "")
+	c.Setenv("LAGO_PROFILE", "")
+	c.Setenv("LAGO_CONFIG", filepath.Join(c.TempDir(), "config.json"))
+	code := run(context.Background(), args, strings.NewReader(stdin), &stdout, &stderr)
+
+	return code, stdout.String(), stderr.String()
+}
+
+const customerPages = `{"customers": [{"external_id": "cus_1", "name": "Acme", "currency": "EUR"}], "meta": {"current_page": 1, "next_page": 2}}`
+
+func TestCustomersList_Paginates(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{
+		"GET /customers?page=1&per_page=100": customerPages,
+		"GET /customers?page=2&per_page=100": `{"customers": [{"external_id": "cus_2", "name": "Globex", "currency": "USD"}], "meta": {"current_page": 2}}`,
+	})
+
+	code, stdout, stderr := runCLI(c, "", "--api-key", "k", "--api-url", server.URL(), "customers", "list")
+	c.Assert(code, qt.Equals, 0, qt.Commentf("stderr: %s", stderr))
+
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	c.Assert(lines, qt.HasLen, 3)
+	c.Assert(strings.Fields(lines[0])[:3], qt.DeepEquals, []string{"EXTERNAL_ID", "NAME", "EMAIL"})
+	c.Assert(strings.Fields(lines[1]), qt.DeepEquals, []string{"cus_1", "Acme", "EUR"})
+	c.Assert(strings.Fields(lines[2]), qt.DeepEquals, []string{"cus_2", "Globex", "USD"})
+}
+
+func TestCustomersList_Limit(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{
+		"GET /customers?page=1&per_page=1": customerPages,
+	})
+
+	code, stdout, _ := runCLI(c, "", "--api-key", "k", "--api-url", server.URL(), "--output", "json", "customers", "list", "--limit", "1")
+	c.Assert(code, qt.Equals, 0)
+
+	var customers []map[string]any
+	c.Assert(json.Unmarshal([]byte(stdout), &customers), qt.IsNil)
+	c.Assert(customers, qt.HasLen, 1)
+	c.Assert(customers[0]["external_id"], qt.Equals, "cus_1")
+}
+
+func TestInvoicesGet_CSV(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{
+		"GET /invoices/inv_1": `{"invoice": {"number": "ACME-001", "status": "finalized", "currency": "EUR", "total_amount_cents": 12345, "customer": {"external_id": "cus_1"}}}`,
+	})
+
+	code, stdout, _ := runCLI(c, "", "--api-key", "k", "--api-url", server.URL(), "--output", "csv", "invoices", "get", "inv_1")
+	c.Assert(code, qt.Equals, 0)
+	c.Assert(stdout, qt.Equals, "lago_id,number,customer,issuing_date,status,payment_status,total,total_due\n"+
+		"00000000-0000-0000-0000-000000000000,ACME-001,cus_1,,finalized,,123.45 EUR,0.00 EUR\n")
+}
+
+func TestEventsSend_Batch(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{
+		"POST /events/batch": `{"events": [{"transaction_id": "t1", "code": "calls"}, {"transaction_id": "t2", "code": "calls"}]}`,
+	})
+
+	stdin := `[{"transaction_id": "t1", "code": "calls", "external_subscription_id": "sub"}, {"code": "calls", "external_subscription_id": "sub"}]`
+	code, stdout, stderr := runCLI(c, stdin, "--api-key", "k", "--api-url", server.URL(), "events", "send")
+	c.Assert(code, qt.Equals, 0, qt.Commentf("stderr: %s", stderr))
+	c.Assert(strings.Count(stdout, "calls"), qt.Equals, 2)
+
+	var sent struct {
+		Events []map[string]any `json:"events"`
+	}
+	c.Assert(json.Unmarshal([]byte(server.Last("POST", "/events/batch").Body), &sent), qt.IsNil)
+	c.Assert(sent.Events, qt.HasLen, 2)
+	c.Assert(sent.Events[0]["transaction_id"], qt.Equals, "t1")
+	// A transaction ID is generated when missing.
+	c.Assert(sent.Events[1]["transaction_id"], qt.Not(qt.Equals), "")
+}
+
+func TestAPIError(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, map[string]string{})
+
+	code, _, stderr := runCLI(c, "", "--api-key", "k", "--api-url", server.URL(), "customers", "get", "missing")
+	c.Assert(code, qt.Equals, 1)
+	c.Assert(stderr, qt.Contains, "API error 404: Not Found (resource_not_found)")
+}
+
+func TestUsage(t *testing.T) {
+	c := qt.New(t)
+
+	code, _, stderr := runCLI(c, "", "invoices", "explode")
+	c.Assert(code, qt.Equals, 2)
+	c.Assert(stderr, qt.Contains, `unknown command "explode"`)
+
+	code, _, stderr = runCLI(c, "", "invoices", "get")
+	c.Assert(code, qt.Equals, 2)
+	c.Assert(stderr, qt.Contains, "Usage: lago invoices get [flags] <invoice-id>")
+}
+
+func TestProfiles(t *testing.T) {
+	c := qt.New(t)
+
+	production := lt.NewRoutesServer(c, map[string]string{
+		"GET /customers/cus_1": `{"customer": {"external_id": "cus_1", "name": "Production"}}`,
+	})
+	staging := lt.NewRoutesServer(c, map[string]string{
+		"GET /customers/cus_1": `{"customer": {"external_id": "cus_1", "name": "Staging"}}`,
+	})
+
+	config := filepath.Join(c.TempDir(), "config.json")
+	cli := func(args ...string) (int, string) {
+		code, stdout, stderr := runCLI(c, "", append([]string{"--config", config}, args...)...)
+		c.Assert(code, qt.Equals, 0, qt.Commentf("stderr: %s", stderr))
+		return code, stdout
+	}
+
+	cli("profiles", "set", "production", "--api-key", "prod_key_1234", "--api-url", production.URL())
+	cli("profiles", "set", "staging", "--api-key", "staging_key_5678", "--api-url", staging.URL())
+
+	// The first profile is the default one.
+	_, stdout := cli("customers", "get", "cus_1")
+	c.Assert(stdout, qt.Contains, "Production")
+
+	_, stdout = cli("--profile", "staging", "customers", "get", "cus_1")
+	c.Assert(stdout, qt.Contains, "Staging")
+
+	cli("profiles", "use", "staging")
+	_, stdout = cli("customers", "get", "cus_1")
+	c.Assert(stdout, qt.Contains, "Staging")
+
+	_, stdout = cli("profiles", "list")
+	c.Assert(stdout, qt.Contains, "****1234")
+	c.Assert(stdout, qt.Not(qt.Contains), "prod_key_1234")
+
+	cli("profiles", "remove", "staging")
+	_, stdout = cli("profiles", "list")
+	c.Assert(stdout, qt.Not(qt.Contains), "staging")
+}
+
+func TestWebhooks_ListShowReplay(t *testing.T) {
+	c := qt.New(t)
+
+	store := c.TempDir()
+	body := `{"webhook_type": "customer.created", "object_type": "customer", "customer": {"external_id": "cus_1"}}`
+	c.Assert(webhooks.NewStore(store).Save(&webhooks.Delivery{
+		ID:           "1a901a90-delivery",
+		Header:       http.Header{webhooks.SignatureHeader: {"sig"}},
+		Body:         []byte(body),
+		Verification: webhooks.Verified,
+	}), qt.IsNil)
+
+	code, stdout, _ := runCLI(c, "", "webhooks", "list", "--store", store)
+	c.Assert(code, qt.Equals, 0)
+	c.Assert(strings.Fields(strings.Split(stdout, "\n")[1]), qt.DeepEquals, []string{"1a901a90-delivery", "customer.created", "customer", "verified"})
+
+	code, stdout, _ = runCLI(c, "", "webhooks", "show", "1a90", "--store", store)
+	c.Assert(code, qt.Equals, 0)
+	c.Assert(stdout, qt.Contains, `"external_id": "cus_1"`)
+
+	var signature string
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		signature = r.Header.Get(webhooks.SignatureHeader)
+	}))
+	c.Cleanup(app.Close)
+
+	code, stdout, _ = runCLI(c, "", "webhooks", "replay", "1a90", "--store", store, "--to", app.URL)
+	c.Assert(code, qt.Equals, 0)
+	c.Assert(stdout, qt.Equals, "1a901a90-delivery -> 200 OK\n")
+	c.Assert(signature, qt.Equals, "sig")
+}
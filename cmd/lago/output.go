@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+type outputFormat string
+
+const (
+	outputTable outputFormat = "table"
+	outputJSON  outputFormat = "json"
+	outputCSV   outputFormat = "csv"
+)
+
+func (o *outputFormat) String() string {
+	return string(*o)
+}
+
+func (o *outputFormat) Set(value string) error {
+	switch format := outputFormat(value); format {
+	case outputTable, outputJSON, outputCSV:
+		*o = format
+		return nil
+	default:
+		return fmt.Errorf("unknown output format %q", value)
+	}
+}
+
+// column extracts one field of a row for the table and CSV outputs. The JSON
+// output prints the rows themselves.
+type column[T any] struct {
+	header string
+	value  func(T) any
+}
+
+// render prints a list of rows in the selected output format.
+func render[T any](e *env, columns []column[T], rows []T) error {
+	switch e.output {
+	case outputJSON:
+		if rows == nil {
+			rows = []T{}
+		}
+		return writeJSON(e, rows)
+
+	case outputCSV:
+		w := csv.NewWriter(e.stdout)
+		record := make([]string, len(columns))
+		for i, col := range columns {
+			record[i] = col.header
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+		for _, row := range rows {
+			for i, col := range columns {
+				record[i] = formatValue(col.value(row))
+			}
+			if err := w.Write(record); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+
+	default:
+		w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+		headers := make([]string, len(columns))
+		for i, col := range columns {
+			headers[i] = strings.ToUpper(col.header)
+		}
+		fmt.Fprintln(w, strings.Join(headers, "\t"))
+		for _, row := range rows {
+			values := make([]string, len(columns))
+			for i, col := range columns {
+				values[i] = formatValue(col.value(row))
+			}
+			fmt.Fprintln(w, strings.Join(values, "\t"))
+		}
+		return w.Flush()
+	}
+}
+
+// renderOne prints a single resource: as an object in JSON and as a
+// field/value list in a table.
+func renderOne[T any](e *env, columns []column[T], row T) error {
+	switch e.output {
+	case outputJSON:
+		return writeJSON(e, row)
+	case outputCSV:
+		return render(e, columns, []T{row})
+	default:
+		w := tabwriter.NewWriter(e.stdout, 0, 0, 2, ' ', 0)
+		for _, col := range columns {
+			fmt.Fprintf(w, "%s\t%s\n", col.header, formatValue(col.value(row)))
+		}
+		return w.Flush()
+	}
+}
+
+func writeJSON(e *env, v any) error {
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+
+	return encoder.Encode(v)
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case time.Time:
+		if v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil || v.IsZero() {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// money formats an amount in cents, leaving it empty without a currency.
+func money(cents int, currency lago.Currency) string {
+	if currency == "" {
+		return ""
+	}
+
+	return lago.NewMoney(int64(cents), currency).String()
+}
+
+// defaultPerPage is the page size used when listing resources.
+const defaultPerPage = 100
+
+// collect lists resources page by page until the last page or until limit
+// resources are read; a zero limit reads every page. Pages are no larger than
+// limit.
+func collect[T any](limit int, fetch func(page, perPage int) ([]T, lago.Metadata, *lago.Error)) ([]T, error) {
+	perPage := defaultPerPage
+	if limit > 0 && limit < perPage {
+		perPage = limit
+	}
+
+	all, err := lago.FetchPages(limit, func(page int) ([]T, lago.Metadata, *lago.Error) {
+		return fetch(page, perPage)
+	})
+	if err != nil {
+		return nil, apiError(err)
+	}
+
+	return all, nil
+}
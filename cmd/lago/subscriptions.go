@@ -0,0 +1,90 @@
+package main
+
+import (
+	"flag"
+	"strings"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+var subscriptionColumns = []column[lago.Subscription]{
+	{"external_id", func(s lago.Subscription) any { return s.ExternalID }},
+	{"external_customer_id", func(s lago.Subscription) any { return s.ExternalCustomerID }},
+	{"plan_code", func(s lago.Subscription) any { return s.PlanCode }},
+	{"status", func(s lago.Subscription) any { return s.Status }},
+	{"name", func(s lago.Subscription) any { return s.Name }},
+	{"subscription_at", func(s lago.Subscription) any { return s.SubscriptionAt }},
+	{"ending_at", func(s lago.Subscription) any { return s.EndingAt }},
+}
+
+func subscriptionsCommand() *command {
+	return &command{
+		name:    "subscriptions",
+		summary: "List and inspect subscriptions",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "List subscriptions",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					customer := flags.String("customer", "", "external ID of the customer")
+					plan := flags.String("plan", "", "plan code")
+					status := flags.String("status", "", "comma separated statuses, such as active,pending")
+					limit := flags.Int("limit", 0, "maximum number of subscriptions, 0 for all")
+
+					return func([]string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						var statuses []lago.SubscriptionStatus
+						for _, s := range strings.Split(*status, ",") {
+							if s = strings.TrimSpace(s); s != "" {
+								statuses = append(statuses, lago.SubscriptionStatus(s))
+							}
+						}
+
+						subscriptions, err := collect(*limit, func(page, perPage int) ([]lago.Subscription, lago.Metadata, *lago.Error) {
+							result, err := client.Subscription().GetList(e.ctx, lago.SubscriptionListInput{
+								ExternalCustomerID: *customer,
+								PlanCode:           *plan,
+								Status:             statuses,
+								Page:               lago.Ptr(page),
+								PerPage:            lago.Ptr(perPage),
+							})
+							if err != nil {
+								return nil, lago.Metadata{}, err
+							}
+							return result.Subscriptions, result.Meta, nil
+						})
+						if err != nil {
+							return err
+						}
+
+						return render(e, subscriptionColumns, subscriptions)
+					}
+				},
+			},
+			{
+				name:    "get",
+				summary: "Show a subscription",
+				usage:   "<external-id>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return func(args []string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						subscription, lagoErr := client.Subscription().Get(e.ctx, args[0])
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return renderOne(e, subscriptionColumns, *subscription)
+					}
+				},
+			},
+		},
+	}
+}
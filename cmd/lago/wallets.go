@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"flag"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+var walletColumns = []column[lago.Wallet]{
+	{"lago_id", func(w lago.Wallet) any { return w.LagoID }},
+	{"external_customer_id", func(w lago.Wallet) any { return w.ExternalCustomerID }},
+	{"name", func(w lago.Wallet) any { return w.Name }},
+	{"status", func(w lago.Wallet) any { return w.Status }},
+	{"credits_balance", func(w lago.Wallet) any { return w.CreditsBalance }},
+	{"balance", func(w lago.Wallet) any { return money(w.BalanceCents, w.Currency) }},
+	{"ongoing_balance", func(w lago.Wallet) any { return money(w.OngoingBalanceCents, w.Currency) }},
+}
+
+var walletTransactionColumns = []column[lago.WalletTransaction]{
+	{"lago_id", func(t lago.WalletTransaction) any { return t.LagoID }},
+	{"transaction_type", func(t lago.WalletTransaction) any { return t.TransactionType }},
+	{"transaction_status", func(t lago.WalletTransaction) any { return t.TransactionStatus }},
+	{"status", func(t lago.WalletTransaction) any { return t.Status }},
+	{"amount", func(t lago.WalletTransaction) any { return t.Amount }},
+	{"credit_amount", func(t lago.WalletTransaction) any { return t.CreditAmount }},
+	{"created_at", func(t lago.WalletTransaction) any { return t.CreatedAt }},
+}
+
+func walletsCommand() *command {
+	return &command{
+		name:    "wallets",
+		summary: "List wallets, top them up and inspect their transactions",
+		subcommands: []*command{
+			{
+				name:    "list",
+				summary: "List wallets",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					customer := flags.String("customer", "", "external ID of the customer")
+					currency := flags.String("currency", "", "wallet currency")
+					limit := flags.Int("limit", 0, "maximum number of wallets, 0 for all")
+
+					return func([]string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						wallets, err := collect(*limit, func(page, perPage int) ([]lago.Wallet, lago.Metadata, *lago.Error) {
+							result, err := client.Wallet().GetList(e.ctx, &lago.WalletListInput{
+								Page:               lago.Ptr(page),
+								PerPage:            lago.Ptr(perPage),
+								ExternalCustomerID: *customer,
+								Currency:           lago.Currency(*currency),
+							})
+							if err != nil {
+								return nil, lago.Metadata{}, err
+							}
+							return result.Wallets, result.Meta, nil
+						})
+						if err != nil {
+							return err
+						}
+
+						return render(e, walletColumns, wallets)
+					}
+				},
+			},
+			{
+				name:    "get",
+				summary: "Show a wallet",
+				usage:   "<wallet-id>",
+				flags: func(e *env, _ *flag.FlagSet) func([]string) error {
+					return func(args []string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						wallet, lagoErr := client.Wallet().Get(e.ctx, args[0])
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return renderOne(e, walletColumns, *wallet)
+					}
+				},
+			},
+			{
+				name:    "top-up",
+				summary: "Add paid or granted credits to a wallet",
+				usage:   "<wallet-id>",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					paid := flags.String("paid", "", "paid credits, invoiced to the customer")
+					granted := flags.String("granted", "", "granted credits, offered to the customer")
+					name := flags.String("name", "", "name of the transaction")
+
+					return func(args []string) error {
+						if *paid == "" && *granted == "" {
+							return errors.New("--paid or --granted is required")
+						}
+
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						result, lagoErr := client.WalletTransaction().Create(e.ctx, &lago.WalletTransactionInput{
+							WalletID:       args[0],
+							Name:           *name,
+							PaidCredits:    *paid,
+							GrantedCredits: *granted,
+						})
+						if lagoErr != nil {
+							return apiError(lagoErr)
+						}
+
+						return render(e, walletTransactionColumns, result.WalletTransactions)
+					}
+				},
+			},
+			{
+				name:    "transactions",
+				summary: "List the transactions of a wallet",
+				usage:   "<wallet-id>",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					limit := flags.Int("limit", 0, "maximum number of transactions, 0 for all")
+
+					return func(args []string) error {
+						client, err := e.lagoClient()
+						if err != nil {
+							return err
+						}
+
+						transactions, err := collect(*limit, func(page, perPage int) ([]lago.WalletTransaction, lago.Metadata, *lago.Error) {
+							result, err := client.WalletTransaction().GetList(e.ctx, &lago.WalletTransactionListInput{
+								WalletID: args[0],
+								Page:     lago.Ptr(page),
+								PerPage:  lago.Ptr(perPage),
+							})
+							if err != nil {
+								return nil, lago.Metadata{}, err
+							}
+							return result.WalletTransactions, result.Meta, nil
+						})
+						if err != nil {
+							return err
+						}
+
+						return render(e, walletTransactionColumns, transactions)
+					}
+				},
+			},
+		},
+	}
+}
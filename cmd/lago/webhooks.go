@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/getlago/lago-go-client/webhooks"
+)
+
+type deliveryRow struct {
+	*webhooks.Delivery
+	webhookType string
+	objectType  string
+}
+
+var deliveryColumns = []column[deliveryRow]{
+	{"id", func(d deliveryRow) any { return d.ID }},
+	{"received_at", func(d deliveryRow) any { return d.ReceivedAt }},
+	{"webhook_type", func(d deliveryRow) any { return d.webhookType }},
+	{"object_type", func(d deliveryRow) any { return d.objectType }},
+	{"verification", func(d deliveryRow) any { return d.Verification }},
+}
+
+func newDeliveryRow(delivery *webhooks.Delivery) deliveryRow {
+	row := deliveryRow{Delivery: delivery}
+	if message, err := delivery.Message(); err == nil {
+		row.webhookType, row.objectType = message.WebhookType, message.ObjectType
+	}
+
+	return row
+}
+
+// defaultWebhookStore is the user cache directory, since deliveries can be
+// received again at any time.
+func defaultWebhookStore() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return ".lago-webhooks"
+	}
+
+	return filepath.Join(dir, "lago", "webhooks")
+}
+
+func webhooksCommand() *command {
+	storeFlag := func(flags *flag.FlagSet) *string {
+		return flags.String("store", defaultWebhookStore(), "directory of the stored deliveries")
+	}
+
+	return &command{
+		name:    "webhooks",
+		summary: "Receive, inspect and replay webhooks locally",
+		subcommands: []*command{
+			{
+				name:    "listen",
+				summary: "Receive webhooks on a local port, verify, print and store them",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					addr := flags.String("addr", "localhost:4000", "listen address")
+					store := storeFlag(flags)
+					forward := flags.String("forward", "", "URL of an application handler receiving every verified delivery")
+					hmacKey := flags.String("hmac-key", "", "HMAC key of the webhook endpoint, to verify hmac signatures")
+					noVerify := flags.Bool("no-verify", false, "skip the verification of JWT signatures")
+
+					return func([]string) error {
+						listener := &webhooks.Listener{
+							Store:   webhooks.NewStore(*store),
+							HMACKey: []byte(*hmacKey),
+						}
+						if !*noVerify {
+							client, err := e.lagoClient()
+							if err != nil {
+								return fmt.Errorf("%w (or use --no-verify)", err)
+							}
+							listener.Verifier = client.Webhook()
+						}
+
+						// Deliveries are forwarded in order by a single goroutine, so
+						// that a slow application handler never delays the answer to
+						// Lago.
+						var mu sync.Mutex
+						forwards := make(chan *webhooks.Delivery, forwardQueueSize)
+						ctx, cancel := context.WithCancel(e.ctx)
+						var forwarding sync.WaitGroup
+						if *forward != "" {
+							forwarding.Go(func() {
+								for {
+									select {
+									case delivery := <-forwards:
+										if err := replay(ctx, *forward, delivery, io.Discard); err != nil {
+											mu.Lock()
+											fmt.Fprintf(e.stderr, "forward %s: %s\n", delivery.ID, err)
+											mu.Unlock()
+										}
+									case <-ctx.Done():
+										return
+									}
+								}
+							})
+						}
+
+						listener.OnDelivery = func(delivery *webhooks.Delivery) {
+							mu.Lock()
+							defer mu.Unlock()
+
+							webhooks.Print(e.stdout, delivery)
+							if *forward != "" && delivery.Verification != webhooks.Invalid {
+								select {
+								case forwards <- delivery:
+								default:
+									fmt.Fprintf(e.stderr, "forward %s: too many pending deliveries, replay it later\n", delivery.ID)
+								}
+							}
+						}
+
+						err := serve(e, *addr, listener, listener.Store.Dir())
+						cancel()
+						forwarding.Wait()
+						return err
+					}
+				},
+			},
+			{
+				name:    "list",
+				summary: "List the stored deliveries",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					store := storeFlag(flags)
+
+					return func([]string) error {
+						deliveries, err := webhooks.NewStore(*store).List()
+						if err != nil {
+							return err
+						}
+
+						rows := make([]deliveryRow, len(deliveries))
+						for i, delivery := range deliveries {
+							rows[i] = newDeliveryRow(delivery)
+						}
+						if e.output == outputJSON {
+							return writeJSON(e, deliveries)
+						}
+
+						return render(e, deliveryColumns, rows)
+					}
+				},
+			},
+			{
+				name:    "show",
+				summary: "Show a stored delivery",
+				usage:   "<delivery-id>",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					store := storeFlag(flags)
+
+					return func(args []string) error {
+						delivery, err := webhooks.NewStore(*store).Get(args[0])
+						if err != nil {
+							return err
+						}
+						if e.output == outputJSON {
+							return writeJSON(e, delivery)
+						}
+
+						return webhooks.Print(e.stdout, delivery)
+					}
+				},
+			},
+			{
+				name:    "replay",
+				summary: "Send a stored delivery again to an application handler",
+				usage:   "<delivery-id>",
+				flags: func(e *env, flags *flag.FlagSet) func([]string) error {
+					store := storeFlag(flags)
+					to := flags.String("to", "", "URL of the application handler (required)")
+
+					return func(args []string) error {
+						if *to == "" {
+							return errors.New("--to is required")
+						}
+
+						delivery, err := webhooks.NewStore(*store).Get(args[0])
+						if err != nil {
+							return err
+						}
+
+						return replay(e.ctx, *to, delivery, e.stdout)
+					}
+				},
+			},
+		},
+	}
+}
+
+// forwardQueueSize is the number of received deliveries waiting to be
+// forwarded past which new ones are dropped.
+const forwardQueueSize = 100
+
+// replayClient bounds the time an application handler may take to answer.
+var replayClient = &http.Client{Timeout: 30 * time.Second}
+
+// replay sends a delivery to url and reports the response status to w.
+func replay(ctx context.Context, url string, delivery *webhooks.Delivery, w io.Writer) error {
+	response, err := webhooks.Replay(ctx, replayClient, url, delivery)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	fmt.Fprintf(w, "%s -> %s\n", delivery.ID, response.Status)
+	if response.StatusCode >= 300 {
+		return fmt.Errorf("application handler answered %s", response.Status)
+	}
+
+	return nil
+}
+
+// serve runs handler on addr until the command context is canceled.
+func serve(e *env, addr string, handler http.Handler, storeDir string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	server := &http.Server{Handler: handler, ReadHeaderTimeout: 10 * time.Second}
+	fmt.Fprintf(e.stderr, "Listening for webhooks on http://%s, storing deliveries in %s\n", ln.Addr(), storeDir)
+
+	errs := make(chan error, 1)
+	go func() { errs <- server.Serve(ln) }()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-e.ctx.Done():
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}
+}
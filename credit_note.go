@@ -151,6 +151,41 @@ type CreditNote struct {
 	Metadata     map[string]*string       `json:"metadata,omitempty"`
 }
 
+// TotalAmount returns TotalAmountCents as Money.
+func (cn *CreditNote) TotalAmount() Money {
+	return NewMoney(int64(cn.TotalAmountCents), cn.Currency)
+}
+
+// CreditAmount returns CreditAmountCents as Money.
+func (cn *CreditNote) CreditAmount() Money {
+	return NewMoney(int64(cn.CreditAmountCents), cn.Currency)
+}
+
+// BalanceAmount returns BalanceAmountCents as Money.
+func (cn *CreditNote) BalanceAmount() Money {
+	return NewMoney(int64(cn.BalanceAmountCents), cn.Currency)
+}
+
+// RefundAmount returns RefundAmountCents as Money.
+func (cn *CreditNote) RefundAmount() Money {
+	return NewMoney(int64(cn.RefundAmountCents), cn.Currency)
+}
+
+// OffsetAmount returns OffsetAmountCents as Money.
+func (cn *CreditNote) OffsetAmount() Money {
+	return NewMoney(int64(cn.OffsetAmountCents), cn.Currency)
+}
+
+// TaxesAmount returns TaxesAmountCents as Money.
+func (cn *CreditNote) TaxesAmount() Money {
+	return NewMoney(int64(cn.TaxesAmountCents), cn.Currency)
+}
+
+// SubTotalExcludingTaxesAmount returns SubTotalExcludingTaxesAmountCents as Money.
+func (cn *CreditNote) SubTotalExcludingTaxesAmount() Money {
+	return NewMoney(int64(cn.SubTotalExcludingTaxesAmountCents), cn.Currency)
+}
+
 type EstimatedCreditNote struct {
 	LagoInvoiceID uuid.UUID `json:"lago_invoice_id,omitempty"`
 	InvoiceNumber string    `json:"invoice_number,omitempty"`
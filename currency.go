@@ -17,6 +17,7 @@ const (
 	BBD Currency = "BBD"
 	BDT Currency = "BDT"
 	BGN Currency = "BGN"
+	BHD Currency = "BHD"
 	BIF Currency = "BIF"
 	BMD Currency = "BMD"
 	BND Currency = "BND"
@@ -29,6 +30,7 @@ const (
 	CAD Currency = "CAD"
 	CDF Currency = "CDF"
 	CHF Currency = "CHF"
+	CLF Currency = "CLF"
 	CLP Currency = "CLP"
 	CNY Currency = "CNY"
 	COP Currency = "COP"
@@ -60,14 +62,17 @@ const (
 	IDR Currency = "IDR"
 	ILS Currency = "ILS"
 	INR Currency = "INR"
+	IQD Currency = "IQD"
 	ISK Currency = "ISK"
 	JMD Currency = "JMD"
+	JOD Currency = "JOD"
 	JPY Currency = "JPY"
 	KES Currency = "KES"
 	KGS Currency = "KGS"
 	KHR Currency = "KHR"
 	KMF Currency = "KMF"
 	KRW Currency = "KRW"
+	KWD Currency = "KWD"
 	KYD Currency = "KYD"
 	KZT Currency = "KZT"
 	LAK Currency = "LAK"
@@ -75,6 +80,7 @@ const (
 	LKR Currency = "LKR"
 	LRD Currency = "LRD"
 	LSL Currency = "LSL"
+	LYD Currency = "LYD"
 	MAD Currency = "MAD"
 	MDL Currency = "MDL"
 	MGA Currency = "MGA"
@@ -95,6 +101,7 @@ const (
 	NOK Currency = "NOK"
 	NPR Currency = "NPR"
 	NZD Currency = "NZD"
+	OMR Currency = "OMR"
 	PAB Currency = "PAB"
 	PEN Currency = "PEN"
 	PGK Currency = "PGK"
@@ -120,6 +127,7 @@ const (
 	SZL Currency = "SZL"
 	THB Currency = "THB"
 	TJS Currency = "TJS"
+	TND Currency = "TND"
 	TOP Currency = "TOP"
 	TRY Currency = "TRY"
 	TTD Currency = "TTD"
@@ -128,7 +136,9 @@ const (
 	UAH Currency = "UAH"
 	UGX Currency = "UGX"
 	USD Currency = "USD"
+	UYI Currency = "UYI"
 	UYU Currency = "UYU"
+	UYW Currency = "UYW"
 	UZS Currency = "UZS"
 	VND Currency = "VND"
 	VUV Currency = "VUV"
@@ -0,0 +1,289 @@
+package lago
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidDecimal is returned when a string is not a valid decimal number.
+var ErrInvalidDecimal = errors.New("lago: invalid decimal")
+
+// Decimal is an exact, arbitrary-precision decimal number, used for the
+// decimal strings of the API (precise amounts, credits, rates, ...).
+//
+// The value is unscaled * 10^-scale. The zero value is 0. Decimal values are
+// immutable: every operation returns a new value.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int32
+}
+
+var bigTen = big.NewInt(10)
+
+// NewDecimal returns unscaled * 10^-scale, for example NewDecimal(1250, 2) is 12.50.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{unscaled: big.NewInt(unscaled), scale: scale}
+}
+
+// maxDecimalScale bounds the exponent and the number of decimal places of
+// parsed decimals, so that "1e300000000" is rejected rather than expanded.
+const maxDecimalScale = 400
+
+// ParseDecimal parses a decimal string such as "12", "-0.005" or "1.5e-3".
+// Values with more than 400 decimal places, or whose exponent adds more than
+// 400 zeros, are rejected.
+func ParseDecimal(value string) (Decimal, error) {
+	s := strings.TrimSpace(value)
+	if s == "" {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+	}
+
+	exponent := int64(0)
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		e, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+		}
+		exponent = e
+		s = s[:i]
+	}
+
+	sign := ""
+	switch {
+	case strings.HasPrefix(s, "-"):
+		sign = "-"
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	integerPart, fractionPart, _ := strings.Cut(s, ".")
+	if integerPart == "" && fractionPart == "" {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+	}
+
+	digits := integerPart + fractionPart
+	for _, digit := range digits {
+		if digit < '0' || digit > '9' {
+			return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+		}
+	}
+
+	unscaled, ok := new(big.Int).SetString(sign+digits, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+	}
+
+	scale := int64(len(fractionPart)) - exponent
+	if scale > maxDecimalScale || scale < -maxDecimalScale {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, value)
+	}
+	if scale < 0 {
+		unscaled.Mul(unscaled, new(big.Int).Exp(bigTen, big.NewInt(-scale), nil))
+		scale = 0
+	}
+
+	return Decimal{unscaled: unscaled, scale: int32(scale)}, nil
+}
+
+// MustParseDecimal is like ParseDecimal but panics on invalid input.
+// It is intended for constants in code and tests.
+func MustParseDecimal(value string) Decimal {
+	d, err := ParseDecimal(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return d
+}
+
+// parseOptionalDecimal parses an API decimal string, treating "" as zero.
+func parseOptionalDecimal(value string) (Decimal, error) {
+	if value == "" {
+		return Decimal{}, nil
+	}
+
+	return ParseDecimal(value)
+}
+
+func (d Decimal) int() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+
+	return d.unscaled
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int32 {
+	return d.scale
+}
+
+// rescale returns the unscaled value of d expressed with the given, larger scale.
+func (d Decimal) rescale(scale int32) *big.Int {
+	if scale <= d.scale {
+		return new(big.Int).Set(d.int())
+	}
+
+	factor := new(big.Int).Exp(bigTen, big.NewInt(int64(scale-d.scale)), nil)
+	return new(big.Int).Mul(d.int(), factor)
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	scale := max(d.scale, other.scale)
+	sum := new(big.Int).Add(d.rescale(scale), other.rescale(scale))
+
+	return Decimal{unscaled: sum, scale: scale}
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return d.Add(other.Neg())
+}
+
+func (d Decimal) Mul(other Decimal) Decimal {
+	product := new(big.Int).Mul(d.int(), other.int())
+
+	return Decimal{unscaled: product, scale: d.scale + other.scale}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{unscaled: new(big.Int).Neg(d.int()), scale: d.scale}
+}
+
+// Sign returns -1, 0 or +1 depending on the sign of d.
+func (d Decimal) Sign() int {
+	return d.int().Sign()
+}
+
+func (d Decimal) IsZero() bool {
+	return d.Sign() == 0
+}
+
+// Cmp compares d and other, returning -1, 0 or +1.
+func (d Decimal) Cmp(other Decimal) int {
+	scale := max(d.scale, other.scale)
+
+	return d.rescale(scale).Cmp(other.rescale(scale))
+}
+
+// Equal reports whether d and other represent the same number, regardless of scale.
+func (d Decimal) Equal(other Decimal) bool {
+	return d.Cmp(other) == 0
+}
+
+// Round rounds d to the given number of decimal places using the given
+// rounding function. RoundRoundingFunction rounds half away from zero,
+// CeilRoundingFunction towards positive infinity and FloorRoundingFunction
+// towards negative infinity. An empty function defaults to round.
+func (d Decimal) Round(places int32, function RoundingFunction) Decimal {
+	if places >= d.scale {
+		return Decimal{unscaled: d.rescale(places), scale: places}
+	}
+
+	factor := new(big.Int).Exp(bigTen, big.NewInt(int64(d.scale-places)), nil)
+	quotient, remainder := new(big.Int).QuoRem(d.int(), factor, new(big.Int))
+
+	if remainder.Sign() != 0 {
+		switch function {
+		case CeilRoundingFunction:
+			if remainder.Sign() > 0 {
+				quotient.Add(quotient, big.NewInt(1))
+			}
+		case FloorRoundingFunction:
+			if remainder.Sign() < 0 {
+				quotient.Sub(quotient, big.NewInt(1))
+			}
+		default:
+			doubled := new(big.Int).Mul(new(big.Int).Abs(remainder), big.NewInt(2))
+			if doubled.Cmp(factor) >= 0 {
+				quotient.Add(quotient, big.NewInt(int64(remainder.Sign())))
+			}
+		}
+	}
+
+	return Decimal{unscaled: quotient, scale: places}
+}
+
+// Int64 returns the integer value of d when d has no fractional part and
+// fits in an int64.
+func (d Decimal) Int64() (int64, bool) {
+	rounded := d.Round(0, FloorRoundingFunction)
+	if !rounded.Equal(d) || !rounded.int().IsInt64() {
+		return 0, false
+	}
+
+	return rounded.int().Int64(), true
+}
+
+// String formats d in plain notation, keeping its scale ("12.50", "-0.005").
+func (d Decimal) String() string {
+	digits := new(big.Int).Abs(d.int()).String()
+	sign := ""
+	if d.Sign() < 0 {
+		sign = "-"
+	}
+
+	if d.scale <= 0 {
+		if d.int().Sign() != 0 && d.scale < 0 {
+			digits += strings.Repeat("0", int(-d.scale))
+		}
+		return sign + digits
+	}
+
+	scale := int(d.scale)
+	if len(digits) <= scale {
+		digits = strings.Repeat("0", scale-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+}
+
+// MarshalJSON encodes d as a JSON string, like the API does.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON accepts both JSON strings and numbers.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*d = Decimal{}
+		return nil
+	}
+
+	value := string(data)
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+	}
+
+	parsed, err := ParseDecimal(value)
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+	return nil
+}
+
+// ToMoney converts d, expressed in major units of currency, to Money, rounding
+// to the currency minor unit with the given rounding function.
+func (d Decimal) ToMoney(currency Currency, function RoundingFunction) (Money, error) {
+	rounded := d.Round(int32(currency.Exponent()), function)
+	if !rounded.int().IsInt64() {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Amount: rounded.int().Int64(), Currency: currency}, nil
+}
+
+// Decimal returns the amount in major units as an exact Decimal.
+func (m Money) Decimal() Decimal {
+	return NewDecimal(m.Amount, int32(m.Currency.Exponent()))
+}
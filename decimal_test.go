@@ -0,0 +1,166 @@
+package lago_test
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		wantErr  bool
+	}{
+		{"12", "12", false},
+		{"12.50", "12.50", false},
+		{"-0.005", "-0.005", false},
+		{"+3.1", "3.1", false},
+		{".5", "0.5", false},
+		{"1.5e-3", "0.0015", false},
+		{"1.5E2", "150", false},
+		{"0.1234567890123456789012345", "0.1234567890123456789012345", false},
+		{"", "", true},
+		{"abc", "", true},
+		{"1.2.3", "", true},
+		{"1e", "", true},
+		{"-", "", true},
+		{"1e400", "1" + strings.Repeat("0", 400), false},
+		{"1e-400", "0." + strings.Repeat("0", 399) + "1", false},
+		{"1e401", "", true},
+		{"1e-401", "", true},
+		{"1e300000000", "", true},
+		{"1e-300000000", "", true},
+		{"0." + strings.Repeat("0", 400) + "1", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c := qt.New(t)
+			d, err := ParseDecimal(tt.input)
+			if tt.wantErr {
+				c.Assert(errors.Is(err, ErrInvalidDecimal), qt.IsTrue, qt.Commentf("err: %v", err))
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(d.String(), qt.Equals, tt.expected)
+		})
+	}
+}
+
+func TestDecimal_Arithmetic(t *testing.T) {
+	c := qt.New(t)
+
+	// 0.1 + 0.2 is exact, unlike float64.
+	sum := MustParseDecimal("0.1").Add(MustParseDecimal("0.2"))
+	c.Assert(sum.String(), qt.Equals, "0.3")
+	c.Assert(sum.Equal(MustParseDecimal("0.30")), qt.IsTrue)
+
+	c.Assert(MustParseDecimal("1").Sub(MustParseDecimal("0.001")).String(), qt.Equals, "0.999")
+	c.Assert(MustParseDecimal("1.5").Mul(MustParseDecimal("-0.25")).String(), qt.Equals, "-0.375")
+	c.Assert(MustParseDecimal("2").Cmp(MustParseDecimal("1.999")), qt.Equals, 1)
+
+	var zero Decimal
+	c.Assert(zero.IsZero(), qt.IsTrue)
+	c.Assert(zero.String(), qt.Equals, "0")
+	c.Assert(zero.Add(NewDecimal(1250, 2)).String(), qt.Equals, "12.50")
+
+	n, ok := MustParseDecimal("42.000").Int64()
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(n, qt.Equals, int64(42))
+	_, ok = MustParseDecimal("42.5").Int64()
+	c.Assert(ok, qt.IsFalse)
+}
+
+func TestDecimal_Round(t *testing.T) {
+	tests := []struct {
+		input    string
+		places   int32
+		function RoundingFunction
+		expected string
+	}{
+		{"1.235", 2, RoundRoundingFunction, "1.24"},
+		{"1.234", 2, RoundRoundingFunction, "1.23"},
+		{"-1.235", 2, RoundRoundingFunction, "-1.24"},
+		{"1.231", 2, CeilRoundingFunction, "1.24"},
+		{"-1.239", 2, CeilRoundingFunction, "-1.23"},
+		{"1.239", 2, FloorRoundingFunction, "1.23"},
+		{"-1.231", 2, FloorRoundingFunction, "-1.24"},
+		{"1.5", 0, "", "2"},
+		{"1.5", 3, RoundRoundingFunction, "1.500"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+"/"+string(tt.function), func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(MustParseDecimal(tt.input).Round(tt.places, tt.function).String(), qt.Equals, tt.expected)
+		})
+	}
+}
+
+func TestDecimal_JSON(t *testing.T) {
+	c := qt.New(t)
+
+	var payload struct {
+		A Decimal `json:"a"`
+		B Decimal `json:"b"`
+	}
+	err := json.Unmarshal([]byte(`{"a": "10.25", "b": 3.5}`), &payload)
+	c.Assert(err, qt.IsNil)
+	c.Assert(payload.A.String(), qt.Equals, "10.25")
+	c.Assert(payload.B.String(), qt.Equals, "3.5")
+
+	data, err := json.Marshal(payload)
+	c.Assert(err, qt.IsNil)
+	c.Assert(string(data), qt.Equals, `{"a":"10.25","b":"3.5"}`)
+
+	err = json.Unmarshal([]byte(`{"a": 1e300000000}`), &payload)
+	c.Assert(err, qt.ErrorIs, ErrInvalidDecimal)
+}
+
+func TestDecimal_Accessors(t *testing.T) {
+	c := qt.New(t)
+
+	fee := &Fee{PreciseAmount: "10.123456789", PreciseUnitAmount: "0.000000001"}
+	amount, err := fee.PreciseAmountDecimal()
+	c.Assert(err, qt.IsNil)
+	c.Assert(amount.String(), qt.Equals, "10.123456789")
+	unitAmount, err := fee.PreciseUnitAmountDecimal()
+	c.Assert(err, qt.IsNil)
+	c.Assert(unitAmount.String(), qt.Equals, "0.000000001")
+	total, err := fee.PreciseTotalAmountDecimal()
+	c.Assert(err, qt.IsNil)
+	c.Assert(total.IsZero(), qt.IsTrue)
+
+	event := &EventInput{}
+	event.SetPreciseTotalAmountCents(MustParseDecimal("1234.5678"))
+	c.Assert(event.PreciseTotalAmountCents, qt.Equals, "1234.5678")
+
+	input := &WalletTransactionInput{}
+	input.SetPaidCredits(MustParseDecimal("100.10"))
+	input.SetGrantedCredits(NewDecimal(5, 0))
+	c.Assert(input.PaidCredits, qt.Equals, "100.10")
+	c.Assert(input.GrantedCredits, qt.Equals, "5")
+	paid, err := input.PaidCreditsDecimal()
+	c.Assert(err, qt.IsNil)
+	c.Assert(paid.String(), qt.Equals, "100.10")
+
+	wallet := &Wallet{Currency: EUR, RateAmount: "0.333", CreditsBalance: "3"}
+	balance, err := wallet.CreditsBalanceDecimal()
+	c.Assert(err, qt.IsNil)
+	money, err := wallet.CreditsToMoney(balance, RoundRoundingFunction)
+	c.Assert(err, qt.IsNil)
+	c.Assert(money, qt.Equals, NewMoney(100, EUR))
+
+	jpyWallet := &Wallet{Currency: JPY, RateAmount: "1.5"}
+	money, err = jpyWallet.CreditsToMoney(MustParseDecimal("3"), FloorRoundingFunction)
+	c.Assert(err, qt.IsNil)
+	c.Assert(money, qt.Equals, NewMoney(4, JPY))
+
+	c.Assert(NewMoney(1250, EUR).Decimal().String(), qt.Equals, "12.50")
+}
@@ -0,0 +1,418 @@
+package lago
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type DocumentType string
+
+const (
+	InvoiceDocument        DocumentType = "invoice"
+	CreditNoteDocument     DocumentType = "credit_note"
+	PaymentReceiptDocument DocumentType = "payment_receipt"
+)
+
+// DefaultDocumentPollInterval is the default delay between two checks of a
+// document file URL.
+const DefaultDocumentPollInterval = 2 * time.Second
+
+// DefaultDocumentMaxSize is the default maximum size of a downloaded document.
+const DefaultDocumentMaxSize int64 = 50 << 20
+
+// ErrDocumentTooLarge is returned when a document exceeds DocumentService.MaxSize.
+var ErrDocumentTooLarge = errors.New("lago: document exceeds maximum size")
+
+// ErrDocumentChecksumMismatch is returned when a downloaded document does not
+// match the expected checksum.
+var ErrDocumentChecksumMismatch = errors.New("lago: document checksum mismatch")
+
+// DocumentInfo describes a downloaded document.
+type DocumentInfo struct {
+	Type DocumentType
+	ID   string
+	// Name is a file name for the document, based on its number when known.
+	Name    string
+	FileURL string
+	Size    int64
+	// SHA256 is the hex encoded SHA-256 checksum of the document.
+	SHA256 string
+}
+
+// DocumentDownloadOptions tunes a single document download.
+type DocumentDownloadOptions struct {
+	// ExpectedSHA256, when set, is compared to the checksum of the downloaded
+	// document and ErrDocumentChecksumMismatch is returned on mismatch.
+	ExpectedSHA256 string
+}
+
+// DocumentService triggers the generation of invoice, credit note and payment
+// receipt PDFs, waits for them to be available and downloads them.
+//
+// The service is long-lived and shared by every caller of Client.Documents:
+// feed it the invoice.generated and credit_note.generated webhooks with
+// HandleWebhook to resume waiting downloads without waiting for the next poll.
+type DocumentService struct {
+	client *Client
+
+	// PollInterval is the delay between two checks of the document file URL.
+	PollInterval time.Duration
+	// MaxSize is the maximum size in bytes of a downloaded document.
+	MaxSize int64
+	// HTTPClient is used to fetch the files. File URLs are pre-signed, so the
+	// Lago API key is never sent with these requests.
+	HTTPClient *http.Client
+
+	mu      sync.Mutex
+	waiters map[string][]chan string
+}
+
+// Documents returns the document service of the client. Every call returns
+// the same instance, so that a webhook handled through one call resumes the
+// downloads started through another.
+func (c *Client) Documents() *DocumentService {
+	c.documentsOnce.Do(func() {
+		c.documents = &DocumentService{
+			client:       c,
+			PollInterval: DefaultDocumentPollInterval,
+			MaxSize:      DefaultDocumentMaxSize,
+			HTTPClient:   http.DefaultClient,
+			waiters:      make(map[string][]chan string),
+		}
+	})
+
+	return c.documents
+}
+
+// FileURL triggers the generation of a document if needed and waits until its
+// file URL is available, or ctx is done.
+func (ds *DocumentService) FileURL(ctx context.Context, documentType DocumentType, id string) (string, *Error) {
+	info, err := ds.fileURL(ctx, documentType, id)
+	if err != nil {
+		return "", err
+	}
+
+	return info.FileURL, nil
+}
+
+// Download waits for a document to be available and writes it into w.
+// Nothing is written when the document exceeds MaxSize or does not match the
+// expected checksum.
+func (ds *DocumentService) Download(ctx context.Context, documentType DocumentType, id string, w io.Writer, opts ...DocumentDownloadOptions) (*DocumentInfo, *Error) {
+	info, err := ds.fileURL(ctx, documentType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var options DocumentDownloadOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	if err := ds.fetch(ctx, info, w, options); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// DownloadCustomerInvoices writes the PDF of every non-draft invoice of a
+// customer into w as a zip archive, one entry per invoice.
+func (ds *DocumentService) DownloadCustomerInvoices(ctx context.Context, externalCustomerID string, w io.Writer) ([]DocumentInfo, *Error) {
+	archive := zip.NewWriter(w)
+
+	invoices, err := FetchPages(0, func(page int) ([]Invoice, Metadata, *Error) {
+		result, err := ds.client.Invoice().GetList(ctx, &InvoiceListInput{
+			ExternalCustomerID: externalCustomerID,
+			Page:               Ptr(page),
+			PerPage:            Ptr(100),
+		})
+		if err != nil {
+			return nil, Metadata{}, err
+		}
+		return result.Invoices, result.Meta, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var documents []DocumentInfo
+	for _, invoice := range invoices {
+		if invoice.Status == InvoiceStatusDraft {
+			continue
+		}
+
+		info, err := ds.fileURL(ctx, InvoiceDocument, invoice.LagoID.String())
+		if err != nil {
+			return nil, err
+		}
+
+		entry, zipErr := archive.Create(info.Name)
+		if zipErr != nil {
+			return nil, &Error{Err: zipErr}
+		}
+		if err := ds.fetch(ctx, info, entry, DocumentDownloadOptions{}); err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, *info)
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, &Error{Err: err}
+	}
+
+	return documents, nil
+}
+
+// HandleWebhook resumes the downloads waiting for the document carried by an
+// invoice.generated or credit_note.generated webhook. Other webhooks are ignored.
+func (ds *DocumentService) HandleWebhook(message *WebhookMessage) {
+	if message == nil {
+		return
+	}
+
+	switch object := message.Object.(type) {
+	case *Invoice:
+		ds.notify(InvoiceDocument, object.LagoID.String(), object.FileURL)
+	case *CreditNote:
+		ds.notify(CreditNoteDocument, object.LagoID.String(), object.FileURL)
+	}
+}
+
+func documentKey(documentType DocumentType, id string) string {
+	return fmt.Sprintf("%s/%s", documentType, id)
+}
+
+func (ds *DocumentService) notify(documentType DocumentType, id string, fileURL string) {
+	if fileURL == "" {
+		return
+	}
+
+	key := documentKey(documentType, id)
+
+	ds.mu.Lock()
+	waiters := ds.waiters[key]
+	delete(ds.waiters, key)
+	ds.mu.Unlock()
+
+	for _, waiter := range waiters {
+		waiter <- fileURL
+	}
+}
+
+func (ds *DocumentService) subscribe(documentType DocumentType, id string) (chan string, func()) {
+	key := documentKey(documentType, id)
+	waiter := make(chan string, 1)
+
+	ds.mu.Lock()
+	if ds.waiters == nil {
+		ds.waiters = make(map[string][]chan string)
+	}
+	ds.waiters[key] = append(ds.waiters[key], waiter)
+	ds.mu.Unlock()
+
+	unsubscribe := func() {
+		ds.mu.Lock()
+		defer ds.mu.Unlock()
+
+		waiters := ds.waiters[key]
+		for i, w := range waiters {
+			if w == waiter {
+				ds.waiters[key] = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(ds.waiters[key]) == 0 {
+			delete(ds.waiters, key)
+		}
+	}
+
+	return waiter, unsubscribe
+}
+
+// fileURL triggers the document generation and polls until the file URL is set.
+func (ds *DocumentService) fileURL(ctx context.Context, documentType DocumentType, id string) (*DocumentInfo, *Error) {
+	waiter, unsubscribe := ds.subscribe(documentType, id)
+	defer unsubscribe()
+
+	info, err := ds.trigger(ctx, documentType, id)
+	if err != nil {
+		return nil, err
+	}
+
+	interval := ds.PollInterval
+	if interval <= 0 {
+		interval = DefaultDocumentPollInterval
+	}
+
+	for info.FileURL == "" {
+		timer := time.NewTimer(interval)
+		select {
+		case fileURL := <-waiter:
+			timer.Stop()
+			info.FileURL = fileURL
+			return info, nil
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, &Error{Err: ctx.Err()}
+		case <-timer.C:
+		}
+
+		info, err = ds.get(ctx, documentType, id)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return info, nil
+}
+
+// trigger asks Lago to generate the document. Payment receipts are generated
+// automatically, so they are only fetched.
+func (ds *DocumentService) trigger(ctx context.Context, documentType DocumentType, id string) (*DocumentInfo, *Error) {
+	switch documentType {
+	case InvoiceDocument:
+		invoice, err := ds.client.Invoice().Download(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if invoice == nil {
+			return &DocumentInfo{Type: documentType, ID: id, Name: documentName(documentType, id, "")}, nil
+		}
+		return documentInfo(documentType, id, invoice.Number, invoice.FileURL), nil
+	case CreditNoteDocument:
+		creditNoteID, parseErr := uuid.Parse(id)
+		if parseErr != nil {
+			return nil, &Error{Err: parseErr}
+		}
+		creditNote, err := ds.client.CreditNote().Download(ctx, creditNoteID)
+		if err != nil {
+			return nil, err
+		}
+		if creditNote == nil {
+			return &DocumentInfo{Type: documentType, ID: id, Name: documentName(documentType, id, "")}, nil
+		}
+		return documentInfo(documentType, id, creditNote.Number, creditNote.FileURL), nil
+	default:
+		return ds.get(ctx, documentType, id)
+	}
+}
+
+func (ds *DocumentService) get(ctx context.Context, documentType DocumentType, id string) (*DocumentInfo, *Error) {
+	switch documentType {
+	case InvoiceDocument:
+		invoice, err := ds.client.Invoice().Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return documentInfo(documentType, id, invoice.Number, invoice.FileURL), nil
+	case CreditNoteDocument:
+		creditNoteID, parseErr := uuid.Parse(id)
+		if parseErr != nil {
+			return nil, &Error{Err: parseErr}
+		}
+		creditNote, err := ds.client.CreditNote().Get(ctx, creditNoteID)
+		if err != nil {
+			return nil, err
+		}
+		return documentInfo(documentType, id, creditNote.Number, creditNote.FileURL), nil
+	case PaymentReceiptDocument:
+		receipt, err := ds.client.PaymentReceipt().Get(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		return documentInfo(documentType, id, receipt.Number, receipt.FileURL), nil
+	default:
+		return nil, &Error{Err: fmt.Errorf("lago: unknown document type %q", documentType)}
+	}
+}
+
+func documentInfo(documentType DocumentType, id string, number string, fileURL string) *DocumentInfo {
+	return &DocumentInfo{
+		Type:    documentType,
+		ID:      id,
+		Name:    documentName(documentType, id, number),
+		FileURL: fileURL,
+	}
+}
+
+func documentName(documentType DocumentType, id string, number string) string {
+	if number == "" {
+		return fmt.Sprintf("%s-%s.pdf", documentType, id)
+	}
+
+	return strings.ReplaceAll(number, "/", "_") + ".pdf"
+}
+
+// fetch writes the document file into w, enforcing MaxSize and computing its checksum.
+func (ds *DocumentService) fetch(ctx context.Context, info *DocumentInfo, w io.Writer, options DocumentDownloadOptions) *Error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, info.FileURL, nil)
+	if err != nil {
+		return &Error{Err: err}
+	}
+
+	httpClient := ds.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return &Error{Err: err}
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return &Error{
+			Err:            fmt.Errorf("lago: cannot download %s %s", info.Type, info.ID),
+			HTTPStatusCode: response.StatusCode,
+			Message:        http.StatusText(response.StatusCode),
+		}
+	}
+
+	maxSize := ds.MaxSize
+	if maxSize <= 0 {
+		maxSize = DefaultDocumentMaxSize
+	}
+	if response.ContentLength > maxSize {
+		return &Error{Err: ErrDocumentTooLarge}
+	}
+
+	// Buffer one byte past MaxSize so that nothing is written to w when the
+	// document is too large or corrupted.
+	var buf bytes.Buffer
+	size, err := io.Copy(&buf, io.LimitReader(response.Body, maxSize+1))
+	if err != nil {
+		return &Error{Err: err}
+	}
+	if size > maxSize {
+		return &Error{Err: ErrDocumentTooLarge}
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	info.Size = size
+	info.SHA256 = hex.EncodeToString(sum[:])
+
+	if options.ExpectedSHA256 != "" && !strings.EqualFold(options.ExpectedSHA256, info.SHA256) {
+		return &Error{Err: ErrDocumentChecksumMismatch}
+	}
+
+	if _, err := buf.WriteTo(w); err != nil {
+		return &Error{Err: err}
+	}
+
+	return nil
+}
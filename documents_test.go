@@ -0,0 +1,192 @@
+package lago_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+const documentInvoiceID = "1a901a90-1a90-1a90-1a90-1a901a901a90"
+
+var documentPDF = []byte("%PDF-1.4 fake invoice")
+
+// documentServer serves an invoice whose file URL appears after readyAfter GETs.
+func documentServer(c *qt.C, readyAfter int32) (*httptest.Server, *int32) {
+	var gets int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v1/invoices/"+documentInvoiceID+"/download":
+			w.WriteHeader(http.StatusOK)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/invoices/"+documentInvoiceID:
+			fileURL := ""
+			if atomic.AddInt32(&gets, 1) >= readyAfter {
+				fileURL = server.URL + "/files/invoice.pdf"
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"invoice": {"lago_id": %q, "number": "INV-001", "status": "finalized", "file_url": %q}}`, documentInvoiceID, fileURL)
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v1/invoices":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"invoices": [{"lago_id": %q, "status": "finalized"}, {"lago_id": "2b902b90-2b90-2b90-2b90-2b902b902b90", "status": "draft"}], "meta": {"current_page": 1}}`, documentInvoiceID)
+		case r.URL.Path == "/files/invoice.pdf":
+			c.Assert(r.Header.Get("Authorization"), qt.Equals, "")
+			_, _ = w.Write(documentPDF)
+		default:
+			c.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+
+	return server, &gets
+}
+
+func TestDocumentService_DownloadPolls(t *testing.T) {
+	c := qt.New(t)
+
+	server, gets := documentServer(c, 2)
+	defer server.Close()
+
+	documents := New().SetBaseURL(server.URL).SetApiKey("test_api_key").Documents()
+	documents.PollInterval = 5 * time.Millisecond
+
+	var buf bytes.Buffer
+	info, err := documents.Download(context.Background(), InvoiceDocument, documentInvoiceID, &buf)
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+
+	sum := sha256.Sum256(documentPDF)
+	c.Assert(buf.Bytes(), qt.DeepEquals, documentPDF)
+	c.Assert(info.Name, qt.Equals, "INV-001.pdf")
+	c.Assert(info.Size, qt.Equals, int64(len(documentPDF)))
+	c.Assert(info.SHA256, qt.Equals, hex.EncodeToString(sum[:]))
+	c.Assert(atomic.LoadInt32(gets), qt.Equals, int32(2))
+}
+
+func TestDocumentService_ChecksumAndSizeLimits(t *testing.T) {
+	c := qt.New(t)
+
+	server, _ := documentServer(c, 1)
+	defer server.Close()
+
+	documents := New().SetBaseURL(server.URL).SetApiKey("test_api_key").Documents()
+	documents.PollInterval = 5 * time.Millisecond
+
+	_, err := documents.Download(context.Background(), InvoiceDocument, documentInvoiceID, io.Discard, DocumentDownloadOptions{ExpectedSHA256: "deadbeef"})
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(errors.Is(err.Err, ErrDocumentChecksumMismatch), qt.IsTrue)
+
+	documents.MaxSize = 4
+	_, err = documents.Download(context.Background(), InvoiceDocument, documentInvoiceID, io.Discard)
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(errors.Is(err.Err, ErrDocumentTooLarge), qt.IsTrue)
+
+	// Without a Content-Length, a body of one byte more than MaxSize is
+	// rejected before anything is written.
+	var buf bytes.Buffer
+	documents.MaxSize = int64(len(documentPDF)) - 1
+	documents.HTTPClient = &http.Client{Transport: unknownLengthTransport{}}
+	_, err = documents.Download(context.Background(), InvoiceDocument, documentInvoiceID, &buf)
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(errors.Is(err.Err, ErrDocumentTooLarge), qt.IsTrue)
+	c.Assert(buf.Len(), qt.Equals, 0)
+
+	documents.MaxSize = int64(len(documentPDF))
+	_, err = documents.Download(context.Background(), InvoiceDocument, documentInvoiceID, io.Discard)
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+}
+
+// unknownLengthTransport hides the Content-Length of responses, as when they
+// are streamed.
+type unknownLengthTransport struct{}
+
+func (unknownLengthTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	response, err := http.DefaultTransport.RoundTrip(request)
+	if err == nil {
+		response.ContentLength = -1
+	}
+	return response, err
+}
+
+func TestDocumentService_WebhookResumesWait(t *testing.T) {
+	c := qt.New(t)
+
+	// The file URL never shows up through polling.
+	server, _ := documentServer(c, 1<<30)
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("test_api_key")
+	documents := client.Documents()
+	documents.PollInterval = time.Hour
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		message, err := ParseWebhook([]byte(fmt.Sprintf(
+			`{"webhook_type": "invoice.generated", "object_type": "invoice", "invoice": {"lago_id": %q, "file_url": %q}}`,
+			documentInvoiceID, server.URL+"/files/invoice.pdf",
+		)))
+		c.Check(err, qt.IsNil)
+		// The webhook handler gets the service from the client too.
+		client.Documents().HandleWebhook(message)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	fileURL, err := documents.FileURL(ctx, InvoiceDocument, documentInvoiceID)
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(fileURL, qt.Equals, server.URL+"/files/invoice.pdf")
+}
+
+func TestDocumentService_ContextCancelled(t *testing.T) {
+	c := qt.New(t)
+
+	server, _ := documentServer(c, 1<<30)
+	defer server.Close()
+
+	documents := New().SetBaseURL(server.URL).SetApiKey("test_api_key").Documents()
+	documents.PollInterval = 5 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	_, err := documents.FileURL(ctx, InvoiceDocument, documentInvoiceID)
+	c.Assert(err, qt.Not(qt.IsNil))
+}
+
+func TestDocumentService_DownloadCustomerInvoices(t *testing.T) {
+	c := qt.New(t)
+
+	server, _ := documentServer(c, 1)
+	defer server.Close()
+
+	documents := New().SetBaseURL(server.URL).SetApiKey("test_api_key").Documents()
+	documents.PollInterval = 5 * time.Millisecond
+
+	var buf bytes.Buffer
+	infos, err := documents.DownloadCustomerInvoices(context.Background(), "CUSTOMER_1", &buf)
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(infos, qt.HasLen, 1)
+
+	archive, zipErr := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	c.Assert(zipErr, qt.IsNil)
+	c.Assert(archive.File, qt.HasLen, 1)
+	c.Assert(archive.File[0].Name, qt.Equals, "INV-001.pdf")
+
+	entry, openErr := archive.File[0].Open()
+	c.Assert(openErr, qt.IsNil)
+	content, _ := io.ReadAll(entry)
+	c.Assert(content, qt.DeepEquals, documentPDF)
+}
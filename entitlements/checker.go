@@ -0,0 +1,414 @@
+// Package entitlements answers feature gating questions from the
+// entitlements of Lago subscriptions, with a cache kept fresh by webhooks.
+package entitlements
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// ErrValueType is returned when a privilege is read with a getter that does
+// not match its value type.
+var ErrValueType = errors.New("entitlements: privilege value type mismatch")
+
+// DefaultTTL is the time entitlements are fresh when Options.TTL is zero.
+const DefaultTTL = time.Minute
+
+// Options configures a Checker.
+type Options struct {
+	// TTL is how long fetched entitlements are used without asking Lago
+	// again. It defaults to DefaultTTL.
+	TTL time.Duration
+	// StaleWhileRevalidate is how long after TTL expired entitlements are
+	// still returned while they are refreshed in the background. Zero
+	// refreshes them before answering.
+	StaleWhileRevalidate time.Duration
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Checker answers entitlement checks for subscriptions, fetching their
+// entitlements once per TTL. It is safe for concurrent use.
+type Checker struct {
+	client *lago.Client
+	opts   Options
+
+	mu       sync.Mutex
+	entries  map[string]*entry
+	inflight map[string]*fetch
+	// customers caches the active subscriptions of customers.
+	customers map[string]*customerEntry
+	// version changes on every invalidation, so that fetches started before
+	// an invalidation are not cached.
+	version uint64
+}
+
+type entry struct {
+	features   map[string]lago.SubscriptionEntitlement
+	fetchedAt  time.Time
+	refreshing bool
+}
+
+type customerEntry struct {
+	subscriptions []string
+	fetchedAt     time.Time
+}
+
+type fetch struct {
+	done     chan struct{}
+	features map[string]lago.SubscriptionEntitlement
+	err      error
+}
+
+// NewChecker returns a Checker fetching entitlements with client.
+func NewChecker(client *lago.Client, opts Options) *Checker {
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &Checker{
+		client:    client,
+		opts:      opts,
+		entries:   map[string]*entry{},
+		inflight:  map[string]*fetch{},
+		customers: map[string]*customerEntry{},
+	}
+}
+
+// Entitlements returns the entitlements of a subscription, by feature code.
+func (c *Checker) Entitlements(ctx context.Context, externalSubscriptionID string) (map[string]lago.SubscriptionEntitlement, error) {
+	c.mu.Lock()
+	if e, ok := c.entries[externalSubscriptionID]; ok {
+		age := c.opts.Now().Sub(e.fetchedAt)
+		if age < c.opts.TTL {
+			c.mu.Unlock()
+			return e.features, nil
+		}
+		if age < c.opts.TTL+c.opts.StaleWhileRevalidate {
+			if !e.refreshing {
+				e.refreshing = true
+				go c.fetch(context.WithoutCancel(ctx), externalSubscriptionID)
+			}
+			c.mu.Unlock()
+			return e.features, nil
+		}
+	}
+	c.mu.Unlock()
+
+	return c.fetch(ctx, externalSubscriptionID)
+}
+
+// fetch gets the entitlements of a subscription from Lago, sharing the call
+// with concurrent fetches of the same subscription.
+func (c *Checker) fetch(ctx context.Context, externalSubscriptionID string) (map[string]lago.SubscriptionEntitlement, error) {
+	c.mu.Lock()
+	if f, ok := c.inflight[externalSubscriptionID]; ok {
+		c.mu.Unlock()
+		select {
+		case <-f.done:
+			return f.features, f.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	f := &fetch{done: make(chan struct{})}
+	c.inflight[externalSubscriptionID] = f
+	version := c.version
+	c.mu.Unlock()
+
+	result, lagoErr := c.client.SubscriptionEntitlement().GetList(ctx, externalSubscriptionID)
+	if lagoErr != nil {
+		f.err = fmt.Errorf("entitlements: subscription %s: %w", externalSubscriptionID, lagoErr)
+	} else {
+		f.features = make(map[string]lago.SubscriptionEntitlement, len(result.Entitlements))
+		for _, entitlement := range result.Entitlements {
+			f.features[entitlement.Code] = entitlement
+		}
+	}
+
+	c.mu.Lock()
+	delete(c.inflight, externalSubscriptionID)
+	if e, ok := c.entries[externalSubscriptionID]; ok {
+		e.refreshing = false
+	}
+	if f.err == nil && version == c.version {
+		now := c.opts.Now()
+		c.evict(now)
+		c.entries[externalSubscriptionID] = &entry{features: f.features, fetchedAt: now}
+	}
+	c.mu.Unlock()
+	close(f.done)
+
+	return f.features, f.err
+}
+
+// evict drops the entries too old to be returned, even stale, so that the
+// cache does not keep every subscription ever checked. c.mu must be held.
+func (c *Checker) evict(now time.Time) {
+	for id, e := range c.entries {
+		if now.Sub(e.fetchedAt) >= c.opts.TTL+c.opts.StaleWhileRevalidate {
+			delete(c.entries, id)
+		}
+	}
+	for id, e := range c.customers {
+		if now.Sub(e.fetchedAt) >= c.opts.TTL {
+			delete(c.customers, id)
+		}
+	}
+}
+
+// CustomerSubscriptions returns the external IDs of the active
+// subscriptions of a customer, fetched once per TTL.
+func (c *Checker) CustomerSubscriptions(ctx context.Context, externalCustomerID string) ([]string, error) {
+	c.mu.Lock()
+	if e, ok := c.customers[externalCustomerID]; ok && c.opts.Now().Sub(e.fetchedAt) < c.opts.TTL {
+		c.mu.Unlock()
+		return e.subscriptions, nil
+	}
+	version := c.version
+	c.mu.Unlock()
+
+	listed, lagoErr := lago.FetchPages(0, func(page int) ([]lago.Subscription, lago.Metadata, *lago.Error) {
+		result, lagoErr := c.client.Subscription().GetList(ctx, lago.SubscriptionListInput{
+			ExternalCustomerID: externalCustomerID,
+			Status:             []lago.SubscriptionStatus{lago.SubscriptionStatusActive},
+			Page:               lago.Ptr(page),
+			PerPage:            lago.Ptr(100),
+		})
+		if lagoErr != nil {
+			return nil, lago.Metadata{}, lagoErr
+		}
+		return result.Subscriptions, result.Meta, nil
+	})
+	if lagoErr != nil {
+		return nil, fmt.Errorf("entitlements: subscriptions of customer %s: %w", externalCustomerID, lagoErr)
+	}
+	var subscriptions []string
+	for _, subscription := range listed {
+		subscriptions = append(subscriptions, subscription.ExternalID)
+	}
+
+	c.mu.Lock()
+	if version == c.version {
+		now := c.opts.Now()
+		c.evict(now)
+		c.customers[externalCustomerID] = &customerEntry{subscriptions: subscriptions, fetchedAt: now}
+	}
+	c.mu.Unlock()
+	return subscriptions, nil
+}
+
+// Has tells whether a subscription is entitled to a feature.
+func (c *Checker) Has(ctx context.Context, externalSubscriptionID, featureCode string) (bool, error) {
+	features, err := c.Entitlements(ctx, externalSubscriptionID)
+	if err != nil {
+		return false, err
+	}
+	_, ok := features[featureCode]
+	return ok, nil
+}
+
+// Privilege returns a privilege of a feature of a subscription, and whether
+// the subscription has it.
+func (c *Checker) Privilege(ctx context.Context, externalSubscriptionID, featureCode, privilegeCode string) (*lago.SubscriptionEntitlementPrivilege, bool, error) {
+	features, err := c.Entitlements(ctx, externalSubscriptionID)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, privilege := range features[featureCode].Privileges {
+		if privilege.Code == privilegeCode {
+			return &privilege, true, nil
+		}
+	}
+	return nil, false, nil
+}
+
+// IntPrivilege returns the value of an integer privilege.
+func (c *Checker) IntPrivilege(ctx context.Context, externalSubscriptionID, featureCode, privilegeCode string) (int64, bool, error) {
+	privilege, ok, err := c.typedPrivilege(ctx, externalSubscriptionID, featureCode, privilegeCode, lago.ValueTypeInteger)
+	if !ok || err != nil {
+		return 0, ok, err
+	}
+	value, err := IntValue(Value(privilege))
+	return value, err == nil, err
+}
+
+// BoolPrivilege returns the value of a boolean privilege.
+func (c *Checker) BoolPrivilege(ctx context.Context, externalSubscriptionID, featureCode, privilegeCode string) (bool, bool, error) {
+	privilege, ok, err := c.typedPrivilege(ctx, externalSubscriptionID, featureCode, privilegeCode, lago.ValueTypeBoolean)
+	if !ok || err != nil {
+		return false, ok, err
+	}
+	value, err := BoolValue(Value(privilege))
+	return value, err == nil, err
+}
+
+// SelectPrivilege returns the value of a select privilege, which is one of
+// its select options.
+func (c *Checker) SelectPrivilege(ctx context.Context, externalSubscriptionID, featureCode, privilegeCode string) (string, bool, error) {
+	privilege, ok, err := c.typedPrivilege(ctx, externalSubscriptionID, featureCode, privilegeCode, lago.ValueTypeSelect)
+	if !ok || err != nil {
+		return "", ok, err
+	}
+	value, err := StringValue(Value(privilege))
+	return value, err == nil, err
+}
+
+// StringPrivilege returns the value of a string privilege.
+func (c *Checker) StringPrivilege(ctx context.Context, externalSubscriptionID, featureCode, privilegeCode string) (string, bool, error) {
+	privilege, ok, err := c.typedPrivilege(ctx, externalSubscriptionID, featureCode, privilegeCode, lago.ValueTypeString)
+	if !ok || err != nil {
+		return "", ok, err
+	}
+	value, err := StringValue(Value(privilege))
+	return value, err == nil, err
+}
+
+func (c *Checker) typedPrivilege(ctx context.Context, externalSubscriptionID, featureCode, privilegeCode string, valueType lago.ValueType) (*lago.SubscriptionEntitlementPrivilege, bool, error) {
+	privilege, ok, err := c.Privilege(ctx, externalSubscriptionID, featureCode, privilegeCode)
+	if !ok || err != nil {
+		return nil, ok, err
+	}
+	if privilege.ValueType != valueType {
+		return nil, false, fmt.Errorf("%w: %s.%s is %s, not %s", ErrValueType, featureCode, privilegeCode, privilege.ValueType, valueType)
+	}
+	return privilege, true, nil
+}
+
+// Invalidate drops the cached entitlements of a subscription.
+func (c *Checker) Invalidate(externalSubscriptionID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	delete(c.entries, externalSubscriptionID)
+}
+
+// InvalidateCustomer drops the cached subscriptions of a customer.
+func (c *Checker) InvalidateCustomer(externalCustomerID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	delete(c.customers, externalCustomerID)
+}
+
+// InvalidateFeature drops the cached entitlements of every subscription
+// entitled to a feature.
+func (c *Checker) InvalidateFeature(featureCode string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	for id, e := range c.entries {
+		if _, ok := e.features[featureCode]; ok {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// InvalidateAll empties the cache.
+func (c *Checker) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.version++
+	c.entries = map[string]*entry{}
+	c.customers = map[string]*customerEntry{}
+}
+
+// HandleWebhook invalidates the entries a webhook, as parsed by
+// lago.ParseWebhook, makes outdated:
+//
+//   - feature.* webhooks invalidate the subscriptions entitled to the
+//     feature;
+//   - subscription.* webhooks, such as subscription.updated, invalidate the
+//     subscription and the subscriptions of its customer;
+//   - plan.* webhooks invalidate every subscription, as the plan of a cached
+//     subscription is not known.
+//
+// Other webhooks, and a nil message, are ignored.
+func (c *Checker) HandleWebhook(message *lago.WebhookMessage) {
+	if message == nil {
+		return
+	}
+	switch object := message.Object.(type) {
+	case *lago.Feature:
+		c.InvalidateFeature(object.Code)
+	case *lago.Subscription:
+		c.Invalidate(object.ExternalID)
+		c.InvalidateCustomer(object.ExternalCustomerID)
+	case *lago.Plan:
+		c.InvalidateAll()
+	}
+}
+
+// IntValue converts a privilege value decoded from JSON to an integer.
+func IntValue(value any) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v != float64(int64(v)) {
+			return 0, fmt.Errorf("%w: %v is not an integer", ErrValueType, v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return v.Int64()
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	}
+	return 0, fmt.Errorf("%w: %v is not an integer", ErrValueType, value)
+}
+
+// BoolValue converts a privilege value decoded from JSON to a boolean.
+func BoolValue(value any) (bool, error) {
+	switch v := value.(type) {
+	case bool:
+		return v, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(v))
+	}
+	return false, fmt.Errorf("%w: %v is not a boolean", ErrValueType, value)
+}
+
+// StringValue converts a privilege value decoded from JSON to a string.
+func StringValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", fmt.Errorf("%w: no value", ErrValueType)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// Value returns the value of a privilege for the subscription: its override
+// when the subscription has one, the value of the plan otherwise.
+func Value(privilege *lago.SubscriptionEntitlementPrivilege) any {
+	value, _ := valueSource(privilege)
+	return value
+}
+
+// valueSource returns the value of a privilege and where it comes from.
+func valueSource(privilege *lago.SubscriptionEntitlementPrivilege) (any, string) {
+	switch {
+	case privilege.OverrideValue != nil:
+		return privilege.OverrideValue, "override"
+	case privilege.PlanValue != nil:
+		return privilege.PlanValue, "plan"
+	}
+	return privilege.Value, "value"
+}
@@ -0,0 +1,228 @@
+package entitlements_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/entitlements"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+const entitlementsResponse = `{"entitlements": [
+	{"code": "seats", "privileges": [
+		{"code": "max", "value_type": "integer", "value": 10, "plan_value": 5, "override_value": 10},
+		{"code": "projects", "value_type": "integer", "value": 3, "plan_value": 3},
+		{"code": "sso", "value_type": "boolean", "value": true},
+		{"code": "tier", "value_type": "select", "value": "gold", "config": {"select_options": ["silver", "gold"]}},
+		{"code": "region", "value_type": "string", "value": "eu"}
+	]},
+	{"code": "feature_1", "privileges": []}
+]}`
+
+const sub1Entitlements = "GET /subscriptions/sub_1/entitlements"
+
+// server serves the entitlements of sub_1, counting the requests, and of
+// sub_0, both active subscriptions of cus_1.
+type server struct {
+	*lt.RoutesServer
+}
+
+func (s server) count() int {
+	count := 0
+	for _, request := range s.Requests() {
+		if request.Method+" "+request.Path == sub1Entitlements {
+			count++
+		}
+	}
+	return count
+}
+
+func (s server) set(response string) {
+	s.SetRoute(sub1Entitlements, response)
+}
+
+func newServer(c *qt.C) (server, *lago.Client) {
+	s := server{lt.NewRoutesServer(c, map[string]string{
+		sub1Entitlements:                                entitlementsResponse,
+		"GET /subscriptions/sub_0/entitlements":         `{"entitlements": []}`,
+		"GET /subscriptions?external_customer_id=cus_1": `{"subscriptions": [{"external_id": "sub_0"}, {"external_id": "sub_1"}], "meta": {"current_page": 1}}`,
+	})}
+	return s, s.Client()
+}
+
+// clock is a settable time source.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestChecker_Getters(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	s, client := newServer(c)
+	checker := entitlements.NewChecker(client, entitlements.Options{})
+
+	has, err := checker.Has(ctx, "sub_1", "seats")
+	c.Assert(err, qt.IsNil)
+	c.Assert(has, qt.IsTrue)
+
+	has, err = checker.Has(ctx, "sub_1", "analytics")
+	c.Assert(err, qt.IsNil)
+	c.Assert(has, qt.IsFalse)
+
+	max, ok, err := checker.IntPrivilege(ctx, "sub_1", "seats", "max")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsTrue)
+	c.Assert(max, qt.Equals, int64(10))
+
+	sso, ok, err := checker.BoolPrivilege(ctx, "sub_1", "seats", "sso")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok && sso, qt.IsTrue)
+
+	tier, _, err := checker.SelectPrivilege(ctx, "sub_1", "seats", "tier")
+	c.Assert(err, qt.IsNil)
+	c.Assert(tier, qt.Equals, "gold")
+
+	region, _, err := checker.StringPrivilege(ctx, "sub_1", "seats", "region")
+	c.Assert(err, qt.IsNil)
+	c.Assert(region, qt.Equals, "eu")
+
+	_, ok, err = checker.IntPrivilege(ctx, "sub_1", "seats", "missing")
+	c.Assert(err, qt.IsNil)
+	c.Assert(ok, qt.IsFalse)
+
+	_, _, err = checker.IntPrivilege(ctx, "sub_1", "seats", "sso")
+	c.Assert(errors.Is(err, entitlements.ErrValueType), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, `entitlements: privilege value type mismatch: seats.sso is boolean, not integer`)
+
+	// Every check was answered by a single request.
+	c.Assert(s.count(), qt.Equals, 1)
+
+	_, err = checker.Has(ctx, "sub_2", "seats")
+	c.Assert(err, qt.ErrorMatches, `entitlements: subscription sub_2: .*`)
+}
+
+func TestChecker_TTL(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	s, client := newServer(c)
+	now := &clock{now: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	checker := entitlements.NewChecker(client, entitlements.Options{TTL: time.Minute, Now: now.Now})
+
+	_, err := checker.Has(ctx, "sub_1", "seats")
+	c.Assert(err, qt.IsNil)
+
+	now.Advance(59 * time.Second)
+	_, err = checker.Has(ctx, "sub_1", "seats")
+	c.Assert(err, qt.IsNil)
+	c.Assert(s.count(), qt.Equals, 1)
+
+	// Without stale-while-revalidate, expired entries are fetched again
+	// before answering.
+	s.set(`{"entitlements": []}`)
+	now.Advance(time.Second)
+	has, err := checker.Has(ctx, "sub_1", "seats")
+	c.Assert(err, qt.IsNil)
+	c.Assert(has, qt.IsFalse)
+	c.Assert(s.count(), qt.Equals, 2)
+}
+
+func TestChecker_StaleWhileRevalidate(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	s, client := newServer(c)
+	now := &clock{now: time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)}
+	checker := entitlements.NewChecker(client, entitlements.Options{
+		TTL:                  time.Minute,
+		StaleWhileRevalidate: time.Hour,
+		Now:                  now.Now,
+	})
+
+	_, err := checker.Has(ctx, "sub_1", "seats")
+	c.Assert(err, qt.IsNil)
+
+	// The stale entry answers while it is refreshed in the background.
+	s.set(`{"entitlements": []}`)
+	now.Advance(2 * time.Minute)
+	has, err := checker.Has(ctx, "sub_1", "seats")
+	c.Assert(err, qt.IsNil)
+	c.Assert(has, qt.IsTrue)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		has, err = checker.Has(ctx, "sub_1", "seats")
+		c.Assert(err, qt.IsNil)
+		if !has || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	c.Assert(has, qt.IsFalse)
+	c.Assert(s.count(), qt.Equals, 2)
+
+	// Past the stale window, the entry is fetched before answering.
+	s.set(entitlementsResponse)
+	now.Advance(2 * time.Hour)
+	has, err = checker.Has(ctx, "sub_1", "seats")
+	c.Assert(err, qt.IsNil)
+	c.Assert(has, qt.IsTrue)
+	c.Assert(s.count(), qt.Equals, 3)
+}
+
+func TestChecker_HandleWebhook(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	s, client := newServer(c)
+	checker := entitlements.NewChecker(client, entitlements.Options{TTL: time.Hour})
+
+	check := func() {
+		_, err := checker.Has(ctx, "sub_1", "seats")
+		c.Assert(err, qt.IsNil)
+	}
+	check()
+
+	// feature_1 is one of the features of sub_1.
+	data, err := os.ReadFile("../testing/fixtures/webhooks/feature_updated.json")
+	c.Assert(err, qt.IsNil)
+	message, err := lago.ParseWebhook(data)
+	c.Assert(err, qt.IsNil)
+	checker.HandleWebhook(message)
+	check()
+	c.Assert(s.count(), qt.Equals, 2)
+
+	// Other subscriptions are left cached.
+	checker.HandleWebhook(&lago.WebhookMessage{WebhookType: "subscription.updated", Object: &lago.Subscription{ExternalID: "sub_2"}})
+	check()
+	c.Assert(s.count(), qt.Equals, 2)
+
+	checker.HandleWebhook(&lago.WebhookMessage{WebhookType: "subscription.updated", Object: &lago.Subscription{ExternalID: "sub_1"}})
+	check()
+	c.Assert(s.count(), qt.Equals, 3)
+
+	checker.HandleWebhook(nil)
+	check()
+	c.Assert(s.count(), qt.Equals, 3)
+}
@@ -0,0 +1,44 @@
+package entitlements
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+func TestChecker_EvictsExpiredEntries(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	client := lt.NewRoutesServer(c, map[string]string{
+		"GET /subscriptions/sub_1/entitlements": `{"entitlements": []}`,
+		"GET /subscriptions/sub_2/entitlements": `{"entitlements": []}`,
+		"GET /subscriptions/sub_3/entitlements": `{"entitlements": []}`,
+	}).Client()
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	checker := NewChecker(client, Options{
+		TTL:                  time.Minute,
+		StaleWhileRevalidate: time.Hour,
+		Now:                  func() time.Time { return now },
+	})
+
+	_, err := checker.Entitlements(ctx, "sub_1")
+	c.Assert(err, qt.IsNil)
+	now = now.Add(30 * time.Minute)
+	_, err = checker.Entitlements(ctx, "sub_2")
+	c.Assert(err, qt.IsNil)
+
+	// sub_1 is past its stale window when sub_3 is cached, sub_2 is not.
+	now = now.Add(31 * time.Minute)
+	_, err = checker.Entitlements(ctx, "sub_3")
+	c.Assert(err, qt.IsNil)
+
+	checker.mu.Lock()
+	defer checker.mu.Unlock()
+	c.Assert(checker.entries, qt.HasLen, 2)
+	c.Assert(checker.entries["sub_1"], qt.IsNil)
+}
@@ -0,0 +1,333 @@
+package entitlements
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Evaluation context keys read by Provider. TargetingKey is the standard
+// OpenFeature targeting key and holds an external subscription ID.
+const (
+	TargetingKey              = "targetingKey"
+	ExternalSubscriptionIDKey = "external_subscription_id"
+	ExternalCustomerIDKey     = "external_customer_id"
+)
+
+const (
+	providerName       = "lago-entitlements"
+	variantEntitled    = "entitled"
+	variantNotEntitled = "not_entitled"
+)
+
+// Reason explains how a flag value was resolved, with the values of the
+// OpenFeature specification.
+type Reason string
+
+const (
+	TargetingMatchReason Reason = "TARGETING_MATCH"
+	DefaultReason        Reason = "DEFAULT"
+	ErrorReason          Reason = "ERROR"
+)
+
+// ErrorCode is an OpenFeature resolution error code.
+type ErrorCode string
+
+const (
+	FlagNotFoundCode        ErrorCode = "FLAG_NOT_FOUND"
+	ParseErrorCode          ErrorCode = "PARSE_ERROR"
+	TypeMismatchCode        ErrorCode = "TYPE_MISMATCH"
+	TargetingKeyMissingCode ErrorCode = "TARGETING_KEY_MISSING"
+	GeneralCode             ErrorCode = "GENERAL"
+)
+
+// ResolutionError is the error of a flag that could not be resolved.
+type ResolutionError struct {
+	Code    ErrorCode
+	Message string
+}
+
+func (e *ResolutionError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// FlattenedContext is an evaluation context, as passed to OpenFeature
+// providers.
+type FlattenedContext map[string]any
+
+// ProviderMetadata describes a provider.
+type ProviderMetadata struct {
+	Name string
+}
+
+// ResolutionDetail carries the reason, variant and debugging metadata of a
+// resolved flag. Its metadata holds the subscription, feature, privilege,
+// value type and plan and override values the flag was resolved from.
+type ResolutionDetail struct {
+	Reason          Reason
+	Variant         string
+	ResolutionError *ResolutionError
+	FlagMetadata    map[string]any
+}
+
+type BoolResolutionDetail struct {
+	Value bool
+	ResolutionDetail
+}
+
+type StringResolutionDetail struct {
+	Value string
+	ResolutionDetail
+}
+
+type IntResolutionDetail struct {
+	Value int64
+	ResolutionDetail
+}
+
+type FloatResolutionDetail struct {
+	Value float64
+	ResolutionDetail
+}
+
+type InterfaceResolutionDetail struct {
+	Value any
+	ResolutionDetail
+}
+
+// Provider resolves feature flags from the entitlements of subscriptions,
+// with the methods of an OpenFeature provider. It has no dependency on the
+// OpenFeature SDK: a thin wrapper converting the result types adapts it.
+//
+// A flag key is either a feature code, or a feature code and a privilege
+// code joined by a dot:
+//
+//   - "sso" resolves as a boolean telling whether the subscription is
+//     entitled to the feature, and as an object mapping its privilege codes
+//     to their values;
+//   - "seats.max" resolves as the value of the privilege, the override of
+//     the subscription taking precedence over the value of the plan.
+//
+// The evaluation context is keyed on an external subscription ID, under
+// TargetingKey or ExternalSubscriptionIDKey, or on an external customer ID
+// under ExternalCustomerIDKey, in which case the first active subscription
+// of the customer entitled to the feature is used.
+type Provider struct {
+	checker *Checker
+}
+
+// NewProvider returns a Provider resolving flags with checker.
+func NewProvider(checker *Checker) *Provider {
+	return &Provider{checker: checker}
+}
+
+func (p *Provider) Metadata() ProviderMetadata {
+	return ProviderMetadata{Name: providerName}
+}
+
+// resolution is the entitlement a flag refers to.
+type resolution struct {
+	subscriptionID string
+	featureCode    string
+	privilegeCode  string
+	feature        *lago.SubscriptionEntitlement
+	privilege      *lago.SubscriptionEntitlementPrivilege
+}
+
+func (r *resolution) metadata() map[string]any {
+	metadata := map[string]any{"feature_code": r.featureCode}
+	if r.subscriptionID != "" {
+		metadata["subscription_id"] = r.subscriptionID
+	}
+	if r.privilegeCode != "" {
+		metadata["privilege_code"] = r.privilegeCode
+	}
+	if r.privilege != nil {
+		metadata["value_type"] = string(r.privilege.ValueType)
+		if r.privilege.PlanValue != nil {
+			metadata["plan_value"] = fmt.Sprint(r.privilege.PlanValue)
+		}
+		if r.privilege.OverrideValue != nil {
+			metadata["override_value"] = fmt.Sprint(r.privilege.OverrideValue)
+		}
+	}
+	return metadata
+}
+
+func (p *Provider) resolve(ctx context.Context, flag string, evalCtx FlattenedContext) (*resolution, *ResolutionError) {
+	r := &resolution{}
+	r.featureCode, r.privilegeCode, _ = strings.Cut(flag, ".")
+	if r.featureCode == "" {
+		return r, &ResolutionError{Code: FlagNotFoundCode, Message: fmt.Sprintf("invalid flag key %q", flag)}
+	}
+
+	var subscriptions []string
+	if id := contextString(evalCtx, TargetingKey, ExternalSubscriptionIDKey); id != "" {
+		subscriptions = []string{id}
+	} else if customer := contextString(evalCtx, ExternalCustomerIDKey); customer != "" {
+		var err error
+		if subscriptions, err = p.checker.CustomerSubscriptions(ctx, customer); err != nil {
+			return r, &ResolutionError{Code: GeneralCode, Message: err.Error()}
+		}
+	} else {
+		return r, &ResolutionError{Code: TargetingKeyMissingCode, Message: "no subscription or customer in the evaluation context"}
+	}
+
+	for _, id := range subscriptions {
+		features, err := p.checker.Entitlements(ctx, id)
+		if err != nil {
+			return r, &ResolutionError{Code: GeneralCode, Message: err.Error()}
+		}
+		if feature, ok := features[r.featureCode]; ok {
+			r.subscriptionID, r.feature = id, &feature
+			break
+		}
+	}
+	if r.feature == nil {
+		if len(subscriptions) > 0 {
+			r.subscriptionID = subscriptions[0]
+		}
+		return r, nil
+	}
+
+	if r.privilegeCode != "" {
+		for _, privilege := range r.feature.Privileges {
+			if privilege.Code == r.privilegeCode {
+				r.privilege = &privilege
+				break
+			}
+		}
+		if r.privilege == nil {
+			return r, &ResolutionError{Code: FlagNotFoundCode, Message: fmt.Sprintf("feature %s has no privilege %s", r.featureCode, r.privilegeCode)}
+		}
+	}
+	return r, nil
+}
+
+func contextString(evalCtx FlattenedContext, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := evalCtx[key].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// evaluatePrivilege resolves a privilege flag whose value type is one of
+// valueTypes, converting its value. It returns false when the default value
+// must be used.
+func (p *Provider) evaluatePrivilege(ctx context.Context, flag string, evalCtx FlattenedContext, convert func(any) (any, error), valueTypes ...lago.ValueType) (any, ResolutionDetail, bool) {
+	r, resolutionErr := p.resolve(ctx, flag, evalCtx)
+	detail := ResolutionDetail{FlagMetadata: r.metadata()}
+	switch {
+	case resolutionErr != nil:
+	case r.privilegeCode == "":
+		resolutionErr = &ResolutionError{Code: TypeMismatchCode, Message: fmt.Sprintf("flag %s is a feature, not a privilege", flag)}
+	case r.feature == nil:
+		detail.Reason = DefaultReason
+		return nil, detail, false
+	case !slices.Contains(valueTypes, r.privilege.ValueType):
+		resolutionErr = &ResolutionError{Code: TypeMismatchCode, Message: fmt.Sprintf("privilege %s is %s", flag, r.privilege.ValueType)}
+	}
+	if resolutionErr != nil {
+		detail.Reason, detail.ResolutionError = ErrorReason, resolutionErr
+		return nil, detail, false
+	}
+
+	raw, source := valueSource(r.privilege)
+	value, err := convert(raw)
+	if err != nil {
+		detail.Reason, detail.ResolutionError = ErrorReason, &ResolutionError{Code: ParseErrorCode, Message: err.Error()}
+		return nil, detail, false
+	}
+	detail.Reason, detail.Variant = TargetingMatchReason, source
+	return value, detail, true
+}
+
+// BooleanEvaluation resolves a feature flag as whether the subscription is
+// entitled to the feature, or a boolean privilege flag as its value.
+func (p *Provider) BooleanEvaluation(ctx context.Context, flag string, defaultValue bool, evalCtx FlattenedContext) BoolResolutionDetail {
+	if !strings.Contains(flag, ".") {
+		r, resolutionErr := p.resolve(ctx, flag, evalCtx)
+		detail := ResolutionDetail{FlagMetadata: r.metadata()}
+		if resolutionErr != nil {
+			detail.Reason, detail.ResolutionError = ErrorReason, resolutionErr
+			return BoolResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+		}
+		detail.Reason, detail.Variant = TargetingMatchReason, variantNotEntitled
+		if r.feature != nil {
+			detail.Variant = variantEntitled
+		}
+		return BoolResolutionDetail{Value: r.feature != nil, ResolutionDetail: detail}
+	}
+
+	value, detail, ok := p.evaluatePrivilege(ctx, flag, evalCtx, func(v any) (any, error) { return BoolValue(v) }, lago.ValueTypeBoolean)
+	if !ok {
+		return BoolResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+	}
+	return BoolResolutionDetail{Value: value.(bool), ResolutionDetail: detail}
+}
+
+// StringEvaluation resolves a string or select privilege flag.
+func (p *Provider) StringEvaluation(ctx context.Context, flag string, defaultValue string, evalCtx FlattenedContext) StringResolutionDetail {
+	value, detail, ok := p.evaluatePrivilege(ctx, flag, evalCtx, func(v any) (any, error) { return StringValue(v) }, lago.ValueTypeString, lago.ValueTypeSelect)
+	if !ok {
+		return StringResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+	}
+	return StringResolutionDetail{Value: value.(string), ResolutionDetail: detail}
+}
+
+// IntEvaluation resolves an integer privilege flag.
+func (p *Provider) IntEvaluation(ctx context.Context, flag string, defaultValue int64, evalCtx FlattenedContext) IntResolutionDetail {
+	value, detail, ok := p.evaluatePrivilege(ctx, flag, evalCtx, func(v any) (any, error) { return IntValue(v) }, lago.ValueTypeInteger)
+	if !ok {
+		return IntResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+	}
+	return IntResolutionDetail{Value: value.(int64), ResolutionDetail: detail}
+}
+
+// FloatEvaluation resolves an integer privilege flag as a float.
+func (p *Provider) FloatEvaluation(ctx context.Context, flag string, defaultValue float64, evalCtx FlattenedContext) FloatResolutionDetail {
+	value, detail, ok := p.evaluatePrivilege(ctx, flag, evalCtx, func(v any) (any, error) {
+		i, err := IntValue(v)
+		return float64(i), err
+	}, lago.ValueTypeInteger)
+	if !ok {
+		return FloatResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+	}
+	return FloatResolutionDetail{Value: value.(float64), ResolutionDetail: detail}
+}
+
+// ObjectEvaluation resolves a feature flag as a map of its privilege codes
+// to their values, or a privilege flag as its value whatever its type.
+func (p *Provider) ObjectEvaluation(ctx context.Context, flag string, defaultValue any, evalCtx FlattenedContext) InterfaceResolutionDetail {
+	if strings.Contains(flag, ".") {
+		value, detail, ok := p.evaluatePrivilege(ctx, flag, evalCtx, func(v any) (any, error) { return v, nil },
+			lago.ValueTypeBoolean, lago.ValueTypeInteger, lago.ValueTypeSelect, lago.ValueTypeString)
+		if !ok {
+			return InterfaceResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+		}
+		return InterfaceResolutionDetail{Value: value, ResolutionDetail: detail}
+	}
+
+	r, resolutionErr := p.resolve(ctx, flag, evalCtx)
+	detail := ResolutionDetail{FlagMetadata: r.metadata()}
+	switch {
+	case resolutionErr != nil:
+		detail.Reason, detail.ResolutionError = ErrorReason, resolutionErr
+		return InterfaceResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+	case r.feature == nil:
+		detail.Reason = DefaultReason
+		return InterfaceResolutionDetail{Value: defaultValue, ResolutionDetail: detail}
+	}
+
+	privileges := make(map[string]any, len(r.feature.Privileges))
+	for _, privilege := range r.feature.Privileges {
+		privileges[privilege.Code] = Value(&privilege)
+	}
+	detail.Reason, detail.Variant = TargetingMatchReason, variantEntitled
+	return InterfaceResolutionDetail{Value: privileges, ResolutionDetail: detail}
+}
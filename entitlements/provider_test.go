@@ -0,0 +1,120 @@
+package entitlements_test
+
+import (
+	"context"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/getlago/lago-go-client/entitlements"
+)
+
+func TestProvider(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	_, client := newServer(c)
+	provider := entitlements.NewProvider(entitlements.NewChecker(client, entitlements.Options{}))
+	subscription := entitlements.FlattenedContext{entitlements.TargetingKey: "sub_1"}
+
+	c.Assert(provider.Metadata().Name, qt.Equals, "lago-entitlements")
+
+	seats := provider.BooleanEvaluation(ctx, "seats", false, subscription)
+	c.Assert(seats.Value, qt.IsTrue)
+	c.Assert(seats.Reason, qt.Equals, entitlements.TargetingMatchReason)
+	c.Assert(seats.Variant, qt.Equals, "entitled")
+
+	analytics := provider.BooleanEvaluation(ctx, "analytics", true, subscription)
+	c.Assert(analytics.Value, qt.IsFalse)
+	c.Assert(analytics.Variant, qt.Equals, "not_entitled")
+
+	// The override takes precedence over the plan value.
+	max := provider.IntEvaluation(ctx, "seats.max", 0, subscription)
+	c.Assert(max.Value, qt.Equals, int64(10))
+	c.Assert(max.Variant, qt.Equals, "override")
+	c.Assert(max.FlagMetadata, qt.DeepEquals, map[string]any{
+		"subscription_id": "sub_1",
+		"feature_code":    "seats",
+		"privilege_code":  "max",
+		"value_type":      "integer",
+		"plan_value":      "5",
+		"override_value":  "10",
+	})
+
+	projects := provider.FloatEvaluation(ctx, "seats.projects", 0, subscription)
+	c.Assert(projects.Value, qt.Equals, 3.0)
+	c.Assert(projects.Variant, qt.Equals, "plan")
+
+	tier := provider.StringEvaluation(ctx, "seats.tier", "silver", subscription)
+	c.Assert(tier.Value, qt.Equals, "gold")
+
+	sso := provider.BooleanEvaluation(ctx, "seats.sso", false, subscription)
+	c.Assert(sso.Value, qt.IsTrue)
+
+	object := provider.ObjectEvaluation(ctx, "seats", nil, subscription)
+	c.Assert(object.Value, qt.DeepEquals, map[string]any{
+		"max": 10.0, "projects": 3.0, "sso": true, "tier": "gold", "region": "eu",
+	})
+
+	// Privileges of features the subscription is not entitled to resolve
+	// to the default value.
+	limit := provider.IntEvaluation(ctx, "analytics.retention", 7, subscription)
+	c.Assert(limit.Value, qt.Equals, int64(7))
+	c.Assert(limit.Reason, qt.Equals, entitlements.DefaultReason)
+	c.Assert(limit.ResolutionError == nil, qt.IsTrue)
+}
+
+func TestProvider_Customer(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	_, client := newServer(c)
+	provider := entitlements.NewProvider(entitlements.NewChecker(client, entitlements.Options{}))
+
+	// sub_0 comes first but only sub_1 is entitled to seats.
+	max := provider.IntEvaluation(ctx, "seats.max", 0, entitlements.FlattenedContext{entitlements.ExternalCustomerIDKey: "cus_1"})
+	c.Assert(max.Value, qt.Equals, int64(10))
+	c.Assert(max.FlagMetadata["subscription_id"], qt.Equals, "sub_1")
+}
+
+func TestProvider_Errors(t *testing.T) {
+	c := qt.New(t)
+	ctx := context.Background()
+
+	_, client := newServer(c)
+	provider := entitlements.NewProvider(entitlements.NewChecker(client, entitlements.Options{}))
+	subscription := entitlements.FlattenedContext{entitlements.ExternalSubscriptionIDKey: "sub_1"}
+
+	tests := []struct {
+		about  string
+		result entitlements.ResolutionDetail
+		code   entitlements.ErrorCode
+	}{{
+		about:  "no targeting key",
+		result: provider.BooleanEvaluation(ctx, "seats", false, nil).ResolutionDetail,
+		code:   entitlements.TargetingKeyMissingCode,
+	}, {
+		about:  "unknown privilege",
+		result: provider.IntEvaluation(ctx, "seats.unknown", 0, subscription).ResolutionDetail,
+		code:   entitlements.FlagNotFoundCode,
+	}, {
+		about:  "wrong type",
+		result: provider.StringEvaluation(ctx, "seats.max", "", subscription).ResolutionDetail,
+		code:   entitlements.TypeMismatchCode,
+	}, {
+		about:  "feature as a string",
+		result: provider.StringEvaluation(ctx, "seats", "", subscription).ResolutionDetail,
+		code:   entitlements.TypeMismatchCode,
+	}, {
+		about:  "API error",
+		result: provider.BooleanEvaluation(ctx, "seats", false, entitlements.FlattenedContext{entitlements.TargetingKey: "sub_2"}).ResolutionDetail,
+		code:   entitlements.GeneralCode,
+	}}
+	for _, test := range tests {
+		c.Run(test.about, func(c *qt.C) {
+			c.Assert(test.result.Reason, qt.Equals, entitlements.ErrorReason)
+			c.Assert(test.result.ResolutionError, qt.Not(qt.IsNil))
+			c.Assert(test.result.ResolutionError.Code, qt.Equals, test.code)
+		})
+	}
+}
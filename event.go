@@ -68,6 +68,16 @@ type EventsErrors struct {
 	InvalidFilterValues          []string `json:"invalid_filter_values"`
 }
 
+// PreciseTotalAmountCentsDecimal parses PreciseTotalAmountCents as an exact Decimal.
+func (ei *EventInput) PreciseTotalAmountCentsDecimal() (Decimal, error) {
+	return parseOptionalDecimal(ei.PreciseTotalAmountCents)
+}
+
+// SetPreciseTotalAmountCents sets PreciseTotalAmountCents from an exact Decimal.
+func (ei *EventInput) SetPreciseTotalAmountCents(amount Decimal) {
+	ei.PreciseTotalAmountCents = amount.String()
+}
+
 func (c *Client) Event() *EventRequest {
 	return &EventRequest{
 		client: c,
@@ -0,0 +1,358 @@
+// Package exporter serves Lago billing analytics and wallet balances as
+// Prometheus metrics, in the text exposition format, without depending on
+// the Prometheus client library.
+//
+// Amounts are exported in major units of their currency, and every metric
+// is labelled with its currency and billing entity.
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/analytics"
+)
+
+// DefaultTTL is the time a collection is served before Lago is polled again.
+const DefaultTTL = 5 * time.Minute
+
+// ContentType is the content type of the text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+const perPage = 100
+
+// Options configure an Exporter.
+type Options struct {
+	// TTL is the time a collection is served before the next scrape polls
+	// Lago again. Zero means DefaultTTL.
+	TTL time.Duration
+	// BillingEntities are the codes of the billing entities to export.
+	// Empty exports all the billing entities of the organization.
+	BillingEntities []string
+	// Now returns the current time. It defaults to time.Now.
+	Now func() time.Time
+}
+
+// Exporter is an http.Handler serving Lago metrics.
+//
+// Scrapes are answered from the last collection. Once it is older than the
+// TTL, a scrape starts a single refresh in the background and is answered
+// from the last collection meanwhile; only the first scrape waits for Lago.
+// When Lago cannot be polled, the last successful collection keeps being
+// served with lago_up set to 0.
+type Exporter struct {
+	client    *lago.Client
+	analytics *analytics.Analytics
+	opts      Options
+
+	mu          sync.Mutex
+	body        []byte
+	attemptedAt time.Time
+	succeededAt time.Time
+	duration    time.Duration
+	err         error
+	// refreshing is closed when the refresh started by a scrape ends; it is
+	// nil when none is running.
+	refreshing chan struct{}
+}
+
+// New returns an Exporter polling Lago with client.
+func New(client *lago.Client, opts Options) *Exporter {
+	if opts.TTL == 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	a := analytics.New(client)
+	a.Now = opts.Now
+	return &Exporter{client: client, analytics: a, opts: opts}
+}
+
+// Collect polls Lago and replaces the metrics served on success. Lago is
+// polled without holding up the scrapes, which keep being answered from the
+// previous collection.
+func (e *Exporter) Collect(ctx context.Context) error {
+	start := e.opts.Now()
+	e.mu.Lock()
+	e.attemptedAt = start
+	e.mu.Unlock()
+
+	body, err := e.build(ctx)
+	duration := e.opts.Now().Sub(start)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.duration = duration
+	e.err = err
+	// A slower collection started earlier does not override a newer one.
+	if err == nil && !start.Before(e.succeededAt) {
+		e.body = body
+		e.succeededAt = start
+	}
+	return err
+}
+
+// build polls Lago and returns the metrics in the text exposition format.
+func (e *Exporter) build(ctx context.Context) ([]byte, error) {
+	m := &metrics{}
+	if err := e.poll(ctx, m); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	if err := m.write(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Run collects the metrics every TTL until ctx is done, so that scrapes are
+// never kept waiting for Lago. Failed collections are reported by lago_up.
+func (e *Exporter) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.opts.TTL)
+	defer ticker.Stop()
+	for {
+		e.Collect(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// ServeHTTP writes the last collected metrics, starting a refresh when the
+// last collection attempt is older than the TTL. Until a collection ends,
+// it waits for the first one.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	if e.refreshing == nil && (e.attemptedAt.IsZero() || e.opts.Now().Sub(e.attemptedAt) >= e.opts.TTL) {
+		e.refresh(context.WithoutCancel(r.Context()))
+	}
+	refreshing := e.refreshing
+	collected := e.body != nil || e.err != nil
+	e.mu.Unlock()
+
+	if !collected && refreshing != nil {
+		select {
+		case <-refreshing:
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	status := &metrics{}
+	up := "1"
+	if e.err != nil {
+		up = "0"
+	}
+	status.family("lago_up", "Whether the last collection from Lago succeeded.").add(up)
+	status.family("lago_collection_duration_seconds", "Duration of the last collection from Lago.").
+		add(formatFloat(e.duration.Seconds()))
+	if !e.succeededAt.IsZero() {
+		status.family("lago_last_success_timestamp_seconds", "Time of the last successful collection from Lago.").
+			add(formatFloat(float64(e.succeededAt.UnixNano()) / 1e9))
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Write(e.body)
+	status.write(w)
+}
+
+// refresh collects the metrics in the background. It must be called with
+// e.mu held and no refresh running.
+func (e *Exporter) refresh(ctx context.Context) {
+	done := make(chan struct{})
+	e.refreshing = done
+	go func() {
+		e.Collect(ctx)
+
+		e.mu.Lock()
+		e.refreshing = nil
+		e.mu.Unlock()
+		close(done)
+	}()
+}
+
+// poll adds the metrics of every billing entity to m.
+func (e *Exporter) poll(ctx context.Context, m *metrics) error {
+	entities := e.opts.BillingEntities
+	if len(entities) == 0 {
+		result, lagoErr := e.client.BillingEntity().GetList(ctx)
+		if lagoErr != nil {
+			return fmt.Errorf("exporter: billing entities: %w", lagoErr)
+		}
+		for _, entity := range result.BillingEntities {
+			entities = append(entities, entity.Code)
+		}
+	}
+
+	for _, entity := range entities {
+		if err := e.pollAnalytics(ctx, m, entity); err != nil {
+			return err
+		}
+	}
+	return e.pollWallets(ctx, m, entities)
+}
+
+func (e *Exporter) pollAnalytics(ctx context.Context, m *metrics, entity string) error {
+	current := analytics.MonthOf(e.opts.Now())
+	month := analytics.Query{From: current.Start(), BillingEntityCode: entity}
+
+	mrr, err := e.analytics.Mrr(ctx, month)
+	if err != nil {
+		return err
+	}
+	addSeries(m.family("lago_mrr", "Monthly recurring revenue of the current month."), mrr, current, entity)
+
+	revenue, err := e.analytics.GrossRevenue(ctx, month)
+	if err != nil {
+		return err
+	}
+	addSeries(m.family("lago_gross_revenue", "Gross revenue invoiced in the current month."), revenue, current, entity)
+	invoices := m.family("lago_gross_revenue_invoices", "Number of invoices issued in the current month.")
+	for _, key := range revenue.Keys() {
+		invoices.add(strconv.Itoa(revenue.Values[key].Count), "currency", string(key.Currency), "billing_entity", entity)
+	}
+
+	// Overdue amounts are reported by month of issue: the balance is their
+	// sum over all the months returned by Lago.
+	overdue, err := e.analytics.OverdueBalance(ctx, analytics.Query{BillingEntityCode: entity})
+	if err != nil {
+		return err
+	}
+	totals := map[lago.Currency]int64{}
+	for key, value := range overdue.Values {
+		totals[key.Currency] += value.AmountCents
+	}
+	overdueBalance := m.family("lago_overdue_balance", "Amount of the invoices past their due date.")
+	for currency, cents := range totals {
+		overdueBalance.add(lago.NewMoney(cents, currency).Major(), "currency", string(currency), "billing_entity", entity)
+	}
+
+	collection, err := e.analytics.InvoiceCollection(ctx, month)
+	if err != nil {
+		return err
+	}
+	amount := m.family("lago_invoice_collection_amount", "Amount invoiced in the current month by payment status.")
+	count := m.family("lago_invoice_collection_invoices", "Number of invoices issued in the current month by payment status.")
+	invoiced := map[lago.Currency]int64{}
+	collected := map[lago.Currency]int64{}
+	for status, series := range collection {
+		for _, key := range series.Keys() {
+			if key.Month != current {
+				continue
+			}
+			value := series.Values[key]
+			labels := []string{"currency", string(key.Currency), "billing_entity", entity, "payment_status", string(status)}
+			amount.add(lago.NewMoney(value.AmountCents, key.Currency).Major(), labels...)
+			count.add(strconv.Itoa(value.Count), labels...)
+			invoiced[key.Currency] += value.AmountCents
+			if status == lago.InvoicePaymentStatusSucceeded {
+				collected[key.Currency] += value.AmountCents
+			}
+		}
+	}
+	rate := m.family("lago_invoice_collection_rate", "Share of the amount invoiced in the current month that is paid.")
+	for currency, total := range invoiced {
+		if total == 0 {
+			continue
+		}
+		rate.add(formatFloat(float64(collected[currency])/float64(total)), "currency", string(currency), "billing_entity", entity)
+	}
+
+	usage, err := e.analytics.InvoicedUsage(ctx, month)
+	if err != nil {
+		return err
+	}
+	usageFamily := m.family("lago_invoiced_usage", "Usage invoiced in the current month by billable metric code.")
+	for code, series := range usage {
+		addSeries(usageFamily, series, current, entity, "code", code)
+	}
+	return nil
+}
+
+// addSeries adds the amounts of a series for month to f.
+func addSeries(f *family, series *analytics.MonthlySeries, month analytics.Month, entity string, labels ...string) {
+	for _, key := range series.Keys() {
+		if key.Month != month {
+			continue
+		}
+		f.add(series.Money(key.Month, key.Currency).Major(),
+			append([]string{"currency", string(key.Currency), "billing_entity", entity}, labels...)...)
+	}
+}
+
+type walletKey struct {
+	currency lago.Currency
+	entity   string
+}
+
+type walletTotals struct {
+	count          int
+	balance        int64
+	ongoingBalance int64
+}
+
+// pollWallets adds the balances of the active wallets of entities to m.
+func (e *Exporter) pollWallets(ctx context.Context, m *metrics, entities []string) error {
+	included := map[string]bool{}
+	for _, entity := range entities {
+		included[entity] = true
+	}
+
+	listed, lagoErr := lago.FetchPages(0, func(page int) ([]lago.Wallet, lago.Metadata, *lago.Error) {
+		result, lagoErr := e.client.Wallet().GetList(ctx, &lago.WalletListInput{
+			Page:    lago.Ptr(page),
+			PerPage: lago.Ptr(perPage),
+		})
+		if lagoErr != nil {
+			return nil, lago.Metadata{}, lagoErr
+		}
+		return result.Wallets, result.Meta, nil
+	})
+	if lagoErr != nil {
+		return fmt.Errorf("exporter: wallets: %w", lagoErr)
+	}
+
+	totals := map[walletKey]*walletTotals{}
+	for _, wallet := range listed {
+		if wallet.Status != lago.Active || !included[wallet.BillingEntityCode] {
+			continue
+		}
+		key := walletKey{currency: wallet.Currency, entity: wallet.BillingEntityCode}
+		t, ok := totals[key]
+		if !ok {
+			t = &walletTotals{}
+			totals[key] = t
+		}
+		t.count++
+		t.balance += int64(wallet.BalanceCents)
+		t.ongoingBalance += int64(wallet.OngoingBalanceCents)
+	}
+
+	wallets := m.family("lago_wallets", "Number of active customer wallets.")
+	balance := m.family("lago_wallet_balance", "Balance of the active customer wallets.")
+	ongoing := m.family("lago_wallet_ongoing_balance", "Balance of the active customer wallets after the current usage.")
+	for key, t := range totals {
+		labels := []string{"currency", string(key.currency), "billing_entity", key.entity}
+		wallets.add(strconv.Itoa(t.count), labels...)
+		balance.add(lago.NewMoney(t.balance, key.currency).Major(), labels...)
+		ongoing.add(lago.NewMoney(t.ongoingBalance, key.currency).Major(), labels...)
+	}
+	return nil
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
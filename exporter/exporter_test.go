@@ -0,0 +1,179 @@
+package exporter_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	"github.com/getlago/lago-go-client/exporter"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+var responses = map[string]string{
+	"/billing_entities": `{"billing_entities": [{"code": "acme"}]}`,
+	"/analytics/mrr?billing_entity_code=acme": `{"mrrs": [
+		{"month": "2026-02-01T00:00:00.000Z", "amount_cents": 9000, "currency": "EUR"},
+		{"month": "2026-03-01T00:00:00.000Z", "amount_cents": 10050, "currency": "EUR"}
+	]}`,
+	"/analytics/gross_revenue?billing_entity_code=acme": `{"gross_revenues": [
+		{"month": "2026-03-01T00:00:00.000Z", "amount_cents": 20000, "currency": "EUR", "invoices_count": 4}
+	]}`,
+	"/analytics/overdue_balance?billing_entity_code=acme": `{"overdue_balances": [
+		{"month": "2026-01-01T00:00:00.000Z", "amount_cents": 1000, "currency": "EUR"},
+		{"month": "2026-02-01T00:00:00.000Z", "amount_cents": 500, "currency": "EUR"}
+	]}`,
+	"/analytics/invoice_collection?billing_entity_code=acme": `{"invoice_collections": [
+		{"month": "2026-03-01T00:00:00.000Z", "payment_status": "succeeded", "invoices_count": 3, "amount_cents": 15000, "currency": "EUR"},
+		{"month": "2026-03-01T00:00:00.000Z", "payment_status": "pending", "invoices_count": 1, "amount_cents": 5000, "currency": "EUR"}
+	]}`,
+	"/analytics/invoiced_usage?billing_entity_code=acme": `{"invoiced_usages": [
+		{"month": "2026-03-01T00:00:00.000Z", "code": "api_calls", "amount_cents": 700, "currency": "EUR"}
+	]}`,
+	"/wallets": `{"wallets": [
+		{"status": "active", "currency": "EUR", "billing_entity_code": "acme", "balance_cents": 1000, "ongoing_balance_cents": 800},
+		{"status": "active", "currency": "EUR", "billing_entity_code": "acme", "balance_cents": 250, "ongoing_balance_cents": 250},
+		{"status": "terminated", "currency": "EUR", "billing_entity_code": "acme", "balance_cents": 99},
+		{"status": "active", "currency": "EUR", "billing_entity_code": "other", "balance_cents": 99}
+	], "meta": {"current_page": 1}}`,
+}
+
+func scrape(c *qt.C, handler http.Handler) string {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	c.Assert(rec.Code, qt.Equals, http.StatusOK)
+	c.Assert(rec.Header().Get("Content-Type"), qt.Equals, exporter.ContentType)
+	body, err := io.ReadAll(rec.Body)
+	c.Assert(err, qt.IsNil)
+	return string(body)
+}
+
+const metrics = `# HELP lago_mrr Monthly recurring revenue of the current month.
+# TYPE lago_mrr gauge
+lago_mrr{currency="EUR",billing_entity="acme"} 100.50
+# HELP lago_gross_revenue Gross revenue invoiced in the current month.
+# TYPE lago_gross_revenue gauge
+lago_gross_revenue{currency="EUR",billing_entity="acme"} 200.00
+# HELP lago_gross_revenue_invoices Number of invoices issued in the current month.
+# TYPE lago_gross_revenue_invoices gauge
+lago_gross_revenue_invoices{currency="EUR",billing_entity="acme"} 4
+# HELP lago_overdue_balance Amount of the invoices past their due date.
+# TYPE lago_overdue_balance gauge
+lago_overdue_balance{currency="EUR",billing_entity="acme"} 15.00
+# HELP lago_invoice_collection_amount Amount invoiced in the current month by payment status.
+# TYPE lago_invoice_collection_amount gauge
+lago_invoice_collection_amount{currency="EUR",billing_entity="acme",payment_status="pending"} 50.00
+lago_invoice_collection_amount{currency="EUR",billing_entity="acme",payment_status="succeeded"} 150.00
+# HELP lago_invoice_collection_invoices Number of invoices issued in the current month by payment status.
+# TYPE lago_invoice_collection_invoices gauge
+lago_invoice_collection_invoices{currency="EUR",billing_entity="acme",payment_status="pending"} 1
+lago_invoice_collection_invoices{currency="EUR",billing_entity="acme",payment_status="succeeded"} 3
+# HELP lago_invoice_collection_rate Share of the amount invoiced in the current month that is paid.
+# TYPE lago_invoice_collection_rate gauge
+lago_invoice_collection_rate{currency="EUR",billing_entity="acme"} 0.75
+# HELP lago_invoiced_usage Usage invoiced in the current month by billable metric code.
+# TYPE lago_invoiced_usage gauge
+lago_invoiced_usage{currency="EUR",billing_entity="acme",code="api_calls"} 7.00
+# HELP lago_wallets Number of active customer wallets.
+# TYPE lago_wallets gauge
+lago_wallets{currency="EUR",billing_entity="acme"} 2
+# HELP lago_wallet_balance Balance of the active customer wallets.
+# TYPE lago_wallet_balance gauge
+lago_wallet_balance{currency="EUR",billing_entity="acme"} 12.50
+# HELP lago_wallet_ongoing_balance Balance of the active customer wallets after the current usage.
+# TYPE lago_wallet_ongoing_balance gauge
+lago_wallet_ongoing_balance{currency="EUR",billing_entity="acme"} 10.50
+`
+
+func TestExporter(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, responses)
+	now := time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)
+	e := exporter.New(server.Client(), exporter.Options{
+		TTL: time.Minute,
+		Now: func() time.Time { return now },
+	})
+
+	body := scrape(c, e)
+	c.Assert(body, qt.Equals, metrics+`# HELP lago_up Whether the last collection from Lago succeeded.
+# TYPE lago_up gauge
+lago_up 1
+# HELP lago_collection_duration_seconds Duration of the last collection from Lago.
+# TYPE lago_collection_duration_seconds gauge
+lago_collection_duration_seconds 0
+# HELP lago_last_success_timestamp_seconds Time of the last successful collection from Lago.
+# TYPE lago_last_success_timestamp_seconds gauge
+lago_last_success_timestamp_seconds 1.7735328e+09
+`)
+
+	// Scrapes within the TTL are answered from the cache.
+	requests := len(server.Requests())
+	now = now.Add(59 * time.Second)
+	c.Assert(scrape(c, e), qt.Equals, body)
+	c.Assert(server.Requests(), qt.HasLen, requests)
+
+	// Stale scrapes are answered from the cache while a single refresh runs.
+	// Failed collections keep the last metrics, reported as down.
+	server.SetRoutes(nil)
+	now = now.Add(time.Second)
+	c.Assert(scrape(c, e), qt.Equals, body)
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(body, "\nlago_up 0\n") && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+		body = scrape(c, e)
+	}
+	c.Assert(server.Requests(), qt.HasLen, requests+1)
+	c.Assert(strings.HasPrefix(body, metrics), qt.IsTrue)
+	c.Assert(body, qt.Contains, "\nlago_up 0\n")
+	c.Assert(body, qt.Contains, "\nlago_last_success_timestamp_seconds 1.7735328e+09\n")
+}
+
+func TestExporter_ConcurrentScrapes(t *testing.T) {
+	c := qt.New(t)
+
+	server := lt.NewRoutesServer(c, responses)
+	now := func() time.Time { return time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC) }
+	c.Assert(exporter.New(server.Client(), exporter.Options{Now: now}).Collect(context.Background()), qt.IsNil)
+	requests := len(server.Requests())
+
+	// The first scrapes all wait for the same collection.
+	e := exporter.New(server.Client(), exporter.Options{Now: now})
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+	for i := range bodies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+			bodies[i] = rec.Body.String()
+		}()
+	}
+	wg.Wait()
+	c.Assert(server.Requests(), qt.HasLen, 2*requests)
+	for _, body := range bodies {
+		c.Assert(strings.HasPrefix(body, metrics), qt.IsTrue)
+	}
+}
+
+func TestExporter_Collect(t *testing.T) {
+	c := qt.New(t)
+
+	e := exporter.New(lt.NewRoutesServer(c, responses).Client(), exporter.Options{
+		BillingEntities: []string{"missing"},
+		Now:             func() time.Time { return time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC) },
+	})
+	err := e.Collect(context.Background())
+	c.Assert(err, qt.ErrorMatches, `analytics: analytics/mrr: .*`)
+
+	body := scrape(c, e)
+	c.Assert(body, qt.Contains, "lago_up 0\n")
+	c.Assert(body, qt.Not(qt.Contains), "lago_last_success_timestamp_seconds")
+}
@@ -0,0 +1,89 @@
+package exporter
+
+import (
+	"bufio"
+	"io"
+	"sort"
+	"strings"
+)
+
+// family is a metric with its samples, written in the Prometheus text
+// exposition format.
+type family struct {
+	name    string
+	help    string
+	samples []sample
+}
+
+type sample struct {
+	// labels are name and value pairs.
+	labels []string
+	value  string
+}
+
+func (f *family) add(value string, labels ...string) {
+	f.samples = append(f.samples, sample{labels: labels, value: value})
+}
+
+// metrics are the families of a collection, in the order they are written.
+type metrics struct {
+	families []*family
+	byName   map[string]*family
+}
+
+func (m *metrics) family(name, help string) *family {
+	if m.byName == nil {
+		m.byName = map[string]*family{}
+	}
+	if f, ok := m.byName[name]; ok {
+		return f
+	}
+	f := &family{name: name, help: help}
+	m.byName[name] = f
+	m.families = append(m.families, f)
+	return f
+}
+
+// write writes the families that have samples, all of them gauges, with
+// their samples sorted by labels.
+func (m *metrics) write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	for _, f := range m.families {
+		if len(f.samples) == 0 {
+			continue
+		}
+		bw.WriteString("# HELP " + f.name + " " + helpReplacer.Replace(f.help) + "\n")
+		bw.WriteString("# TYPE " + f.name + " gauge\n")
+
+		lines := make([]string, len(f.samples))
+		for i, s := range f.samples {
+			lines[i] = f.name + formatLabels(s.labels) + " " + s.value + "\n"
+		}
+		sort.Strings(lines)
+		for _, line := range lines {
+			bw.WriteString(line)
+		}
+	}
+	return bw.Flush()
+}
+
+var (
+	helpReplacer  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func formatLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i] + `="` + labelReplacer.Replace(labels[i+1]) + `"`)
+	}
+	b.WriteByte('}')
+	return b.String()
+}
@@ -162,6 +162,36 @@ type Fee struct {
 	PricingUnitDetails *PricingUnitDetails `json:"pricing_unit_details,omitempty"`
 }
 
+// Amount returns AmountCents as Money.
+func (f *Fee) Amount() Money {
+	return NewMoney(int64(f.AmountCents), Currency(f.AmountCurrency))
+}
+
+// TaxesAmount returns TaxesAmountCents as Money.
+func (f *Fee) TaxesAmount() Money {
+	return NewMoney(int64(f.TaxesAmountCents), Currency(f.AmountCurrency))
+}
+
+// TotalAmount returns TotalAmountCents as Money.
+func (f *Fee) TotalAmount() Money {
+	return NewMoney(int64(f.TotalAmountCents), Currency(f.TotalAmountCurrency))
+}
+
+// PreciseAmountDecimal parses PreciseAmount as an exact Decimal.
+func (f *Fee) PreciseAmountDecimal() (Decimal, error) {
+	return parseOptionalDecimal(f.PreciseAmount)
+}
+
+// PreciseUnitAmountDecimal parses PreciseUnitAmount as an exact Decimal.
+func (f *Fee) PreciseUnitAmountDecimal() (Decimal, error) {
+	return parseOptionalDecimal(f.PreciseUnitAmount)
+}
+
+// PreciseTotalAmountDecimal parses PreciseTotalAmount as an exact Decimal.
+func (f *Fee) PreciseTotalAmountDecimal() (Decimal, error) {
+	return parseOptionalDecimal(f.PreciseTotalAmount)
+}
+
 func (c *Client) Fee() *FeeRequest {
 	return &FeeRequest{
 		client: c,
@@ -9,6 +9,7 @@ require (
 	github.com/go-resty/resty/v2 v2.16.5
 	github.com/golang-jwt/jwt/v5 v5.2.2
 	github.com/google/go-querystring v1.1.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -209,6 +209,7 @@ type Invoice struct {
 	PaymentDisputeLostAt time.Time `json:"payment_dispute_lost_at,omitempty"`
 	PaymentDueDate       string    `json:"payment_due_date,omitempty"`
 	PaymentOverdue       bool      `json:"payment_overdue,omitempty"`
+	VoidedAt             time.Time `json:"voided_at,omitempty"`
 
 	InvoiceType   InvoiceType          `json:"invoice_type,omitempty"`
 	Status        InvoiceStatus        `json:"status,omitempty"`
@@ -246,6 +247,51 @@ type Invoice struct {
 	AppliedUsageThreshold        []AppliedUsageThreshold              `json:"applied_usage_threshold,omitempty"`
 }
 
+// FeesAmount returns FeesAmountCents as Money.
+func (i *Invoice) FeesAmount() Money {
+	return NewMoney(int64(i.FeesAmountCents), i.Currency)
+}
+
+// TaxesAmount returns TaxesAmountCents as Money.
+func (i *Invoice) TaxesAmount() Money {
+	return NewMoney(int64(i.TaxesAmountCents), i.Currency)
+}
+
+// CouponsAmount returns CouponsAmountCents as Money.
+func (i *Invoice) CouponsAmount() Money {
+	return NewMoney(int64(i.CouponsAmountCents), i.Currency)
+}
+
+// CreditNotesAmount returns CreditNotesAmountCents as Money.
+func (i *Invoice) CreditNotesAmount() Money {
+	return NewMoney(int64(i.CreditNotesAmountCents), i.Currency)
+}
+
+// PrepaidCreditAmount returns PrepaidCreditAmountCents as Money.
+func (i *Invoice) PrepaidCreditAmount() Money {
+	return NewMoney(int64(i.PrepaidCreditAmountCents), i.Currency)
+}
+
+// SubTotalExcludingTaxesAmount returns SubTotalExcludingTaxesAmountCents as Money.
+func (i *Invoice) SubTotalExcludingTaxesAmount() Money {
+	return NewMoney(int64(i.SubTotalExcludingTaxesAmountCents), i.Currency)
+}
+
+// SubTotalIncludingTaxesAmount returns SubTotalIncludingTaxesAmountCents as Money.
+func (i *Invoice) SubTotalIncludingTaxesAmount() Money {
+	return NewMoney(int64(i.SubTotalIncludingTaxesAmountCents), i.Currency)
+}
+
+// TotalAmount returns TotalAmountCents as Money.
+func (i *Invoice) TotalAmount() Money {
+	return NewMoney(int64(i.TotalAmountCents), i.Currency)
+}
+
+// TotalDueAmount returns TotalDueAmountCents as Money.
+func (i *Invoice) TotalDueAmount() Money {
+	return NewMoney(int64(i.TotalDueAmountCents), i.Currency)
+}
+
 type InvoicePaymentDetails struct {
 	LagoCustomerID     uuid.UUID `json:"lago_customer_id,omitempty"`
 	LagoInvoiceID      uuid.UUID `json:"lago_invoice_id,omitempty"`
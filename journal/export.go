@@ -0,0 +1,263 @@
+package journal
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+const (
+	dateLayout = "2006-01-02"
+	perPage    = 100
+)
+
+// Journal is the set of entries posted over a date range.
+type Journal struct {
+	From    string  `json:"from"`
+	To      string  `json:"to"`
+	Entries []Entry `json:"entries"`
+}
+
+// ExportOptions configures Export.
+type ExportOptions struct {
+	// From and To are the first and last days of the range, inclusive.
+	// Only their date in UTC is used.
+	From, To time.Time
+	Mapping  Mapping
+}
+
+// Export posts the documents dated within a range:
+//
+//   - finalized and voided invoices by issuing date, and the reversal of
+//     voided invoices by void date, even when they were issued before From;
+//   - credit notes by issuing date;
+//   - succeeded payments by creation date;
+//   - settled granted and voided wallet transactions by settlement date.
+//
+// Entries are sorted by date, then in that order. It stops at the first API
+// error or unbalanced entry.
+func Export(ctx context.Context, client *lago.Client, opts ExportOptions) (*Journal, error) {
+	e := &exporter{
+		ctx:     ctx,
+		client:  client,
+		mapping: &opts.Mapping,
+		journal: &Journal{
+			From:    opts.From.UTC().Format(dateLayout),
+			To:      opts.To.UTC().Format(dateLayout),
+			Entries: []Entry{},
+		},
+	}
+
+	for _, export := range []func() error{e.invoices, e.creditNotes, e.payments, e.walletTransactions} {
+		if err := export(); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(e.journal.Entries, func(i, j int) bool {
+		return e.journal.Entries[i].Date < e.journal.Entries[j].Date
+	})
+	return e.journal, nil
+}
+
+type exporter struct {
+	ctx     context.Context
+	client  *lago.Client
+	mapping *Mapping
+	journal *Journal
+}
+
+func (e *exporter) inRange(date string) bool {
+	return date >= e.journal.From && date <= e.journal.To
+}
+
+func (e *exporter) add(entry *Entry, err error) error {
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		e.journal.Entries = append(e.journal.Entries, *entry)
+	}
+	return nil
+}
+
+func (e *exporter) invoices() error {
+	finalized, err := e.listInvoices(lago.InvoiceStatusFinalized, e.journal.From)
+	if err != nil {
+		return err
+	}
+	// Invoices voided within the range may have been issued before it.
+	voided, err := e.listInvoices(lago.InvoiceStatusVoided, "")
+	if err != nil {
+		return err
+	}
+
+	for _, listed := range finalized {
+		if listed.Status != lago.InvoiceStatusFinalized || !e.inRange(listed.IssuingDate) {
+			continue
+		}
+		if err := e.invoice(listed.LagoID.String()); err != nil {
+			return err
+		}
+	}
+	for _, listed := range voided {
+		if listed.Status != lago.InvoiceStatusVoided {
+			continue
+		}
+		if !e.inRange(listed.IssuingDate) && !listed.VoidedAt.IsZero() && !e.inRange(listed.VoidedAt.UTC().Format(dateLayout)) {
+			continue
+		}
+		if err := e.invoice(listed.LagoID.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listInvoices lists the invoices with status issued from from, when given,
+// to the end of the range.
+func (e *exporter) listInvoices(status lago.InvoiceStatus, from string) ([]lago.Invoice, error) {
+	invoices, err := lago.FetchPages(0, func(page int) ([]lago.Invoice, lago.Metadata, *lago.Error) {
+		result, err := e.client.Invoice().GetList(e.ctx, &lago.InvoiceListInput{
+			Page:            lago.Ptr(page),
+			PerPage:         lago.Ptr(perPage),
+			IssuingDateFrom: from,
+			IssuingDateTo:   e.journal.To,
+			Status:          status,
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Invoices, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("journal: %s invoices: %w", status, err)
+	}
+	return invoices, nil
+}
+
+// invoice posts an invoice issued within the range, and its reversal when it
+// was voided within the range.
+func (e *exporter) invoice(id string) error {
+	// Listed invoices do not include their fees.
+	invoice, lagoErr := e.client.Invoice().Get(e.ctx, id)
+	if lagoErr != nil {
+		return fmt.Errorf("journal: invoice %s: %w", id, lagoErr)
+	}
+	if e.inRange(invoice.IssuingDate) {
+		if err := e.add(e.mapping.InvoiceEntry(invoice)); err != nil {
+			return err
+		}
+	}
+	if invoice.Status == lago.InvoiceStatusVoided && e.inRange(invoice.VoidedAt.UTC().Format(dateLayout)) {
+		if err := e.add(e.mapping.VoidedInvoiceEntry(invoice)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *exporter) creditNotes() error {
+	creditNotes, err := lago.FetchPages(0, func(page int) ([]lago.CreditNote, lago.Metadata, *lago.Error) {
+		result, err := e.client.CreditNote().GetList(e.ctx, &lago.CreditNoteListInput{
+			Page:            lago.Ptr(page),
+			PerPage:         lago.Ptr(perPage),
+			IssuingDateFrom: e.journal.From,
+			IssuingDateTo:   e.journal.To,
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.CreditNotes, result.Meta, nil
+	})
+	if err != nil {
+		return fmt.Errorf("journal: credit notes: %w", err)
+	}
+
+	for _, listed := range creditNotes {
+		if !e.inRange(listed.IssuingDate) {
+			continue
+		}
+		creditNote, lagoErr := e.client.CreditNote().Get(e.ctx, listed.LagoID)
+		if lagoErr != nil {
+			return fmt.Errorf("journal: credit note %s: %w", listed.LagoID, lagoErr)
+		}
+		if err := e.add(e.mapping.CreditNoteEntry(creditNote)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *exporter) payments() error {
+	// Payments cannot be filtered by date, so they are all listed.
+	payments, err := lago.FetchPages(0, func(page int) ([]lago.Payment, lago.Metadata, *lago.Error) {
+		result, err := e.client.Payment().GetList(e.ctx, &lago.PaymentListInput{
+			Page:    lago.Ptr(page),
+			PerPage: lago.Ptr(perPage),
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Payments, result.Meta, nil
+	})
+	if err != nil {
+		return fmt.Errorf("journal: payments: %w", err)
+	}
+
+	for _, payment := range payments {
+		if payment.PaymentStatus != string(lago.InvoicePaymentStatusSucceeded) || !e.inRange(payment.CreatedAt.UTC().Format(dateLayout)) {
+			continue
+		}
+		e.journal.Entries = append(e.journal.Entries, *e.mapping.PaymentEntry(&payment))
+	}
+	return nil
+}
+
+func (e *exporter) walletTransactions() error {
+	wallets, err := lago.FetchPages(0, func(page int) ([]lago.Wallet, lago.Metadata, *lago.Error) {
+		result, err := e.client.Wallet().GetList(e.ctx, &lago.WalletListInput{
+			Page:    lago.Ptr(page),
+			PerPage: lago.Ptr(perPage),
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Wallets, result.Meta, nil
+	})
+	if err != nil {
+		return fmt.Errorf("journal: wallets: %w", err)
+	}
+
+	for _, wallet := range wallets {
+		transactions, err := lago.FetchPages(0, func(page int) ([]lago.WalletTransaction, lago.Metadata, *lago.Error) {
+			result, err := e.client.WalletTransaction().GetList(e.ctx, &lago.WalletTransactionListInput{
+				Page:     lago.Ptr(page),
+				PerPage:  lago.Ptr(perPage),
+				WalletID: wallet.LagoID.String(),
+				Status:   lago.WalletTransactionStatusSettled,
+			})
+			if err != nil {
+				return nil, lago.Metadata{}, err
+			}
+			return result.WalletTransactions, result.Meta, nil
+		})
+		if err != nil {
+			return fmt.Errorf("journal: transactions of wallet %s: %w", wallet.LagoID, err)
+		}
+
+		for _, transaction := range transactions {
+			entry, err := e.mapping.WalletTransactionEntry(&wallet, &transaction)
+			if err != nil {
+				return err
+			}
+			if entry != nil && e.inRange(entry.Date) {
+				e.journal.Entries = append(e.journal.Entries, *entry)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,280 @@
+// Package journal turns Lago invoices, credit notes, payments and wallet
+// transactions into double-entry journal entries for a general ledger.
+package journal
+
+import (
+	"errors"
+	"fmt"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// ErrUnbalanced is returned when the debits of an entry do not add up to its
+// credits, which happens when the totals of a document are inconsistent.
+var ErrUnbalanced = errors.New("journal: unbalanced entry")
+
+// SourceType is the kind of Lago document an entry is posted from.
+type SourceType string
+
+const (
+	SourceInvoice           SourceType = "invoice"
+	SourceVoidedInvoice     SourceType = "voided_invoice"
+	SourceCreditNote        SourceType = "credit_note"
+	SourcePayment           SourceType = "payment"
+	SourceWalletTransaction SourceType = "wallet_transaction"
+)
+
+// Line is one side of an entry on a single account. Amounts are in cents of
+// the entry currency and exactly one of Debit and Credit is set.
+type Line struct {
+	Account     string `json:"account"`
+	Debit       int64  `json:"debit_cents"`
+	Credit      int64  `json:"credit_cents"`
+	Description string `json:"description,omitempty"`
+}
+
+// Entry is a balanced set of lines posted from one document.
+type Entry struct {
+	// ID is unique within a journal, such as "invoice:<lago_id>".
+	ID         string     `json:"id"`
+	Date       string     `json:"date"`
+	SourceType SourceType `json:"source_type"`
+	SourceID   string     `json:"source_id"`
+	// Reference is the number of the invoice or credit note, or the
+	// reference of the payment.
+	Reference          string        `json:"reference,omitempty"`
+	ExternalCustomerID string        `json:"external_customer_id,omitempty"`
+	Currency           lago.Currency `json:"currency"`
+	Description        string        `json:"description,omitempty"`
+	Lines              []Line        `json:"lines"`
+}
+
+// Totals returns the sums of the debits and credits of the entry.
+func (e *Entry) Totals() (debit, credit int64) {
+	for _, line := range e.Lines {
+		debit += line.Debit
+		credit += line.Credit
+	}
+	return debit, credit
+}
+
+func (e *Entry) debit(account string, amount int64, description string) {
+	e.post(account, amount, description)
+}
+
+func (e *Entry) credit(account string, amount int64, description string) {
+	e.post(account, -amount, description)
+}
+
+// post adds a debit for a positive amount and a credit for a negative one,
+// so that a negative fee ends up on the opposite side.
+func (e *Entry) post(account string, amount int64, description string) {
+	switch {
+	case amount > 0:
+		e.Lines = append(e.Lines, Line{Account: account, Debit: amount, Description: description})
+	case amount < 0:
+		e.Lines = append(e.Lines, Line{Account: account, Credit: -amount, Description: description})
+	}
+}
+
+func (e *Entry) check() error {
+	if debit, credit := e.Totals(); debit != credit {
+		return fmt.Errorf("%w %s: debits %d, credits %d", ErrUnbalanced, e.ID, debit, credit)
+	}
+	return nil
+}
+
+// InvoiceEntry posts a finalized invoice, as returned by InvoiceRequest.Get
+// with its fees, credits and applied taxes:
+//
+//	debit  Receivable          total_amount_cents
+//	debit  CustomerCredits     credit_notes_amount_cents
+//	debit  PrepaidCredits      prepaid_credit_amount_cents
+//	debit  Discounts           coupons_amount_cents
+//	debit  Revenue             progressive_billing_credit_amount_cents
+//	credit <revenue account>   amount_cents of every fee
+//	credit <tax account>       amount_cents of every applied tax
+//
+// Fees of wallet top-ups are credited to PrepaidCredits instead of revenue.
+func (m *Mapping) InvoiceEntry(invoice *lago.Invoice) (*Entry, error) {
+	entry := &Entry{
+		ID:          fmt.Sprintf("%s:%s", SourceInvoice, invoice.LagoID),
+		Date:        invoice.IssuingDate,
+		SourceType:  SourceInvoice,
+		SourceID:    invoice.LagoID.String(),
+		Reference:   invoice.Number,
+		Currency:    invoice.Currency,
+		Description: "Invoice " + invoice.Number,
+	}
+	if invoice.Customer != nil {
+		entry.ExternalCustomerID = invoice.Customer.ExternalID
+	}
+
+	entry.debit(m.account(m.Receivable, DefaultReceivable), int64(invoice.TotalAmountCents), "Amount due")
+	entry.debit(m.account(m.CustomerCredits, DefaultCustomerCredits), int64(invoice.CreditNotesAmountCents), "Credit notes applied")
+	entry.debit(m.account(m.PrepaidCredits, DefaultPrepaidCredits), int64(invoice.PrepaidCreditAmountCents), "Prepaid credits applied")
+	entry.debit(m.account(m.Discounts, DefaultDiscounts), int64(invoice.CouponsAmountCents), "Coupons")
+	entry.debit(m.account(m.Revenue, DefaultRevenue), int64(invoice.ProgressiveBillingCreditAmountCents), "Progressive billing credits")
+
+	for _, fee := range invoice.Fees {
+		entry.credit(m.feeAccount(&fee), int64(fee.AmountCents), feeDescription(&fee))
+	}
+	for _, tax := range invoice.AppliedTaxes {
+		entry.credit(m.taxAccount(tax.TaxCode), int64(tax.AmountCents), taxDescription(tax.TaxName, tax.TaxCode))
+	}
+
+	if err := entry.check(); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// VoidedInvoiceEntry reverses the entry of an invoice voided after it was
+// posted by InvoiceEntry, on the day it was voided: every line of the
+// invoice entry is posted on the opposite side.
+func (m *Mapping) VoidedInvoiceEntry(invoice *lago.Invoice) (*Entry, error) {
+	if invoice.VoidedAt.IsZero() {
+		return nil, fmt.Errorf("journal: invoice %s: no void date", invoice.LagoID)
+	}
+	entry, err := m.InvoiceEntry(invoice)
+	if err != nil {
+		return nil, err
+	}
+	entry.ID = fmt.Sprintf("%s:%s", SourceVoidedInvoice, invoice.LagoID)
+	entry.Date = invoice.VoidedAt.UTC().Format(dateLayout)
+	entry.SourceType = SourceVoidedInvoice
+	entry.Description = "Void of invoice " + invoice.Number
+	for i := range entry.Lines {
+		line := &entry.Lines[i]
+		line.Debit, line.Credit = line.Credit, line.Debit
+	}
+	return entry, nil
+}
+
+// CreditNoteEntry posts a credit note, as returned by CreditNoteRequest.Get
+// with its items and applied taxes. It reverses the revenue and taxes of the
+// credited fees and credits the way the amount is given back:
+//
+//	debit  <revenue account>   amount_cents of every item
+//	debit  <tax account>       amount_cents of every applied tax
+//	credit Discounts           coupons_adjustment_amount_cents
+//	credit CustomerCredits     credit_amount_cents
+//	credit Refunds             refund_amount_cents
+//	credit Receivable          offset_amount_cents
+func (m *Mapping) CreditNoteEntry(creditNote *lago.CreditNote) (*Entry, error) {
+	entry := &Entry{
+		ID:          fmt.Sprintf("%s:%s", SourceCreditNote, creditNote.LagoID),
+		Date:        creditNote.IssuingDate,
+		SourceType:  SourceCreditNote,
+		SourceID:    creditNote.LagoID.String(),
+		Reference:   creditNote.Number,
+		Currency:    creditNote.Currency,
+		Description: fmt.Sprintf("Credit note %s on invoice %s", creditNote.Number, creditNote.InvoiceNumber),
+	}
+	if creditNote.Customer != nil {
+		entry.ExternalCustomerID = creditNote.Customer.ExternalID
+	}
+
+	for _, item := range creditNote.Items {
+		entry.debit(m.feeAccount(&item.Fee), int64(item.AmountCents), feeDescription(&item.Fee))
+	}
+	for _, tax := range creditNote.AppliedTaxes {
+		entry.debit(m.taxAccount(tax.TaxCode), int64(tax.AmountCents), taxDescription(tax.TaxName, tax.TaxCode))
+	}
+
+	entry.credit(m.account(m.Discounts, DefaultDiscounts), int64(creditNote.CouponsAdjustmentAmountCents), "Coupons adjustment")
+	entry.credit(m.account(m.CustomerCredits, DefaultCustomerCredits), int64(creditNote.CreditAmountCents), "Credited to customer")
+	entry.credit(m.account(m.Refunds, DefaultRefunds), int64(creditNote.RefundAmountCents), "Refunded to customer")
+	entry.credit(m.account(m.Receivable, DefaultReceivable), int64(creditNote.OffsetAmountCents), "Offset against invoice")
+
+	if err := entry.check(); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// PaymentEntry posts a payment received from a customer: it debits Cash and
+// credits Receivable.
+func (m *Mapping) PaymentEntry(payment *lago.Payment) *Entry {
+	entry := &Entry{
+		ID:                 fmt.Sprintf("%s:%s", SourcePayment, payment.LagoID),
+		Date:               payment.CreatedAt.UTC().Format(dateLayout),
+		SourceType:         SourcePayment,
+		SourceID:           payment.LagoID.String(),
+		Reference:          payment.Reference,
+		ExternalCustomerID: payment.ExternalCustomerID,
+		Currency:           payment.AmountCurrency,
+		Description:        "Payment",
+	}
+	if len(payment.InvoiceNumbers) > 0 {
+		entry.Description = fmt.Sprintf("Payment of %v", payment.InvoiceNumbers)
+	}
+
+	entry.debit(m.account(m.Cash, DefaultCash), int64(payment.AmountCents), "")
+	entry.credit(m.account(m.Receivable, DefaultReceivable), int64(payment.AmountCents), "")
+	return entry
+}
+
+// WalletTransactionEntry posts the settled wallet transactions that no
+// invoice accounts for:
+//
+//   - granted credits debit GrantedCredits and credit PrepaidCredits;
+//   - voided credits debit PrepaidCredits and credit ExpiredCredits.
+//
+// Purchased credits are posted with the invoice of the top-up and consumed
+// credits with the invoice they are applied to, so it returns nil for them.
+func (m *Mapping) WalletTransactionEntry(wallet *lago.Wallet, transaction *lago.WalletTransaction) (*Entry, error) {
+	if transaction.Status != lago.WalletTransactionStatusSettled {
+		return nil, nil
+	}
+
+	var debit, credit, description string
+	switch {
+	case transaction.TransactionType == lago.Inbound && transaction.TransactionStatus == lago.Granted:
+		debit, credit, description = m.account(m.GrantedCredits, DefaultGrantedCredits), m.account(m.PrepaidCredits, DefaultPrepaidCredits), "Granted credits"
+	case transaction.TransactionType == lago.Outbound && transaction.TransactionStatus == lago.Voided:
+		debit, credit, description = m.account(m.PrepaidCredits, DefaultPrepaidCredits), m.account(m.ExpiredCredits, DefaultExpiredCredits), "Voided credits"
+	default:
+		return nil, nil
+	}
+
+	amount, err := lago.ParseMoney(transaction.Amount, wallet.Currency)
+	if err != nil {
+		return nil, fmt.Errorf("journal: wallet transaction %s: %w", transaction.LagoID, err)
+	}
+
+	date := transaction.SettledAt
+	if date.IsZero() {
+		date = transaction.CreatedAt
+	}
+	entry := &Entry{
+		ID:                 fmt.Sprintf("%s:%s", SourceWalletTransaction, transaction.LagoID),
+		Date:               date.UTC().Format(dateLayout),
+		SourceType:         SourceWalletTransaction,
+		SourceID:           transaction.LagoID.String(),
+		ExternalCustomerID: wallet.ExternalCustomerID,
+		Currency:           wallet.Currency,
+		Description:        fmt.Sprintf("%s on wallet %s", description, wallet.Name),
+	}
+	entry.debit(debit, amount.Amount, "")
+	entry.credit(credit, amount.Amount, "")
+	return entry, nil
+}
+
+func feeDescription(fee *lago.Fee) string {
+	name := fee.Item.InvoiceDisplayName
+	if name == "" {
+		name = fee.Item.Name
+	}
+	if name == "" {
+		name = fee.Item.Code
+	}
+	return fmt.Sprintf("%s %s", fee.Item.Type, name)
+}
+
+func taxDescription(name, code string) string {
+	if name == "" {
+		return "Tax " + code
+	}
+	return fmt.Sprintf("Tax %s (%s)", name, code)
+}
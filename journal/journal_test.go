@@ -0,0 +1,277 @@
+package journal_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/journal"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+const (
+	invoiceID     = "1a901a90-1a90-1a90-1a90-1a901a901a90"
+	creditNoteID  = "2b902b90-2b90-2b90-2b90-2b902b902b90"
+	paymentID     = "3c903c90-3c90-3c90-3c90-3c903c903c90"
+	walletID      = "4d904d90-4d90-4d90-4d90-4d904d904d90"
+	grantID       = "5e905e90-5e90-5e90-5e90-5e905e905e90"
+	oldInvoiceID  = "6f906f90-6f90-6f90-6f90-6f906f906f90"
+	oldPaymentID  = "7a907a90-7a90-7a90-7a90-7a907a907a90"
+	purchaseTxnID = "8b908b90-8b90-8b90-8b90-8b908b908b90"
+	voidedID      = "9c909c90-9c90-9c90-9c90-9c909c909c90"
+	oldVoidedID   = "0d900d90-0d90-0d90-0d90-0d900d900d90"
+)
+
+// invoice has an API calls charge of 100.00 EUR and a setup add-on of
+// 50.00 EUR, a 10.00 EUR coupon, 20% VAT, 5.00 EUR of credit notes and
+// 15.00 EUR of prepaid credits.
+const invoice = `{
+	"lago_id": "` + invoiceID + `", "number": "INV-001", "issuing_date": "2026-03-05",
+	"status": "finalized", "currency": "EUR", "customer": {"external_id": "cus_1"},
+	"fees": [
+		{"amount_cents": 10000, "item": {"type": "charge", "code": "api_calls", "name": "API calls"}},
+		{"amount_cents": 5000, "item": {"type": "add_on", "code": "setup", "name": "Setup"}}
+	],
+	"applied_taxes": [{"tax_code": "vat_20", "tax_name": "VAT", "amount_cents": 2800}],
+	"fees_amount_cents": 15000, "coupons_amount_cents": 1000, "taxes_amount_cents": 2800,
+	"credit_notes_amount_cents": 500, "prepaid_credit_amount_cents": 1500, "total_amount_cents": 14800
+}`
+
+// creditNote gives back 24.00 EUR of the API calls charge, taxes included,
+// as 12.00 EUR of credits and 12.00 EUR of refund.
+const creditNote = `{
+	"lago_id": "` + creditNoteID + `", "number": "CN-001", "invoice_number": "INV-001",
+	"issuing_date": "2026-03-10", "currency": "EUR", "customer": {"external_id": "cus_1"},
+	"items": [{"amount_cents": 2000, "fee": {"item": {"type": "charge", "code": "api_calls"}}}],
+	"applied_taxes": [{"tax_code": "vat_20", "amount_cents": 400}],
+	"credit_amount_cents": 1200, "refund_amount_cents": 1200, "total_amount_cents": 2400
+}`
+
+func mapping() journal.Mapping {
+	return journal.Mapping{
+		Receivable:       "1100",
+		RevenueByCode:    map[string]string{"api_calls": "4000"},
+		RevenueByFeeType: map[lago.FeeType]string{lago.FeeItemAddOn: "4100"},
+		TaxPayableByCode: map[string]string{"vat_20": "2200"},
+	}
+}
+
+type posting struct {
+	Account       string
+	Debit, Credit int64
+}
+
+func postings(entry *journal.Entry) []posting {
+	var all []posting
+	for _, line := range entry.Lines {
+		all = append(all, posting{line.Account, line.Debit, line.Credit})
+	}
+	return all
+}
+
+func TestExport(t *testing.T) {
+	c := qt.New(t)
+
+	client := lt.NewRoutesServer(c, map[string]string{
+		"/invoices": `{"invoices": [
+			{"lago_id": "` + invoiceID + `", "issuing_date": "2026-03-05", "status": "finalized"},
+			{"lago_id": "` + oldInvoiceID + `", "issuing_date": "2026-02-27", "status": "finalized"}
+		], "meta": {"current_page": 1}}`,
+		"/invoices/" + invoiceID:        `{"invoice": ` + invoice + `}`,
+		"/credit_notes":                 `{"credit_notes": [{"lago_id": "` + creditNoteID + `", "issuing_date": "2026-03-10"}], "meta": {}}`,
+		"/credit_notes/" + creditNoteID: `{"credit_note": ` + creditNote + `}`,
+		"/payments": `{"payments": [
+			{"lago_id": "` + paymentID + `", "amount_cents": 14800, "amount_currency": "EUR", "payment_status": "succeeded", "reference": "wire 42", "external_customer_id": "cus_1", "invoice_numbers": ["INV-001"], "created_at": "2026-03-07T10:00:00Z"},
+			{"lago_id": "` + oldPaymentID + `", "amount_cents": 100, "amount_currency": "EUR", "payment_status": "succeeded", "created_at": "2026-01-07T10:00:00Z"}
+		], "meta": {}}`,
+		"/wallets": `{"wallets": [{"lago_id": "` + walletID + `", "name": "Main", "currency": "EUR", "external_customer_id": "cus_1"}], "meta": {}}`,
+		"/wallets/" + walletID + "/wallet_transactions": `{"wallet_transactions": [
+			{"lago_id": "` + grantID + `", "status": "settled", "transaction_type": "inbound", "transaction_status": "granted", "amount": "10.0", "credit_amount": "10.0", "settled_at": "2026-03-01T08:00:00Z"},
+			{"lago_id": "` + purchaseTxnID + `", "status": "settled", "transaction_type": "inbound", "transaction_status": "purchased", "amount": "20.0", "credit_amount": "20.0", "settled_at": "2026-03-02T08:00:00Z"}
+		], "meta": {}}`,
+	}).Client()
+
+	result, err := journal.Export(context.Background(), client, journal.ExportOptions{
+		From:    time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC),
+		To:      time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC),
+		Mapping: mapping(),
+	})
+	c.Assert(err, qt.IsNil)
+
+	var ids []string
+	for _, entry := range result.Entries {
+		ids = append(ids, entry.ID)
+	}
+	c.Assert(ids, qt.DeepEquals, []string{
+		"wallet_transaction:" + grantID,
+		"invoice:" + invoiceID,
+		"payment:" + paymentID,
+		"credit_note:" + creditNoteID,
+	})
+
+	c.Assert(postings(&result.Entries[0]), qt.DeepEquals, []posting{
+		{"granted_credits_expense", 1000, 0},
+		{"prepaid_credits", 0, 1000},
+	})
+	c.Assert(postings(&result.Entries[1]), qt.DeepEquals, []posting{
+		{"1100", 14800, 0},
+		{"customer_credits", 500, 0},
+		{"prepaid_credits", 1500, 0},
+		{"discounts", 1000, 0},
+		{"4000", 0, 10000},
+		{"4100", 0, 5000},
+		{"2200", 0, 2800},
+	})
+	c.Assert(postings(&result.Entries[2]), qt.DeepEquals, []posting{
+		{"cash", 14800, 0},
+		{"1100", 0, 14800},
+	})
+	c.Assert(postings(&result.Entries[3]), qt.DeepEquals, []posting{
+		{"4000", 2000, 0},
+		{"2200", 400, 0},
+		{"customer_credits", 0, 1200},
+		{"refunds_payable", 0, 1200},
+	})
+
+	var csv bytes.Buffer
+	c.Assert(result.WriteCSV(&csv), qt.IsNil)
+	lines := strings.Split(csv.String(), "\n")
+	c.Assert(lines[0], qt.Equals, "entry_id,date,source_type,source_id,reference,external_customer_id,account,debit,credit,currency,description")
+	c.Assert(lines[3], qt.Equals, "invoice:"+invoiceID+",2026-03-05,invoice,"+invoiceID+",INV-001,cus_1,1100,148.00,,EUR,Amount due")
+	c.Assert(lines[7], qt.Equals, "invoice:"+invoiceID+",2026-03-05,invoice,"+invoiceID+",INV-001,cus_1,4000,,100.00,EUR,charge API calls")
+}
+
+func TestExport_VoidedInvoice(t *testing.T) {
+	c := qt.New(t)
+
+	// INV-002 was issued in January and voided in March; INV-000 was
+	// issued and voided outside of both months.
+	client := lt.NewRoutesServer(c, map[string]string{
+		"/invoices?status=finalized": `{"invoices": [], "meta": {}}`,
+		"/invoices?status=voided": `{"invoices": [
+			{"lago_id": "` + voidedID + `", "issuing_date": "2026-01-20", "status": "voided", "voided_at": "2026-03-12T09:00:00Z"},
+			{"lago_id": "` + oldVoidedID + `", "issuing_date": "2025-12-05", "status": "voided", "voided_at": "2026-02-01T09:00:00Z"}
+		], "meta": {}}`,
+		"/invoices/" + voidedID: `{"invoice": {
+			"lago_id": "` + voidedID + `", "number": "INV-002", "issuing_date": "2026-01-20", "voided_at": "2026-03-12T09:00:00Z",
+			"status": "voided", "currency": "EUR", "customer": {"external_id": "cus_1"},
+			"fees": [{"amount_cents": 10000, "item": {"type": "charge", "code": "api_calls", "name": "API calls"}}],
+			"applied_taxes": [{"tax_code": "vat_20", "tax_name": "VAT", "amount_cents": 2000}],
+			"fees_amount_cents": 10000, "taxes_amount_cents": 2000, "total_amount_cents": 12000
+		}}`,
+		"/credit_notes": `{"credit_notes": [], "meta": {}}`,
+		"/payments":     `{"payments": [], "meta": {}}`,
+		"/wallets":      `{"wallets": [], "meta": {}}`,
+	}).Client()
+
+	// The invoice is posted in January, and reversed in March.
+	for _, tc := range []struct {
+		month time.Month
+		ids   []string
+	}{
+		{time.January, []string{"invoice:" + voidedID}},
+		{time.March, []string{"voided_invoice:" + voidedID}},
+	} {
+		result, err := journal.Export(context.Background(), client, journal.ExportOptions{
+			From:    time.Date(2026, tc.month, 1, 0, 0, 0, 0, time.UTC),
+			To:      time.Date(2026, tc.month+1, 0, 0, 0, 0, 0, time.UTC),
+			Mapping: mapping(),
+		})
+		c.Assert(err, qt.IsNil)
+
+		var ids []string
+		for _, entry := range result.Entries {
+			ids = append(ids, entry.ID)
+		}
+		c.Assert(ids, qt.DeepEquals, tc.ids)
+	}
+
+	mapping := mapping()
+	invoice := &lago.Invoice{
+		Number: "INV-002", Currency: "EUR", VoidedAt: time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC),
+		Fees:             []lago.Fee{{AmountCents: 10000, Item: lago.FeeItem{Type: lago.FeeItemCharge, Code: "api_calls"}}},
+		AppliedTaxes:     []lago.InvoiceAppliedTax{{TaxCode: "vat_20", AmountCents: 2000}},
+		TotalAmountCents: 12000,
+	}
+	entry, err := mapping.VoidedInvoiceEntry(invoice)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.Date, qt.Equals, "2026-03-12")
+	c.Assert(entry.SourceType, qt.Equals, journal.SourceVoidedInvoice)
+	c.Assert(postings(entry), qt.DeepEquals, []posting{
+		{"1100", 0, 12000},
+		{"4000", 10000, 0},
+		{"2200", 2000, 0},
+	})
+
+	invoice.VoidedAt = time.Time{}
+	_, err = mapping.VoidedInvoiceEntry(invoice)
+	c.Assert(err, qt.ErrorMatches, `journal: invoice .*: no void date`)
+}
+
+func TestInvoiceEntry_Unbalanced(t *testing.T) {
+	c := qt.New(t)
+
+	mapping := mapping()
+	_, err := mapping.InvoiceEntry(&lago.Invoice{
+		Currency:         "EUR",
+		Fees:             []lago.Fee{{AmountCents: 10000}},
+		TotalAmountCents: 12000,
+	})
+	c.Assert(errors.Is(err, journal.ErrUnbalanced), qt.IsTrue)
+	c.Assert(err, qt.ErrorMatches, `journal: unbalanced entry invoice:.*: debits 12000, credits 10000`)
+}
+
+func TestWalletTransactionEntry_ZeroDecimalCurrency(t *testing.T) {
+	c := qt.New(t)
+
+	// Lago formats amounts with one decimal place, even for yen.
+	mapping := mapping()
+	entry, err := mapping.WalletTransactionEntry(
+		&lago.Wallet{Currency: "JPY", ExternalCustomerID: "cus_1", Name: "Credits"},
+		&lago.WalletTransaction{
+			Status:            lago.WalletTransactionStatusSettled,
+			TransactionType:   lago.Inbound,
+			TransactionStatus: lago.Granted,
+			Amount:            "1000.0",
+			SettledAt:         time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC),
+		},
+	)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entry.Currency, qt.Equals, lago.Currency("JPY"))
+	c.Assert(postings(entry), qt.DeepEquals, []posting{
+		{journal.DefaultGrantedCredits, 1000, 0},
+		{journal.DefaultPrepaidCredits, 0, 1000},
+	})
+
+	_, err = mapping.WalletTransactionEntry(
+		&lago.Wallet{Currency: "JPY"},
+		&lago.WalletTransaction{
+			Status:            lago.WalletTransactionStatusSettled,
+			TransactionType:   lago.Inbound,
+			TransactionStatus: lago.Granted,
+			Amount:            "1000.5",
+		},
+	)
+	c.Assert(err, qt.ErrorMatches, `journal: wallet transaction .*: lago: amount "1000.5" has more than 0 decimal places for JPY`)
+}
+
+func TestLoadMapping(t *testing.T) {
+	c := qt.New(t)
+
+	mapping, err := journal.LoadMapping(strings.NewReader(`{
+		"receivable": "1100",
+		"revenue_by_code": {"api_calls": "4000"},
+		"revenue_by_fee_type": {"subscription": "4200"}
+	}`))
+	c.Assert(err, qt.IsNil)
+	c.Assert(mapping.RevenueByFeeType[lago.FeeItemSubscription], qt.Equals, "4200")
+
+	_, err = journal.LoadMapping(strings.NewReader(`{"receivables": "1100"}`))
+	c.Assert(err, qt.ErrorMatches, `journal: mapping: json: unknown field "receivables"`)
+}
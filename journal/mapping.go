@@ -0,0 +1,86 @@
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Default accounts, used for every account left empty in a Mapping.
+const (
+	DefaultReceivable      = "accounts_receivable"
+	DefaultRevenue         = "revenue"
+	DefaultDiscounts       = "discounts"
+	DefaultTaxPayable      = "tax_payable"
+	DefaultPrepaidCredits  = "prepaid_credits"
+	DefaultCustomerCredits = "customer_credits"
+	DefaultRefunds         = "refunds_payable"
+	DefaultCash            = "cash"
+	DefaultGrantedCredits  = "granted_credits_expense"
+	DefaultExpiredCredits  = "expired_credits_income"
+)
+
+// Mapping assigns general ledger accounts to the amounts of Lago documents.
+// Its zero value posts everything to the default accounts.
+//
+// Revenue of a fee goes to the first account found in RevenueByCode for the
+// code of its item (the billable metric code of a charge, the add-on code of
+// an add-on, the plan code of a subscription fee), then in RevenueByFeeType,
+// then to Revenue.
+type Mapping struct {
+	Receivable      string `json:"receivable,omitempty"`
+	Revenue         string `json:"revenue,omitempty"`
+	Discounts       string `json:"discounts,omitempty"`
+	TaxPayable      string `json:"tax_payable,omitempty"`
+	PrepaidCredits  string `json:"prepaid_credits,omitempty"`
+	CustomerCredits string `json:"customer_credits,omitempty"`
+	Refunds         string `json:"refunds,omitempty"`
+	Cash            string `json:"cash,omitempty"`
+	GrantedCredits  string `json:"granted_credits,omitempty"`
+	ExpiredCredits  string `json:"expired_credits,omitempty"`
+
+	RevenueByCode    map[string]string       `json:"revenue_by_code,omitempty"`
+	RevenueByFeeType map[lago.FeeType]string `json:"revenue_by_fee_type,omitempty"`
+	// TaxPayableByCode maps tax codes to accounts, defaulting to TaxPayable.
+	TaxPayableByCode map[string]string `json:"tax_payable_by_code,omitempty"`
+}
+
+// LoadMapping reads a Mapping from its JSON representation.
+func LoadMapping(r io.Reader) (*Mapping, error) {
+	var mapping Mapping
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&mapping); err != nil {
+		return nil, fmt.Errorf("journal: mapping: %w", err)
+	}
+	return &mapping, nil
+}
+
+func (m *Mapping) account(account, fallback string) string {
+	if account == "" {
+		return fallback
+	}
+	return account
+}
+
+func (m *Mapping) feeAccount(fee *lago.Fee) string {
+	if fee.Item.Type == lago.FeeItemCredit {
+		return m.account(m.PrepaidCredits, DefaultPrepaidCredits)
+	}
+	if account, ok := m.RevenueByCode[fee.Item.Code]; ok && fee.Item.Code != "" {
+		return account
+	}
+	if account, ok := m.RevenueByFeeType[fee.Item.Type]; ok {
+		return account
+	}
+	return m.account(m.Revenue, DefaultRevenue)
+}
+
+func (m *Mapping) taxAccount(code string) string {
+	if account, ok := m.TaxPayableByCode[code]; ok {
+		return account
+	}
+	return m.account(m.TaxPayable, DefaultTaxPayable)
+}
@@ -0,0 +1,85 @@
+package journal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Lines returns the lines of every entry, in order.
+func (j *Journal) Lines() []Line {
+	var lines []Line
+	for _, entry := range j.Entries {
+		lines = append(lines, entry.Lines...)
+	}
+	return lines
+}
+
+// WriteJSON writes the journal as a JSON document of the form
+//
+//	{"from": "2026-01-01", "to": "2026-01-31", "entries": [{
+//		"id": "invoice:...", "date": "2026-01-05",
+//		"source_type": "invoice", "source_id": "...", "reference": "INV-001",
+//		"external_customer_id": "...", "currency": "EUR", "description": "...",
+//		"lines": [{"account": "accounts_receivable", "debit_cents": 1200, "credit_cents": 0}]
+//	}]}
+func (j *Journal) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(j)
+}
+
+var csvHeader = []string{
+	"entry_id", "date", "source_type", "source_id", "reference", "external_customer_id",
+	"account", "debit", "credit", "currency", "description",
+}
+
+// WriteCSV writes one row per line, with amounts in major units ("12.00")
+// and the line description falling back to the entry description.
+func (j *Journal) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, entry := range j.Entries {
+		for _, line := range entry.Lines {
+			description := line.Description
+			if description == "" {
+				description = entry.Description
+			}
+			err := writer.Write([]string{
+				entry.ID,
+				entry.Date,
+				string(entry.SourceType),
+				entry.SourceID,
+				entry.Reference,
+				entry.ExternalCustomerID,
+				line.Account,
+				major(line.Debit, entry.Currency),
+				major(line.Credit, entry.Currency),
+				string(entry.Currency),
+				description,
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func major(cents int64, currency lago.Currency) string {
+	if cents == 0 {
+		return ""
+	}
+	if currency == "" {
+		return strconv.FormatInt(cents, 10)
+	}
+	return lago.NewMoney(cents, currency).Major()
+}
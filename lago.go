@@ -3,7 +3,9 @@ package lago
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
+	"sync"
 	"time"
 
 	"github.com/go-resty/resty/v2"
@@ -15,23 +17,35 @@ import (
 //
 // On a non-429 response, if the RetryPolicy.OnRateLimitInfo callback is set,
 // the parsed x-ratelimit-* headers are delivered to it for observability.
-func (c *Client) executeWithRetry(ctx context.Context, fn func() (*resty.Response, error)) (*resty.Response, error) {
+//
+// Per-call RequestOptions carried by ctx are honoured: the timeout bounds all
+// attempts, WithoutRetry disables retries and response hooks receive the final
+// response.
+func (c *Client) executeWithRetry(ctx context.Context, fn func(ctx context.Context) (*resty.Response, error)) (*resty.Response, error) {
+	opts := requestOptionsFromContext(ctx)
+	ctx, cancel := withRequestTimeout(ctx)
+	defer cancel()
+
 	for attempt := 0; ; attempt++ {
-		resp, err := fn()
+		resp, err := fn(ctx)
 		if err != nil {
+			opts.emitResponse(resp, attempt+1)
 			return resp, err
 		}
 
 		// If not rate limited, emit observability info and return
 		if resp.StatusCode() != 429 {
 			c.emitRateLimitInfo(resp)
+			opts.emitResponse(resp, attempt+1)
 			return resp, nil
 		}
 
 		// Rate limited but retries disabled or max attempts reached: return as-is
-		if c.RetryPolicy == nil ||
+		if opts.disableRetry ||
+			c.RetryPolicy == nil ||
 			!c.RetryPolicy.EnableRetry ||
 			attempt >= c.RetryPolicy.MaxAttempts-1 {
+			opts.emitResponse(resp, attempt+1)
 			return resp, nil
 		}
 
@@ -43,11 +57,31 @@ func (c *Client) executeWithRetry(ctx context.Context, fn func() (*resty.Respons
 			// continue to next attempt
 		case <-ctx.Done():
 			timer.Stop()
+			opts.emitResponse(resp, attempt+1)
 			return resp, ctx.Err()
 		}
 	}
 }
 
+// newRequest builds a request bound to ctx, with the per-call headers and API
+// key override applied.
+func (c *Client) newRequest(ctx context.Context, httpClient *resty.Client) *resty.Request {
+	opts := requestOptionsFromContext(ctx)
+
+	request := httpClient.R().
+		SetContext(ctx).
+		SetError(&Error{})
+
+	for key, value := range opts.headers {
+		request.SetHeader(key, value)
+	}
+	if opts.apiKey != "" {
+		request.SetAuthToken(opts.apiKey)
+	}
+
+	return request
+}
+
 // emitRateLimitInfo invokes the configured OnRateLimitInfo callback (if any)
 // with parsed x-ratelimit-* headers from the given response.
 func (c *Client) emitRateLimitInfo(resp *resty.Response) {
@@ -77,6 +111,9 @@ type Client struct {
 	HttpClient       *resty.Client
 	IngestHttpClient *resty.Client
 	RetryPolicy      *RetryPolicy
+
+	documentsOnce sync.Once
+	documents     *DocumentService
 }
 
 type ClientRequest struct {
@@ -97,14 +134,25 @@ type Metadata struct {
 }
 
 func New() *Client {
+	return newClient(resty.New(), resty.New())
+}
+
+// NewWithHTTPClient creates a Client whose requests go through the given
+// http.Client. Use it to share a transport (and its connection pool) between
+// several clients, or to plug in a custom RoundTripper.
+func NewWithHTTPClient(httpClient *http.Client) *Client {
+	return newClient(resty.NewWithClient(httpClient), resty.NewWithClient(httpClient))
+}
+
+func newClient(restyClient *resty.Client, ingestRestyClient *resty.Client) *Client {
 	url := fmt.Sprintf("%s%s", baseURL, apiPath)
 
-	restyClient := resty.New().
+	restyClient.
 		SetBaseURL(url).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("User-Agent", "lago-go-client github.com/getlago/lago-go-client/v1")
 
-	ingestRestyClient := resty.New().
+	ingestRestyClient.
 		SetBaseURL(url).
 		SetHeader("Content-Type", "application/json").
 		SetHeader("User-Agent", "lago-go-client github.com/getlago/lago-go-client/v1")
@@ -205,10 +253,8 @@ func (c *Client) handleErrorResponse(resp *resty.Response) *Error {
 func (c *Client) Get(ctx context.Context, cr *ClientRequest) (interface{}, *Error) {
 	hasResult := cr.Result != nil
 
-	resp, retryErr := c.executeWithRetry(ctx, func() (*resty.Response, error) {
-		request := c.HttpClient.R().
-			SetContext(ctx).
-			SetError(&Error{}).
+	resp, retryErr := c.executeWithRetry(ctx, func(ctx context.Context) (*resty.Response, error) {
+		request := c.newRequest(ctx, c.HttpClient).
 			SetQueryParams(cr.QueryParams).
 			SetQueryParamsFromValues(cr.UrlValues)
 
@@ -244,10 +290,8 @@ func (c *Client) Patch(ctx context.Context, cr *ClientRequest) (interface{}, *Er
 		httpClient = c.IngestHttpClient
 	}
 
-	resp, retryErr := c.executeWithRetry(ctx, func() (*resty.Response, error) {
-		return httpClient.R().
-			SetContext(ctx).
-			SetError(&Error{}).
+	resp, retryErr := c.executeWithRetry(ctx, func(ctx context.Context) (*resty.Response, error) {
+		return c.newRequest(ctx, httpClient).
 			SetResult(cr.Result).
 			SetBody(cr.Body).
 			SetQueryParams(cr.QueryParams).
@@ -275,10 +319,8 @@ func (c *Client) Post(ctx context.Context, cr *ClientRequest) (interface{}, *Err
 		httpClient = c.IngestHttpClient
 	}
 
-	resp, retryErr := c.executeWithRetry(ctx, func() (*resty.Response, error) {
-		return httpClient.R().
-			SetContext(ctx).
-			SetError(&Error{}).
+	resp, retryErr := c.executeWithRetry(ctx, func(ctx context.Context) (*resty.Response, error) {
+		return c.newRequest(ctx, httpClient).
 			SetResult(cr.Result).
 			SetBody(cr.Body).
 			SetQueryParams(cr.QueryParams).
@@ -301,10 +343,8 @@ func (c *Client) Post(ctx context.Context, cr *ClientRequest) (interface{}, *Err
 }
 
 func (c *Client) PostWithoutResult(ctx context.Context, cr *ClientRequest) *Error {
-	resp, retryErr := c.executeWithRetry(ctx, func() (*resty.Response, error) {
-		request := c.HttpClient.R().
-			SetContext(ctx).
-			SetError(&Error{})
+	resp, retryErr := c.executeWithRetry(ctx, func(ctx context.Context) (*resty.Response, error) {
+		request := c.newRequest(ctx, c.HttpClient)
 
 		if cr.Body != nil {
 			request.SetBody(cr.Body)
@@ -329,10 +369,8 @@ func (c *Client) PostWithoutResult(ctx context.Context, cr *ClientRequest) *Erro
 }
 
 func (c *Client) PostWithoutBody(ctx context.Context, cr *ClientRequest) (interface{}, *Error) {
-	resp, retryErr := c.executeWithRetry(ctx, func() (*resty.Response, error) {
-		return c.HttpClient.R().
-			SetContext(ctx).
-			SetError(&Error{}).
+	resp, retryErr := c.executeWithRetry(ctx, func(ctx context.Context) (*resty.Response, error) {
+		return c.newRequest(ctx, c.HttpClient).
 			SetResult(cr.Result).
 			Post(cr.Path)
 	})
@@ -353,10 +391,8 @@ func (c *Client) PostWithoutBody(ctx context.Context, cr *ClientRequest) (interf
 }
 
 func (c *Client) Put(ctx context.Context, cr *ClientRequest) (interface{}, *Error) {
-	resp, retryErr := c.executeWithRetry(ctx, func() (*resty.Response, error) {
-		return c.HttpClient.R().
-			SetContext(ctx).
-			SetError(&Error{}).
+	resp, retryErr := c.executeWithRetry(ctx, func(ctx context.Context) (*resty.Response, error) {
+		return c.newRequest(ctx, c.HttpClient).
 			SetResult(cr.Result).
 			SetBody(cr.Body).
 			SetQueryParams(cr.QueryParams).
@@ -381,10 +417,8 @@ func (c *Client) Put(ctx context.Context, cr *ClientRequest) (interface{}, *Erro
 func (c *Client) Delete(ctx context.Context, cr *ClientRequest) (interface{}, *Error) {
 	hasResult := cr.Result != nil
 
-	resp, retryErr := c.executeWithRetry(ctx, func() (*resty.Response, error) {
-		request := c.HttpClient.R().
-			SetContext(ctx).
-			SetError(&Error{}).
+	resp, retryErr := c.executeWithRetry(ctx, func(ctx context.Context) (*resty.Response, error) {
+		request := c.newRequest(ctx, c.HttpClient).
 			SetBody(cr.Body).
 			SetQueryParams(cr.QueryParams)
 
@@ -0,0 +1,170 @@
+package migrate
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Status is the outcome of the migration of a subscription.
+type Status string
+
+const (
+	// StatusMigrated subscriptions were moved to the target plan.
+	StatusMigrated Status = "migrated"
+	// StatusSkipped subscriptions were already on the target plan.
+	StatusSkipped Status = "skipped"
+	// StatusFailed subscriptions were left unchanged by an error.
+	StatusFailed Status = "failed"
+	// StatusPlanned subscriptions would be migrated, in dry-run mode.
+	StatusPlanned Status = "planned"
+	// StatusRolledBack subscriptions were moved back to their previous plan.
+	StatusRolledBack Status = "rolled_back"
+	// StatusRollbackFailed subscriptions could not be moved back.
+	StatusRollbackFailed Status = "rollback_failed"
+)
+
+// Entry is a line of the journal: the state of a subscription before and
+// after a change.
+type Entry struct {
+	Time               time.Time          `json:"time"`
+	ExternalID         string             `json:"external_id"`
+	ExternalCustomerID string             `json:"external_customer_id"`
+	Status             Status             `json:"status"`
+	Before             *lago.Subscription `json:"before,omitempty"`
+	After              *lago.Subscription `json:"after,omitempty"`
+	Error              string             `json:"error,omitempty"`
+}
+
+// ReadJournal reads the entries of a journal file, oldest first. A missing
+// file is an empty journal. An unreadable last line, left by a run
+// interrupted while writing it, is ignored; other unreadable lines are
+// errors.
+func ReadJournal(path string) ([]Entry, error) {
+	entries, _, err := readJournal(path)
+	return entries, err
+}
+
+// readJournal reads a journal like ReadJournal and returns the size of its
+// valid part, which excludes an unreadable last line.
+func readJournal(path string) ([]Entry, int64, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	var (
+		entries      []Entry
+		offset, size int64
+		corrupt      error
+	)
+	reader := bufio.NewReader(file)
+	for line := 1; ; line++ {
+		data, readErr := reader.ReadBytes('\n')
+		offset += int64(len(data))
+		if data = bytes.TrimSpace(data); len(data) > 0 {
+			if corrupt != nil {
+				return entries, 0, corrupt
+			}
+			var entry Entry
+			if err := json.Unmarshal(data, &entry); err != nil {
+				corrupt = fmt.Errorf("migrate: journal %s line %d: %w", path, line, err)
+			} else {
+				entries = append(entries, entry)
+				size = offset
+			}
+		}
+		if readErr == io.EOF {
+			return entries, size, nil
+		}
+		if readErr != nil {
+			return entries, 0, readErr
+		}
+	}
+}
+
+// latest returns the last entry of every subscription of a journal.
+func latest(entries []Entry) map[string]Entry {
+	last := make(map[string]Entry, len(entries))
+	for _, entry := range entries {
+		last[entry.ExternalID] = entry
+	}
+	return last
+}
+
+// journal appends entries to a file as JSON lines, one write per entry so
+// that an interruption loses at most the entry being written.
+type journal struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// openJournal opens a journal whose valid part is size bytes long, dropping
+// what follows so that new entries are not appended to a truncated line.
+func openJournal(path string, size int64) (*journal, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("migrate: journal: %w", err)
+	}
+	j := &journal{file: file}
+	if err := j.truncate(size); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("migrate: journal: %w", err)
+	}
+	return j, nil
+}
+
+// truncate cuts the file to size bytes, ending with a newline, and moves to
+// its end.
+func (j *journal) truncate(size int64) error {
+	if err := j.file.Truncate(size); err != nil {
+		return err
+	}
+	if _, err := j.file.Seek(size, io.SeekStart); err != nil {
+		return err
+	}
+	if size == 0 {
+		return nil
+	}
+	last := make([]byte, 1)
+	if _, err := j.file.ReadAt(last, size-1); err != nil {
+		return err
+	}
+	if last[0] != '\n' {
+		_, err := j.file.Write([]byte{'\n'})
+		return err
+	}
+	return nil
+}
+
+func (j *journal) write(entry Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := j.file.Write(data); err != nil {
+		return fmt.Errorf("migrate: journal: %w", err)
+	}
+	return nil
+}
+
+func (j *journal) Close() error {
+	return j.file.Close()
+}
@@ -0,0 +1,462 @@
+// Package migrate moves subscriptions in bulk from one plan to another, or
+// changes their plan overrides, with a journal of every change to resume an
+// interrupted run or roll it back.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// DefaultConcurrency is the number of subscriptions migrated at once by
+// default.
+const DefaultConcurrency = 4
+
+const perPage = 100
+
+// ErrNoJournal is returned when a migration is run without journal.
+var ErrNoJournal = errors.New("migrate: a journal is required")
+
+// Migration selects subscriptions and the change to make to them.
+type Migration struct {
+	// Select filters the subscriptions to migrate, typically by plan code and
+	// status. Its pagination is ignored.
+	Select lago.SubscriptionListInput
+	// PlanCode is the target plan. When empty or equal to the current plan,
+	// only the plan overrides are changed.
+	PlanCode string
+	// PlanOverrides are applied to the target plan.
+	PlanOverrides *lago.PlanOverridesInput
+}
+
+// Options configures Run and Rollback.
+type Options struct {
+	// Journal is the path of the journal file, appended to by every run. It
+	// is required unless in dry-run mode.
+	Journal string
+	// Concurrency is the number of subscriptions migrated at once. It
+	// defaults to DefaultConcurrency.
+	Concurrency int
+	// DryRun only reports the subscriptions to migrate, nothing is sent to
+	// Lago nor written to the journal.
+	DryRun bool
+	// Log receives one line per subscription. It may be nil.
+	Log io.Writer
+}
+
+// Report summarizes a run.
+type Report struct {
+	// Selected is the number of subscriptions selected.
+	Selected int
+	// Resumed is the number of subscriptions already handled by a previous
+	// run of the journal, and left alone.
+	Resumed int
+	// Migrated is the number of subscriptions changed, or moved back by
+	// Rollback.
+	Migrated int
+	Skipped  int
+	Planned  int
+	Failed   int
+	// Failures are the entries of the subscriptions that failed.
+	Failures []Entry
+	Duration time.Duration
+}
+
+// String returns a one-line summary of the report.
+func (r *Report) String() string {
+	return fmt.Sprintf("%d selected, %d resumed, %d migrated, %d skipped, %d planned, %d failed in %s",
+		r.Selected, r.Resumed, r.Migrated, r.Skipped, r.Planned, r.Failed, r.Duration.Round(time.Millisecond))
+}
+
+func (r *Report) add(entry Entry) {
+	switch entry.Status {
+	case StatusMigrated, StatusRolledBack:
+		r.Migrated++
+	case StatusSkipped:
+		r.Skipped++
+	case StatusPlanned:
+		r.Planned++
+	case StatusFailed, StatusRollbackFailed:
+		r.Failed++
+		r.Failures = append(r.Failures, entry)
+	}
+}
+
+// Run migrates the selected subscriptions, a few at a time, and journals the
+// state of every subscription before and after its change.
+//
+// Subscriptions are moved to another plan the way Lago changes plans, by
+// creating a subscription with the same external ID: upgrades apply
+// immediately and downgrades at the end of the current period. Changes of
+// overrides only update the subscription.
+//
+// Subscriptions migrated or skipped by a previous run of the same journal
+// are left alone, so an interrupted run is resumed by running it again;
+// failed ones are retried. All calls are paused when the rate limit is
+// exhausted. Calls rejected by it are retried by the client when its retry
+// policy enables retries, or else by Run up to the attempts of the policy,
+// after the window resets or the backoff of the policy.
+func Run(ctx context.Context, client *lago.Client, migration Migration, opts Options) (*Report, error) {
+	start := time.Now()
+	r, err := newRunner(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.close()
+
+	subscriptions, err := selectSubscriptions(ctx, client, migration.Select)
+	if err != nil {
+		return nil, err
+	}
+	report := &Report{Selected: len(subscriptions)}
+
+	var todo []lago.Subscription
+	for _, sub := range subscriptions {
+		if entry, ok := r.previous[sub.ExternalID]; ok && (entry.Status == StatusMigrated || entry.Status == StatusSkipped) {
+			report.Resumed++
+			continue
+		}
+		todo = append(todo, sub)
+	}
+
+	err = r.each(ctx, todo, report, func(ctx context.Context, sub *lago.Subscription) Entry {
+		return r.migrate(ctx, sub, migration)
+	})
+	report.Duration = time.Since(start)
+	return report, err
+}
+
+// Rollback moves the subscriptions migrated in a journal back to the plan
+// code, amount and trial period they had before, and journals it. Other
+// overrides, such as those of charges, are not restored.
+func Rollback(ctx context.Context, client *lago.Client, opts Options) (*Report, error) {
+	start := time.Now()
+	r, err := newRunner(client, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer r.close()
+
+	var todo []lago.Subscription
+	seen := map[string]bool{}
+	for _, entry := range r.entries {
+		last := r.previous[entry.ExternalID]
+		if seen[entry.ExternalID] || last.Before == nil || (last.Status != StatusMigrated && last.Status != StatusRollbackFailed) {
+			continue
+		}
+		seen[entry.ExternalID] = true
+		todo = append(todo, *last.Before)
+	}
+	report := &Report{Selected: len(todo)}
+
+	err = r.each(ctx, todo, report, r.rollback)
+	report.Duration = time.Since(start)
+	return report, err
+}
+
+// runner runs changes on subscriptions concurrently.
+type runner struct {
+	client   *lago.Client
+	opts     Options
+	journal  *journal
+	entries  []Entry
+	previous map[string]Entry
+	// migrated is the last StatusMigrated entry of every subscription.
+	migrated map[string]Entry
+	throttle throttle
+	logMu    sync.Mutex
+}
+
+func newRunner(client *lago.Client, opts Options) (*runner, error) {
+	if opts.Journal == "" && !opts.DryRun {
+		return nil, ErrNoJournal
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	r := &runner{client: client, opts: opts}
+
+	var size int64
+	if opts.Journal != "" {
+		entries, valid, err := readJournal(opts.Journal)
+		if err != nil {
+			return nil, err
+		}
+		r.entries, r.previous, size = entries, latest(entries), valid
+		r.migrated = map[string]Entry{}
+		for _, entry := range entries {
+			if entry.Status == StatusMigrated {
+				r.migrated[entry.ExternalID] = entry
+			}
+		}
+	}
+	if !opts.DryRun {
+		journal, err := openJournal(opts.Journal, size)
+		if err != nil {
+			return nil, err
+		}
+		r.journal = journal
+	}
+	return r, nil
+}
+
+func (r *runner) close() {
+	if r.journal != nil {
+		r.journal.Close()
+	}
+}
+
+// each runs change on the subscriptions with the configured concurrency,
+// stopping early when ctx is done or the journal cannot be written.
+func (r *runner) each(ctx context.Context, subscriptions []lago.Subscription, report *Report, change func(context.Context, *lago.Subscription) Entry) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	queue := make(chan *lago.Subscription)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	for i := 0; i < r.opts.Concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for sub := range queue {
+				entry := change(ctx, sub)
+				if ctx.Err() != nil && entry.Status != StatusMigrated && entry.Status != StatusRolledBack {
+					// Left for the next run.
+					continue
+				}
+				entry.Time = time.Now().UTC()
+
+				mu.Lock()
+				report.add(entry)
+				mu.Unlock()
+				r.log(entry)
+				if r.journal != nil && entry.Status != StatusPlanned {
+					if err := r.journal.write(entry); err != nil {
+						mu.Lock()
+						if firstErr == nil {
+							firstErr = err
+						}
+						mu.Unlock()
+						cancel()
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range subscriptions {
+		select {
+		case queue <- &subscriptions[i]:
+			continue
+		case <-ctx.Done():
+		}
+		break
+	}
+	close(queue)
+	wg.Wait()
+
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+func (r *runner) migrate(ctx context.Context, sub *lago.Subscription, migration Migration) Entry {
+	entry := Entry{ExternalID: sub.ExternalID, ExternalCustomerID: sub.ExternalCustomerID, Before: sub}
+
+	planCode := migration.PlanCode
+	if planCode == "" {
+		planCode = sub.PlanCode
+	}
+	switch {
+	case planCode == sub.PlanCode && migration.PlanOverrides == nil:
+		entry.Status = StatusSkipped
+		return entry
+	case r.opts.DryRun:
+		entry.Status = StatusPlanned
+		return entry
+	}
+
+	input := &lago.SubscriptionInput{
+		ExternalCustomerID: sub.ExternalCustomerID,
+		ExternalID:         sub.ExternalID,
+		PlanCode:           planCode,
+		PlanOverrides:      migration.PlanOverrides,
+		Name:               sub.Name,
+	}
+	return r.apply(ctx, entry, input, planCode != sub.PlanCode, StatusMigrated, StatusFailed)
+}
+
+func (r *runner) rollback(ctx context.Context, before *lago.Subscription) Entry {
+	entry := Entry{ExternalID: before.ExternalID, ExternalCustomerID: before.ExternalCustomerID, Before: before}
+	// A failed rollback has no After: the plan is the one migrated to.
+	migrated := r.migrated[before.ExternalID]
+	if r.opts.DryRun {
+		entry.Status = StatusPlanned
+		return entry
+	}
+
+	input := &lago.SubscriptionInput{
+		ExternalCustomerID: before.ExternalCustomerID,
+		ExternalID:         before.ExternalID,
+		PlanCode:           before.PlanCode,
+		Name:               before.Name,
+	}
+	changePlan := migrated.After == nil || migrated.After.PlanCode != before.PlanCode
+	if !changePlan {
+		input.PlanOverrides = &lago.PlanOverridesInput{
+			AmountCents:    before.PlanAmountCents,
+			AmountCurrency: before.PlanAmountCurrency,
+		}
+		if before.Plan != nil {
+			input.PlanOverrides.TrialPeriod = before.Plan.TrialPeriod
+		}
+	}
+	return r.apply(ctx, entry, input, changePlan, StatusRolledBack, StatusRollbackFailed)
+}
+
+// apply creates the subscription when changing its plan, or updates it, and
+// completes entry with the outcome.
+func (r *runner) apply(ctx context.Context, entry Entry, input *lago.SubscriptionInput, changePlan bool, ok, failed Status) Entry {
+	policy := r.client.RetryPolicy
+	if policy == nil {
+		policy = lago.DefaultRetryPolicy()
+	}
+
+	for attempt := 0; ; attempt++ {
+		if err := r.throttle.wait(ctx); err != nil {
+			entry.Status, entry.Error = failed, err.Error()
+			return entry
+		}
+
+		var meta lago.ResponseMeta
+		callCtx := lago.WithRequestOptions(ctx, lago.WithResponseMeta(&meta))
+		var sub *lago.Subscription
+		var lagoErr *lago.Error
+		if changePlan {
+			sub, lagoErr = r.client.Subscription().Create(callCtx, input)
+		} else {
+			sub, lagoErr = r.client.Subscription().Update(callCtx, input)
+		}
+		r.throttle.observe(meta.RateLimit)
+
+		if lagoErr == nil {
+			entry.Status, entry.After = ok, sub
+			return entry
+		}
+		// A client retrying calls rejected by the rate limit has already
+		// made every attempt; otherwise they are retried here, pausing
+		// every call in the meantime.
+		var rlErr *lago.RateLimitError
+		if errors.As(lagoErr.Err, &rlErr) && !policy.EnableRetry && attempt < policy.MaxAttempts-1 {
+			r.throttle.pause(retryDelay(rlErr, policy, attempt))
+			continue
+		}
+		entry.Status, entry.Error = failed, lagoErr.Error()
+		return entry
+	}
+}
+
+func (r *runner) log(entry Entry) {
+	if r.opts.Log == nil {
+		return
+	}
+	r.logMu.Lock()
+	defer r.logMu.Unlock()
+
+	line := fmt.Sprintf("%s %s", entry.Status, entry.ExternalID)
+	if entry.Before != nil && entry.After != nil {
+		line += fmt.Sprintf(" %s -> %s", entry.Before.PlanCode, entry.After.PlanCode)
+	}
+	if entry.Error != "" {
+		line += ": " + entry.Error
+	}
+	fmt.Fprintln(r.opts.Log, line)
+}
+
+// selectSubscriptions lists every selected subscription before any is
+// migrated, as migrating them moves them out of the pages of the selection.
+func selectSubscriptions(ctx context.Context, client *lago.Client, input lago.SubscriptionListInput) ([]lago.Subscription, error) {
+	subscriptions, err := lago.FetchPages(0, func(page int) ([]lago.Subscription, lago.Metadata, *lago.Error) {
+		input.Page, input.PerPage = lago.Ptr(page), lago.Ptr(perPage)
+		result, err := client.Subscription().GetList(ctx, input)
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.Subscriptions, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("migrate: subscriptions: %w", err)
+	}
+	return subscriptions, nil
+}
+
+// throttle pauses every call until the rate limit window resets once it is
+// exhausted.
+type throttle struct {
+	mu    sync.Mutex
+	until time.Time
+}
+
+func (t *throttle) observe(info *lago.RateLimitInfo) {
+	if info == nil || info.Remaining == nil || *info.Remaining > 0 || info.Reset == nil {
+		return
+	}
+	t.pause(time.Duration(*info.Reset) * time.Second)
+}
+
+// pause holds every call for at least delay.
+func (t *throttle) pause(delay time.Duration) {
+	until := time.Now().Add(delay)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until.After(t.until) {
+		t.until = until
+	}
+}
+
+// retryDelay is the time to wait before retrying a call rejected by the rate
+// limit: until the window resets when the API tells, or else the backoff of
+// policy for attempt.
+func retryDelay(rlErr *lago.RateLimitError, policy *lago.RetryPolicy, attempt int) time.Duration {
+	if rlErr.Reset != nil && *rlErr.Reset > 0 {
+		return time.Duration(*rlErr.Reset) * time.Second
+	}
+	delay := float64(policy.InitialBackoff)
+	for range attempt {
+		delay *= policy.BackoffMultiplier
+	}
+	if policy.MaxRetryDelay > 0 && delay > float64(policy.MaxRetryDelay) {
+		delay = float64(policy.MaxRetryDelay)
+	}
+	return time.Duration(delay * float64(time.Second))
+}
+
+func (t *throttle) wait(ctx context.Context) error {
+	t.mu.Lock()
+	delay := time.Until(t.until)
+	t.mu.Unlock()
+	if delay <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
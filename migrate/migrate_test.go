@@ -0,0 +1,312 @@
+package migrate_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/migrate"
+)
+
+// server fakes the subscriptions of Lago: three on the legacy plan, listed
+// two per page, and one on the new plan.
+type server struct {
+	mu            sync.Mutex
+	plans         map[string]string
+	fail          map[string]bool
+	limited       map[string]int
+	rejected      int
+	creates       []string
+	updates       []string
+	listPlanCodes []string
+}
+
+func newServer(c *qt.C) (*lago.Client, *server) {
+	s := &server{
+		plans:   map[string]string{"sub_1": "legacy", "sub_2": "legacy", "sub_3": "legacy", "sub_4": "new"},
+		fail:    map[string]bool{},
+		limited: map[string]int{},
+	}
+	httpServer := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	c.Cleanup(httpServer.Close)
+
+	return lago.New().SetBaseURL(httpServer.URL).SetApiKey("k"), s
+}
+
+func (s *server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	path := strings.TrimPrefix(r.URL.Path, "/api/v1")
+	switch {
+	case r.Method == http.MethodGet && path == "/subscriptions":
+		planCode := r.URL.Query().Get("plan_code")
+		s.listPlanCodes = append(s.listPlanCodes, planCode)
+		var ids []string
+		for _, id := range []string{"sub_1", "sub_2", "sub_3", "sub_4"} {
+			if planCode == "" || s.plans[id] == planCode {
+				ids = append(ids, id)
+			}
+		}
+		page := 1
+		fmt.Sscan(r.URL.Query().Get("page"), &page)
+		from, to := min((page-1)*2, len(ids)), min(page*2, len(ids))
+		next := 0
+		if to < len(ids) {
+			next = page + 1
+		}
+		subscriptions := []string{}
+		for _, id := range ids[from:to] {
+			subscriptions = append(subscriptions, s.subscription(id))
+		}
+		fmt.Fprintf(w, `{"subscriptions": [%s], "meta": {"current_page": %d, "next_page": %d}}`, strings.Join(subscriptions, ","), page, next)
+	case r.Method == http.MethodPost && path == "/subscriptions", r.Method == http.MethodPut:
+		var body struct {
+			Subscription lago.SubscriptionInput `json:"subscription"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		input := body.Subscription
+		if s.limited[input.ExternalID] > 0 {
+			s.limited[input.ExternalID]--
+			s.rejected++
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"status": 429, "error": "Too Many Requests"}`))
+			return
+		}
+		if s.fail[input.ExternalID] {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			w.Write([]byte(`{"status": 422, "error": "Unprocessable Entity", "code": "validation_errors"}`))
+			return
+		}
+		if r.Method == http.MethodPost {
+			s.creates = append(s.creates, input.ExternalID+" "+input.PlanCode)
+			s.plans[input.ExternalID] = input.PlanCode
+		} else {
+			s.updates = append(s.updates, fmt.Sprintf("%s %d", input.ExternalID, input.PlanOverrides.AmountCents))
+		}
+		fmt.Fprintf(w, `{"subscription": %s}`, s.subscription(input.ExternalID))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"status": 404, "error": "Not Found"}`))
+	}
+}
+
+func (s *server) subscription(id string) string {
+	return fmt.Sprintf(`{"external_id": %q, "external_customer_id": "cus_%s", "name": "Main", "plan_code": %q, "plan_amount_cents": 1000, "plan_amount_currency": "EUR"}`,
+		id, id, s.plans[id])
+}
+
+func (s *server) calls() ([]string, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	creates, updates := append([]string(nil), s.creates...), append([]string(nil), s.updates...)
+	s.creates, s.updates = nil, nil
+	return creates, updates
+}
+
+var legacy = migrate.Migration{
+	Select:   lago.SubscriptionListInput{PlanCode: "legacy", Status: []lago.SubscriptionStatus{lago.SubscriptionStatusActive}},
+	PlanCode: "new",
+}
+
+func sorted(values []string) []string {
+	sort.Strings(values)
+	return values
+}
+
+func TestRun(t *testing.T) {
+	c := qt.New(t)
+	client, s := newServer(c)
+	s.fail["sub_3"] = true
+	journal := filepath.Join(c.TempDir(), "journal.jsonl")
+
+	var log bytes.Buffer
+	report, err := migrate.Run(context.Background(), client, legacy, migrate.Options{Journal: journal, Concurrency: 2, Log: &log})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Selected, qt.Equals, 3)
+	c.Assert(report.Migrated, qt.Equals, 2)
+	c.Assert(report.Failed, qt.Equals, 1)
+	c.Assert(report.Failures[0].ExternalID, qt.Equals, "sub_3")
+	c.Assert(report.Failures[0].Error, qt.Contains, "validation_errors")
+	c.Assert(log.String(), qt.Contains, "migrated sub_1 legacy -> new\n")
+
+	// Every selected subscription is listed before any is migrated.
+	c.Assert(s.listPlanCodes, qt.DeepEquals, []string{"legacy", "legacy"})
+	creates, _ := s.calls()
+	c.Assert(sorted(creates), qt.DeepEquals, []string{"sub_1 new", "sub_2 new"})
+
+	entries, err := migrate.ReadJournal(journal)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.HasLen, 3)
+	for _, entry := range entries {
+		c.Assert(entry.Before.PlanCode, qt.Equals, "legacy")
+		if entry.Status == migrate.StatusMigrated {
+			c.Assert(entry.After.PlanCode, qt.Equals, "new")
+			c.Assert(entry.After.Name, qt.Equals, "Main")
+		}
+	}
+
+	// Resuming retries the failed subscription only, even when the selection
+	// includes the migrated ones.
+	s.fail["sub_3"] = false
+	all := legacy
+	all.Select.PlanCode = ""
+	report, err = migrate.Run(context.Background(), client, all, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Selected, qt.Equals, 4)
+	c.Assert(report.Resumed, qt.Equals, 2)
+	c.Assert(report.Migrated, qt.Equals, 1)
+	c.Assert(report.Skipped, qt.Equals, 1)
+	c.Assert(report.String(), qt.Matches, `4 selected, 2 resumed, 1 migrated, 1 skipped, 0 planned, 0 failed in .*`)
+	creates, _ = s.calls()
+	c.Assert(creates, qt.DeepEquals, []string{"sub_3 new"})
+
+	// Rolling back moves every migrated subscription back, once.
+	report, err = migrate.Rollback(context.Background(), client, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Selected, qt.Equals, 3)
+	c.Assert(report.Migrated, qt.Equals, 3)
+	creates, _ = s.calls()
+	c.Assert(sorted(creates), qt.DeepEquals, []string{"sub_1 legacy", "sub_2 legacy", "sub_3 legacy"})
+
+	report, err = migrate.Rollback(context.Background(), client, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Selected, qt.Equals, 0)
+}
+
+func TestRun_RateLimited(t *testing.T) {
+	c := qt.New(t)
+	client, s := newServer(c)
+	migration := migrate.Migration{
+		Select:        lago.SubscriptionListInput{PlanCode: "new"},
+		PlanOverrides: &lago.PlanOverridesInput{AmountCents: 1500},
+	}
+
+	// Without retries in the client, the runner retries after the backoff.
+	client.SetRetryPolicy(&lago.RetryPolicy{MaxAttempts: 3, InitialBackoff: 0, BackoffMultiplier: 2})
+	s.limited["sub_4"] = 2
+	report, err := migrate.Run(context.Background(), client, migration, migrate.Options{Journal: filepath.Join(c.TempDir(), "journal.jsonl")})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Migrated, qt.Equals, 1)
+	c.Assert(s.rejected, qt.Equals, 2)
+
+	// An explicit policy without retries is honored.
+	client.SetRetryPolicy(nil)
+	s.limited["sub_4"], s.rejected = 1, 0
+	report, err = migrate.Run(context.Background(), client, migration, migrate.Options{Journal: filepath.Join(c.TempDir(), "journal.jsonl")})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Failed, qt.Equals, 1)
+	c.Assert(s.rejected, qt.Equals, 1)
+
+	// A client retrying itself is not retried again by the runner.
+	client.SetRetryPolicy(&lago.RetryPolicy{MaxAttempts: 2, EnableRetry: true, InitialBackoff: 0, BackoffMultiplier: 2})
+	s.limited["sub_4"], s.rejected = 5, 0
+	report, err = migrate.Run(context.Background(), client, migration, migrate.Options{Journal: filepath.Join(c.TempDir(), "journal.jsonl")})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Failed, qt.Equals, 1)
+	c.Assert(s.rejected, qt.Equals, 2)
+}
+
+func TestRun_Overrides(t *testing.T) {
+	c := qt.New(t)
+	client, s := newServer(c)
+	journal := filepath.Join(c.TempDir(), "journal.jsonl")
+
+	migration := migrate.Migration{
+		Select:        lago.SubscriptionListInput{PlanCode: "new"},
+		PlanOverrides: &lago.PlanOverridesInput{AmountCents: 1500},
+	}
+	report, err := migrate.Run(context.Background(), client, migration, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Migrated, qt.Equals, 1)
+	creates, updates := s.calls()
+	c.Assert(creates, qt.HasLen, 0)
+	c.Assert(updates, qt.DeepEquals, []string{"sub_4 1500"})
+
+	// The amount the subscription had before is restored, with an update
+	// even after a failed rollback.
+	s.fail["sub_4"] = true
+	report, err = migrate.Rollback(context.Background(), client, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Failed, qt.Equals, 1)
+	s.fail["sub_4"] = false
+	_, err = migrate.Rollback(context.Background(), client, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	creates, updates = s.calls()
+	c.Assert(creates, qt.HasLen, 0)
+	c.Assert(updates, qt.DeepEquals, []string{"sub_4 1000"})
+}
+
+func TestRun_TruncatedJournal(t *testing.T) {
+	c := qt.New(t)
+	client, s := newServer(c)
+	s.fail["sub_3"] = true
+	journal := filepath.Join(c.TempDir(), "journal.jsonl")
+
+	_, err := migrate.Run(context.Background(), client, legacy, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	s.calls()
+
+	// A run interrupted while writing an entry leaves a truncated last line.
+	file, err := os.OpenFile(journal, os.O_APPEND|os.O_WRONLY, 0)
+	c.Assert(err, qt.IsNil)
+	_, err = file.WriteString(`{"time": "2026-03-01T10:00:00Z", "external_id": "sub_`)
+	c.Assert(err, qt.IsNil)
+	c.Assert(file.Close(), qt.IsNil)
+	entries, err := migrate.ReadJournal(journal)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.HasLen, 3)
+
+	s.fail["sub_3"] = false
+	report, err := migrate.Run(context.Background(), client, legacy, migrate.Options{Journal: journal})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Migrated, qt.Equals, 1)
+	creates, _ := s.calls()
+	c.Assert(creates, qt.DeepEquals, []string{"sub_3 new"})
+
+	// The truncated line is replaced by the entries of the new run.
+	entries, err = migrate.ReadJournal(journal)
+	c.Assert(err, qt.IsNil)
+	c.Assert(entries, qt.HasLen, 4)
+	c.Assert(entries[3].ExternalID, qt.Equals, "sub_3")
+
+	// Unreadable lines before the last one are errors.
+	corrupt := filepath.Join(c.TempDir(), "corrupt.jsonl")
+	c.Assert(os.WriteFile(corrupt, []byte("{\"external_id\": \"sub_\n{\"external_id\": \"sub_1\"}\n"), 0o600), qt.IsNil)
+	_, err = migrate.ReadJournal(corrupt)
+	c.Assert(err, qt.ErrorMatches, `migrate: journal .*corrupt.jsonl line 1: .*`)
+	_, err = migrate.Run(context.Background(), client, legacy, migrate.Options{Journal: corrupt})
+	c.Assert(err, qt.ErrorMatches, `migrate: journal .*corrupt.jsonl line 1: .*`)
+}
+
+func TestRun_DryRun(t *testing.T) {
+	c := qt.New(t)
+	client, s := newServer(c)
+
+	migration := legacy
+	migration.Select.PlanCode = ""
+	report, err := migrate.Run(context.Background(), client, migration, migrate.Options{DryRun: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Selected, qt.Equals, 4)
+	c.Assert(report.Planned, qt.Equals, 3)
+	c.Assert(report.Skipped, qt.Equals, 1)
+	creates, updates := s.calls()
+	c.Assert(creates, qt.HasLen, 0)
+	c.Assert(updates, qt.HasLen, 0)
+
+	_, err = migrate.Run(context.Background(), client, legacy, migrate.Options{})
+	c.Assert(err, qt.Equals, migrate.ErrNoJournal)
+}
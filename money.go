@@ -0,0 +1,225 @@
+package lago
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+)
+
+// ErrCurrencyMismatch is returned when an operation mixes two currencies.
+var ErrCurrencyMismatch = errors.New("lago: currency mismatch")
+
+// ErrMoneyOverflow is returned when an operation overflows int64 minor units.
+var ErrMoneyOverflow = errors.New("lago: money overflow")
+
+// defaultCurrencyExponent is the ISO 4217 minor unit of most currencies.
+const defaultCurrencyExponent int = 2
+
+// currencyExponents lists the ISO 4217 minor units of currencies not using
+// two decimal places.
+var currencyExponents = map[Currency]int{
+	BIF: 0,
+	CLP: 0,
+	DJF: 0,
+	GNF: 0,
+	ISK: 0,
+	JPY: 0,
+	KMF: 0,
+	KRW: 0,
+	PYG: 0,
+	RWF: 0,
+	UGX: 0,
+	VND: 0,
+	VUV: 0,
+	XAF: 0,
+	XOF: 0,
+	XPF: 0,
+	UYI: 0,
+
+	BHD: 3,
+	IQD: 3,
+	JOD: 3,
+	KWD: 3,
+	LYD: 3,
+	OMR: 3,
+	TND: 3,
+
+	CLF: 4,
+	UYW: 4,
+}
+
+// Exponent returns the number of decimal places of the currency minor unit
+// as defined by ISO 4217 (2 for EUR, 0 for JPY, 3 for KWD, ...).
+func (c Currency) Exponent() int {
+	if exponent, ok := currencyExponents[Currency(strings.ToUpper(string(c)))]; ok {
+		return exponent
+	}
+
+	return defaultCurrencyExponent
+}
+
+// Money is an amount expressed in the minor unit of its currency, which is
+// what Lago calls "cents" in the *_cents fields.
+type Money struct {
+	Amount   int64
+	Currency Currency
+}
+
+func NewMoney(amount int64, currency Currency) Money {
+	return Money{Amount: amount, Currency: currency}
+}
+
+// ParseMoney parses an amount expressed in major units ("12.50", "-3", "1000.125")
+// for the given currency. It refuses more decimal places than the currency
+// allows, unless the extra ones are zeros ("1000.0" for JPY).
+func ParseMoney(amount string, currency Currency) (Money, error) {
+	exponent := currency.Exponent()
+
+	value := strings.TrimSpace(amount)
+	negative := false
+	switch {
+	case strings.HasPrefix(value, "-"):
+		negative = true
+		value = value[1:]
+	case strings.HasPrefix(value, "+"):
+		value = value[1:]
+	}
+
+	integerPart, fractionPart, hasFraction := strings.Cut(value, ".")
+	if integerPart == "" && fractionPart == "" || hasFraction && fractionPart == "" {
+		return Money{}, fmt.Errorf("lago: invalid amount %q", amount)
+	}
+	if len(fractionPart) > exponent {
+		if strings.TrimRight(fractionPart[exponent:], "0") != "" {
+			return Money{}, fmt.Errorf("lago: amount %q has more than %d decimal places for %s", amount, exponent, currency)
+		}
+		fractionPart = fractionPart[:exponent]
+	}
+	fractionPart += strings.Repeat("0", exponent-len(fractionPart))
+
+	var minor int64
+	for _, digit := range integerPart + fractionPart {
+		if digit < '0' || digit > '9' {
+			return Money{}, fmt.Errorf("lago: invalid amount %q", amount)
+		}
+		if minor > (math.MaxInt64-int64(digit-'0'))/10 {
+			return Money{}, ErrMoneyOverflow
+		}
+		minor = minor*10 + int64(digit-'0')
+	}
+
+	if negative {
+		minor = -minor
+	}
+
+	return Money{Amount: minor, Currency: currency}, nil
+}
+
+// Add returns m + other. Both amounts must share the same currency.
+func (m Money) Add(other Money) (Money, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return Money{}, err
+	}
+	if (other.Amount > 0 && m.Amount > math.MaxInt64-other.Amount) ||
+		(other.Amount < 0 && m.Amount < math.MinInt64-other.Amount) {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Amount: m.Amount + other.Amount, Currency: m.Currency}, nil
+}
+
+// Sub returns m - other. Both amounts must share the same currency.
+func (m Money) Sub(other Money) (Money, error) {
+	negated, err := other.Neg()
+	if err != nil {
+		return Money{}, err
+	}
+
+	return m.Add(negated)
+}
+
+// Mul returns m multiplied by an integer factor.
+func (m Money) Mul(factor int64) (Money, error) {
+	if m.Amount != 0 && factor != 0 {
+		product := m.Amount * factor
+		if product/factor != m.Amount || (m.Amount == -1 && factor == math.MinInt64) || (factor == -1 && m.Amount == math.MinInt64) {
+			return Money{}, ErrMoneyOverflow
+		}
+		return Money{Amount: product, Currency: m.Currency}, nil
+	}
+
+	return Money{Amount: 0, Currency: m.Currency}, nil
+}
+
+// Neg returns -m.
+func (m Money) Neg() (Money, error) {
+	if m.Amount == math.MinInt64 {
+		return Money{}, ErrMoneyOverflow
+	}
+
+	return Money{Amount: -m.Amount, Currency: m.Currency}, nil
+}
+
+// Cmp compares m and other, returning -1, 0 or +1. Both amounts must share
+// the same currency.
+func (m Money) Cmp(other Money) (int, error) {
+	if err := m.checkCurrency(other); err != nil {
+		return 0, err
+	}
+
+	switch {
+	case m.Amount < other.Amount:
+		return -1, nil
+	case m.Amount > other.Amount:
+		return 1, nil
+	default:
+		return 0, nil
+	}
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+// Major returns the amount in major units as a decimal string, using the
+// currency exponent ("12.50" for 1250 EUR cents, "1250" for 1250 JPY).
+func (m Money) Major() string {
+	exponent := m.Currency.Exponent()
+
+	amount := m.Amount
+	sign := ""
+	if amount < 0 {
+		sign = "-"
+	}
+
+	digits := fmt.Sprintf("%d", amount)
+	digits = strings.TrimPrefix(digits, "-")
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// String formats the amount in major units followed by the currency code,
+// for example "12.50 EUR".
+func (m Money) String() string {
+	return fmt.Sprintf("%s %s", m.Major(), m.Currency)
+}
+
+func (m Money) checkCurrency(other Money) error {
+	if !strings.EqualFold(string(m.Currency), string(other.Currency)) {
+		return fmt.Errorf("%w: %s and %s", ErrCurrencyMismatch, m.Currency, other.Currency)
+	}
+
+	return nil
+}
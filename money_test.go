@@ -0,0 +1,150 @@
+package lago_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+func TestCurrency_Exponent(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(EUR.Exponent(), qt.Equals, 2)
+	c.Assert(USD.Exponent(), qt.Equals, 2)
+	c.Assert(JPY.Exponent(), qt.Equals, 0)
+	c.Assert(Currency("KWD").Exponent(), qt.Equals, 3)
+	c.Assert(Currency("jpy").Exponent(), qt.Equals, 0)
+}
+
+func TestMoney_Major(t *testing.T) {
+	tests := []struct {
+		money    Money
+		expected string
+	}{
+		{NewMoney(1250, EUR), "12.50"},
+		{NewMoney(5, EUR), "0.05"},
+		{NewMoney(-5, EUR), "-0.05"},
+		{NewMoney(0, EUR), "0.00"},
+		{NewMoney(1250, JPY), "1250"},
+		{NewMoney(1250, "KWD"), "1.250"},
+		{NewMoney(math.MinInt64, EUR), "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			c := qt.New(t)
+			c.Assert(tt.money.Major(), qt.Equals, tt.expected)
+		})
+	}
+
+	c := qt.New(t)
+	c.Assert(NewMoney(1250, EUR).String(), qt.Equals, "12.50 EUR")
+}
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		input    string
+		currency Currency
+		expected int64
+		wantErr  bool
+	}{
+		{"12.50", EUR, 1250, false},
+		{"12.5", EUR, 1250, false},
+		{"12", EUR, 1200, false},
+		{"-0.05", EUR, -5, false},
+		{".5", EUR, 50, false},
+		{"1250", JPY, 1250, false},
+		{"1.250", "KWD", 1250, false},
+		{"1000.0", JPY, 1000, false},
+		{"12.500", EUR, 1250, false},
+		{"12.5000", JPY, 0, true},
+		{"12.505", EUR, 0, true},
+		{"12.5", JPY, 0, true},
+		{"12.", EUR, 0, true},
+		{"", EUR, 0, true},
+		{"1,000", EUR, 0, true},
+		{"abc", EUR, 0, true},
+		{"99999999999999999999", EUR, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			c := qt.New(t)
+			money, err := ParseMoney(tt.input, tt.currency)
+			if tt.wantErr {
+				c.Assert(err, qt.Not(qt.IsNil))
+				return
+			}
+			c.Assert(err, qt.IsNil)
+			c.Assert(money, qt.Equals, NewMoney(tt.expected, tt.currency))
+		})
+	}
+}
+
+func TestMoney_Arithmetic(t *testing.T) {
+	c := qt.New(t)
+
+	sum, err := NewMoney(100, EUR).Add(NewMoney(250, EUR))
+	c.Assert(err, qt.IsNil)
+	c.Assert(sum, qt.Equals, NewMoney(350, EUR))
+
+	diff, err := NewMoney(100, EUR).Sub(NewMoney(250, EUR))
+	c.Assert(err, qt.IsNil)
+	c.Assert(diff, qt.Equals, NewMoney(-150, EUR))
+	c.Assert(diff.IsNegative(), qt.IsTrue)
+
+	product, err := NewMoney(125, EUR).Mul(3)
+	c.Assert(err, qt.IsNil)
+	c.Assert(product, qt.Equals, NewMoney(375, EUR))
+
+	cmp, err := NewMoney(100, EUR).Cmp(NewMoney(99, EUR))
+	c.Assert(err, qt.IsNil)
+	c.Assert(cmp, qt.Equals, 1)
+
+	_, err = NewMoney(100, EUR).Add(NewMoney(100, USD))
+	c.Assert(errors.Is(err, ErrCurrencyMismatch), qt.IsTrue)
+
+	_, err = NewMoney(100, EUR).Cmp(NewMoney(100, JPY))
+	c.Assert(errors.Is(err, ErrCurrencyMismatch), qt.IsTrue)
+
+	_, err = NewMoney(math.MaxInt64, EUR).Add(NewMoney(1, EUR))
+	c.Assert(errors.Is(err, ErrMoneyOverflow), qt.IsTrue)
+
+	_, err = NewMoney(math.MaxInt64/2+1, EUR).Mul(2)
+	c.Assert(errors.Is(err, ErrMoneyOverflow), qt.IsTrue)
+
+	_, err = NewMoney(math.MinInt64, EUR).Neg()
+	c.Assert(errors.Is(err, ErrMoneyOverflow), qt.IsTrue)
+
+	_, err = NewMoney(0, EUR).Sub(NewMoney(math.MinInt64, EUR))
+	c.Assert(errors.Is(err, ErrMoneyOverflow), qt.IsTrue)
+
+	neg, err := NewMoney(100, EUR).Neg()
+	c.Assert(err, qt.IsNil)
+	c.Assert(neg, qt.Equals, NewMoney(-100, EUR))
+}
+
+func TestMoney_Accessors(t *testing.T) {
+	c := qt.New(t)
+
+	invoice := &Invoice{Currency: JPY, TotalAmountCents: 1500, TotalDueAmountCents: 500}
+	c.Assert(invoice.TotalAmount().String(), qt.Equals, "1500 JPY")
+	c.Assert(invoice.TotalDueAmount(), qt.Equals, NewMoney(500, JPY))
+
+	fee := &Fee{AmountCents: 1000, AmountCurrency: "EUR", TotalAmountCents: 1200, TotalAmountCurrency: "EUR"}
+	c.Assert(fee.Amount(), qt.Equals, NewMoney(1000, EUR))
+	c.Assert(fee.TotalAmount(), qt.Equals, NewMoney(1200, EUR))
+
+	wallet := &Wallet{Currency: USD, BalanceCents: 4200}
+	c.Assert(wallet.Balance().String(), qt.Equals, "42.00 USD")
+
+	creditNote := &CreditNote{Currency: EUR, RefundAmountCents: 300}
+	c.Assert(creditNote.RefundAmount(), qt.Equals, NewMoney(300, EUR))
+
+	payment := &Payment{AmountCurrency: EUR, AmountCents: 99}
+	c.Assert(payment.Amount().String(), qt.Equals, "0.99 EUR")
+}
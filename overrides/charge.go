@@ -0,0 +1,477 @@
+package overrides
+
+import (
+	"encoding/json"
+	"fmt"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// GraduatedPercentageRange is a range of the graduated percentage charge
+// model.
+type GraduatedPercentageRange struct {
+	FromValue  int    `json:"from_value"`
+	ToValue    *int   `json:"to_value"`
+	Rate       string `json:"rate"`
+	FlatAmount string `json:"flat_amount"`
+}
+
+// Charge overrides a charge of the plan. Its properties are those of the
+// charge model of the charge, which cannot be overridden.
+type Charge struct {
+	code       string
+	charge     *lago.Charge
+	properties map[string]any
+	// set lists the properties set through the builder.
+	set                []string
+	invoiceDisplayName *string
+	minAmountCents     *int
+}
+
+func newCharge(code string, charge *lago.Charge) *Charge {
+	properties, _ := normalize(charge.Properties).(map[string]any)
+	if properties == nil {
+		properties = make(map[string]any)
+	}
+	return &Charge{code: code, charge: charge, properties: properties}
+}
+
+// Property overrides a property of the charge by name, for the properties
+// without a typed setter.
+func (c *Charge) Property(name string, value any) *Charge {
+	c.properties[name] = normalize(value)
+	for _, set := range c.set {
+		if set == name {
+			return c
+		}
+	}
+	c.set = append(c.set, name)
+	return c
+}
+
+// Amount overrides the unit amount of the standard model, or the amount of a
+// package of the package model.
+func (c *Charge) Amount(amount lago.Decimal) *Charge {
+	return c.Property("amount", amount.String())
+}
+
+// PackageSize overrides the number of units of a package.
+func (c *Charge) PackageSize(units int) *Charge {
+	return c.Property("package_size", units)
+}
+
+// FreeUnits overrides the number of free units of the package model.
+func (c *Charge) FreeUnits(units int) *Charge {
+	return c.Property("free_units", units)
+}
+
+// Rate overrides the rate of the percentage model, in percent.
+func (c *Charge) Rate(rate lago.Decimal) *Charge {
+	return c.Property("rate", rate.String())
+}
+
+// FixedAmount overrides the amount added per transaction by the percentage
+// model.
+func (c *Charge) FixedAmount(amount lago.Decimal) *Charge {
+	return c.Property("fixed_amount", amount.String())
+}
+
+// FreeUnitsPerEvents overrides the number of free transactions of the
+// percentage model.
+func (c *Charge) FreeUnitsPerEvents(events int) *Charge {
+	return c.Property("free_units_per_events", events)
+}
+
+// FreeUnitsPerTotalAggregation overrides the free amount of the percentage
+// model.
+func (c *Charge) FreeUnitsPerTotalAggregation(amount lago.Decimal) *Charge {
+	return c.Property("free_units_per_total_aggregation", amount.String())
+}
+
+// PerTransactionMinAmount overrides the minimum fee per transaction of the
+// percentage model.
+func (c *Charge) PerTransactionMinAmount(amount lago.Decimal) *Charge {
+	return c.Property("per_transaction_min_amount", amount.String())
+}
+
+// PerTransactionMaxAmount overrides the maximum fee per transaction of the
+// percentage model.
+func (c *Charge) PerTransactionMaxAmount(amount lago.Decimal) *Charge {
+	return c.Property("per_transaction_max_amount", amount.String())
+}
+
+// GraduatedRanges overrides the ranges of the graduated model.
+func (c *Charge) GraduatedRanges(ranges ...lago.GraduatedRange) *Charge {
+	return c.Property("graduated_ranges", ranges)
+}
+
+// VolumeRanges overrides the ranges of the volume model.
+func (c *Charge) VolumeRanges(ranges ...lago.VolumeRange) *Charge {
+	return c.Property("volume_ranges", ranges)
+}
+
+// GraduatedPercentageRanges overrides the ranges of the graduated percentage
+// model.
+func (c *Charge) GraduatedPercentageRanges(ranges ...GraduatedPercentageRange) *Charge {
+	return c.Property("graduated_percentage_ranges", ranges)
+}
+
+// InvoiceDisplayName overrides the name of the charge on invoices.
+func (c *Charge) InvoiceDisplayName(name string) *Charge {
+	c.invoiceDisplayName = &name
+	return c
+}
+
+// MinAmountCents overrides the minimum amount of the charge per period.
+func (c *Charge) MinAmountCents(cents int) *Charge {
+	c.minAmountCents = &cents
+	return c
+}
+
+func (c *Charge) override() (lago.ChargeOverridesInput, bool) {
+	override := lago.ChargeOverridesInput{ID: &c.charge.LagoID, Properties: c.properties}
+	changed := len(c.set) > 0 && !equal(c.properties, c.charge.Properties)
+	if c.invoiceDisplayName != nil && *c.invoiceDisplayName != c.charge.InvoiceDisplayName {
+		override.InvoiceDisplayName = *c.invoiceDisplayName
+		changed = true
+	}
+	if c.minAmountCents != nil && *c.minAmountCents != c.charge.MinAmountCents {
+		override.MinAmountCents = *c.minAmountCents
+		changed = true
+	}
+	return override, changed
+}
+
+type valueKind int
+
+const (
+	decimalValue valueKind = iota
+	integerValue
+	// unitRanges have a flat and a per unit amount.
+	unitRanges
+	// percentageRanges have a rate and a flat amount.
+	percentageRanges
+)
+
+type property struct {
+	name     string
+	kind     valueKind
+	required bool
+}
+
+// chargeModels lists the properties of every charge model.
+var chargeModels = map[lago.ChargeModel][]property{
+	lago.StandardChargeModel: {{"amount", decimalValue, true}},
+	lago.PackageChargeModel: {
+		{"amount", decimalValue, true},
+		{"package_size", integerValue, true},
+		{"free_units", integerValue, false},
+	},
+	lago.PercentageChargeModel: {
+		{"rate", decimalValue, true},
+		{"fixed_amount", decimalValue, false},
+		{"free_units_per_events", integerValue, false},
+		{"free_units_per_total_aggregation", decimalValue, false},
+		{"per_transaction_min_amount", decimalValue, false},
+		{"per_transaction_max_amount", decimalValue, false},
+	},
+	lago.GraduatedChargeModel:           {{"graduated_ranges", unitRanges, true}},
+	lago.GraduatedPercentageChargeModel: {{"graduated_percentage_ranges", percentageRanges, true}},
+	lago.VolumeChargeModel:              {{"volume_ranges", unitRanges, true}},
+	lago.DynamicChargeModel:             {},
+}
+
+// groupingProperties are accepted by every charge model.
+var groupingProperties = map[string]bool{"grouped_by": true, "pricing_group_keys": true}
+
+func (c *Charge) validate() []string {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("charge %q: ", c.code)+fmt.Sprintf(format, args...))
+	}
+
+	if c.minAmountCents != nil {
+		switch {
+		case *c.minAmountCents < 0:
+			fail("negative min_amount_cents")
+		case *c.minAmountCents == 0 && c.charge.MinAmountCents != 0:
+			fail("min_amount_cents cannot be overridden to 0")
+		}
+	}
+
+	properties, ok := chargeModels[c.charge.ChargeModel]
+	if !ok {
+		return problems
+	}
+	known := make(map[string]bool, len(properties))
+	for _, property := range properties {
+		known[property.name] = true
+	}
+	for _, name := range c.set {
+		if !known[name] && !groupingProperties[name] {
+			fail("%s is not a property of the %s charge model", name, c.charge.ChargeModel)
+		}
+	}
+
+	for _, property := range properties {
+		value, ok := c.properties[property.name]
+		if !ok || value == nil {
+			if property.required {
+				fail("missing %s", property.name)
+			}
+			continue
+		}
+		for _, problem := range checkValue(property.name, property.kind, value) {
+			fail("%s", problem)
+		}
+	}
+
+	if c.charge.ChargeModel == lago.PackageChargeModel {
+		if size, ok := c.properties["package_size"].(float64); ok && size < 1 {
+			fail("package_size must be at least 1")
+		}
+	}
+	if c.charge.ChargeModel == lago.PercentageChargeModel {
+		lower, lowerErr := lago.ParseDecimal(fmt.Sprint(c.properties["per_transaction_min_amount"]))
+		upper, upperErr := lago.ParseDecimal(fmt.Sprint(c.properties["per_transaction_max_amount"]))
+		if lowerErr == nil && upperErr == nil && lower.Cmp(upper) > 0 {
+			fail("per_transaction_min_amount is greater than per_transaction_max_amount")
+		}
+	}
+	return problems
+}
+
+// checkValue checks a property value as decoded from JSON.
+func checkValue(name string, kind valueKind, value any) []string {
+	switch kind {
+	case decimalValue:
+		s, ok := value.(string)
+		if !ok {
+			return []string{fmt.Sprintf("%s must be a decimal string", name)}
+		}
+		if problem := checkDecimal(name, s); problem != "" {
+			return []string{problem}
+		}
+	case integerValue:
+		n, ok := value.(float64)
+		if !ok || n != float64(int64(n)) {
+			return []string{fmt.Sprintf("%s must be an integer", name)}
+		}
+		if n < 0 {
+			return []string{fmt.Sprintf("negative %s", name)}
+		}
+	case unitRanges, percentageRanges:
+		var ranges []bound
+		data, _ := json.Marshal(value)
+		if err := json.Unmarshal(data, &ranges); err != nil {
+			return []string{fmt.Sprintf("%s must be a list of ranges", name)}
+		}
+		amounts := []string{"flat_amount", "per_unit_amount"}
+		if kind == percentageRanges {
+			amounts = []string{"flat_amount", "rate"}
+		}
+		return checkRanges(name, ranges, amounts)
+	}
+	return nil
+}
+
+func checkDecimal(name, value string) string {
+	d, err := lago.ParseDecimal(value)
+	switch {
+	case err != nil:
+		return fmt.Sprintf("%s %q is not a decimal", name, value)
+	case d.Sign() < 0:
+		return fmt.Sprintf("negative %s", name)
+	}
+	return ""
+}
+
+// bound is a range of any charge model.
+type bound struct {
+	FromValue     *int    `json:"from_value"`
+	ToValue       *int    `json:"to_value"`
+	FlatAmount    *string `json:"flat_amount"`
+	PerUnitAmount *string `json:"per_unit_amount"`
+	Rate          *string `json:"rate"`
+}
+
+func (b bound) amount(name string) *string {
+	switch name {
+	case "flat_amount":
+		return b.FlatAmount
+	case "per_unit_amount":
+		return b.PerUnitAmount
+	default:
+		return b.Rate
+	}
+}
+
+// checkRanges checks that ranges start at 0, follow each other without gap
+// and end with an open range, and that they carry the given amounts.
+func checkRanges(name string, ranges []bound, amounts []string) []string {
+	if len(ranges) == 0 {
+		return []string{fmt.Sprintf("%s: no ranges", name)}
+	}
+
+	var problems []string
+	fail := func(i int, format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("%s: range %d: ", name, i+1)+fmt.Sprintf(format, args...))
+	}
+	next := 0
+	for i, r := range ranges {
+		switch {
+		case r.FromValue == nil:
+			fail(i, "missing from_value")
+		case *r.FromValue != next:
+			fail(i, "from_value is %d, not %d", *r.FromValue, next)
+		}
+		last := i == len(ranges)-1
+		switch {
+		case last && r.ToValue != nil:
+			fail(i, "the last range must have no to_value")
+		case !last && r.ToValue == nil:
+			fail(i, "missing to_value")
+		case r.ToValue != nil && r.FromValue != nil && *r.ToValue < *r.FromValue:
+			fail(i, "to_value is lower than from_value")
+		}
+		if r.ToValue != nil {
+			next = *r.ToValue + 1
+		}
+
+		for _, amount := range amounts {
+			value := r.amount(amount)
+			if value == nil {
+				fail(i, "missing %s", amount)
+			} else if problem := checkDecimal(amount, *value); problem != "" {
+				fail(i, "%s", problem)
+			}
+		}
+	}
+	return problems
+}
+
+// FixedCharge overrides a fixed charge of the plan.
+type FixedCharge struct {
+	code                  string
+	fixedCharge           *lago.FixedCharge
+	units                 *float64
+	properties            lago.FixedChargeProperties
+	set                   []string
+	invoiceDisplayName    *string
+	applyUnitsImmediately bool
+}
+
+func newFixedCharge(code string, fixedCharge *lago.FixedCharge) *FixedCharge {
+	f := &FixedCharge{code: code, fixedCharge: fixedCharge}
+	if fixedCharge.Properties != nil {
+		f.properties = *fixedCharge.Properties
+	}
+	return f
+}
+
+// Units overrides the number of units billed.
+func (f *FixedCharge) Units(units float64) *FixedCharge {
+	f.units = &units
+	return f
+}
+
+// ApplyUnitsImmediately bills the overridden units from the current period
+// rather than the next one.
+func (f *FixedCharge) ApplyUnitsImmediately() *FixedCharge {
+	f.applyUnitsImmediately = true
+	return f
+}
+
+// Amount overrides the unit amount of the standard model.
+func (f *FixedCharge) Amount(amount lago.Decimal) *FixedCharge {
+	f.properties.Amount = lago.Ptr(amount.String())
+	f.set = append(f.set, "amount")
+	return f
+}
+
+// GraduatedRanges overrides the ranges of the graduated model.
+func (f *FixedCharge) GraduatedRanges(ranges ...lago.GraduatedRange) *FixedCharge {
+	f.properties.GraduatedRanges = ranges
+	f.set = append(f.set, "graduated_ranges")
+	return f
+}
+
+// VolumeRanges overrides the ranges of the volume model.
+func (f *FixedCharge) VolumeRanges(ranges ...lago.VolumeRange) *FixedCharge {
+	f.properties.VolumeRanges = ranges
+	f.set = append(f.set, "volume_ranges")
+	return f
+}
+
+// InvoiceDisplayName overrides the name of the fixed charge on invoices.
+func (f *FixedCharge) InvoiceDisplayName(name string) *FixedCharge {
+	f.invoiceDisplayName = &name
+	return f
+}
+
+func (f *FixedCharge) override() (lago.FixedChargeOverridesInput, bool) {
+	override := lago.FixedChargeOverridesInput{ID: &f.fixedCharge.LagoID}
+	changed := false
+	if f.units != nil && *f.units != f.fixedCharge.Units {
+		override.Units = f.units
+		override.ApplyUnitsImmediately = f.applyUnitsImmediately
+		changed = true
+	}
+	if len(f.set) > 0 && !equal(f.properties, f.fixedCharge.Properties) {
+		properties := f.properties
+		override.Properties = &properties
+		changed = true
+	}
+	if f.invoiceDisplayName != nil && *f.invoiceDisplayName != f.fixedCharge.InvoiceDisplayName {
+		override.InvoiceDisplayName = *f.invoiceDisplayName
+		changed = true
+	}
+	return override, changed
+}
+
+// fixedChargeModels lists the property of every fixed charge model.
+var fixedChargeModels = map[lago.FixedChargeModel]property{
+	lago.StandardFixedChargeModel:  {"amount", decimalValue, true},
+	lago.GraduatedFixedChargeModel: {"graduated_ranges", unitRanges, true},
+	lago.VolumeFixedChargeModel:    {"volume_ranges", unitRanges, true},
+}
+
+func (f *FixedCharge) validate() []string {
+	var problems []string
+	fail := func(format string, args ...any) {
+		problems = append(problems, fmt.Sprintf("fixed charge %q: ", f.code)+fmt.Sprintf(format, args...))
+	}
+
+	if f.units != nil && *f.units < 0 {
+		fail("negative units")
+	}
+	property, ok := fixedChargeModels[f.fixedCharge.ChargeModel]
+	if !ok {
+		return problems
+	}
+	for _, name := range f.set {
+		if name != property.name {
+			fail("%s is not a property of the %s charge model", name, f.fixedCharge.ChargeModel)
+		}
+	}
+
+	var value any
+	switch property.name {
+	case "amount":
+		if f.properties.Amount != nil {
+			value = *f.properties.Amount
+		}
+	case "graduated_ranges":
+		value = normalize(f.properties.GraduatedRanges)
+	case "volume_ranges":
+		value = normalize(f.properties.VolumeRanges)
+	}
+	if value == nil {
+		fail("missing %s", property.name)
+		return problems
+	}
+	for _, problem := range checkValue(property.name, property.kind, value) {
+		fail("%s", problem)
+	}
+	return problems
+}
@@ -0,0 +1,247 @@
+// Package overrides builds the plan overrides of a subscription from the plan
+// it overrides, with typed setters, local validation against the charge
+// models and a payload holding only what changed.
+package overrides
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// ErrInvalidOverrides is returned by Build when the overrides would be
+// rejected by Lago.
+var ErrInvalidOverrides = errors.New("overrides: invalid plan overrides")
+
+// Builder overrides a plan. Setters can be chained; mistakes are reported by
+// Build.
+type Builder struct {
+	plan *lago.Plan
+
+	name               *string
+	invoiceDisplayName *string
+	description        *string
+	amountCents        *int
+	amountCurrency     *lago.Currency
+	trialPeriod        *float32
+	minimumCommitment  *int
+	taxCodes           []string
+
+	charges      map[string]*Charge
+	fixedCharges map[string]*FixedCharge
+	problems     []string
+}
+
+// New returns a builder overriding plan.
+func New(plan *lago.Plan) *Builder {
+	return &Builder{
+		plan:         plan,
+		charges:      make(map[string]*Charge),
+		fixedCharges: make(map[string]*FixedCharge),
+	}
+}
+
+// Fetch returns a builder overriding the plan of the given code.
+func Fetch(ctx context.Context, client *lago.Client, planCode string) (*Builder, error) {
+	plan, err := client.Plan().Get(ctx, planCode)
+	if err != nil {
+		return nil, fmt.Errorf("overrides: plan %s: %w", planCode, err)
+	}
+	return New(plan), nil
+}
+
+// Name overrides the name of the plan.
+func (b *Builder) Name(name string) *Builder {
+	b.name = &name
+	return b
+}
+
+// InvoiceDisplayName overrides the name of the plan on invoices.
+func (b *Builder) InvoiceDisplayName(name string) *Builder {
+	b.invoiceDisplayName = &name
+	return b
+}
+
+// Description overrides the description of the plan.
+func (b *Builder) Description(description string) *Builder {
+	b.description = &description
+	return b
+}
+
+// AmountCents overrides the subscription fee.
+func (b *Builder) AmountCents(cents int) *Builder {
+	b.amountCents = &cents
+	return b
+}
+
+// AmountCurrency overrides the currency of the subscription fee.
+func (b *Builder) AmountCurrency(currency lago.Currency) *Builder {
+	b.amountCurrency = &currency
+	return b
+}
+
+// TrialPeriod overrides the trial period, in days.
+func (b *Builder) TrialPeriod(days float32) *Builder {
+	b.trialPeriod = &days
+	return b
+}
+
+// MinimumCommitmentCents overrides the amount of the minimum commitment.
+func (b *Builder) MinimumCommitmentCents(cents int) *Builder {
+	b.minimumCommitment = &cents
+	return b
+}
+
+// TaxCodes overrides the taxes of the plan.
+func (b *Builder) TaxCodes(codes ...string) *Builder {
+	b.taxCodes = codes
+	return b
+}
+
+// Charge returns the overrides of the charge of the given code, or of the
+// given billable metric code for charges without code.
+func (b *Builder) Charge(code string) *Charge {
+	if charge, ok := b.charges[code]; ok {
+		return charge
+	}
+	for i := range b.plan.Charges {
+		if chargeCode(&b.plan.Charges[i]) == code {
+			charge := newCharge(code, &b.plan.Charges[i])
+			b.charges[code] = charge
+			return charge
+		}
+	}
+	b.problems = append(b.problems, fmt.Sprintf("no charge %q in plan %q", code, b.plan.Code))
+	// Detached: its changes are ignored.
+	return newCharge(code, &lago.Charge{})
+}
+
+// FixedCharge returns the overrides of the fixed charge of the given code, or
+// of the given add-on code for fixed charges without code.
+func (b *Builder) FixedCharge(code string) *FixedCharge {
+	if fixedCharge, ok := b.fixedCharges[code]; ok {
+		return fixedCharge
+	}
+	for i := range b.plan.FixedCharges {
+		if fixedChargeCode(&b.plan.FixedCharges[i]) == code {
+			fixedCharge := newFixedCharge(code, &b.plan.FixedCharges[i])
+			b.fixedCharges[code] = fixedCharge
+			return fixedCharge
+		}
+	}
+	b.problems = append(b.problems, fmt.Sprintf("no fixed charge %q in plan %q", code, b.plan.Code))
+	return newFixedCharge(code, &lago.FixedCharge{})
+}
+
+// Build validates the overrides and returns them as a payload holding only
+// the fields that differ from the plan, along with the subscription fee and
+// trial period of the plan, which Lago always expects.
+//
+// The properties of an overridden charge are sent whole, as Lago replaces
+// them, with the properties of the plan for those not overridden.
+func (b *Builder) Build() (*lago.PlanOverridesInput, error) {
+	plan := b.plan
+	problems := append([]string(nil), b.problems...)
+
+	input := &lago.PlanOverridesInput{
+		AmountCents: plan.AmountCents,
+		TrialPeriod: plan.TrialPeriod,
+	}
+	if b.amountCents != nil {
+		if *b.amountCents < 0 {
+			problems = append(problems, "negative amount_cents")
+		}
+		input.AmountCents = *b.amountCents
+	}
+	if b.trialPeriod != nil {
+		if *b.trialPeriod < 0 {
+			problems = append(problems, "negative trial_period")
+		}
+		input.TrialPeriod = *b.trialPeriod
+	}
+	if b.amountCurrency != nil && *b.amountCurrency != plan.AmountCurrency {
+		input.AmountCurrency = *b.amountCurrency
+	}
+	if b.name != nil && *b.name != plan.Name {
+		input.Name = *b.name
+	}
+	if b.invoiceDisplayName != nil && *b.invoiceDisplayName != plan.InvoiceDisplayName {
+		input.InvoiceDisplayName = *b.invoiceDisplayName
+	}
+	if b.description != nil && *b.description != plan.Description {
+		input.Description = *b.description
+	}
+	if b.minimumCommitment != nil {
+		if *b.minimumCommitment <= 0 {
+			problems = append(problems, "minimum commitment amount_cents must be positive")
+		}
+		if plan.MinimumCommitment == nil || plan.MinimumCommitment.AmountCents != *b.minimumCommitment {
+			input.MinimumCommitment = &lago.MinimumCommitmentOverridesInput{AmountCents: *b.minimumCommitment}
+		}
+	}
+	input.TaxCodes = b.taxCodes
+
+	// Charges follow the order of the plan.
+	for i := range plan.Charges {
+		charge, ok := b.charges[chargeCode(&plan.Charges[i])]
+		if !ok {
+			continue
+		}
+		problems = append(problems, charge.validate()...)
+		if override, changed := charge.override(); changed {
+			input.Charges = append(input.Charges, override)
+		}
+	}
+	for i := range plan.FixedCharges {
+		fixedCharge, ok := b.fixedCharges[fixedChargeCode(&plan.FixedCharges[i])]
+		if !ok {
+			continue
+		}
+		problems = append(problems, fixedCharge.validate()...)
+		if override, changed := fixedCharge.override(); changed {
+			input.FixedCharges = append(input.FixedCharges, override)
+		}
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidOverrides, strings.Join(problems, "; "))
+	}
+	return input, nil
+}
+
+func chargeCode(charge *lago.Charge) string {
+	if charge.Code != "" {
+		return charge.Code
+	}
+	return charge.BillableMetricCode
+}
+
+func fixedChargeCode(fixedCharge *lago.FixedCharge) string {
+	if fixedCharge.Code != "" {
+		return fixedCharge.Code
+	}
+	return fixedCharge.AddOnCode
+}
+
+// normalize returns value as decoded from its JSON encoding, so that values
+// set through the builder compare with those returned by Lago.
+func normalize(value any) any {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return value
+	}
+	var normalized any
+	if err := json.Unmarshal(data, &normalized); err != nil {
+		return value
+	}
+	return normalized
+}
+
+func equal(a, b any) bool {
+	return reflect.DeepEqual(normalize(a), normalize(b))
+}
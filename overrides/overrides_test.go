@@ -0,0 +1,180 @@
+package overrides_test
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/overrides"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+const planJSON = `{
+	"lago_id": "00000000-0000-0000-0000-000000000000", "code": "pro", "name": "Pro", "interval": "monthly",
+	"amount_cents": 5000, "amount_currency": "EUR", "trial_period": 14,
+	"minimum_commitment": {"amount_cents": 10000},
+	"charges": [
+		{"lago_id": "00000000-0000-0000-0000-000000000001", "code": "api_calls", "charge_model": "standard",
+		 "properties": {"amount": "0.02"}},
+		{"lago_id": "00000000-0000-0000-0000-000000000002", "billable_metric_code": "storage", "charge_model": "package",
+		 "properties": {"amount": "5", "package_size": 100, "free_units": 0}},
+		{"lago_id": "00000000-0000-0000-0000-000000000003", "code": "tiers", "charge_model": "graduated",
+		 "properties": {"graduated_ranges": [
+			{"from_value": 0, "to_value": 10, "flat_amount": "0", "per_unit_amount": "1"},
+			{"from_value": 11, "to_value": null, "flat_amount": "0", "per_unit_amount": "0.5"}
+		 ]}},
+		{"lago_id": "00000000-0000-0000-0000-000000000004", "code": "cards", "charge_model": "percentage",
+		 "properties": {"rate": "1.5"}}
+	],
+	"fixed_charges": [
+		{"lago_id": "00000000-0000-0000-0000-000000000010", "add_on_code": "seats", "charge_model": "standard",
+		 "units": 5, "properties": {"amount": "10"}}
+	]
+}`
+
+func newPlan(c *qt.C) *lago.Plan {
+	var plan lago.Plan
+	c.Assert(json.Unmarshal([]byte(planJSON), &plan), qt.IsNil)
+	return &plan
+}
+
+func marshal(c *qt.C, input *lago.PlanOverridesInput) string {
+	data, err := json.Marshal(input)
+	c.Assert(err, qt.IsNil)
+	return string(data)
+}
+
+func TestBuild_Unchanged(t *testing.T) {
+	c := qt.New(t)
+
+	// Only the fields Lago always expects are sent, with the values of the
+	// plan rather than zeros.
+	b := overrides.New(newPlan(c)).TrialPeriod(14).MinimumCommitmentCents(10000)
+	b.Charge("tiers").GraduatedRanges(
+		lago.GraduatedRange{FromValue: 0, ToValue: lago.Ptr(10), FlatAmount: "0", PerUnitAmount: "1"},
+		lago.GraduatedRange{FromValue: 11, FlatAmount: "0", PerUnitAmount: "0.5"},
+	)
+	input, err := b.Build()
+	c.Assert(err, qt.IsNil)
+	c.Assert(marshal(c, input), qt.JSONEquals, map[string]any{"amount_cents": 5000, "trial_period": 14, "minimum_commitment": nil})
+}
+
+func TestBuild(t *testing.T) {
+	c := qt.New(t)
+
+	b := overrides.New(newPlan(c)).AmountCents(4000).Name("Pro (negotiated)")
+	b.Charge("api_calls").Amount(lago.MustParseDecimal("0.015"))
+	b.Charge("storage").InvoiceDisplayName("Storage").PackageSize(50)
+	b.Charge("cards").PerTransactionMaxAmount(lago.MustParseDecimal("2"))
+	b.FixedCharge("seats").Units(8).ApplyUnitsImmediately()
+	input, err := b.Build()
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(marshal(c, input), qt.JSONEquals, map[string]any{
+		"name":               "Pro (negotiated)",
+		"amount_cents":       4000,
+		"trial_period":       14,
+		"minimum_commitment": nil,
+		"charges": []any{
+			map[string]any{"id": "00000000-0000-0000-0000-000000000001", "properties": map[string]any{"amount": "0.015"}},
+			map[string]any{
+				"id":                   "00000000-0000-0000-0000-000000000002",
+				"invoice_display_name": "Storage",
+				"properties":           map[string]any{"amount": "5", "package_size": 50, "free_units": 0},
+			},
+			map[string]any{"id": "00000000-0000-0000-0000-000000000004", "properties": map[string]any{"rate": "1.5", "per_transaction_max_amount": "2"}},
+		},
+		"fixed_charges": []any{
+			map[string]any{"id": "00000000-0000-0000-0000-000000000010", "units": 8, "apply_units_immediately": true},
+		},
+	})
+}
+
+func TestBuild_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		build func(b *overrides.Builder)
+		want  string
+	}{{
+		name:  "negative amount",
+		build: func(b *overrides.Builder) { b.AmountCents(-1) },
+		want:  "negative amount_cents",
+	}, {
+		name:  "unknown charge",
+		build: func(b *overrides.Builder) { b.Charge("sms").Amount(lago.MustParseDecimal("1")) },
+		want:  `no charge "sms" in plan "pro"`,
+	}, {
+		name:  "property of another charge model",
+		build: func(b *overrides.Builder) { b.Charge("api_calls").Rate(lago.MustParseDecimal("1")) },
+		want:  `charge "api_calls": rate is not a property of the standard charge model`,
+	}, {
+		name:  "invalid decimal",
+		build: func(b *overrides.Builder) { b.Charge("api_calls").Property("amount", "two") },
+		want:  `charge "api_calls": amount "two" is not a decimal`,
+	}, {
+		name:  "empty package",
+		build: func(b *overrides.Builder) { b.Charge("storage").PackageSize(0) },
+		want:  `charge "storage": package_size must be at least 1`,
+	}, {
+		name: "percentage bounds",
+		build: func(b *overrides.Builder) {
+			b.Charge("cards").PerTransactionMinAmount(lago.MustParseDecimal("3")).PerTransactionMaxAmount(lago.MustParseDecimal("2"))
+		},
+		want: `charge "cards": per_transaction_min_amount is greater than per_transaction_max_amount`,
+	}, {
+		name: "gap between ranges",
+		build: func(b *overrides.Builder) {
+			b.Charge("tiers").GraduatedRanges(
+				lago.GraduatedRange{FromValue: 0, ToValue: lago.Ptr(10), FlatAmount: "0", PerUnitAmount: "1"},
+				lago.GraduatedRange{FromValue: 20, FlatAmount: "0", PerUnitAmount: "0.5"},
+			)
+		},
+		want: `charge "tiers": graduated_ranges: range 2: from_value is 20, not 11`,
+	}, {
+		name: "closed last range",
+		build: func(b *overrides.Builder) {
+			b.Charge("tiers").GraduatedRanges(lago.GraduatedRange{FromValue: 0, ToValue: lago.Ptr(10), PerUnitAmount: "1"})
+		},
+		want: `charge "tiers": graduated_ranges: range 1: the last range must have no to_value; ` +
+			`charge "tiers": graduated_ranges: range 1: missing flat_amount`,
+	}, {
+		name: "fixed charge",
+		build: func(b *overrides.Builder) {
+			b.FixedCharge("seats").Units(-1).VolumeRanges(lago.VolumeRange{FlatAmount: "1", PerUnitAmount: "1"})
+		},
+		want: `fixed charge "seats": negative units; fixed charge "seats": volume_ranges is not a property of the standard charge model`,
+	}}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			c := qt.New(t)
+
+			b := overrides.New(newPlan(c))
+			test.build(b)
+			_, err := b.Build()
+			c.Assert(err, qt.ErrorIs, overrides.ErrInvalidOverrides)
+			c.Assert(err, qt.ErrorMatches, `overrides: invalid plan overrides: `+regexp.QuoteMeta(test.want))
+		})
+	}
+}
+
+func TestFetch(t *testing.T) {
+	c := qt.New(t)
+
+	client := lt.NewRoutesServer(c, map[string]string{
+		"GET /plans/pro": `{"plan": ` + planJSON + `}`,
+	}).Client()
+
+	b, err := overrides.Fetch(context.Background(), client, "pro")
+	c.Assert(err, qt.IsNil)
+	input, err := b.AmountCents(100).Build()
+	c.Assert(err, qt.IsNil)
+	c.Assert(input.AmountCents, qt.Equals, 100)
+
+	_, err = overrides.Fetch(context.Background(), client, "basic")
+	c.Assert(err, qt.ErrorMatches, `overrides: plan basic: .*resource_not_found.*`)
+}
@@ -0,0 +1,32 @@
+package lago
+
+// FetchPages reads the pages of a list endpoint and returns their items. fetch
+// is called with page 1, then with the next page of the metadata it returns,
+// until the last or an empty page, or until limit items are read; a zero limit
+// reads every page.
+//
+//	invoices, err := lago.FetchPages(0, func(page int) ([]lago.Invoice, lago.Metadata, *lago.Error) {
+//		result, err := client.Invoice().GetList(ctx, &lago.InvoiceListInput{Page: lago.Ptr(page), PerPage: lago.Ptr(100)})
+//		if err != nil {
+//			return nil, lago.Metadata{}, err
+//		}
+//		return result.Invoices, result.Meta, nil
+//	})
+func FetchPages[T any](limit int, fetch func(page int) ([]T, Metadata, *Error)) ([]T, *Error) {
+	var all []T
+	for page := 1; ; {
+		items, meta, err := fetch(page)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+
+		if limit > 0 && len(all) >= limit {
+			return all[:limit], nil
+		}
+		if meta.NextPage == 0 || meta.NextPage <= page || len(items) == 0 {
+			return all, nil
+		}
+		page = meta.NextPage
+	}
+}
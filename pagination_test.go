@@ -0,0 +1,75 @@
+package lago_test
+
+import (
+	"errors"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+// pages fakes a list endpoint of seven items, three per page.
+func pages(calls *[]int) func(page int) ([]int, Metadata, *Error) {
+	return func(page int) ([]int, Metadata, *Error) {
+		*calls = append(*calls, page)
+		items := []int{}
+		for i := (page-1)*3 + 1; i <= min(page*3, 7); i++ {
+			items = append(items, i)
+		}
+		meta := Metadata{CurrentPage: page}
+		if page < 3 {
+			meta.NextPage = page + 1
+		}
+		return items, meta, nil
+	}
+}
+
+func TestFetchPages(t *testing.T) {
+	c := qt.New(t)
+
+	var calls []int
+	items, err := FetchPages(0, pages(&calls))
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(items, qt.DeepEquals, []int{1, 2, 3, 4, 5, 6, 7})
+	c.Assert(calls, qt.DeepEquals, []int{1, 2, 3})
+}
+
+func TestFetchPages_Limit(t *testing.T) {
+	c := qt.New(t)
+
+	// The third page is never read.
+	var calls []int
+	items, err := FetchPages(4, pages(&calls))
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(items, qt.DeepEquals, []int{1, 2, 3, 4})
+	c.Assert(calls, qt.DeepEquals, []int{1, 2})
+}
+
+func TestFetchPages_Error(t *testing.T) {
+	c := qt.New(t)
+
+	failure := &Error{HTTPStatusCode: 500, Err: errors.New("boom")}
+	items, err := FetchPages(0, func(page int) ([]int, Metadata, *Error) {
+		if page == 2 {
+			return nil, Metadata{}, failure
+		}
+		return []int{page}, Metadata{NextPage: page + 1}, nil
+	})
+	c.Assert(err, qt.Equals, failure)
+	c.Assert(items, qt.IsNil)
+}
+
+func TestFetchPages_StopsOnStalePage(t *testing.T) {
+	c := qt.New(t)
+
+	// A next page that does not move forward would loop forever.
+	var calls []int
+	items, err := FetchPages(0, func(page int) ([]int, Metadata, *Error) {
+		calls = append(calls, page)
+		return []int{page}, Metadata{NextPage: 1}, nil
+	})
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(items, qt.DeepEquals, []int{1})
+	c.Assert(calls, qt.DeepEquals, []int{1})
+}
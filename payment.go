@@ -52,6 +52,11 @@ type Payment struct {
 	NextAction         *NextAction `json:"next_action,omitempty"`
 }
 
+// Amount returns AmountCents as Money.
+func (p *Payment) Amount() Money {
+	return NewMoney(int64(p.AmountCents), p.AmountCurrency)
+}
+
 type PaymentParams struct {
 	Payment *PaymentInput `json:"payment"`
 }
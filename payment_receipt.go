@@ -29,6 +29,7 @@ type PaymentReceiptListInput struct {
 type PaymentReceipt struct {
 	LagoID    uuid.UUID `json:"lago_id,omitempty"`
 	Number    string    `json:"number,omitempty"`
+	FileURL   string    `json:"file_url,omitempty"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	Payment   *Payment  `json:"payment,omitempty"`
 }
@@ -115,6 +115,7 @@ type Plan struct {
 	PayInAdvance            bool               `json:"pay_in_advance,omitempty"`
 	BillChargesMonthly      bool               `json:"bill_charges_monthly,omitempty"`
 	BillFixedChargesMonthly *bool              `json:"bill_fixed_charges_monthly,omitempty"`
+	TrialPeriod             float32            `json:"trial_period,omitempty"`
 	Charges                 []Charge           `json:"charges,omitempty"`
 	FixedCharges            []FixedCharge      `json:"fixed_charges,omitempty"`
 	MinimumCommitment       *MinimumCommitment `json:"minimum_commitment"`
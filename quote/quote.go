@@ -0,0 +1,299 @@
+// Package quote prices a plan change before it is made: the credit for the
+// unused part of the current plan, the prorated fee of the new one and the
+// date the change takes effect, with the invoice Lago previews for it.
+package quote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/billing"
+)
+
+// Change is the kind of a plan change.
+type Change string
+
+const (
+	// Upgrade moves to a plan of a higher or equal yearly amount. It takes
+	// effect immediately.
+	Upgrade Change = "upgrade"
+	// Downgrade moves to a plan of a lower yearly amount. It takes effect at
+	// the end of the current billing period.
+	Downgrade Change = "downgrade"
+	// Override keeps the plan and changes its overrides. It takes effect
+	// immediately, for the rest of the current billing period.
+	Override Change = "override"
+)
+
+// ErrSamePlan is returned when the target plan is the current one and no
+// overrides are given.
+var ErrSamePlan = errors.New("quote: the subscription is already on this plan")
+
+// Request is a plan change to price.
+type Request struct {
+	ExternalSubscriptionID string
+	// PlanCode is the code of the target plan.
+	PlanCode string
+	// PlanOverrides are applied to the target plan.
+	PlanOverrides *lago.PlanOverridesInput
+	// At is the time of the change. It defaults to now.
+	At time.Time
+}
+
+// Quote is the financial impact of a plan change. Amounts exclude taxes and
+// usage charges, which are billed as usual at the end of the period.
+type Quote struct {
+	Request            Request
+	ExternalCustomerID string
+	// SubscriptionName is kept by Apply.
+	SubscriptionName string
+	CurrentPlanCode  string
+	Change           Change
+	// EffectiveAt is when the target plan starts.
+	EffectiveAt time.Time
+	Currency    lago.Currency
+
+	// CurrentPeriodFrom and CurrentPeriodTo bound the billing period of the
+	// subscription at the time of the change.
+	CurrentPeriodFrom, CurrentPeriodTo time.Time
+	// UnusedCreditCents is the subscription fee of the current plan for the
+	// days left in the current period, credited by Lago when an upgrade
+	// ends a plan paid in advance.
+	UnusedCreditCents int64
+
+	// NewPeriodFrom and NewPeriodTo bound the first period of the target
+	// plan; for overrides, the rest of the current period.
+	NewPeriodFrom, NewPeriodTo time.Time
+	// NewFeeCents is the subscription fee of the target plan for its first
+	// period, prorated when the period is shorter than the plan interval.
+	NewFeeCents int64
+	// NewFeeInAdvance tells whether NewFeeCents is invoiced at EffectiveAt,
+	// or at the end of the new period.
+	NewFeeInAdvance bool
+
+	// Preview is the invoice previewed by Lago for the change. Lago previews
+	// the target plan without its overrides; it is nil for overrides only.
+	Preview *lago.Invoice
+}
+
+// NetCents returns the fee of the target plan less the credit for the
+// current one.
+func (q *Quote) NetCents() int64 {
+	return q.NewFeeCents - q.UnusedCreditCents
+}
+
+// Prepare prices a plan change following the rules of Lago: a target plan
+// whose yearly amount is at least the current one is an upgrade, applied
+// immediately with the unused part of a plan paid in advance credited;
+// otherwise it is a downgrade applied at the end of the current period.
+// Overrides of the current plan apply immediately, to the rest of the
+// current period.
+//
+// Fees are prorated by days in the timezone of the customer, the day of an
+// upgrade being billed by both plans.
+func Prepare(ctx context.Context, client *lago.Client, req Request) (*Quote, error) {
+	if req.At.IsZero() {
+		req.At = time.Now()
+	}
+
+	sub, lagoErr := client.Subscription().Get(ctx, req.ExternalSubscriptionID)
+	if lagoErr != nil {
+		return nil, fmt.Errorf("quote: subscription %s: %w", req.ExternalSubscriptionID, lagoErr)
+	}
+	if sub.PlanCode == req.PlanCode && req.PlanOverrides == nil {
+		return nil, ErrSamePlan
+	}
+	current, err := plan(ctx, client, sub.Plan, sub.PlanCode)
+	if err != nil {
+		return nil, err
+	}
+	target := current
+	if req.PlanCode != sub.PlanCode {
+		if target, err = plan(ctx, client, nil, req.PlanCode); err != nil {
+			return nil, err
+		}
+	}
+	customer, lagoErr := client.Customer().Get(ctx, sub.ExternalCustomerID)
+	if lagoErr != nil {
+		return nil, fmt.Errorf("quote: customer %s: %w", sub.ExternalCustomerID, lagoErr)
+	}
+	timezone := customer.ApplicableTimezone
+
+	q := &Quote{
+		Request:            req,
+		ExternalCustomerID: sub.ExternalCustomerID,
+		SubscriptionName:   sub.Name,
+		CurrentPlanCode:    sub.PlanCode,
+		Currency:           target.AmountCurrency,
+		NewFeeInAdvance:    target.PayInAdvance,
+	}
+	targetCents := int64(target.AmountCents)
+	if overrides := req.PlanOverrides; overrides != nil {
+		targetCents = int64(overrides.AmountCents)
+		if overrides.AmountCurrency != "" {
+			q.Currency = overrides.AmountCurrency
+		}
+	}
+
+	q.CurrentPeriodFrom, q.CurrentPeriodTo, err = billing.Period(sub, billing.Options{Plan: current, Timezone: timezone}, req.At)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case req.PlanCode == sub.PlanCode:
+		q.Change = Override
+	case yearlyCents(targetCents, target.Interval) >= yearlyCents(int64(sub.PlanAmountCents), current.Interval):
+		q.Change = Upgrade
+	default:
+		q.Change = Downgrade
+	}
+
+	// The target plan keeps the subscription date, from which anniversary
+	// periods are counted; overrides keep the subscription itself.
+	next := *sub
+	next.Plan = target
+	next.TrialEndedAt = nil
+	switch q.Change {
+	case Upgrade:
+		q.EffectiveAt = req.At.In(q.CurrentPeriodFrom.Location())
+		if current.PayInAdvance {
+			q.UnusedCreditCents = prorate(int64(sub.PlanAmountCents), q.CurrentPeriodFrom, q.CurrentPeriodTo, q.EffectiveAt.AddDate(0, 0, 1), q.CurrentPeriodTo)
+		}
+	case Downgrade:
+		q.EffectiveAt = q.CurrentPeriodTo.Add(time.Second)
+	case Override:
+		// Overrides apply from the time of the change, within the current
+		// period.
+		q.EffectiveAt = req.At.In(q.CurrentPeriodFrom.Location())
+	}
+	if q.Change != Override {
+		next.StartedAt = &q.EffectiveAt
+	}
+
+	q.NewPeriodFrom, q.NewPeriodTo, err = billing.Period(&next, billing.Options{Plan: target, Timezone: timezone}, q.EffectiveAt)
+	if err != nil {
+		return nil, err
+	}
+	if q.Change == Override {
+		q.NewPeriodFrom = q.EffectiveAt
+	}
+	// The first period is prorated over the length of a full period ending
+	// where it ends.
+	fullFrom := fullPeriodStart(q.NewPeriodTo, target.Interval)
+	q.NewFeeCents = prorate(targetCents, fullFrom, q.NewPeriodTo, q.NewPeriodFrom, q.NewPeriodTo)
+
+	if q.Change != Override {
+		preview, lagoErr := client.Invoice().Preview(ctx, &lago.InvoicePreviewInput{
+			Customer: &lago.CustomerInput{ExternalID: sub.ExternalCustomerID},
+			Subscriptions: &lago.SubscriptionsInput{
+				ExternalIds: []string{sub.ExternalID},
+				PlanCode:    req.PlanCode,
+			},
+		})
+		if lagoErr != nil {
+			return nil, fmt.Errorf("quote: preview: %w", lagoErr)
+		}
+		q.Preview = preview
+	}
+	return q, nil
+}
+
+// Apply makes the quoted change: a new subscription with the same external
+// ID for upgrades and downgrades, which Lago applies according to its
+// rules, or an update of the plan overrides.
+func Apply(ctx context.Context, client *lago.Client, q *Quote) (*lago.Subscription, error) {
+	input := &lago.SubscriptionInput{
+		ExternalCustomerID: q.ExternalCustomerID,
+		ExternalID:         q.Request.ExternalSubscriptionID,
+		PlanCode:           q.Request.PlanCode,
+		PlanOverrides:      q.Request.PlanOverrides,
+		Name:               q.SubscriptionName,
+	}
+
+	var sub *lago.Subscription
+	var lagoErr *lago.Error
+	if q.Change == Override {
+		sub, lagoErr = client.Subscription().Update(ctx, input)
+	} else {
+		sub, lagoErr = client.Subscription().Create(ctx, input)
+	}
+	if lagoErr != nil {
+		return nil, fmt.Errorf("quote: %s %s: %w", q.Change, q.Request.ExternalSubscriptionID, lagoErr)
+	}
+	return sub, nil
+}
+
+// plan returns p when it carries its interval, or fetches the plan.
+func plan(ctx context.Context, client *lago.Client, p *lago.Plan, code string) (*lago.Plan, error) {
+	if p != nil && p.Interval != "" {
+		return p, nil
+	}
+	p, lagoErr := client.Plan().Get(ctx, code)
+	if lagoErr != nil {
+		return nil, fmt.Errorf("quote: plan %s: %w", code, lagoErr)
+	}
+	return p, nil
+}
+
+// yearlyCents returns the amount of a plan billed every interval over a
+// year, which Lago compares to tell upgrades from downgrades.
+func yearlyCents(cents int64, interval lago.PlanInterval) int64 {
+	switch interval {
+	case lago.PlanWeekly:
+		return cents * 52
+	case lago.PlanMonthly:
+		return cents * 12
+	case lago.PlanQuarterly:
+		return cents * 4
+	case lago.PlanSemiannual:
+		return cents * 2
+	default:
+		return cents
+	}
+}
+
+// fullPeriodStart returns the start of the full period of interval ending at
+// to.
+func fullPeriodStart(to time.Time, interval lago.PlanInterval) time.Time {
+	next := to.Add(time.Second)
+	switch interval {
+	case lago.PlanWeekly:
+		return next.AddDate(0, 0, -7)
+	case lago.PlanMonthly:
+		return next.AddDate(0, -1, 0)
+	case lago.PlanQuarterly:
+		return next.AddDate(0, -3, 0)
+	case lago.PlanSemiannual:
+		return next.AddDate(0, -6, 0)
+	default:
+		return next.AddDate(-1, 0, 0)
+	}
+}
+
+// prorate returns the share of cents for the days from from to to, out of
+// the days of the period from periodFrom to periodTo, all inclusive.
+func prorate(cents int64, periodFrom, periodTo, from, to time.Time) int64 {
+	total := days(periodFrom, periodTo)
+	used := days(from, to)
+	if total <= 0 || used <= 0 {
+		return 0
+	}
+	if used >= total {
+		return cents
+	}
+	return int64(math.Round(float64(cents) * float64(used) / float64(total)))
+}
+
+// days returns the number of calendar days from from to to, inclusive.
+func days(from, to time.Time) int {
+	fy, fm, fd := from.Date()
+	ty, tm, td := to.Date()
+	start := time.Date(fy, fm, fd, 0, 0, 0, 0, time.UTC)
+	end := time.Date(ty, tm, td, 0, 0, 0, 0, time.UTC)
+	return int(end.Sub(start).Hours()/24) + 1
+}
@@ -0,0 +1,146 @@
+package quote_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/quote"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+var responses = map[string]string{
+	"GET /subscriptions/sub_1": `{"subscription": {"external_id": "sub_1", "external_customer_id": "cus_1", "name": "Main",
+		"plan_code": "basic", "plan_amount_cents": 3000, "plan_amount_currency": "EUR", "billing_time": "calendar",
+		"subscription_at": "2026-03-01T00:00:00Z", "started_at": "2026-03-01T00:00:00Z"}}`,
+	"GET /customers/cus_1":     `{"customer": {"external_id": "cus_1", "applicable_timezone": "UTC"}}`,
+	"GET /plans/basic":         `{"plan": {"code": "basic", "interval": "monthly", "amount_cents": 3000, "amount_currency": "EUR", "pay_in_advance": true}}`,
+	"GET /plans/pro":           `{"plan": {"code": "pro", "interval": "monthly", "amount_cents": 6000, "amount_currency": "EUR", "pay_in_advance": true}}`,
+	"GET /plans/starter":       `{"plan": {"code": "starter", "interval": "yearly", "amount_cents": 20000, "amount_currency": "EUR"}}`,
+	"POST /invoices/preview":   `{"invoice": {"number": "preview", "currency": "EUR", "total_amount_cents": 4200}}`,
+	"POST /subscriptions":      `{"subscription": {"external_id": "sub_1", "plan_code": "pro"}}`,
+	"PUT /subscriptions/sub_1": `{"subscription": {"external_id": "sub_1", "plan_code": "basic"}}`,
+}
+
+func date(value string) time.Time {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestPrepare_Upgrade(t *testing.T) {
+	c := qt.New(t)
+	server := lt.NewRoutesServer(c, responses)
+	client := server.Client()
+
+	q, err := quote.Prepare(context.Background(), client, quote.Request{
+		ExternalSubscriptionID: "sub_1",
+		PlanCode:               "pro",
+		At:                     date("2026-04-11T10:00:00Z"),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(q.Change, qt.Equals, quote.Upgrade)
+	c.Assert(q.EffectiveAt.Equal(date("2026-04-11T10:00:00Z")), qt.IsTrue)
+	c.Assert(q.Currency, qt.Equals, lago.EUR)
+	c.Assert(q.CurrentPeriodFrom, qt.Equals, date("2026-04-01T00:00:00Z"))
+	c.Assert(q.CurrentPeriodTo, qt.Equals, date("2026-04-30T23:59:59Z"))
+	// 19 of 30 days left, from the day after the upgrade.
+	c.Assert(q.UnusedCreditCents, qt.Equals, int64(1900))
+	c.Assert(q.NewPeriodFrom.Equal(date("2026-04-11T10:00:00Z")), qt.IsTrue)
+	c.Assert(q.NewPeriodTo, qt.Equals, date("2026-04-30T23:59:59Z"))
+	// 20 of 30 days, the day of the upgrade included.
+	c.Assert(q.NewFeeCents, qt.Equals, int64(4000))
+	c.Assert(q.NewFeeInAdvance, qt.IsTrue)
+	c.Assert(q.NetCents(), qt.Equals, int64(2100))
+	c.Assert(q.Preview.Number, qt.Equals, "preview")
+
+	var preview map[string]any
+	c.Assert(json.Unmarshal([]byte(server.Last("POST", "/invoices/preview").Body), &preview), qt.IsNil)
+	c.Assert(preview["customer"].(map[string]any)["external_id"], qt.Equals, "cus_1")
+	c.Assert(preview["subscriptions"], qt.DeepEquals, map[string]any{"external_ids": []any{"sub_1"}, "plan_code": "pro"})
+}
+
+func TestPrepare_Downgrade(t *testing.T) {
+	c := qt.New(t)
+	client := lt.NewRoutesServer(c, responses).Client()
+
+	q, err := quote.Prepare(context.Background(), client, quote.Request{
+		ExternalSubscriptionID: "sub_1",
+		PlanCode:               "starter",
+		At:                     date("2026-04-11T10:00:00Z"),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(q.Change, qt.Equals, quote.Downgrade)
+	c.Assert(q.EffectiveAt, qt.Equals, date("2026-05-01T00:00:00Z"))
+	c.Assert(q.UnusedCreditCents, qt.Equals, int64(0))
+	c.Assert(q.NewPeriodFrom, qt.Equals, date("2026-05-01T00:00:00Z"))
+	c.Assert(q.NewPeriodTo, qt.Equals, date("2026-12-31T23:59:59Z"))
+	// 245 of 365 days of the calendar year.
+	c.Assert(q.NewFeeCents, qt.Equals, int64(13425))
+	c.Assert(q.NewFeeInAdvance, qt.IsFalse)
+	c.Assert(q.Preview, qt.Not(qt.IsNil))
+}
+
+func TestPrepare_Override(t *testing.T) {
+	c := qt.New(t)
+	server := lt.NewRoutesServer(c, responses)
+	client := server.Client()
+
+	q, err := quote.Prepare(context.Background(), client, quote.Request{
+		ExternalSubscriptionID: "sub_1",
+		PlanCode:               "basic",
+		PlanOverrides:          &lago.PlanOverridesInput{AmountCents: 4000},
+		At:                     date("2026-04-11T10:00:00Z"),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(q.Change, qt.Equals, quote.Override)
+	// Overrides apply at once, to the 20 days left in April.
+	c.Assert(q.EffectiveAt, qt.Equals, date("2026-04-11T10:00:00Z"))
+	c.Assert(q.NewPeriodFrom, qt.Equals, date("2026-04-11T10:00:00Z"))
+	c.Assert(q.NewPeriodTo, qt.Equals, date("2026-04-30T23:59:59Z"))
+	c.Assert(q.NewFeeCents, qt.Equals, int64(2667))
+	c.Assert(q.UnusedCreditCents, qt.Equals, int64(0))
+	c.Assert(q.Preview == nil, qt.IsTrue)
+
+	_, err = quote.Apply(context.Background(), client, q)
+	c.Assert(err, qt.IsNil)
+	c.Assert(server.Last("PUT", "/subscriptions/sub_1").Body, qt.Contains, `"amount_cents":4000`)
+}
+
+func TestPrepare_SamePlan(t *testing.T) {
+	c := qt.New(t)
+	client := lt.NewRoutesServer(c, responses).Client()
+
+	_, err := quote.Prepare(context.Background(), client, quote.Request{ExternalSubscriptionID: "sub_1", PlanCode: "basic"})
+	c.Assert(err, qt.Equals, quote.ErrSamePlan)
+
+	_, err = quote.Prepare(context.Background(), client, quote.Request{ExternalSubscriptionID: "sub_2", PlanCode: "pro"})
+	c.Assert(err, qt.ErrorMatches, `quote: subscription sub_2: .*`)
+}
+
+func TestApply(t *testing.T) {
+	c := qt.New(t)
+	server := lt.NewRoutesServer(c, responses)
+	client := server.Client()
+
+	q, err := quote.Prepare(context.Background(), client, quote.Request{ExternalSubscriptionID: "sub_1", PlanCode: "pro"})
+	c.Assert(err, qt.IsNil)
+	sub, err := quote.Apply(context.Background(), client, q)
+	c.Assert(err, qt.IsNil)
+	c.Assert(sub.PlanCode, qt.Equals, "pro")
+
+	var body struct {
+		Subscription map[string]any `json:"subscription"`
+	}
+	c.Assert(json.Unmarshal([]byte(server.Last("POST", "/subscriptions").Body), &body), qt.IsNil)
+	c.Assert(body.Subscription["external_id"], qt.Equals, "sub_1")
+	c.Assert(body.Subscription["external_customer_id"], qt.Equals, "cus_1")
+	c.Assert(body.Subscription["plan_code"], qt.Equals, "pro")
+	c.Assert(body.Subscription["name"], qt.Equals, "Main")
+}
@@ -0,0 +1,174 @@
+package reconcile
+
+import (
+	"fmt"
+	"math"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Severity tells how reliable a finding is.
+type Severity string
+
+const (
+	// SeverityError is an exact identity that does not hold.
+	SeverityError Severity = "error"
+	// SeverityWarning is a difference larger than the rounding Lago applies
+	// to taxes, which usually but not always points to an error.
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is an amount of an invoice that does not match the amounts it is
+// computed from.
+type Finding struct {
+	Severity Severity
+	// Field is the JSON path of the checked amount, such as
+	// "sub_total_excluding_taxes_amount_cents" or "fees[2].total_amount_cents".
+	Field string
+	// FeeID is set for findings on a fee.
+	FeeID string
+	// Rule is the identity that does not hold, such as
+	// "fees_amount_cents = sum(fees[].amount_cents)".
+	Rule     string
+	Expected int64
+	Actual   int64
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s is %d, expected %d (%s)", f.Severity, f.Field, f.Actual, f.Expected, f.Rule)
+}
+
+// CheckInvoice checks the arithmetic of an invoice as returned by
+// InvoiceRequest.Get, with its fees, credits and applied taxes:
+//
+//	fees_amount_cents                       = sum(fees[].amount_cents)
+//	coupons_amount_cents                    = sum(coupon credits)
+//	progressive_billing_credit_amount_cents = sum(invoice credits)
+//	credit_notes_amount_cents               = sum(credit note credits)
+//	sub_total_excluding_taxes_amount_cents  = fees - coupons - progressive billing credits
+//	taxes_amount_cents                      = sum(applied_taxes[].amount_cents)
+//	sub_total_including_taxes_amount_cents  = sub total excluding taxes + taxes
+//	total_amount_cents                      = sub total including taxes - credit notes - prepaid credits
+//	0 <= total_due_amount_cents <= total_amount_cents
+//
+// and for every fee that total_amount_cents = amount_cents +
+// taxes_amount_cents.
+//
+// Taxes are computed on precise amounts and rounded late, so the sum of the
+// fee taxes, the applied taxes of each fee and the amount of each applied
+// tax are only compared within a rounding tolerance and reported as
+// warnings.
+//
+// It returns no finding when the invoice is consistent.
+func CheckInvoice(invoice *lago.Invoice) []Finding {
+	c := &invoiceChecker{}
+
+	var feesAmount, feesTaxes int64
+	for i, fee := range invoice.Fees {
+		feesAmount += int64(fee.AmountCents)
+		feesTaxes += int64(fee.TaxesAmountCents)
+		c.checkFee(invoice, i, &fee)
+	}
+
+	var coupons, creditNotes, progressiveBilling int64
+	for i, credit := range invoice.Credits {
+		switch credit.Item.Type {
+		case lago.InvoiceCreditItemCoupon:
+			coupons += int64(credit.AmountCents)
+		case lago.InvoiceCreditItemCreditNote:
+			creditNotes += int64(credit.AmountCents)
+		case lago.InvoiceCreditItemInvoice:
+			progressiveBilling += int64(credit.AmountCents)
+		}
+		if credit.AmountCurrency != "" && credit.AmountCurrency != invoice.Currency {
+			c.add(Finding{
+				Severity: SeverityError,
+				Field:    fmt.Sprintf("credits[%d].amount_currency", i),
+				Rule:     fmt.Sprintf("credit currency %s = invoice currency %s", credit.AmountCurrency, invoice.Currency),
+			})
+		}
+	}
+
+	var taxes int64
+	for i, tax := range invoice.AppliedTaxes {
+		taxes += int64(tax.AmountCents)
+		expected := int64(math.Round(float64(tax.FeesAmountCents) * float64(tax.TaxRate) / 100))
+		c.within(1, fmt.Sprintf("applied_taxes[%d].amount_cents", i), "", "amount_cents = fees_amount_cents * tax_rate / 100", expected, int64(tax.AmountCents))
+	}
+
+	subTotalExcludingTaxes := int64(invoice.FeesAmountCents) - int64(invoice.CouponsAmountCents) - int64(invoice.ProgressiveBillingCreditAmountCents)
+	subTotalIncludingTaxes := int64(invoice.SubTotalExcludingTaxesAmountCents) + int64(invoice.TaxesAmountCents)
+	total := int64(invoice.SubTotalIncludingTaxesAmountCents) - int64(invoice.CreditNotesAmountCents) - int64(invoice.PrepaidCreditAmountCents)
+
+	c.equal("fees_amount_cents", "", "fees_amount_cents = sum(fees[].amount_cents)", feesAmount, int64(invoice.FeesAmountCents))
+	c.equal("coupons_amount_cents", "", "coupons_amount_cents = sum(coupon credits)", coupons, int64(invoice.CouponsAmountCents))
+	c.equal("progressive_billing_credit_amount_cents", "", "progressive_billing_credit_amount_cents = sum(progressive billing invoice credits)", progressiveBilling, int64(invoice.ProgressiveBillingCreditAmountCents))
+	c.equal("credit_notes_amount_cents", "", "credit_notes_amount_cents = sum(credit note credits)", creditNotes, int64(invoice.CreditNotesAmountCents))
+	c.equal("sub_total_excluding_taxes_amount_cents", "", "sub_total_excluding_taxes_amount_cents = fees - coupons - progressive billing credits", subTotalExcludingTaxes, int64(invoice.SubTotalExcludingTaxesAmountCents))
+	c.equal("taxes_amount_cents", "", "taxes_amount_cents = sum(applied_taxes[].amount_cents)", taxes, int64(invoice.TaxesAmountCents))
+	c.within(int64(len(invoice.Fees)), "taxes_amount_cents", "", "taxes_amount_cents = sum(fees[].taxes_amount_cents)", feesTaxes, int64(invoice.TaxesAmountCents))
+	c.equal("sub_total_including_taxes_amount_cents", "", "sub_total_including_taxes_amount_cents = sub total excluding taxes + taxes", subTotalIncludingTaxes, int64(invoice.SubTotalIncludingTaxesAmountCents))
+
+	if granted, purchased := invoice.PrepaidGrantedCreditAmountCents, invoice.PrepaidPurchasedCreditAmountCents; granted != nil && purchased != nil {
+		c.equal("prepaid_credit_amount_cents", "", "prepaid_credit_amount_cents = granted + purchased prepaid credits", int64(*granted)+int64(*purchased), int64(invoice.PrepaidCreditAmountCents))
+	}
+
+	c.equal("total_amount_cents", "", "total_amount_cents = sub total including taxes - credit notes - prepaid credits", total, int64(invoice.TotalAmountCents))
+
+	switch due := int64(invoice.TotalDueAmountCents); {
+	case due < 0:
+		c.add(Finding{Severity: SeverityError, Field: "total_due_amount_cents", Rule: "total_due_amount_cents >= 0", Expected: 0, Actual: due})
+	case due > int64(invoice.TotalAmountCents):
+		c.add(Finding{Severity: SeverityError, Field: "total_due_amount_cents", Rule: "total_due_amount_cents <= total_amount_cents", Expected: int64(invoice.TotalAmountCents), Actual: due})
+	}
+
+	return c.findings
+}
+
+type invoiceChecker struct {
+	findings []Finding
+}
+
+func (c *invoiceChecker) add(finding Finding) {
+	c.findings = append(c.findings, finding)
+}
+
+func (c *invoiceChecker) equal(field, feeID, rule string, expected, actual int64) {
+	if expected != actual {
+		c.add(Finding{Severity: SeverityError, Field: field, FeeID: feeID, Rule: rule, Expected: expected, Actual: actual})
+	}
+}
+
+// within reports a warning when actual is more than tolerance cents away
+// from expected.
+func (c *invoiceChecker) within(tolerance int64, field, feeID, rule string, expected, actual int64) {
+	if diff := expected - actual; diff > tolerance || -diff > tolerance {
+		c.add(Finding{Severity: SeverityWarning, Field: field, FeeID: feeID, Rule: rule, Expected: expected, Actual: actual})
+	}
+}
+
+func (c *invoiceChecker) checkFee(invoice *lago.Invoice, i int, fee *lago.Fee) {
+	field := func(name string) string {
+		return fmt.Sprintf("fees[%d].%s", i, name)
+	}
+	id := fee.LagoID.String()
+
+	if fee.AmountCurrency != "" && lago.Currency(fee.AmountCurrency) != invoice.Currency {
+		c.add(Finding{
+			Severity: SeverityError,
+			Field:    field("amount_currency"),
+			FeeID:    id,
+			Rule:     fmt.Sprintf("fee currency %s = invoice currency %s", fee.AmountCurrency, invoice.Currency),
+		})
+	}
+
+	var taxes int64
+	for _, tax := range fee.AppliedTaxes {
+		taxes += int64(tax.AmountCents)
+	}
+	if len(fee.AppliedTaxes) > 0 {
+		c.within(int64(len(fee.AppliedTaxes)), field("taxes_amount_cents"), id, "taxes_amount_cents = sum(applied_taxes[].amount_cents)", taxes, int64(fee.TaxesAmountCents))
+	}
+
+	c.equal(field("total_amount_cents"), id, "total_amount_cents = amount_cents + taxes_amount_cents", int64(fee.AmountCents)+int64(fee.TaxesAmountCents), int64(fee.TotalAmountCents))
+}
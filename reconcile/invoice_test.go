@@ -0,0 +1,113 @@
+package reconcile_test
+
+import (
+	"encoding/json"
+	"os"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/reconcile"
+)
+
+// consistentInvoice has two fees of 100.00 EUR and 50.00 EUR, a 10.00 EUR
+// coupon, 20% VAT on the 140.00 EUR left, 5.00 EUR of credit notes and
+// 15.00 EUR of prepaid credits.
+func consistentInvoice() *lago.Invoice {
+	return &lago.Invoice{
+		Currency: "EUR",
+		Fees: []lago.Fee{
+			{
+				AmountCents: 10000, AmountCurrency: "EUR", TaxesAmountCents: 1867, TotalAmountCents: 11867,
+				AppliedTaxes: []lago.FeeAppliedTax{{AmountCents: 1867}},
+			},
+			{
+				AmountCents: 5000, AmountCurrency: "EUR", TaxesAmountCents: 933, TotalAmountCents: 5933,
+				AppliedTaxes: []lago.FeeAppliedTax{{AmountCents: 933}},
+			},
+		},
+		Credits: []lago.InvoiceCredit{
+			{Item: lago.InvoiceCreditItem{Type: lago.InvoiceCreditItemCoupon}, AmountCents: 1000, AmountCurrency: "EUR"},
+			{Item: lago.InvoiceCreditItem{Type: lago.InvoiceCreditItemCreditNote}, AmountCents: 500, AmountCurrency: "EUR"},
+		},
+		AppliedTaxes: []lago.InvoiceAppliedTax{{TaxRate: 20, FeesAmountCents: 14000, AmountCents: 2800}},
+
+		FeesAmountCents:                   15000,
+		CouponsAmountCents:                1000,
+		SubTotalExcludingTaxesAmountCents: 14000,
+		TaxesAmountCents:                  2800,
+		SubTotalIncludingTaxesAmountCents: 16800,
+		CreditNotesAmountCents:            500,
+		PrepaidCreditAmountCents:          1500,
+		PrepaidGrantedCreditAmountCents:   lago.Ptr(1000),
+		PrepaidPurchasedCreditAmountCents: lago.Ptr(500),
+		TotalAmountCents:                  14800,
+		TotalDueAmountCents:               14800,
+	}
+}
+
+func TestCheckInvoice_Consistent(t *testing.T) {
+	c := qt.New(t)
+
+	c.Assert(reconcile.CheckInvoice(consistentInvoice()), qt.HasLen, 0)
+}
+
+func TestCheckInvoice_Findings(t *testing.T) {
+	c := qt.New(t)
+
+	invoice := consistentInvoice()
+	invoice.Fees[1].TotalAmountCents = 5900
+	invoice.Fees[1].AmountCurrency = "USD"
+	invoice.TaxesAmountCents = 2700
+	invoice.TotalDueAmountCents = 20000
+
+	var findings []string
+	for _, finding := range reconcile.CheckInvoice(invoice) {
+		findings = append(findings, finding.String())
+	}
+	c.Assert(findings, qt.DeepEquals, []string{
+		"error: fees[1].amount_currency is 0, expected 0 (fee currency USD = invoice currency EUR)",
+		"error: fees[1].total_amount_cents is 5900, expected 5933 (total_amount_cents = amount_cents + taxes_amount_cents)",
+		"error: taxes_amount_cents is 2700, expected 2800 (taxes_amount_cents = sum(applied_taxes[].amount_cents))",
+		"warning: taxes_amount_cents is 2700, expected 2800 (taxes_amount_cents = sum(fees[].taxes_amount_cents))",
+		"error: sub_total_including_taxes_amount_cents is 16800, expected 16700 (sub_total_including_taxes_amount_cents = sub total excluding taxes + taxes)",
+		"error: total_due_amount_cents is 20000, expected 14800 (total_due_amount_cents <= total_amount_cents)",
+	})
+}
+
+func TestCheckInvoice_RoundingTolerance(t *testing.T) {
+	c := qt.New(t)
+
+	// Fee taxes are rounded separately: 18.67 + 9.34 is a cent above the
+	// invoice taxes, which is within the tolerance.
+	invoice := consistentInvoice()
+	invoice.Fees[1].TaxesAmountCents = 934
+	invoice.Fees[1].TotalAmountCents = 5934
+	invoice.Fees[1].AppliedTaxes[0].AmountCents = 934
+
+	c.Assert(reconcile.CheckInvoice(invoice), qt.HasLen, 0)
+}
+
+func TestCheckInvoice_WebhookFixture(t *testing.T) {
+	c := qt.New(t)
+
+	data, err := os.ReadFile("../testing/fixtures/webhooks/invoice_created.json")
+	c.Assert(err, qt.IsNil)
+	var webhook struct {
+		Invoice lago.Invoice `json:"invoice"`
+	}
+	c.Assert(json.Unmarshal(data, &webhook), qt.IsNil)
+
+	// The fixture has a coupon credit missing from its totals and applied
+	// taxes of 1.00 EUR on a fee taxed 0.20 EUR.
+	var fields []string
+	for _, finding := range reconcile.CheckInvoice(&webhook.Invoice) {
+		fields = append(fields, finding.Field)
+	}
+	c.Assert(fields, qt.DeepEquals, []string{
+		"fees[0].taxes_amount_cents",
+		"coupons_amount_cents",
+		"taxes_amount_cents",
+	})
+}
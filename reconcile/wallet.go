@@ -0,0 +1,398 @@
+// Package reconcile checks that amounts reported by Lago are consistent with
+// the records they derive from.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+const perPage = 100
+
+// DiscrepancyKind identifies the check that failed.
+type DiscrepancyKind string
+
+const (
+	// CreditsBalanceMismatch means the rebuilt credits balance differs from
+	// Wallet.CreditsBalance.
+	CreditsBalanceMismatch DiscrepancyKind = "credits_balance"
+	// BalanceMismatch means the rebuilt balance converted with the wallet rate
+	// differs from Wallet.BalanceCents.
+	BalanceMismatch DiscrepancyKind = "balance_cents"
+	// RemainingCreditsMismatch means the consumptions of an inbound
+	// transaction do not add up to its credit amount minus its remaining
+	// credits.
+	RemainingCreditsMismatch DiscrepancyKind = "remaining_credits"
+	// FundingMismatch means the fundings of an outbound transaction do not
+	// add up to its credit amount.
+	FundingMismatch DiscrepancyKind = "funding"
+	// InvalidAmount means an amount returned by the API cannot be parsed.
+	InvalidAmount DiscrepancyKind = "invalid_amount"
+)
+
+// Discrepancy is a difference between what Lago reports and what the
+// transaction history implies.
+type Discrepancy struct {
+	Kind DiscrepancyKind
+	// TransactionID is empty for wallet level discrepancies.
+	TransactionID string
+	Expected      string
+	Actual        string
+}
+
+func (d Discrepancy) String() string {
+	if d.TransactionID == "" {
+		return fmt.Sprintf("%s: expected %s, got %s", d.Kind, d.Expected, d.Actual)
+	}
+
+	return fmt.Sprintf("%s on transaction %s: expected %s, got %s", d.Kind, d.TransactionID, d.Expected, d.Actual)
+}
+
+// LedgerEntry is a transaction with its effect on the wallet credits.
+type LedgerEntry struct {
+	Transaction lago.WalletTransaction
+	// Credits is the signed change of the credits balance: positive for
+	// settled inbound transactions, negative for settled outbound ones and
+	// zero for pending or failed transactions.
+	Credits lago.Decimal
+	// Balance is the running credits balance after the transaction.
+	Balance lago.Decimal
+}
+
+// UnmatchedConsumption is consumed credits that cannot be traced back to
+// their funding: an outbound transaction only partly funded by inbound
+// transactions, or a consumption record whose outbound transaction is not in
+// the wallet history.
+type UnmatchedConsumption struct {
+	// OutboundTransactionID is the consuming transaction.
+	OutboundTransactionID string
+	// InboundTransactionID is set for consumptions read from an inbound
+	// transaction that reference an unknown outbound transaction.
+	InboundTransactionID string
+	Credits              lago.Decimal
+}
+
+// ExpiredCredit is credits left on an inbound transaction of a wallet past
+// its expiration date.
+type ExpiredCredit struct {
+	TransactionID string
+	Credits       lago.Decimal
+	ExpiredAt     time.Time
+}
+
+// WalletReport is the outcome of the reconciliation of a wallet.
+type WalletReport struct {
+	Wallet *lago.Wallet
+	// Entries is the transaction history, oldest first, with the running
+	// credits balance.
+	Entries []LedgerEntry
+
+	// Credits is the credits balance rebuilt from the history.
+	Credits lago.Decimal
+	// Balance is Credits converted with the wallet rate amount.
+	Balance lago.Money
+
+	Discrepancies         []Discrepancy
+	UnmatchedConsumptions []UnmatchedConsumption
+	ExpiredCredits        []ExpiredCredit
+}
+
+// OK reports whether the wallet reconciles without finding.
+func (r *WalletReport) OK() bool {
+	return len(r.Discrepancies) == 0 && len(r.UnmatchedConsumptions) == 0 && len(r.ExpiredCredits) == 0
+}
+
+// WalletOptions tunes a wallet reconciliation.
+type WalletOptions struct {
+	// SkipTraceability skips the consumption and funding checks, which cost
+	// one request per transaction.
+	SkipTraceability bool
+	// Now is the date used to decide whether the wallet has expired; zero
+	// means time.Now.
+	Now time.Time
+}
+
+// Wallet rebuilds the balance of a wallet from its transaction history and
+// compares it to the balance reported by Lago.
+//
+// Settled inbound transactions (purchased or granted credits) add credits
+// and settled outbound transactions (invoiced or voided credits) remove
+// them; pending and failed transactions have no effect. Unless
+// SkipTraceability is set, the consumptions of every inbound transaction and
+// the fundings of every outbound transaction are read to check that consumed
+// credits are traced to their funding.
+func Wallet(ctx context.Context, client *lago.Client, walletID string, opts WalletOptions) (*WalletReport, error) {
+	wallet, lagoErr := client.Wallet().Get(ctx, walletID)
+	if lagoErr != nil {
+		return nil, lagoErr
+	}
+
+	transactions, err := walletTransactions(ctx, client, walletID)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &reconciler{ctx: ctx, client: client, opts: opts, report: &WalletReport{Wallet: wallet}}
+	r.rebuild(transactions)
+	r.checkBalance()
+	if !opts.SkipTraceability {
+		if err := r.checkTraceability(); err != nil {
+			return nil, err
+		}
+	}
+
+	return r.report, nil
+}
+
+type reconciler struct {
+	ctx    context.Context
+	client *lago.Client
+	opts   WalletOptions
+	report *WalletReport
+
+	// remaining is the credits left on each settled inbound transaction, as
+	// reported by Lago or computed from its consumptions.
+	remaining map[string]lago.Decimal
+}
+
+func (r *reconciler) discrepancy(kind DiscrepancyKind, transactionID string, expected, actual fmt.Stringer) {
+	r.report.Discrepancies = append(r.report.Discrepancies, Discrepancy{
+		Kind:          kind,
+		TransactionID: transactionID,
+		Expected:      expected.String(),
+		Actual:        actual.String(),
+	})
+}
+
+// amount parses a credit amount, recording a discrepancy when it is invalid.
+func (r *reconciler) amount(transactionID string, value string) lago.Decimal {
+	if value == "" {
+		return lago.Decimal{}
+	}
+
+	amount, err := lago.ParseDecimal(value)
+	if err != nil {
+		r.report.Discrepancies = append(r.report.Discrepancies, Discrepancy{
+			Kind:          InvalidAmount,
+			TransactionID: transactionID,
+			Expected:      "a decimal",
+			Actual:        value,
+		})
+	}
+
+	return amount
+}
+
+func (r *reconciler) rebuild(transactions []lago.WalletTransaction) {
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].CreatedAt.Before(transactions[j].CreatedAt)
+	})
+
+	balance := lago.Decimal{}
+	for _, transaction := range transactions {
+		credits := lago.Decimal{}
+		if transaction.Status == lago.WalletTransactionStatusSettled {
+			credits = r.amount(transaction.LagoID.String(), transaction.CreditAmount)
+			if transaction.TransactionType == lago.Outbound {
+				credits = credits.Neg()
+			}
+		}
+		balance = balance.Add(credits)
+
+		r.report.Entries = append(r.report.Entries, LedgerEntry{
+			Transaction: transaction,
+			Credits:     credits,
+			Balance:     balance,
+		})
+	}
+
+	r.report.Credits = balance
+}
+
+func (r *reconciler) checkBalance() {
+	wallet := r.report.Wallet
+
+	reported := r.amount("", wallet.CreditsBalance)
+	if !reported.Equal(r.report.Credits) {
+		r.discrepancy(CreditsBalanceMismatch, "", r.report.Credits, reported)
+	}
+
+	balance, err := wallet.CreditsToMoney(r.report.Credits, lago.RoundRoundingFunction)
+	if err != nil {
+		r.report.Discrepancies = append(r.report.Discrepancies, Discrepancy{
+			Kind:     InvalidAmount,
+			Expected: "a decimal rate amount",
+			Actual:   wallet.RateAmount,
+		})
+		return
+	}
+	r.report.Balance = balance
+
+	if reported := wallet.Balance(); reported != balance {
+		r.discrepancy(BalanceMismatch, "", balance, reported)
+	}
+}
+
+func (r *reconciler) checkTraceability() error {
+	known := map[string]lago.WalletTransaction{}
+	for _, entry := range r.report.Entries {
+		known[entry.Transaction.LagoID.String()] = entry.Transaction
+	}
+
+	r.remaining = map[string]lago.Decimal{}
+	for _, entry := range r.report.Entries {
+		transaction := entry.Transaction
+		if transaction.Status != lago.WalletTransactionStatusSettled {
+			continue
+		}
+
+		var err error
+		if transaction.TransactionType == lago.Inbound {
+			err = r.checkConsumptions(transaction, known)
+		} else {
+			err = r.checkFundings(transaction)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	r.checkExpiration()
+
+	return nil
+}
+
+// checkConsumptions compares the consumptions of an inbound transaction with
+// its remaining credits.
+func (r *reconciler) checkConsumptions(inbound lago.WalletTransaction, known map[string]lago.WalletTransaction) error {
+	id := inbound.LagoID.String()
+
+	consumptions, err := lago.FetchPages(0, func(page int) ([]lago.WalletTransactionConsumption, lago.Metadata, *lago.Error) {
+		result, err := r.client.WalletTransaction().Consumptions(r.ctx, id, &lago.WalletTransactionPaginationInput{
+			Page:    lago.Ptr(page),
+			PerPage: lago.Ptr(perPage),
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.WalletTransactionConsumptions, result.Meta, nil
+	})
+	if err != nil {
+		return fmt.Errorf("reconcile: consumptions of transaction %s: %w", id, err)
+	}
+
+	consumed := lago.Decimal{}
+	for _, consumption := range consumptions {
+		credits := r.amount(id, consumption.CreditAmount)
+		consumed = consumed.Add(credits)
+
+		if consumption.WalletTransaction == nil {
+			continue
+		}
+		outbound, ok := known[consumption.WalletTransaction.LagoID.String()]
+		if !ok || outbound.TransactionType != lago.Outbound {
+			r.report.UnmatchedConsumptions = append(r.report.UnmatchedConsumptions, UnmatchedConsumption{
+				OutboundTransactionID: consumption.WalletTransaction.LagoID.String(),
+				InboundTransactionID:  id,
+				Credits:               credits,
+			})
+		}
+	}
+
+	funded := r.amount(id, inbound.CreditAmount)
+	remaining := funded.Sub(consumed)
+	if inbound.RemainingCreditAmount != nil {
+		reported := r.amount(id, *inbound.RemainingCreditAmount)
+		if !reported.Equal(remaining) {
+			r.discrepancy(RemainingCreditsMismatch, id, remaining, reported)
+		}
+	}
+	r.remaining[id] = remaining
+
+	return nil
+}
+
+// checkFundings compares the fundings of an outbound transaction with its
+// credit amount.
+func (r *reconciler) checkFundings(outbound lago.WalletTransaction) error {
+	id := outbound.LagoID.String()
+
+	fundings, err := lago.FetchPages(0, func(page int) ([]lago.WalletTransactionFunding, lago.Metadata, *lago.Error) {
+		result, err := r.client.WalletTransaction().Fundings(r.ctx, id, &lago.WalletTransactionPaginationInput{
+			Page:    lago.Ptr(page),
+			PerPage: lago.Ptr(perPage),
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.WalletTransactionFundings, result.Meta, nil
+	})
+	if err != nil {
+		return fmt.Errorf("reconcile: fundings of transaction %s: %w", id, err)
+	}
+
+	funded := lago.Decimal{}
+	for _, funding := range fundings {
+		funded = funded.Add(r.amount(id, funding.CreditAmount))
+	}
+
+	consumed := r.amount(id, outbound.CreditAmount)
+	switch {
+	case funded.Cmp(consumed) < 0:
+		r.report.UnmatchedConsumptions = append(r.report.UnmatchedConsumptions, UnmatchedConsumption{
+			OutboundTransactionID: id,
+			Credits:               consumed.Sub(funded),
+		})
+	case funded.Cmp(consumed) > 0:
+		r.discrepancy(FundingMismatch, id, consumed, funded)
+	}
+
+	return nil
+}
+
+// checkExpiration reports the credits left on inbound transactions once the
+// wallet has expired: Lago should have voided them.
+func (r *reconciler) checkExpiration() {
+	expiration := r.report.Wallet.ExpirationAt
+	now := r.opts.Now
+	if now.IsZero() {
+		now = time.Now()
+	}
+	if expiration.IsZero() || expiration.After(now) {
+		return
+	}
+
+	for _, entry := range r.report.Entries {
+		id := entry.Transaction.LagoID.String()
+		if remaining, ok := r.remaining[id]; ok && remaining.Sign() > 0 {
+			r.report.ExpiredCredits = append(r.report.ExpiredCredits, ExpiredCredit{
+				TransactionID: id,
+				Credits:       remaining,
+				ExpiredAt:     expiration,
+			})
+		}
+	}
+}
+
+func walletTransactions(ctx context.Context, client *lago.Client, walletID string) ([]lago.WalletTransaction, error) {
+	transactions, err := lago.FetchPages(0, func(page int) ([]lago.WalletTransaction, lago.Metadata, *lago.Error) {
+		result, err := client.WalletTransaction().GetList(ctx, &lago.WalletTransactionListInput{
+			WalletID: walletID,
+			Page:     lago.Ptr(page),
+			PerPage:  lago.Ptr(perPage),
+		})
+		if err != nil {
+			return nil, lago.Metadata{}, err
+		}
+		return result.WalletTransactions, result.Meta, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("reconcile: transactions of wallet %s: %w", walletID, err)
+	}
+
+	return transactions, nil
+}
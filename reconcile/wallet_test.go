@@ -0,0 +1,122 @@
+package reconcile_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/reconcile"
+	lt "github.com/getlago/lago-go-client/testing"
+)
+
+const (
+	walletID   = "1a901a90-1a90-1a90-1a90-1a901a901a90"
+	purchaseID = "2b902b90-2b90-2b90-2b90-2b902b902b90"
+	grantID    = "3c903c90-3c90-3c90-3c90-3c903c903c90"
+	invoicedID = "4d904d90-4d90-4d90-4d90-4d904d904d90"
+	pendingID  = "5e905e90-5e90-5e90-5e90-5e905e905e90"
+)
+
+// walletResponses describes a wallet credited with 10 purchased and 10
+// granted credits, 5 of which were invoiced, and a pending purchase of 100.
+func walletResponses(wallet string, grantRemaining string, invoicedFunding string) map[string]string {
+	return map[string]string{
+		"/wallets/" + walletID: `{"wallet": ` + wallet + `}`,
+		// Transactions are listed newest first.
+		"/wallets/" + walletID + "/wallet_transactions": `{"wallet_transactions": [
+			{"lago_id": "` + pendingID + `", "status": "pending", "transaction_type": "inbound", "transaction_status": "purchased", "credit_amount": "100.0", "created_at": "2026-03-04T00:00:00Z"},
+			{"lago_id": "` + invoicedID + `", "status": "settled", "transaction_type": "outbound", "transaction_status": "invoiced", "credit_amount": "5.0", "created_at": "2026-03-03T00:00:00Z"},
+			{"lago_id": "` + grantID + `", "status": "settled", "transaction_type": "inbound", "transaction_status": "granted", "credit_amount": "10.0", "remaining_credit_amount": "` + grantRemaining + `", "created_at": "2026-03-02T00:00:00Z"},
+			{"lago_id": "` + purchaseID + `", "status": "settled", "transaction_type": "inbound", "transaction_status": "purchased", "credit_amount": "10.0", "remaining_credit_amount": "5.0", "created_at": "2026-03-01T00:00:00Z"}
+		], "meta": {"current_page": 1}}`,
+		"/wallet_transactions/" + purchaseID + "/consumptions": `{"wallet_transaction_consumptions": [
+			{"credit_amount": "5.0", "wallet_transaction": {"lago_id": "` + invoicedID + `"}}
+		], "meta": {}}`,
+		"/wallet_transactions/" + grantID + "/consumptions": `{"wallet_transaction_consumptions": [], "meta": {}}`,
+		"/wallet_transactions/" + invoicedID + "/fundings": `{"wallet_transaction_fundings": [
+			{"credit_amount": "` + invoicedFunding + `", "wallet_transaction": {"lago_id": "` + purchaseID + `"}}
+		], "meta": {}}`,
+	}
+}
+
+func TestWallet_Reconciles(t *testing.T) {
+	c := qt.New(t)
+
+	client := lt.NewRoutesServer(c, walletResponses(
+		`{"lago_id": "`+walletID+`", "currency": "EUR", "rate_amount": "1.5", "credits_balance": "15.0", "balance_cents": 2250}`,
+		"10.0", "5.0",
+	)).Client()
+
+	report, err := reconcile.Wallet(context.Background(), client, walletID, reconcile.WalletOptions{})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.Discrepancies, qt.HasLen, 0)
+	c.Assert(report.OK(), qt.IsTrue)
+	c.Assert(report.Credits.String(), qt.Equals, "15.0")
+	c.Assert(report.Balance, qt.Equals, lago.NewMoney(2250, "EUR"))
+
+	// Entries are sorted oldest first with a running balance.
+	var balances []string
+	for _, entry := range report.Entries {
+		balances = append(balances, entry.Balance.String())
+	}
+	c.Assert(balances, qt.DeepEquals, []string{"10.0", "20.0", "15.0", "15.0"})
+}
+
+func TestWallet_Findings(t *testing.T) {
+	c := qt.New(t)
+
+	client := lt.NewRoutesServer(c, walletResponses(
+		`{"lago_id": "`+walletID+`", "currency": "EUR", "rate_amount": "1", "credits_balance": "20.0", "balance_cents": 1500, "expiration_at": "2026-04-01T00:00:00Z"}`,
+		"8.0", "3.0",
+	)).Client()
+
+	report, err := reconcile.Wallet(context.Background(), client, walletID, reconcile.WalletOptions{
+		Now: time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+	})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.OK(), qt.IsFalse)
+
+	var findings []string
+	for _, d := range report.Discrepancies {
+		findings = append(findings, d.String())
+	}
+	c.Assert(findings, qt.DeepEquals, []string{
+		"credits_balance: expected 15.0, got 20.0",
+		"remaining_credits on transaction " + grantID + ": expected 10.0, got 8.0",
+	})
+
+	c.Assert(report.UnmatchedConsumptions, qt.HasLen, 1)
+	c.Assert(report.UnmatchedConsumptions[0].OutboundTransactionID, qt.Equals, invoicedID)
+	c.Assert(report.UnmatchedConsumptions[0].Credits.String(), qt.Equals, "2.0")
+
+	c.Assert(report.ExpiredCredits, qt.HasLen, 2)
+	c.Assert(report.ExpiredCredits[0].TransactionID, qt.Equals, purchaseID)
+	c.Assert(report.ExpiredCredits[0].Credits.String(), qt.Equals, "5.0")
+	c.Assert(report.ExpiredCredits[1].Credits.String(), qt.Equals, "10.0")
+}
+
+func TestWallet_SkipTraceability(t *testing.T) {
+	c := qt.New(t)
+
+	responses := walletResponses(
+		`{"lago_id": "`+walletID+`", "currency": "EUR", "rate_amount": "1", "credits_balance": "15.0", "balance_cents": 1500}`,
+		"10.0", "5.0",
+	)
+	for path := range responses {
+		if strings.HasPrefix(path, "/wallet_transactions/") {
+			delete(responses, path)
+		}
+	}
+	client := lt.NewRoutesServer(c, responses).Client()
+
+	report, err := reconcile.Wallet(context.Background(), client, walletID, reconcile.WalletOptions{SkipTraceability: true})
+	c.Assert(err, qt.IsNil)
+	c.Assert(report.OK(), qt.IsTrue)
+
+	_, err = reconcile.Wallet(context.Background(), client, walletID, reconcile.WalletOptions{})
+	c.Assert(err, qt.ErrorMatches, "reconcile: consumptions of transaction "+purchaseID+": .*")
+}
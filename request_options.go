@@ -0,0 +1,173 @@
+package lago
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+)
+
+// IdempotencyKeyHeader is the header used to send an idempotency key with a request.
+const IdempotencyKeyHeader string = "Idempotency-Key"
+
+// RequestOption customises a single API call.
+//
+// Options are carried by the context passed to any resource method, so every
+// existing call site can opt in without a signature change:
+//
+//	ctx := lago.WithRequestOptions(ctx,
+//		lago.WithTimeout(5*time.Second),
+//		lago.WithApiKeyOverride(orgApiKey),
+//	)
+//	invoice, err := client.Invoice().Get(ctx, invoiceID)
+type RequestOption func(*requestOptions)
+
+// ResponseHook is invoked with the final HTTP response of a call, after all
+// retries, whether it succeeded or not.
+type ResponseHook func(resp *http.Response, attempts int)
+
+type requestOptions struct {
+	timeout       time.Duration
+	headers       map[string]string
+	apiKey        string
+	disableRetry  bool
+	responseHooks []ResponseHook
+}
+
+type requestOptionsKey struct{}
+
+// WithRequestOptions returns a copy of ctx carrying the given options. Options
+// already present on ctx are kept, and the new ones are applied on top.
+func WithRequestOptions(ctx context.Context, opts ...RequestOption) context.Context {
+	merged := requestOptionsFromContext(ctx).clone()
+	for _, opt := range opts {
+		if opt != nil {
+			opt(merged)
+		}
+	}
+
+	return context.WithValue(ctx, requestOptionsKey{}, merged)
+}
+
+// WithTimeout bounds the total duration of the call, including retries.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithHeader adds an extra header to the call.
+func WithHeader(key, value string) RequestOption {
+	return func(o *requestOptions) {
+		if o.headers == nil {
+			o.headers = make(map[string]string)
+		}
+		o.headers[key] = value
+	}
+}
+
+// WithHeaders adds several extra headers to the call.
+func WithHeaders(headers map[string]string) RequestOption {
+	return func(o *requestOptions) {
+		for key, value := range headers {
+			WithHeader(key, value)(o)
+		}
+	}
+}
+
+// WithIdempotencyKey sends the given key in the Idempotency-Key header.
+func WithIdempotencyKey(key string) RequestOption {
+	return WithHeader(IdempotencyKeyHeader, key)
+}
+
+// WithApiKeyOverride authenticates the call with the given API key instead of
+// the one configured on the client. Useful when a single client is used
+// against several organizations.
+func WithApiKeyOverride(apiKey string) RequestOption {
+	return func(o *requestOptions) {
+		o.apiKey = apiKey
+	}
+}
+
+// WithoutRetry disables the automatic retry on HTTP 429 responses for the call,
+// regardless of the client RetryPolicy.
+func WithoutRetry() RequestOption {
+	return func(o *requestOptions) {
+		o.disableRetry = true
+	}
+}
+
+// WithResponseHook registers a hook receiving the final HTTP response of the call.
+func WithResponseHook(hook ResponseHook) RequestOption {
+	return func(o *requestOptions) {
+		if hook != nil {
+			o.responseHooks = append(o.responseHooks, hook)
+		}
+	}
+}
+
+func requestOptionsFromContext(ctx context.Context) *requestOptions {
+	if ctx == nil {
+		return &requestOptions{}
+	}
+	if opts, ok := ctx.Value(requestOptionsKey{}).(*requestOptions); ok && opts != nil {
+		return opts
+	}
+
+	return &requestOptions{}
+}
+
+func (o *requestOptions) clone() *requestOptions {
+	cloned := &requestOptions{
+		timeout:      o.timeout,
+		apiKey:       o.apiKey,
+		disableRetry: o.disableRetry,
+	}
+	if len(o.headers) > 0 {
+		cloned.headers = make(map[string]string, len(o.headers))
+		for key, value := range o.headers {
+			cloned.headers[key] = value
+		}
+	}
+	if len(o.responseHooks) > 0 {
+		cloned.responseHooks = append([]ResponseHook(nil), o.responseHooks...)
+	}
+
+	return cloned
+}
+
+// withRequestTimeout applies the per-call timeout, if any, to ctx.
+func withRequestTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	opts := requestOptionsFromContext(ctx)
+	if opts.timeout <= 0 {
+		return ctx, func() {}
+	}
+
+	return context.WithTimeout(ctx, opts.timeout)
+}
+
+// emitResponse delivers the final response to the registered hooks. Panics from
+// a hook are recovered and logged so the request flow is never affected.
+func (o *requestOptions) emitResponse(resp *resty.Response, attempts int) {
+	if len(o.responseHooks) == 0 {
+		return
+	}
+
+	var rawResponse *http.Response
+	if resp != nil {
+		rawResponse = resp.RawResponse
+	}
+
+	for _, hook := range o.responseHooks {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("lago: response hook panicked: %v", r)
+				}
+			}()
+			hook(rawResponse, attempts)
+		}()
+	}
+}
@@ -0,0 +1,143 @@
+package lago_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+func TestRequestOptions_HeadersAndApiKeyOverride(t *testing.T) {
+	c := qt.New(t)
+
+	var captured http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data": "ok"}`))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("client_key")
+
+	ctx := WithRequestOptions(context.Background(),
+		WithHeader("X-Trace", "abc"),
+		WithIdempotencyKey("idem-1"),
+		WithApiKeyOverride("org_key"),
+	)
+	_, err := client.Get(ctx, &ClientRequest{Path: "test"})
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+
+	c.Assert(captured.Get("X-Trace"), qt.Equals, "abc")
+	c.Assert(captured.Get(IdempotencyKeyHeader), qt.Equals, "idem-1")
+	c.Assert(captured.Get("Authorization"), qt.Equals, "Bearer org_key")
+
+	// Without options the client API key is used.
+	_, err = client.Get(context.Background(), &ClientRequest{Path: "test"})
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(captured.Get("Authorization"), qt.Equals, "Bearer client_key")
+	c.Assert(captured.Get("X-Trace"), qt.Equals, "")
+}
+
+func TestRequestOptions_AreMerged(t *testing.T) {
+	c := qt.New(t)
+
+	var captured http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		captured = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	parent := WithRequestOptions(context.Background(), WithHeader("X-A", "1"))
+	child := WithRequestOptions(parent, WithHeader("X-B", "2"))
+
+	_, err := client.Post(child, &ClientRequest{Path: "test", Body: map[string]string{}})
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(captured.Get("X-A"), qt.Equals, "1")
+	c.Assert(captured.Get("X-B"), qt.Equals, "2")
+
+	_, err = client.Post(parent, &ClientRequest{Path: "test", Body: map[string]string{}})
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(captured.Get("X-B"), qt.Equals, "")
+}
+
+func TestRequestOptions_Timeout(t *testing.T) {
+	c := qt.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	ctx := WithRequestOptions(context.Background(), WithTimeout(50*time.Millisecond))
+	start := time.Now()
+	_, err := client.Get(ctx, &ClientRequest{Path: "test"})
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(errors.Is(err.Err, context.DeadlineExceeded), qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(time.Since(start) < time.Second, qt.IsTrue)
+}
+
+func TestRequestOptions_WithoutRetry(t *testing.T) {
+	c := qt.New(t)
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-ratelimit-reset", "1")
+		w.WriteHeader(http.StatusTooManyRequests)
+		_, _ = w.Write([]byte(`{"status": 429, "error": "Too Many Requests"}`))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	var attempts int
+	var status int
+	ctx := WithRequestOptions(context.Background(),
+		WithoutRetry(),
+		WithResponseHook(func(resp *http.Response, n int) {
+			attempts = n
+			status = resp.StatusCode
+		}),
+	)
+	_, err := client.Get(ctx, &ClientRequest{Path: "test"})
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(err.HTTPStatusCode, qt.Equals, http.StatusTooManyRequests)
+	c.Assert(requests, qt.Equals, 1)
+	c.Assert(attempts, qt.Equals, 1)
+	c.Assert(status, qt.Equals, http.StatusTooManyRequests)
+}
+
+func TestRequestOptions_ResponseHookPanicIsRecovered(t *testing.T) {
+	c := qt.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	ctx := WithRequestOptions(context.Background(), WithResponseHook(func(*http.Response, int) {
+		panic("intentional")
+	}))
+	_, err := client.Get(ctx, &ClientRequest{Path: "test"})
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+}
@@ -0,0 +1,58 @@
+package lago
+
+import (
+	"net/http"
+)
+
+// RequestIDHeader is the header carrying the Lago request id. The same id can
+// be used to fetch the call from the API logs with ApiLogRequest.Get.
+const RequestIDHeader string = "x-request-id"
+
+// ResponseMeta holds the HTTP level details of a call.
+type ResponseMeta struct {
+	// StatusCode is the HTTP status of the final response.
+	StatusCode int
+	// Header holds the headers of the final response.
+	Header http.Header
+	// RequestID is the x-request-id header of the final response.
+	RequestID string
+	// RateLimit holds the parsed x-ratelimit-* headers (nil when absent).
+	RateLimit *RateLimitInfo
+	// Attempts is the number of attempts made, including retries on HTTP 429.
+	Attempts int
+	// Method is the HTTP method of the call (GET, POST, ...).
+	Method string
+	// URL is the request URL.
+	URL string
+}
+
+// WithResponseMeta fills meta with the details of the final response of the
+// call, whether it succeeded or not:
+//
+//	var meta lago.ResponseMeta
+//	ctx := lago.WithRequestOptions(ctx, lago.WithResponseMeta(&meta))
+//	customer, err := client.Customer().Get(ctx, externalID)
+//	log.Printf("request %s answered %d after %d attempt(s)", meta.RequestID, meta.StatusCode, meta.Attempts)
+func WithResponseMeta(meta *ResponseMeta) RequestOption {
+	return WithResponseHook(func(resp *http.Response, attempts int) {
+		if meta == nil {
+			return
+		}
+
+		*meta = ResponseMeta{Attempts: attempts}
+		if resp == nil {
+			return
+		}
+
+		if resp.Request != nil {
+			meta.Method = resp.Request.Method
+			if resp.Request.URL != nil {
+				meta.URL = resp.Request.URL.String()
+			}
+		}
+		meta.StatusCode = resp.StatusCode
+		meta.Header = resp.Header.Clone()
+		meta.RequestID = resp.Header.Get(RequestIDHeader)
+		meta.RateLimit = parseRateLimitInfo(resp, meta.Method, meta.URL)
+	})
+}
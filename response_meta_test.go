@@ -0,0 +1,76 @@
+package lago_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+func TestWithResponseMeta_AfterRetry(t *testing.T) {
+	c := qt.New(t)
+	requests := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		if requests == 1 {
+			w.Header().Set("x-ratelimit-reset", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"status": 429, "error": "Too Many Requests"}`))
+			return
+		}
+		w.Header().Set("x-request-id", "req-123")
+		w.Header().Set("x-ratelimit-limit", "100")
+		w.Header().Set("x-ratelimit-remaining", "42")
+		w.Header().Set("x-ratelimit-reset", "5")
+		_, _ = w.Write([]byte(`{"customer": {"external_id": "CUSTOMER_1"}}`))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	var meta ResponseMeta
+	ctx := WithRequestOptions(context.Background(), WithResponseMeta(&meta))
+	customer, err := client.Customer().Get(ctx, "CUSTOMER_1")
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(customer.ExternalID, qt.Equals, "CUSTOMER_1")
+
+	c.Assert(meta.StatusCode, qt.Equals, http.StatusOK)
+	c.Assert(meta.Attempts, qt.Equals, 2)
+	c.Assert(meta.RequestID, qt.Equals, "req-123")
+	c.Assert(meta.Method, qt.Equals, http.MethodGet)
+	c.Assert(meta.URL, qt.Equals, server.URL+"/api/v1/customers/CUSTOMER_1")
+	c.Assert(meta.Header.Get("x-request-id"), qt.Equals, "req-123")
+	c.Assert(meta.RateLimit, qt.Not(qt.IsNil))
+	c.Assert(*meta.RateLimit.Remaining, qt.Equals, 42)
+}
+
+func TestWithResponseMeta_OnError(t *testing.T) {
+	c := qt.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("x-request-id", "req-404")
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"status": 404, "error": "Not Found", "code": "customer_not_found"}`))
+	}))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	var meta ResponseMeta
+	ctx := WithRequestOptions(context.Background(), WithResponseMeta(&meta))
+	_, err := client.Customer().Get(ctx, "UNKNOWN")
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(err.HTTPStatusCode, qt.Equals, http.StatusNotFound)
+
+	c.Assert(meta.StatusCode, qt.Equals, http.StatusNotFound)
+	c.Assert(meta.RequestID, qt.Equals, "req-404")
+	c.Assert(meta.Attempts, qt.Equals, 1)
+	c.Assert(meta.RateLimit, qt.IsNil)
+}
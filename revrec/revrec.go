@@ -0,0 +1,423 @@
+// Package revrec builds revenue recognition schedules from invoiced fees:
+// how much of the billed revenue is recognized in each day or month, how much
+// is deferred to later periods and how much is recognized before being
+// billed.
+package revrec
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+const dateLayout = "2006-01-02"
+
+// Key groups the revenue of a schedule.
+type Key struct {
+	ExternalCustomerID string
+	// PlanCode is the plan of the subscription the fee was billed for, empty
+	// for fees billed outside a subscription.
+	PlanCode string
+	FeeType  lago.FeeType
+	// ItemCode is the code of the fee item: the billable metric of a charge,
+	// the add-on or the plan of a subscription fee.
+	ItemCode string
+	Currency lago.Currency
+}
+
+// Posting is an amount billed on a fee, by its invoice or by a credit note.
+type Posting struct {
+	Date time.Time
+	// Amount is in cents, negative for credit notes.
+	Amount int64
+	// Source is "invoice:<number>", "credit_note:<number>" or, for the
+	// reversal of a voided invoice, "void:<number>".
+	Source string
+	// catchUp makes the days of the service period elapsed before Date
+	// recognized on Date, so that earlier periods are left untouched.
+	catchUp bool
+	// void stops the recognition on Date: the posting reverses the amount
+	// billed but not recognized yet.
+	void bool
+}
+
+// Obligation is the revenue of one fee, recognized evenly over each day of
+// its service period, or at once when it has none.
+type Obligation struct {
+	Key
+	FeeID         string
+	InvoiceNumber string
+	// ServiceStart and ServiceEnd are the first and last days of the service
+	// period, in UTC.
+	ServiceStart, ServiceEnd time.Time
+	Postings                 []Posting
+}
+
+// Amount returns the billed amount of the obligation, net of credit notes.
+func (o *Obligation) Amount() int64 {
+	var amount int64
+	for _, posting := range o.Postings {
+		amount += posting.Amount
+	}
+	return amount
+}
+
+// billedThrough returns the amount billed up to the end of day.
+func (o *Obligation) billedThrough(day time.Time) int64 {
+	var billed int64
+	for _, posting := range o.Postings {
+		if !posting.Date.After(day) {
+			billed += posting.Amount
+		}
+	}
+	return billed
+}
+
+// recognizedThrough returns the amount recognized up to the end of day.
+func (o *Obligation) recognizedThrough(day time.Time) int64 {
+	var recognized int64
+	for _, posting := range o.Postings {
+		if posting.void && !day.Before(posting.Date) {
+			// Nothing is recognized from the void date on.
+			return o.recognizedThrough(posting.Date.AddDate(0, 0, -1))
+		}
+		if posting.void || posting.catchUp && day.Before(posting.Date) {
+			continue
+		}
+		recognized += spread(posting.Amount, o.ServiceStart, o.ServiceEnd, day)
+	}
+	return recognized
+}
+
+// void stops the recognition of o on day and reverses the amount billed but
+// not recognized before.
+func (o *Obligation) void(day time.Time, source string) {
+	remainder := o.billedThrough(day) - o.recognizedThrough(day.AddDate(0, 0, -1))
+	o.Postings = append(o.Postings, Posting{Date: day, Amount: -remainder, Source: source, void: true})
+}
+
+// spread returns the part of amount recognized up to the end of day when it
+// is recognized evenly from start to end, rounding the cumulated amount so
+// that the daily amounts add up to amount.
+func spread(amount int64, start, end, day time.Time) int64 {
+	switch {
+	case day.Before(start):
+		return 0
+	case !day.Before(end):
+		return amount
+	}
+
+	elapsed := days(start, day) + 1
+	total := days(start, end) + 1
+	if amount < 0 {
+		return -((-amount*2*elapsed + total) / (2 * total))
+	}
+	return (amount*2*elapsed + total) / (2 * total)
+}
+
+func days(from, to time.Time) int64 {
+	return int64(to.Sub(from).Hours() / 24)
+}
+
+// Schedule is the set of obligations built from invoices and credit notes.
+type Schedule struct {
+	Obligations []*Obligation
+}
+
+// Build turns the fees of invoices, as returned by InvoiceRequest.Get, into
+// obligations and applies the credit notes issued on them:
+//
+//   - fees with a service period are recognized over it, including
+//     subscription fees paid in advance, which are billed on the first day
+//     of their period and deferred until its end;
+//   - charges paid in advance, add-ons and one-off fees are recognized on
+//     the issuing date of their invoice;
+//   - wallet top-ups are not revenue and are left out, as are draft
+//     invoices;
+//   - a voided invoice is recognized like a finalized one until the day it
+//     is voided, when the amount billed but not recognized yet is reversed;
+//     voided invoices without VoidedAt are left out;
+//   - a credit note reverses, on its issuing date, the part of the credited
+//     amount already recognized and reduces the revenue of the remaining
+//     days.
+//
+// Amounts are fee amounts before coupons and taxes. Dates are taken in UTC.
+func Build(invoices []lago.Invoice, creditNotes []lago.CreditNote) (*Schedule, error) {
+	s := &Schedule{}
+	byFee := map[string]*Obligation{}
+
+	for _, invoice := range invoices {
+		voided := invoice.Status == lago.InvoiceStatusVoided && !invoice.VoidedAt.IsZero()
+		if invoice.Status != lago.InvoiceStatusFinalized && !voided {
+			continue
+		}
+		issued, err := parseDate(invoice.IssuingDate)
+		if err != nil {
+			return nil, fmt.Errorf("revrec: invoice %s: %w", invoice.Number, err)
+		}
+
+		plans := map[string]string{}
+		for _, subscription := range invoice.Subscriptions {
+			plans[subscription.ExternalID] = subscription.PlanCode
+		}
+
+		for _, fee := range invoice.Fees {
+			if fee.Item.Type == lago.FeeItemCredit {
+				continue
+			}
+			obligation, err := newObligation(&fee, issued)
+			if err != nil {
+				return nil, fmt.Errorf("revrec: fee %s of invoice %s: %w", fee.LagoID, invoice.Number, err)
+			}
+			obligation.InvoiceNumber = invoice.Number
+			obligation.PlanCode = plans[fee.ExternalSubscriptionID]
+			if obligation.PlanCode == "" && fee.Item.Type == lago.FeeItemSubscription {
+				obligation.PlanCode = fee.Item.Code
+			}
+			if invoice.Customer != nil {
+				obligation.ExternalCustomerID = invoice.Customer.ExternalID
+			}
+			if obligation.Currency == "" {
+				obligation.Currency = invoice.Currency
+			}
+			obligation.Postings = []Posting{{Date: issued, Amount: int64(fee.AmountCents), Source: "invoice:" + invoice.Number}}
+			if voided {
+				obligation.void(day(invoice.VoidedAt), "void:"+invoice.Number)
+			}
+
+			s.Obligations = append(s.Obligations, obligation)
+			byFee[obligation.FeeID] = obligation
+		}
+	}
+
+	for _, creditNote := range creditNotes {
+		issued, err := parseDate(creditNote.IssuingDate)
+		if err != nil {
+			return nil, fmt.Errorf("revrec: credit note %s: %w", creditNote.Number, err)
+		}
+
+		for _, item := range creditNote.Items {
+			if item.Fee.Item.Type == lago.FeeItemCredit {
+				continue
+			}
+			obligation, ok := byFee[item.Fee.LagoID.String()]
+			if !ok {
+				// The fee was invoiced before the invoices given: it is
+				// rebuilt from the credit note item, as billed when created.
+				invoiced := issued
+				if !item.Fee.CreatedAt.IsZero() {
+					invoiced = day(item.Fee.CreatedAt)
+				}
+				obligation, err = newObligation(&item.Fee, invoiced)
+				if err != nil {
+					return nil, fmt.Errorf("revrec: fee %s of credit note %s: %w", item.Fee.LagoID, creditNote.Number, err)
+				}
+				obligation.Postings = []Posting{{Date: invoiced, Amount: int64(item.Fee.AmountCents), Source: "invoice:" + creditNote.InvoiceNumber}}
+				obligation.InvoiceNumber = creditNote.InvoiceNumber
+				if obligation.PlanCode == "" && item.Fee.Item.Type == lago.FeeItemSubscription {
+					obligation.PlanCode = item.Fee.Item.Code
+				}
+				if creditNote.Customer != nil {
+					obligation.ExternalCustomerID = creditNote.Customer.ExternalID
+				}
+				if obligation.Currency == "" {
+					obligation.Currency = creditNote.Currency
+				}
+				s.Obligations = append(s.Obligations, obligation)
+				byFee[obligation.FeeID] = obligation
+			}
+
+			obligation.Postings = append(obligation.Postings, Posting{
+				Date:    issued,
+				Amount:  -int64(item.AmountCents),
+				Source:  "credit_note:" + creditNote.Number,
+				catchUp: true,
+			})
+		}
+	}
+
+	return s, nil
+}
+
+func newObligation(fee *lago.Fee, issued time.Time) (*Obligation, error) {
+	obligation := &Obligation{
+		Key: Key{
+			FeeType:  fee.Item.Type,
+			ItemCode: fee.Item.Code,
+			Currency: lago.Currency(fee.AmountCurrency),
+		},
+		FeeID:        fee.LagoID.String(),
+		ServiceStart: issued,
+		ServiceEnd:   issued,
+	}
+
+	instant := fee.PayInAdvance && fee.Item.Type == lago.FeeItemCharge
+	if fee.FromDate == "" || fee.ToDate == "" || instant {
+		return obligation, nil
+	}
+
+	var err error
+	if obligation.ServiceStart, err = parseDate(fee.FromDate); err != nil {
+		return nil, err
+	}
+	if obligation.ServiceEnd, err = parseDate(fee.ToDate); err != nil {
+		return nil, err
+	}
+	if obligation.ServiceEnd.Before(obligation.ServiceStart) {
+		return nil, fmt.Errorf("service period ends on %s before it starts on %s", fee.ToDate, fee.FromDate)
+	}
+	return obligation, nil
+}
+
+// parseDate parses a date or a timestamp and returns its day in UTC.
+func parseDate(value string) (time.Time, error) {
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		if t, err = time.Parse(dateLayout, value); err != nil {
+			return time.Time{}, fmt.Errorf("invalid date %q", value)
+		}
+	}
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC), nil
+}
+
+// Granularity is the length of the periods of a report.
+type Granularity string
+
+const (
+	Daily   Granularity = "daily"
+	Monthly Granularity = "monthly"
+)
+
+// Row is the revenue of a key over a period. Amounts are in cents.
+type Row struct {
+	Key
+	// Period is "2006-01-02" for daily rows and "2006-01" for monthly rows.
+	Period string
+	// Billed is the amount invoiced during the period, net of credit notes.
+	Billed int64
+	// Recognized is the revenue recognized during the period.
+	Recognized int64
+	// Deferred is the revenue billed but not recognized yet at the end of
+	// the period.
+	Deferred int64
+	// Unbilled is the revenue recognized but not billed yet at the end of
+	// the period, such as usage billed in arrears.
+	Unbilled int64
+}
+
+// Rows reports the schedule for every period from the one including from to
+// the one including to. Rows are sorted by period, then key, and keys with
+// nothing billed, recognized, deferred or unbilled in a period are left out.
+func (s *Schedule) Rows(granularity Granularity, from, to time.Time) []Row {
+	from, to = day(from), day(to)
+
+	var rows []Row
+	for start := periodStart(granularity, from); !start.After(to); {
+		end := periodEnd(granularity, start)
+		label := start.Format(dateLayout)
+		if granularity == Monthly {
+			label = start.Format("2006-01")
+		}
+
+		byKey := map[Key]*Row{}
+		for _, o := range s.Obligations {
+			before := start.AddDate(0, 0, -1)
+			billed, recognized := o.billedThrough(end), o.recognizedThrough(end)
+
+			row := Row{
+				Key:        o.Key,
+				Period:     label,
+				Billed:     billed - o.billedThrough(before),
+				Recognized: recognized - o.recognizedThrough(before),
+			}
+			if balance := billed - recognized; balance > 0 {
+				row.Deferred = balance
+			} else {
+				row.Unbilled = -balance
+			}
+			if row == (Row{Key: o.Key, Period: label}) {
+				continue
+			}
+
+			total, ok := byKey[o.Key]
+			if !ok {
+				total = &Row{Key: o.Key, Period: label}
+				byKey[o.Key] = total
+			}
+			total.Billed += row.Billed
+			total.Recognized += row.Recognized
+			total.Deferred += row.Deferred
+			total.Unbilled += row.Unbilled
+		}
+
+		periodRows := make([]Row, 0, len(byKey))
+		for _, row := range byKey {
+			periodRows = append(periodRows, *row)
+		}
+		sort.Slice(periodRows, func(i, j int) bool {
+			return keyString(periodRows[i].Key) < keyString(periodRows[j].Key)
+		})
+		rows = append(rows, periodRows...)
+
+		start = end.AddDate(0, 0, 1)
+	}
+	return rows
+}
+
+func keyString(key Key) string {
+	return strings.Join([]string{key.ExternalCustomerID, key.PlanCode, string(key.FeeType), key.ItemCode, string(key.Currency)}, "\x00")
+}
+
+func day(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func periodStart(granularity Granularity, t time.Time) time.Time {
+	if granularity == Monthly {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t
+}
+
+func periodEnd(granularity Granularity, start time.Time) time.Time {
+	if granularity == Monthly {
+		return start.AddDate(0, 1, -1)
+	}
+	return start
+}
+
+var csvHeader = []string{
+	"period", "external_customer_id", "plan_code", "fee_type", "item_code", "currency",
+	"billed", "recognized", "deferred", "unbilled",
+}
+
+// WriteCSV writes rows as CSV, with amounts in major units ("12.00").
+func WriteCSV(w io.Writer, rows []Row) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		major := func(cents int64) string {
+			return lago.NewMoney(cents, row.Currency).Major()
+		}
+		err := writer.Write([]string{
+			row.Period, row.ExternalCustomerID, row.PlanCode, string(row.FeeType), row.ItemCode, string(row.Currency),
+			major(row.Billed), major(row.Recognized), major(row.Deferred), major(row.Unbilled),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
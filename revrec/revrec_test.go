@@ -0,0 +1,189 @@
+package revrec_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/google/uuid"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/revrec"
+)
+
+var (
+	annualFeeID = uuid.MustParse("1a901a90-1a90-1a90-1a90-1a901a901a90")
+	usageFeeID  = uuid.MustParse("2b902b90-2b90-2b90-2b90-2b902b902b90")
+	oldFeeID    = uuid.MustParse("3c903c90-3c90-3c90-3c90-3c903c903c90")
+)
+
+func date(month time.Month, day int) time.Time {
+	return time.Date(2026, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// invoices returns an annual subscription of 365.00 EUR paid in advance on
+// January 1st, API calls of February billed 10.00 EUR in arrears on March
+// 1st, a voided invoice and a wallet top-up.
+func invoices() []lago.Invoice {
+	customer := &lago.Customer{ExternalID: "cus_1"}
+	return []lago.Invoice{
+		{
+			Number: "INV-001", IssuingDate: "2026-01-01", Status: lago.InvoiceStatusFinalized, Currency: "EUR", Customer: customer,
+			Subscriptions: []lago.Subscription{{ExternalID: "sub_1", PlanCode: "premium"}},
+			Fees: []lago.Fee{{
+				LagoID: annualFeeID, AmountCents: 36500, AmountCurrency: "EUR", PayInAdvance: true, ExternalSubscriptionID: "sub_1",
+				FromDate: "2026-01-01T00:00:00Z", ToDate: "2026-12-31T23:59:59Z",
+				Item: lago.FeeItem{Type: lago.FeeItemSubscription, Code: "premium"},
+			}},
+		},
+		{
+			Number: "INV-002", IssuingDate: "2026-03-01", Status: lago.InvoiceStatusFinalized, Currency: "EUR", Customer: customer,
+			Subscriptions: []lago.Subscription{{ExternalID: "sub_1", PlanCode: "premium"}},
+			Fees: []lago.Fee{{
+				LagoID: usageFeeID, AmountCents: 1000, AmountCurrency: "EUR", ExternalSubscriptionID: "sub_1",
+				FromDate: "2026-02-01T00:00:00Z", ToDate: "2026-02-28T23:59:59Z",
+				Item: lago.FeeItem{Type: lago.FeeItemCharge, Code: "api_calls"},
+			}},
+		},
+		{
+			Number: "INV-003", IssuingDate: "2026-01-15", Status: lago.InvoiceStatusVoided, Currency: "EUR", Customer: customer,
+			Fees: []lago.Fee{{AmountCents: 5000, Item: lago.FeeItem{Type: lago.FeeItemAddOn, Code: "setup"}}},
+		},
+		{
+			Number: "INV-004", IssuingDate: "2026-01-15", Status: lago.InvoiceStatusFinalized, Currency: "EUR", Customer: customer,
+			Fees: []lago.Fee{{AmountCents: 5000, Item: lago.FeeItem{Type: lago.FeeItemCredit, Code: "credit"}}},
+		},
+	}
+}
+
+type amounts struct {
+	Period                                 string
+	Billed, Recognized, Deferred, Unbilled int64
+}
+
+func rowsOf(rows []revrec.Row, itemCode string) []amounts {
+	var all []amounts
+	for _, row := range rows {
+		if row.ItemCode == itemCode {
+			all = append(all, amounts{row.Period, row.Billed, row.Recognized, row.Deferred, row.Unbilled})
+		}
+	}
+	return all
+}
+
+func TestRows_Monthly(t *testing.T) {
+	c := qt.New(t)
+
+	schedule, err := revrec.Build(invoices(), nil)
+	c.Assert(err, qt.IsNil)
+
+	rows := schedule.Rows(revrec.Monthly, date(1, 1), date(3, 31))
+	c.Assert(rowsOf(rows, "premium"), qt.DeepEquals, []amounts{
+		{"2026-01", 36500, 3100, 33400, 0},
+		{"2026-02", 0, 2800, 30600, 0},
+		{"2026-03", 0, 3100, 27500, 0},
+	})
+	c.Assert(rowsOf(rows, "api_calls"), qt.DeepEquals, []amounts{
+		{"2026-02", 0, 1000, 0, 1000},
+		{"2026-03", 1000, 0, 0, 0},
+	})
+	c.Assert(rowsOf(rows, "setup"), qt.HasLen, 0)
+	c.Assert(rowsOf(rows, "credit"), qt.HasLen, 0)
+
+	c.Assert(rows[0].Key, qt.Equals, revrec.Key{
+		ExternalCustomerID: "cus_1", PlanCode: "premium", FeeType: lago.FeeItemSubscription, ItemCode: "premium", Currency: "EUR",
+	})
+	c.Assert(rows[1].PlanCode, qt.Equals, "premium")
+}
+
+func TestRows_DailyRoundingAddsUp(t *testing.T) {
+	c := qt.New(t)
+
+	schedule, err := revrec.Build([]lago.Invoice{{
+		Number: "INV-001", IssuingDate: "2026-02-01", Status: lago.InvoiceStatusFinalized, Currency: "EUR",
+		Fees: []lago.Fee{{
+			AmountCents: 1000, FromDate: "2026-02-01", ToDate: "2026-02-03",
+			Item: lago.FeeItem{Type: lago.FeeItemSubscription, Code: "basic"},
+		}},
+	}}, nil)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(rowsOf(schedule.Rows(revrec.Daily, date(2, 1), date(2, 4)), "basic"), qt.DeepEquals, []amounts{
+		{"2026-02-01", 1000, 333, 667, 0},
+		{"2026-02-02", 0, 334, 333, 0},
+		{"2026-02-03", 0, 333, 0, 0},
+	})
+}
+
+func TestRows_CreditNotes(t *testing.T) {
+	c := qt.New(t)
+
+	// Half of the annual subscription is credited on March 31st, after 90
+	// days of service, and a fee invoiced before the given invoices is
+	// fully credited.
+	schedule, err := revrec.Build(invoices(), []lago.CreditNote{
+		{
+			Number: "CN-001", InvoiceNumber: "INV-001", IssuingDate: "2026-03-31",
+			Items: []lago.CreditNoteItem{{AmountCents: 18250, Fee: lago.Fee{LagoID: annualFeeID}}},
+		},
+		{
+			Number: "CN-002", InvoiceNumber: "INV-000", IssuingDate: "2026-02-10", Currency: "EUR",
+			Customer: &lago.Customer{ExternalID: "cus_2"},
+			Items: []lago.CreditNoteItem{{AmountCents: 500, Fee: lago.Fee{
+				LagoID: oldFeeID, AmountCents: 500, CreatedAt: date(1, 20),
+				Item: lago.FeeItem{Type: lago.FeeItemAddOn, Code: "setup"},
+			}}},
+		},
+	})
+	c.Assert(err, qt.IsNil)
+
+	rows := schedule.Rows(revrec.Monthly, date(1, 1), date(4, 30))
+	c.Assert(rowsOf(rows, "premium"), qt.DeepEquals, []amounts{
+		{"2026-01", 36500, 3100, 33400, 0},
+		{"2026-02", 0, 2800, 30600, 0},
+		// 31.00 EUR of March minus the 45.00 EUR recognized since January
+		// on the credited half.
+		{"2026-03", -18250, -1400, 13750, 0},
+		{"2026-04", 0, 1500, 12250, 0},
+	})
+	c.Assert(rowsOf(rows, "setup"), qt.DeepEquals, []amounts{
+		{"2026-01", 500, 500, 0, 0},
+		{"2026-02", -500, -500, 0, 0},
+	})
+}
+
+func TestRows_VoidedInvoice(t *testing.T) {
+	c := qt.New(t)
+
+	// The annual subscription is voided on March 1st, after 59 days of
+	// service: what was recognized by then stays, the rest is reversed.
+	voided := invoices()[:1]
+	voided[0].Status = lago.InvoiceStatusVoided
+	voided[0].VoidedAt = time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	schedule, err := revrec.Build(voided, nil)
+	c.Assert(err, qt.IsNil)
+
+	c.Assert(rowsOf(schedule.Rows(revrec.Monthly, date(1, 1), date(4, 30)), "premium"), qt.DeepEquals, []amounts{
+		{"2026-01", 36500, 3100, 33400, 0},
+		{"2026-02", 0, 2800, 30600, 0},
+		{"2026-03", -30600, 0, 0, 0},
+	})
+}
+
+func TestWriteCSV(t *testing.T) {
+	c := qt.New(t)
+
+	var buf bytes.Buffer
+	err := revrec.WriteCSV(&buf, []revrec.Row{{
+		Key:    revrec.Key{ExternalCustomerID: "cus_1", PlanCode: "premium", FeeType: lago.FeeItemSubscription, ItemCode: "premium", Currency: "EUR"},
+		Period: "2026-01", Billed: 36500, Recognized: 3100, Deferred: 33400,
+	}})
+	c.Assert(err, qt.IsNil)
+	c.Assert(strings.Split(buf.String(), "\n"), qt.DeepEquals, []string{
+		"period,external_customer_id,plan_code,fee_type,item_code,currency,billed,recognized,deferred,unbilled",
+		"2026-01,cus_1,premium,subscription,premium,EUR,365.00,31.00,334.00,0.00",
+		"",
+	})
+}
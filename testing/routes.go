@@ -0,0 +1,168 @@
+package testing
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	qt "github.com/frankban/quicktest"
+	"github.com/getlago/lago-go-client"
+)
+
+// RoutesServer is a fake Lago API answering canned JSON responses by route,
+// for tests reading several endpoints. It records the requests it receives.
+//
+// A route is "METHOD /path?query", with a path relative to /api/v1; the
+// method and the query are optional. A request matches a route when it has
+// its method and path and every parameter of its query. The route with a
+// method and the most query parameters wins. Requests matching no route get
+// a 404 error.
+type RoutesServer struct {
+	server *httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]string
+	requests []Request
+}
+
+// Request is a request received by a RoutesServer.
+type Request struct {
+	Method string
+	// Path is relative to /api/v1.
+	Path  string
+	Query url.Values
+	Body  string
+}
+
+// NewRoutesServer starts a server answering routes, closed when the test
+// ends.
+func NewRoutesServer(c *qt.C, routes map[string]string) *RoutesServer {
+	s := &RoutesServer{}
+	s.SetRoutes(routes)
+	s.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		c.Check(err, qt.IsNil)
+		request := Request{
+			Method: r.Method,
+			Path:   strings.TrimPrefix(r.URL.Path, "/api/v1"),
+			Query:  r.URL.Query(),
+			Body:   string(body),
+		}
+
+		s.mu.Lock()
+		s.requests = append(s.requests, request)
+		response, ok := s.match(request)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"status": 404, "error": "Not Found", "code": "resource_not_found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(response))
+	}))
+	c.Cleanup(s.server.Close)
+
+	return s
+}
+
+// match returns the response of the most specific route matching request.
+func (s *RoutesServer) match(request Request) (string, bool) {
+	response, found, best, bestRoute := "", false, -1, ""
+	for route, routeResponse := range s.routes {
+		method, target, hasMethod := strings.Cut(route, " ")
+		if !hasMethod {
+			method, target = "", route
+		}
+		path, rawQuery, _ := strings.Cut(target, "?")
+		if method != "" && method != request.Method || path != request.Path {
+			continue
+		}
+		query, err := url.ParseQuery(rawQuery)
+		if err != nil {
+			continue
+		}
+		matches := true
+		for key, values := range query {
+			if !slices.Equal(values, request.Query[key]) {
+				matches = false
+			}
+		}
+		if !matches {
+			continue
+		}
+
+		score := 2 * len(query)
+		if method != "" {
+			score++
+		}
+		// Ties go to the first route in lexical order.
+		if score > best || score == best && route < bestRoute {
+			response, found, best, bestRoute = routeResponse, true, score, route
+		}
+	}
+
+	return response, found
+}
+
+// URL returns the base URL of the server.
+func (s *RoutesServer) URL() string {
+	return s.server.URL
+}
+
+// Client returns a client of the server.
+func (s *RoutesServer) Client() *lago.Client {
+	return lago.New().SetBaseURL(s.server.URL).SetApiKey("test_api_key")
+}
+
+// SetRoute answers route with response from now on, or removes route when
+// response is empty.
+func (s *RoutesServer) SetRoute(route, response string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if response == "" {
+		delete(s.routes, route)
+		return
+	}
+	s.routes[route] = response
+}
+
+// SetRoutes replaces every route of the server.
+func (s *RoutesServer) SetRoutes(routes map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.routes = make(map[string]string, len(routes))
+	for route, response := range routes {
+		s.routes[route] = response
+	}
+}
+
+// Requests returns the requests received so far, in order.
+func (s *RoutesServer) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+// Last returns the last request received for method and path, or a zero
+// Request.
+func (s *RoutesServer) Last(method, path string) Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := len(s.requests) - 1; i >= 0; i-- {
+		if s.requests[i].Method == method && s.requests[i].Path == path {
+			return s.requests[i]
+		}
+	}
+
+	return Request{}
+}
@@ -0,0 +1,193 @@
+package lago
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrWaitTimeout is returned when a waiter gives up before the expected state is reached.
+var ErrWaitTimeout = errors.New("lago: timed out waiting for state")
+
+// ErrWaitTerminalState is returned when a waited object reaches a final state
+// other than the expected one (a failed invoice, a canceled subscription, ...).
+var ErrWaitTerminalState = errors.New("lago: object reached an unexpected final state")
+
+// Backoff controls how often a waiter polls and for how long.
+type Backoff struct {
+	// Initial is the delay before the second poll.
+	Initial time.Duration
+	// Max caps the delay between two polls.
+	Max time.Duration
+	// Multiplier grows the delay after each poll.
+	Multiplier float64
+	// Timeout bounds the total wait. Zero means the wait is only bounded by the context.
+	Timeout time.Duration
+}
+
+// DefaultBackoff returns a Backoff polling after 1s, then 2s, 4s... up to 30s,
+// for at most 10 minutes.
+func DefaultBackoff() *Backoff {
+	return &Backoff{
+		Initial:    1 * time.Second,
+		Max:        30 * time.Second,
+		Multiplier: 2.0,
+		Timeout:    10 * time.Minute,
+	}
+}
+
+func (b *Backoff) delay(attempt int) time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+
+	delay := time.Duration(float64(b.Initial) * math.Pow(multiplier, float64(attempt)))
+	if b.Max > 0 && (delay > b.Max || delay < 0) {
+		delay = b.Max
+	}
+
+	return delay
+}
+
+// WaitPredicate reports whether the polled object reached the expected state.
+// Returning an error stops the wait, for example on a terminal failure state.
+type WaitPredicate[T any] func(T) (bool, error)
+
+// WaitFor polls getter until predicate is satisfied, the predicate fails, the
+// backoff timeout elapses or ctx is done. It returns the last polled object
+// along with the error, if any.
+//
+// Rate limited polls (HTTP 429 after the client retries) do not stop the wait:
+// WaitFor sleeps until the rate limit window resets and polls again.
+//
+// Example:
+//
+//	invoice, err := lago.WaitFor(ctx, func(ctx context.Context) (*lago.Invoice, *lago.Error) {
+//		return client.Invoice().Get(ctx, invoiceID)
+//	}, func(invoice *lago.Invoice) (bool, error) {
+//		return invoice.FileURL != "", nil
+//	}, nil)
+func WaitFor[T any](ctx context.Context, getter func(ctx context.Context) (T, *Error), predicate WaitPredicate[T], backoff *Backoff) (T, *Error) {
+	if backoff == nil {
+		backoff = DefaultBackoff()
+	}
+	if backoff.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, backoff.Timeout)
+		defer cancel()
+	}
+
+	var last T
+	for attempt := 0; ; attempt++ {
+		value, err := getter(ctx)
+		delay := backoff.delay(attempt)
+
+		switch {
+		case err != nil && ctx.Err() != nil:
+			return last, waitTimeoutError(ctx)
+		case err != nil:
+			var rlErr *RateLimitError
+			if !errors.As(err.Err, &rlErr) {
+				return last, err
+			}
+			if rlErr.Reset != nil && time.Duration(*rlErr.Reset)*time.Second > delay {
+				delay = time.Duration(*rlErr.Reset) * time.Second
+			}
+		default:
+			last = value
+
+			done, predicateErr := predicate(value)
+			if predicateErr != nil {
+				return last, &Error{Err: predicateErr}
+			}
+			if done {
+				return last, nil
+			}
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return last, waitTimeoutError(ctx)
+		}
+	}
+}
+
+func waitTimeoutError(ctx context.Context) *Error {
+	return &Error{Err: fmt.Errorf("%w: %w", ErrWaitTimeout, ctx.Err())}
+}
+
+// WaitInvoiceFinalized waits until an invoice is finalized, for example after
+// InvoiceRequest.Finalize, Refresh or Retry.
+func WaitInvoiceFinalized(ctx context.Context, client *Client, invoiceID string, backoff *Backoff) (*Invoice, *Error) {
+	return WaitFor(ctx, func(ctx context.Context) (*Invoice, *Error) {
+		return client.Invoice().Get(ctx, invoiceID)
+	}, func(invoice *Invoice) (bool, error) {
+		switch invoice.Status {
+		case InvoiceStatusFinalized:
+			return true, nil
+		case InvoiceStatusFailed, InvoiceStatusVoided:
+			return false, fmt.Errorf("%w: invoice %s is %s", ErrWaitTerminalState, invoiceID, invoice.Status)
+		default:
+			return false, nil
+		}
+	}, backoff)
+}
+
+// WaitPaymentSettled waits until the payment of an invoice succeeds, for
+// example after InvoiceRequest.RetryPayment.
+func WaitPaymentSettled(ctx context.Context, client *Client, invoiceID string, backoff *Backoff) (*Invoice, *Error) {
+	return WaitFor(ctx, func(ctx context.Context) (*Invoice, *Error) {
+		return client.Invoice().Get(ctx, invoiceID)
+	}, func(invoice *Invoice) (bool, error) {
+		switch invoice.PaymentStatus {
+		case InvoicePaymentStatusSucceeded:
+			return true, nil
+		case InvoicePaymentStatusFailed:
+			return false, fmt.Errorf("%w: payment of invoice %s failed", ErrWaitTerminalState, invoiceID)
+		default:
+			return false, nil
+		}
+	}, backoff)
+}
+
+// WaitSubscriptionActive waits until a subscription is active, for example
+// after SubscriptionRequest.Create with a SubscriptionActivationRule.
+func WaitSubscriptionActive(ctx context.Context, client *Client, externalSubscriptionID string, backoff *Backoff) (*Subscription, *Error) {
+	return WaitFor(ctx, func(ctx context.Context) (*Subscription, *Error) {
+		return client.Subscription().Get(ctx, externalSubscriptionID)
+	}, func(subscription *Subscription) (bool, error) {
+		switch subscription.Status {
+		case SubscriptionStatusActive:
+			return true, nil
+		case SubscriptionStatusTerminated, SubscriptionStatusCanceled:
+			return false, fmt.Errorf("%w: subscription %s is %s", ErrWaitTerminalState, externalSubscriptionID, subscription.Status)
+		default:
+			return false, nil
+		}
+	}, backoff)
+}
+
+// WaitCreditNoteRefunded waits until the refund of a credit note succeeds,
+// after CreditNoteRequest.Create with a refund amount.
+func WaitCreditNoteRefunded(ctx context.Context, client *Client, creditNoteID uuid.UUID, backoff *Backoff) (*CreditNote, *Error) {
+	return WaitFor(ctx, func(ctx context.Context) (*CreditNote, *Error) {
+		return client.CreditNote().Get(ctx, creditNoteID)
+	}, func(creditNote *CreditNote) (bool, error) {
+		switch creditNote.RefundStatus {
+		case CreditNoteRefundStatusSucceeded:
+			return true, nil
+		case CreditNoteRefundStatusFailed:
+			return false, fmt.Errorf("%w: refund of credit note %s failed", ErrWaitTerminalState, creditNoteID)
+		default:
+			return false, nil
+		}
+	}, backoff)
+}
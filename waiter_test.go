@@ -0,0 +1,121 @@
+package lago_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	qt "github.com/frankban/quicktest"
+
+	. "github.com/getlago/lago-go-client"
+)
+
+var fastBackoff = &Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, Multiplier: 2, Timeout: 2 * time.Second}
+
+// sequenceServer answers each request with the next body of the sequence,
+// repeating the last one.
+func sequenceServer(bodies ...string) (*httptest.Server, *int32) {
+	var calls int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		i := int(atomic.AddInt32(&calls, 1)) - 1
+		if i >= len(bodies) {
+			i = len(bodies) - 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if bodies[i] == "429" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"status": 429, "error": "Too Many Requests"}`))
+			return
+		}
+		_, _ = w.Write([]byte(bodies[i]))
+	}))
+
+	return server, &calls
+}
+
+func invoiceBody(status, paymentStatus string) string {
+	return fmt.Sprintf(`{"invoice": {"lago_id": "1a901a90-1a90-1a90-1a90-1a901a901a90", "status": %q, "payment_status": %q}}`, status, paymentStatus)
+}
+
+func TestWaitInvoiceFinalized(t *testing.T) {
+	c := qt.New(t)
+
+	server, calls := sequenceServer(invoiceBody("draft", "pending"), invoiceBody("draft", "pending"), invoiceBody("finalized", "pending"))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	invoice, err := WaitInvoiceFinalized(context.Background(), client, "1a901a90-1a90-1a90-1a90-1a901a901a90", fastBackoff)
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(invoice.Status, qt.Equals, InvoiceStatusFinalized)
+	c.Assert(atomic.LoadInt32(calls), qt.Equals, int32(3))
+}
+
+func TestWaitInvoiceFinalized_TerminalState(t *testing.T) {
+	c := qt.New(t)
+
+	server, _ := sequenceServer(invoiceBody("draft", "pending"), invoiceBody("failed", "pending"))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	invoice, err := WaitInvoiceFinalized(context.Background(), client, "1a901a90-1a90-1a90-1a90-1a901a901a90", fastBackoff)
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(errors.Is(err.Err, ErrWaitTerminalState), qt.IsTrue)
+	c.Assert(invoice.Status, qt.Equals, InvoiceStatusFailed)
+}
+
+func TestWaitPaymentSettled_Timeout(t *testing.T) {
+	c := qt.New(t)
+
+	server, _ := sequenceServer(invoiceBody("finalized", "pending"))
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k")
+
+	backoff := *fastBackoff
+	backoff.Timeout = 30 * time.Millisecond
+
+	invoice, err := WaitPaymentSettled(context.Background(), client, "1a901a90-1a90-1a90-1a90-1a901a901a90", &backoff)
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(errors.Is(err.Err, ErrWaitTimeout), qt.IsTrue)
+	c.Assert(invoice, qt.Not(qt.IsNil))
+	c.Assert(invoice.PaymentStatus, qt.Equals, InvoicePaymentStatusPending)
+}
+
+func TestWaitSubscriptionActive_RateLimited(t *testing.T) {
+	c := qt.New(t)
+
+	server, calls := sequenceServer(
+		`{"subscription": {"external_id": "sub_1", "status": "pending"}}`,
+		"429",
+		`{"subscription": {"external_id": "sub_1", "status": "active"}}`,
+	)
+	defer server.Close()
+
+	client := New().SetBaseURL(server.URL).SetApiKey("k").SetRetryPolicy(nil)
+
+	subscription, err := WaitSubscriptionActive(context.Background(), client, "sub_1", fastBackoff)
+	c.Assert(err == nil, qt.IsTrue, qt.Commentf("err: %v", err))
+	c.Assert(subscription.Status, qt.Equals, SubscriptionStatusActive)
+	c.Assert(atomic.LoadInt32(calls), qt.Equals, int32(3))
+}
+
+func TestWaitFor_StopsOnError(t *testing.T) {
+	c := qt.New(t)
+
+	calls := 0
+	_, err := WaitFor(context.Background(), func(ctx context.Context) (int, *Error) {
+		calls++
+		return 0, &Error{HTTPStatusCode: http.StatusNotFound, Message: "Not Found"}
+	}, func(int) (bool, error) { return false, nil }, fastBackoff)
+
+	c.Assert(err, qt.Not(qt.IsNil))
+	c.Assert(err.HTTPStatusCode, qt.Equals, http.StatusNotFound)
+	c.Assert(calls, qt.Equals, 1)
+}
@@ -150,6 +150,42 @@ type Wallet struct {
 	AppliedInvoiceCustomSections     []AppliedInvoiceCustomSection      `json:"applied_invoice_custom_sections,omitempty"`
 }
 
+// Balance returns BalanceCents as Money.
+func (w *Wallet) Balance() Money {
+	return NewMoney(int64(w.BalanceCents), w.Currency)
+}
+
+// OngoingBalance returns OngoingBalanceCents as Money.
+func (w *Wallet) OngoingBalance() Money {
+	return NewMoney(int64(w.OngoingBalanceCents), w.Currency)
+}
+
+// OngoingUsageBalance returns OngoingUsageBalanceCents as Money.
+func (w *Wallet) OngoingUsageBalance() Money {
+	return NewMoney(int64(w.OngoingUsageBalanceCents), w.Currency)
+}
+
+// CreditsBalanceDecimal parses CreditsBalance as an exact Decimal.
+func (w *Wallet) CreditsBalanceDecimal() (Decimal, error) {
+	return parseOptionalDecimal(w.CreditsBalance)
+}
+
+// RateAmountDecimal parses RateAmount as an exact Decimal.
+func (w *Wallet) RateAmountDecimal() (Decimal, error) {
+	return parseOptionalDecimal(w.RateAmount)
+}
+
+// CreditsToMoney converts a number of credits to Money using the wallet rate
+// amount, rounding to the currency minor unit with the given rounding function.
+func (w *Wallet) CreditsToMoney(credits Decimal, function RoundingFunction) (Money, error) {
+	rate, err := w.RateAmountDecimal()
+	if err != nil {
+		return Money{}, err
+	}
+
+	return credits.Mul(rate).ToMoney(w.Currency, function)
+}
+
 func (c *Client) Wallet() *WalletRequest {
 	return &WalletRequest{
 		client: c,
@@ -68,6 +68,26 @@ type WalletTransactionInput struct {
 	InvoiceCustomSection             *InvoiceCustomSectionInput  `json:"invoice_custom_section,omitempty"`
 }
 
+// SetPaidCredits sets PaidCredits from an exact Decimal.
+func (wti *WalletTransactionInput) SetPaidCredits(credits Decimal) {
+	wti.PaidCredits = credits.String()
+}
+
+// SetGrantedCredits sets GrantedCredits from an exact Decimal.
+func (wti *WalletTransactionInput) SetGrantedCredits(credits Decimal) {
+	wti.GrantedCredits = credits.String()
+}
+
+// SetVoidedCredits sets VoidedCredits from an exact Decimal.
+func (wti *WalletTransactionInput) SetVoidedCredits(credits Decimal) {
+	wti.VoidedCredits = credits.String()
+}
+
+// PaidCreditsDecimal parses PaidCredits as an exact Decimal.
+func (wti *WalletTransactionInput) PaidCreditsDecimal() (Decimal, error) {
+	return parseOptionalDecimal(wti.PaidCredits)
+}
+
 type WalletTransactionMetadata struct {
 	Key   string `json:"key"`
 	Value string `json:"value"`
@@ -0,0 +1,182 @@
+// Package webhooks receives Lago webhooks during development: Listener
+// verifies and records deliveries, Store keeps them on disk, Print shows them
+// and Replay sends them again to an application handler.
+package webhooks
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	lago "github.com/getlago/lago-go-client"
+)
+
+// Headers sent by Lago with every webhook.
+const (
+	SignatureHeader          = "X-Lago-Signature"
+	SignatureAlgorithmHeader = "X-Lago-Signature-Algorithm"
+	UniqueKeyHeader          = "X-Lago-Unique-Key"
+)
+
+// MaxBodySize is the maximum size of a webhook body accepted by Listener.
+const MaxBodySize = 10 << 20
+
+// ErrInvalidSignature is recorded on deliveries whose signature does not match
+// their body.
+var ErrInvalidSignature = errors.New("webhooks: invalid signature")
+
+// Verification is the outcome of the signature check of a delivery.
+type Verification string
+
+const (
+	// Verified deliveries carry a valid signature.
+	Verified Verification = "verified"
+	// Invalid deliveries carry a missing or invalid signature.
+	Invalid Verification = "invalid"
+	// Unverified deliveries were not checked, either because the listener
+	// has no verifier or because the signature algorithm is not configured.
+	Unverified Verification = "unverified"
+)
+
+// Delivery is a webhook received by Listener.
+type Delivery struct {
+	// ID is the X-Lago-Unique-Key header, or a random UUID without it.
+	ID         string      `json:"id"`
+	ReceivedAt time.Time   `json:"received_at"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+
+	Verification Verification `json:"verification"`
+	// VerificationError explains an Invalid verification.
+	VerificationError string `json:"verification_error,omitempty"`
+}
+
+// Message parses the delivery body with lago.ParseWebhook.
+func (d *Delivery) Message() (*lago.WebhookMessage, error) {
+	return lago.ParseWebhook(d.Body)
+}
+
+// BodyVerifier checks a JWT webhook signature. *lago.WebhookRequest
+// implements it.
+type BodyVerifier interface {
+	ValidateBody(ctx context.Context, signature string, body string) (bool, *lago.Error)
+}
+
+// Listener is an http.Handler receiving Lago webhooks.
+//
+// Every delivery is recorded, including the ones with an invalid signature,
+// which are answered with 401 so that Lago retries them.
+type Listener struct {
+	// Verifier checks JWT signatures; nil skips their verification.
+	Verifier BodyVerifier
+	// HMACKey checks HMAC signatures; empty skips their verification.
+	HMACKey []byte
+	// Store, when set, saves every delivery.
+	Store *Store
+	// OnDelivery, when set, is called with every delivery once stored.
+	OnDelivery func(*Delivery)
+}
+
+func (l *Listener) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, MaxBodySize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	delivery := &Delivery{
+		ID:           r.Header.Get(UniqueKeyHeader),
+		ReceivedAt:   time.Now().UTC(),
+		Header:       r.Header.Clone(),
+		Body:         body,
+		Verification: Unverified,
+	}
+	if delivery.ID == "" {
+		delivery.ID = uuid.NewString()
+	}
+
+	if err := l.verify(r.Context(), delivery); err != nil {
+		delivery.Verification = Invalid
+		delivery.VerificationError = err.Error()
+	}
+
+	if l.Store != nil {
+		if err := l.Store.Save(delivery); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	}
+	if l.OnDelivery != nil {
+		l.OnDelivery(delivery)
+	}
+
+	if delivery.Verification == Invalid {
+		http.Error(w, delivery.VerificationError, http.StatusUnauthorized)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// verify checks the signature of a delivery, setting its verification to
+// Verified on success. It returns an error for invalid signatures and nil when
+// the signature cannot be checked.
+func (l *Listener) verify(ctx context.Context, delivery *Delivery) error {
+	signature := delivery.Header.Get(SignatureHeader)
+
+	switch lago.SignatureAlgo(delivery.Header.Get(SignatureAlgorithmHeader)) {
+	case lago.HMac:
+		if len(l.HMACKey) == 0 {
+			return nil
+		}
+		if !ValidHMAC(l.HMACKey, signature, delivery.Body) {
+			return ErrInvalidSignature
+		}
+
+	default:
+		if l.Verifier == nil {
+			return nil
+		}
+		if signature == "" {
+			return ErrInvalidSignature
+		}
+		valid, err := l.Verifier.ValidateBody(ctx, signature, string(delivery.Body))
+		if err != nil {
+			return err
+		}
+		if !valid {
+			return ErrInvalidSignature
+		}
+	}
+
+	delivery.Verification = Verified
+
+	return nil
+}
+
+// ValidHMAC reports whether signature is the base64 encoded HMAC-SHA256 of
+// body with key, as sent by webhook endpoints using the hmac algorithm.
+func ValidHMAC(key []byte, signature string, body []byte) bool {
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(body)
+
+	return hmac.Equal(expected, mac.Sum(nil))
+}
@@ -0,0 +1,191 @@
+package webhooks_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	qt "github.com/frankban/quicktest"
+	jwt "github.com/golang-jwt/jwt/v5"
+
+	lago "github.com/getlago/lago-go-client"
+	"github.com/getlago/lago-go-client/webhooks"
+)
+
+func invoiceCreated(c *qt.C) []byte {
+	body, err := os.ReadFile("../testing/fixtures/webhooks/invoice_created.json")
+	c.Assert(err, qt.IsNil)
+
+	return body
+}
+
+// lagoServer serves the webhook public key of privateKey.
+func lagoServer(c *qt.C, privateKey *rsa.PrivateKey) *lago.Client {
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	c.Assert(err, qt.IsNil)
+	publicKey := base64.StdEncoding.EncodeToString(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.Check(r.URL.Path, qt.Equals, "/api/v1/webhooks/public_key")
+		w.Write([]byte(publicKey))
+	}))
+	c.Cleanup(server.Close)
+
+	return lago.New().SetBaseURL(server.URL).SetApiKey("k")
+}
+
+func post(c *qt.C, handler http.Handler, body []byte, header map[string]string) *http.Response {
+	request := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(body))
+	for name, value := range header {
+		request.Header.Set(name, value)
+	}
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	return recorder.Result()
+}
+
+func TestListener_JWT(t *testing.T) {
+	c := qt.New(t)
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	c.Assert(err, qt.IsNil)
+
+	body := invoiceCreated(c)
+	signature, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"data": string(body), "iss": "lago"}).SignedString(privateKey)
+	c.Assert(err, qt.IsNil)
+
+	store := webhooks.NewStore(c.TempDir())
+	var received []*webhooks.Delivery
+	listener := &webhooks.Listener{
+		Verifier:   lagoServer(c, privateKey).Webhook(),
+		Store:      store,
+		OnDelivery: func(d *webhooks.Delivery) { received = append(received, d) },
+	}
+
+	response := post(c, listener, body, map[string]string{
+		webhooks.SignatureHeader:          signature,
+		webhooks.SignatureAlgorithmHeader: "jwt",
+		webhooks.UniqueKeyHeader:          "delivery-1",
+	})
+	c.Assert(response.StatusCode, qt.Equals, http.StatusOK)
+
+	// The signature does not match a modified body.
+	tampered := bytes.Replace(body, []byte(`"total_amount_cents": 120`), []byte(`"total_amount_cents": 1`), 1)
+	c.Assert(tampered, qt.Not(qt.DeepEquals), body)
+	response = post(c, listener, tampered, map[string]string{
+		webhooks.SignatureHeader:          signature,
+		webhooks.SignatureAlgorithmHeader: "jwt",
+		webhooks.UniqueKeyHeader:          "delivery-2",
+	})
+	c.Assert(response.StatusCode, qt.Equals, http.StatusUnauthorized)
+
+	c.Assert(received, qt.HasLen, 2)
+	c.Assert(received[0].Verification, qt.Equals, webhooks.Verified)
+	c.Assert(received[1].Verification, qt.Equals, webhooks.Invalid)
+
+	stored, err := store.List()
+	c.Assert(err, qt.IsNil)
+	c.Assert(stored, qt.HasLen, 2)
+
+	delivery, err := store.Get("delivery-1")
+	c.Assert(err, qt.IsNil)
+	c.Assert(delivery.Body, qt.DeepEquals, body)
+	c.Assert(delivery.Header.Get(webhooks.SignatureHeader), qt.Equals, signature)
+
+	message, err := delivery.Message()
+	c.Assert(err, qt.IsNil)
+	c.Assert(message.Object.(*lago.Invoice).Number, qt.Equals, "LAG-1234-001-002")
+}
+
+func TestListener_HMAC(t *testing.T) {
+	c := qt.New(t)
+
+	body := invoiceCreated(c)
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(body)
+	signature := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	listener := &webhooks.Listener{HMACKey: []byte("secret")}
+
+	response := post(c, listener, body, map[string]string{
+		webhooks.SignatureHeader:          signature,
+		webhooks.SignatureAlgorithmHeader: "hmac",
+	})
+	c.Assert(response.StatusCode, qt.Equals, http.StatusOK)
+
+	response = post(c, listener, body, map[string]string{
+		webhooks.SignatureHeader:          base64.StdEncoding.EncodeToString([]byte("forged")),
+		webhooks.SignatureAlgorithmHeader: "hmac",
+	})
+	c.Assert(response.StatusCode, qt.Equals, http.StatusUnauthorized)
+}
+
+func TestStore_GetByPrefix(t *testing.T) {
+	c := qt.New(t)
+
+	store := webhooks.NewStore(c.TempDir())
+	c.Assert(store.Save(&webhooks.Delivery{ID: "3f1c0000-aaaa"}), qt.IsNil)
+	c.Assert(store.Save(&webhooks.Delivery{ID: "3f2d0000-bbbb"}), qt.IsNil)
+
+	delivery, err := store.Get("3f1c")
+	c.Assert(err, qt.IsNil)
+	c.Assert(delivery.ID, qt.Equals, "3f1c0000-aaaa")
+
+	_, err = store.Get("3f")
+	c.Assert(err, qt.ErrorMatches, "webhooks: ambiguous delivery ID 3f")
+
+	_, err = store.Get("../3f1c0000-aaaa")
+	c.Assert(err, qt.ErrorIs, webhooks.ErrDeliveryNotFound)
+}
+
+func TestPrintAndReplay(t *testing.T) {
+	c := qt.New(t)
+
+	delivery := &webhooks.Delivery{
+		ID:           "delivery-1",
+		Header:       http.Header{"X-Lago-Signature": {"sig"}, "X-Lago-Unique-Key": {"delivery-1"}},
+		Body:         invoiceCreated(c),
+		Verification: webhooks.Verified,
+	}
+
+	var out bytes.Buffer
+	c.Assert(webhooks.Print(&out, delivery), qt.IsNil)
+	c.Assert(out.String(), qt.Contains, "invoice.created [verified] delivery-1")
+	c.Assert(out.String(), qt.Contains, `"number": "LAG-1234-001-002"`)
+
+	var replayed *http.Request
+	var replayedBody []byte
+	app := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replayed = r
+		replayedBody, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	c.Cleanup(app.Close)
+
+	response, err := webhooks.Replay(context.Background(), nil, app.URL+"/webhooks", delivery)
+	c.Assert(err, qt.IsNil)
+	response.Body.Close()
+	c.Assert(response.StatusCode, qt.Equals, http.StatusNoContent)
+	c.Assert(replayed.URL.Path, qt.Equals, "/webhooks")
+	c.Assert(replayed.Header.Get(webhooks.SignatureHeader), qt.Equals, "sig")
+	c.Assert(replayed.Header.Get("Content-Type"), qt.Equals, "application/json")
+	c.Assert(replayedBody, qt.DeepEquals, delivery.Body)
+
+	out.Reset()
+	delivery.Body = []byte(`{"webhook_type": "unknown.event", "object_type": "unknown"}`)
+	c.Assert(webhooks.Print(&out, delivery), qt.IsNil)
+	c.Assert(strings.Contains(out.String(), "cannot parse webhook: unknown object_type: unknown"), qt.IsTrue)
+}
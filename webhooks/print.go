@@ -0,0 +1,53 @@
+package webhooks
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Print writes a human readable description of a delivery: a summary line,
+// then the object parsed by lago.ParseWebhook. Bodies that cannot be parsed
+// are printed as received, with the parsing error.
+//
+//	2026-03-01T10:00:00Z invoice.created [verified] 3f1c...
+//	invoice {
+//	  "lago_id": "...",
+//	  ...
+//	}
+func Print(w io.Writer, delivery *Delivery) error {
+	message, parseErr := delivery.Message()
+
+	webhookType := "unknown"
+	if message != nil {
+		webhookType = message.WebhookType
+	}
+	if _, err := fmt.Fprintf(w, "%s %s [%s] %s\n", delivery.ReceivedAt.Format(time.RFC3339), webhookType, delivery.Verification, delivery.ID); err != nil {
+		return err
+	}
+	if delivery.VerificationError != "" {
+		if _, err := fmt.Fprintf(w, "signature: %s\n", delivery.VerificationError); err != nil {
+			return err
+		}
+	}
+
+	if parseErr != nil {
+		var body bytes.Buffer
+		if json.Indent(&body, delivery.Body, "", "  ") != nil {
+			body.Reset()
+			body.Write(delivery.Body)
+		}
+		_, err := fmt.Fprintf(w, "cannot parse webhook: %s\n%s\n", parseErr, body.Bytes())
+		return err
+	}
+
+	object, err := json.MarshalIndent(message.Object, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s %s\n", message.ObjectType, object)
+
+	return err
+}
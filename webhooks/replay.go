@@ -0,0 +1,40 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// replayedHeaders are the delivery headers sent again by Replay, so that the
+// application handler can verify the original signature.
+var replayedHeaders = []string{
+	"Content-Type",
+	SignatureHeader,
+	SignatureAlgorithmHeader,
+	UniqueKeyHeader,
+}
+
+// Replay POSTs a stored delivery to url with its original body and Lago
+// headers, and returns the response of the application handler. The caller
+// must close the response body.
+func Replay(ctx context.Context, client *http.Client, url string, delivery *Delivery) (*http.Response, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(delivery.Body))
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range replayedHeaders {
+		if value := delivery.Header.Get(name); value != "" {
+			request.Header.Set(name, value)
+		}
+	}
+	if request.Header.Get("Content-Type") == "" {
+		request.Header.Set("Content-Type", "application/json")
+	}
+
+	return client.Do(request)
+}
@@ -0,0 +1,119 @@
+package webhooks
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrDeliveryNotFound is returned by Store.Get for unknown deliveries.
+var ErrDeliveryNotFound = errors.New("webhooks: delivery not found")
+
+// Store keeps deliveries as JSON files in a directory, one file per delivery.
+type Store struct {
+	dir string
+}
+
+// NewStore returns a store writing to dir, created on the first save.
+func NewStore(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Dir returns the directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Save writes a delivery, replacing a previous delivery with the same ID.
+func (s *Store) Save(delivery *Delivery) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(delivery, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(s.path(delivery.ID), data, 0o600)
+}
+
+// Get reads a delivery. The ID may be abbreviated to any unambiguous prefix.
+func (s *Store) Get(id string) (*Delivery, error) {
+	if id == "" {
+		return nil, ErrDeliveryNotFound
+	}
+	if delivery, err := s.read(s.path(id)); !errors.Is(err, fs.ErrNotExist) {
+		return delivery, err
+	}
+
+	matches, err := filepath.Glob(filepath.Join(s.dir, strings.TrimSuffix(fileName(id), ".json")+"*.json"))
+	if err != nil {
+		return nil, err
+	}
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("%w: %s", ErrDeliveryNotFound, id)
+	case 1:
+		return s.read(matches[0])
+	default:
+		return nil, fmt.Errorf("webhooks: ambiguous delivery ID %s", id)
+	}
+}
+
+// List reads every delivery, oldest first.
+func (s *Store) List() ([]*Delivery, error) {
+	matches, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*Delivery, 0, len(matches))
+	for _, match := range matches {
+		delivery, err := s.read(match)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].ReceivedAt.Before(deliveries[j].ReceivedAt)
+	})
+
+	return deliveries, nil
+}
+
+func (s *Store) read(path string) (*Delivery, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	delivery := &Delivery{}
+	if err := json.Unmarshal(data, delivery); err != nil {
+		return nil, fmt.Errorf("webhooks: invalid delivery %s: %w", path, err)
+	}
+
+	return delivery, nil
+}
+
+func (s *Store) path(id string) string {
+	return filepath.Join(s.dir, fileName(id))
+}
+
+// fileName keeps IDs coming from request headers inside the store directory.
+func fileName(id string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, id) + ".json"
+}